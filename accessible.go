@@ -0,0 +1,84 @@
+package rss
+
+import (
+	"strings"
+	"time"
+)
+
+// accessibleDateLayout spells out dates, rather than giving them as numbers
+// separated by slashes.
+const accessibleDateLayout = "2 January 2006"
+
+// Accessible writes items for screen readers and braille displays: one line to
+// an item with each part labelled, and no colour codes, tabs or symbols e.g.
+//
+//	Title: Go 1.22 is released. Published: 6 February 2024. Link: https://go.dev/blog/go1.22
+func Accessible() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		item.accessible = true
+		return item
+	}
+}
+
+func formatAccessible(fi FeedItem, settings *formatSettings) string {
+	var parts []string
+	if len(fi.Links) == 0 && len(fi.Title) != 0 {
+		// Title cards head the items of a feed
+		parts = append(parts, sentence("Feed: "+fi.Title))
+		if !fi.updated.IsZero() {
+			since := settings.now.Sub(fi.updated)
+			if since < time.Minute {
+				parts = append(parts, "Updated just now.")
+			} else {
+				parts = append(parts, sentence("Updated "+longDuration(since)+" ago"))
+			}
+		}
+	} else {
+		parts = append(parts, sentence("Title: "+fi.Title))
+	}
+	if !fi.PublishTime.IsZero() {
+		layout := accessibleDateLayout
+		if fi.dateLayout != "" {
+			layout = fi.dateLayout
+		}
+		parts = append(parts, sentence("Published: "+fi.PublishTime.Format(layout)))
+	}
+	if len(fi.Tags) > 0 {
+		parts = append(parts, sentence("Tags: "+strings.Join(fi.Tags, ", ")))
+	}
+	if fi.Note != "" {
+		parts = append(parts, sentence("Note: "+fi.Note))
+	}
+	if settings.includeLinks {
+		// Links aren't followed by full stops, which could be taken to be
+		// part of them
+		for i, link := range fi.Links {
+			label := "Link: "
+			if i > 0 {
+				label = "Comments: "
+			}
+			parts = append(parts, label+link)
+		}
+	}
+	return strings.Join(parts, " ") + "\n"
+}
+
+// sentence ends the text with a full stop, unless it already ends a sentence.
+func sentence(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!") {
+		return text
+	}
+	return text + "."
+}
+
+// longDuration gives the duration in words e.g. "3 hours".
+func longDuration(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return plural(int(d.Minutes()), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d.Hours()), "hour")
+	}
+	return plural(int(d.Hours()/24), "day")
+}
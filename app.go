@@ -4,15 +4,52 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
+const (
+	// starMarker is shown before the titles of items starred in the app.
+	starMarker = "[yellow]*[white] "
+	// queuedMarker is shown before the titles of items added to the queue.
+	queuedMarker = "[blue]+[white] "
+	// doneMarker is shown before the titles of items passed to OnOpen, e.g.
+	// those popped from the queue.
+	doneMarker = "[green]✓[white] "
+)
+
 type appOptions struct {
-	display []DisplayOption
-	filters []Filter
+	order         []string
+	display       []DisplayOption
+	filters       []Filter
+	history       io.Writer
+	stars         io.Writer
+	tags          io.Writer
+	queue         io.Writer
+	onOpen        func(FeedItem) error
+	onArrival     func([]FeedItem)
+	noBrowser     bool
+	description   func(FeedItem) ([]byte, error)
+	whenArrived   bool
+	articles      *Articles
+	prefetch      int
+	prefetchOnly  Filter
+	saveToWayback bool
+	onExit        func([]FeedItem)
+	watch         []string
+	reload        func() ([]string, <-chan *Feed, error)
+	screen        tcell.Screen
+	theme         Theme
+	spacedRows    bool
+	noPreview     bool
+	layout        Layout
+	opener        Opener
+	errs          *ErrorLog
 }
 
 type AppOption func(*appOptions)
@@ -29,157 +66,1208 @@ func WithFilters(filters ...Filter) AppOption {
 	}
 }
 
-func RunApp(feeds <-chan *Feed, mode DisplayMode, opts ...AppOption) error {
-	app := tview.NewApplication()
-	list := tview.NewList()
-	list.ShowSecondaryText(false)
+// WithFeedOrder shows the items from feeds in the order of the given URLs,
+// regardless of the order in which the feeds arrive. Feeds not in the list
+// come last.
+func WithFeedOrder(urls []string) AppOption {
+	return func(ao *appOptions) {
+		ao.order = urls
+	}
+}
 
-	textView := tview.NewTextView().SetDynamicColors(true)
-	textView.SetChangedFunc(func() {
-		app.Draw()
-	})
-	listFlex := tview.NewFlex()
-	listFlex.AddItem(list, 0, 1, true)
-	listFlex.SetBorder(true)
+// WithHistory records each item opened in the app to w.
+func WithHistory(w io.Writer) AppOption {
+	return func(ao *appOptions) {
+		ao.history = w
+	}
+}
+
+// WithStars allows items to be starred in the app, recording them to w. If
+// saveToWayback is true, starred items are also saved to the Wayback Machine
+// in the background and the result recorded.
+func WithStars(w io.Writer, saveToWayback bool) AppOption {
+	return func(ao *appOptions) {
+		ao.stars = w
+		ao.saveToWayback = saveToWayback
+	}
+}
+
+// WithTags allows tags to be added to and removed from items in the app,
+// recording them to w.
+func WithTags(w io.Writer) AppOption {
+	return func(ao *appOptions) {
+		ao.tags = w
+	}
+}
+
+// WithQueue allows items to be added to the reading queue in the app with 'q',
+// recording them to w.
+func WithQueue(w io.Writer) AppOption {
+	return func(ao *appOptions) {
+		ao.queue = w
+	}
+}
+
+// OnOpen calls fn with each item opened in the app, e.g. to pop it from the
+// reading queue, marking the item as done unless fn fails.
+func OnOpen(fn func(FeedItem) error) AppOption {
+	return func(ao *appOptions) {
+		ao.onOpen = fn
+	}
+}
+
+// OnArrival calls fn with the items of each feed as it arrives, once they have
+// been filtered, e.g. to notify about them. It is called outside of the app's
+// goroutine.
+func OnArrival(fn func([]FeedItem)) AppOption {
+	return func(ao *appOptions) {
+		ao.onArrival = fn
+	}
+}
+
+// WithoutBrowser shows the description of items when they are opened instead
+// of fetching their pages with the browser, which isn't started.
+func WithoutBrowser() AppOption {
+	return func(ao *appOptions) {
+		ao.noBrowser = true
+	}
+}
+
+// WithStoredDescriptions reads the descriptions of items shown without the
+// browser with load when they are opened, rather than keeping them from the
+// feeds, which is useful when descriptions were dropped while fetching.
+func WithStoredDescriptions(load func(FeedItem) ([]byte, error)) AppOption {
+	return func(ao *appOptions) {
+		ao.description = load
+	}
+}
 
-	textFlex := tview.NewFlex()
-	textFlex.AddItem(textView, 0, 1, false)
-	textFlex.SetBorder(true)
+// ShowWhenArrived shows the items once every feed has arrived rather than as
+// each of them does, passing them to the display mode all at once. Modes which
+// limit the items in the order they show them, e.g. the highest scored, need
+// this to see every item.
+func ShowWhenArrived() AppOption {
+	return func(ao *appOptions) {
+		ao.whenArrived = true
+	}
+}
+
+// WithPrefetch keeps the pages opened in articles so that they can be read
+// again offline. Once every feed has arrived, the pages of the first n items
+// passing the filter, e.g. those which are unread, are fetched in the
+// background so that they open instantly.
+func WithPrefetch(articles *Articles, n int, filter Filter) AppOption {
+	return func(ao *appOptions) {
+		ao.articles = articles
+		ao.prefetch = n
+		ao.prefetchOnly = filter
+	}
+}
+
+// WithReload watches the files, e.g. the feeds file and config, and offers to
+// reload the feeds with 'r' when they change. reload returns the URLs of the
+// feeds to show instead, in order, and the feeds as they arrive.
+func WithReload(files []string, reload func() ([]string, <-chan *Feed, error)) AppOption {
+	return func(ao *appOptions) {
+		ao.watch = files
+		ao.reload = reload
+	}
+}
+
+// WithScreen runs the app on the screen instead of the terminal, e.g. a
+// tcell.SimulationScreen so that the app can be driven in tests. The screen
+// must already be initialised.
+func WithScreen(screen tcell.Screen) AppOption {
+	return func(ao *appOptions) {
+		ao.screen = screen
+	}
+}
+
+// WithTheme colours the app with the theme instead of DefaultTheme.
+func WithTheme(theme Theme) AppOption {
+	return func(ao *appOptions) {
+		ao.theme = theme
+	}
+}
 
-	listFlex.SetBorderColor(tcell.ColorGreen)
-	textFlex.SetBorderColor(tcell.ColorGray)
+// WithSpacedRows leaves a blank line below each item in the list, so that
+// they are easier to tell apart.
+func WithSpacedRows() AppOption {
+	return func(ao *appOptions) {
+		ao.spacedRows = true
+	}
+}
 
-	flex := tview.NewFlex()
-	flex.AddItem(listFlex, 0, 1, true)
-	flex.AddItem(textFlex, 0, 1, false)
+// WithoutPreview gives the whole screen to the list, showing the right pane in
+// its place while an item is open.
+func WithoutPreview() AppOption {
+	return func(ao *appOptions) {
+		ao.noPreview = true
+	}
+}
 
-	options := &appOptions{}
+// WithLayout arranges the panes with the layout, which can be resized with '<'
+// and '>'.
+func WithLayout(layout Layout) AppOption {
+	return func(ao *appOptions) {
+		ao.layout = layout
+		if layout.ListOnly {
+			ao.noPreview = true
+		}
+	}
+}
+
+// WithOpener opens items with 'o' using the opener, rather than the desktop's
+// handler for links.
+func WithOpener(opener Opener) AppOption {
+	return func(ao *appOptions) {
+		ao.opener = opener
+	}
+}
+
+// WithErrors shows the errors added to the log, e.g. by scripts and plugins,
+// below the panes while the app is running.
+func WithErrors(log *ErrorLog) AppOption {
+	return func(ao *appOptions) {
+		ao.errs = log
+	}
+}
+
+// OnExit allows the app to be quit with 'e', after which fn is called with the
+// items in the list, e.g. to print them for other commands to use.
+func OnExit(fn func([]FeedItem)) AppOption {
+	return func(ao *appOptions) {
+		ao.onExit = fn
+	}
+}
+
+// RunApp shows the feeds as they arrive until the app is quit. It returns once
+// the stars being saved to the Wayback Machine have been written, so that the
+// writers can then be closed.
+func RunApp(feeds <-chan *Feed, mode DisplayMode, opts ...AppOption) error {
+	options := &appOptions{theme: DefaultTheme}
 
 	for _, o := range opts {
 		o(options)
 	}
+	v := newAppView(options, mode)
+	options.errs.Watch(func(err error) {
+		v.app.QueueUpdateDraw(func() {
+			v.showError(err)
+		})
+	})
+	// Stars are written again once they have been saved
+	defer v.saving.Wait()
+	// Errors after the app has stopped are kept for the caller
+	defer options.errs.Watch(nil)
 
+	// arrived is closed once every feed has arrived
+	arrived := make(chan struct{})
 	go func() {
-		var i int
-		for feed := range feeds {
-			if feed == nil {
-				continue
-			}
-			currentPosition := list.GetCurrentItem()
-			feedItems := UnpackFeed(feed, options.filters...)
-			items := make([]FeedItem, 0, len(feedItems))
-			for _, item := range mode(feedItems) {
-				for _, o := range options.display {
-					item = o(item)
-				}
-				items = append(items, item)
-			}
+		v.receive(feeds, 0)
+		// Queued after the feeds so that they have all been shown
+		v.app.QueueUpdate(func() {
+			close(arrived)
+		})
+	}()
 
-			for _, item := range items {
-				link := ""
-				if len(item.Links) > 0 {
-					link = item.Links[0]
-				}
-				list.InsertItem(i, formatFeedInteractive(item), link, 0, nil)
-				i++
+	if options.reload != nil && len(options.watch) > 0 {
+		stop, err := Watch(options.watch, func(file string) {
+			v.app.QueueUpdateDraw(func() {
+				v.notice = fmt.Sprintf("[yellow]%s has changed, press r to reload the feeds[white]", filepath.Base(file))
+			})
+		})
+		if err != nil {
+			return err
+		}
+		defer stop()
+	}
+
+	if !options.noBrowser {
+		v.ready.Add(1)
+		go func() {
+			defer v.ready.Done()
+			var err error
+			v.browser, err = NewBrowser()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, err.Error())
+				os.Exit(1)
 			}
-			app.Draw()
-			// Keep the cursor where it was
-			list = list.SetCurrentItem(currentPosition)
+		}()
+	}
+
+	if options.articles != nil && options.prefetch > 0 && !options.noBrowser {
+		// The pages of the first items shown are fetched into the articles
+		go func() {
+			<-arrived
+			v.prefetch()
+		}()
+	}
+
+	v.app.SetRoot(v.root, true)
+	if options.screen != nil {
+		v.app.SetScreen(options.screen)
+	}
+	err := v.app.Run()
+	if err != nil || !v.exiting {
+		return err
+	}
+	// The items are passed on once the app has released the terminal
+	v.shownMu.Lock()
+	items := append([]FeedItem(nil), v.shown...)
+	v.shownMu.Unlock()
+	options.onExit(items)
+	return nil
+}
+
+// appView holds the panes of the app run by RunApp and what they show.
+type appView struct {
+	options *appOptions
+	mode    DisplayMode
+	theme   Theme
+
+	app       *tview.Application
+	list      *tview.List
+	textView  *tview.TextView
+	linksList *tview.List
+	listFlex  *tview.Flex
+	textFlex  *tview.Flex
+	flex      *tview.Flex
+	// root holds the footer below the panes, or the input in its place
+	// while the user is prompted
+	root   *tview.Flex
+	input  *tview.InputField
+	footer *tview.TextView
+	// notice is shown in the footer in place of the keys, e.g. when the
+	// watched files change, and failure in place of both until the next key
+	// is pressed
+	notice  string
+	failure string
+	layout  Layout
+	// fullScreen gives the whole screen to the pane while reading
+	fullScreen bool
+
+	// shownMu guards the items shown and the feeds they came from, which
+	// change as feeds arrive
+	shownMu sync.Mutex
+	// shown holds the items in the same order as the list
+	shown []FeedItem
+	// counts holds the number of items shown from the feeds at each rank in
+	// the feed order
+	counts map[int]int
+	order  []string
+	// channels holds the feeds by the source and channel of their items, for
+	// showing what they say about themselves
+	channels map[string]*Feed
+	// generation is incremented when the feeds are reloaded, so that feeds
+	// still arriving from before are dropped
+	generation int
+
+	// descriptions holds the descriptions of items by ID, which are shown
+	// instead of their pages when the browser isn't used
+	descriptionsMu sync.Mutex
+	descriptions   map[string][]byte
+
+	// browser is started in the background, and ready waits for it
+	browser *Browser
+	ready   sync.WaitGroup
+
+	// canonical holds the canonical URLs of the pages which have been opened,
+	// keyed by their link
+	canonicalMu sync.Mutex
+	canonical   map[string]string
+
+	// reading is the link of the article in the right pane, and offsets the
+	// rows scrolled to in those read before, so that going back to one
+	// carries on from where it was left
+	reading string
+	offsets map[string]int
+	// readingItem is the item of the article, and readingLinks the links in
+	// its text
+	readingItem  FeedItem
+	readingLinks []PageLink
+
+	// searched is the text of the right pane before the matches of a search
+	// were marked in it, matches the number of them and match the one
+	// highlighted
+	searched       string
+	matches, match int
+
+	// showingLinks is whether the links of the article are listed in place
+	// of its text, and textTitle the title of the pane before they were
+	showingLinks bool
+	textTitle    string
+
+	// Stars are written from background goroutines when saving to the
+	// wayback machine, which RunApp waits on
+	starsMu sync.Mutex
+	saving  sync.WaitGroup
+	exiting bool
+}
+
+// newAppView lays out the panes of the app.
+func newAppView(options *appOptions, mode DisplayMode) *appView {
+	theme := options.theme
+	v := &appView{
+		options:      options,
+		mode:         mode,
+		theme:        theme,
+		app:          tview.NewApplication(),
+		list:         tview.NewList(),
+		textView:     tview.NewTextView().SetDynamicColors(true),
+		linksList:    tview.NewList(),
+		listFlex:     tview.NewFlex(),
+		textFlex:     tview.NewFlex(),
+		flex:         tview.NewFlex(),
+		root:         tview.NewFlex().SetDirection(tview.FlexRow),
+		input:        tview.NewInputField(),
+		footer:       tview.NewTextView().SetDynamicColors(true),
+		layout:       options.layout,
+		counts:       make(map[int]int),
+		order:        options.order,
+		channels:     make(map[string]*Feed),
+		descriptions: make(map[string][]byte),
+		canonical:    make(map[string]string),
+		offsets:      make(map[string]int),
+	}
+
+	textStyle := tcell.StyleDefault.Foreground(theme.Text).Background(theme.Background).Bold(theme.Bold)
+	v.list.SetMainTextStyle(textStyle)
+	v.list.SetSelectedStyle(textStyle.Foreground(theme.SelectedText).Background(theme.SelectedBackground))
+	v.list.SetBackgroundColor(theme.Background)
+	// Items have no secondary text, so showing it leaves a blank line below
+	// each of them
+	v.list.ShowSecondaryText(options.spacedRows)
+	v.list.SetHighlightFullLine(true)
+	v.list.SetSelectedFunc(func(i int, main, secondary string, r rune) {
+		v.read(i)
+	})
+
+	v.textView.SetTextColor(theme.Text)
+	v.textView.SetBackgroundColor(theme.Background)
+	v.textView.SetChangedFunc(func() {
+		v.app.Draw()
+	})
+	v.textView.SetDoneFunc(func(key tcell.Key) {
+		v.app.SetFocus(v.list)
+		v.toggleBorder()
+	})
+	// linksList lists the links in the article in place of its text
+	v.linksList.SetMainTextStyle(textStyle)
+	v.linksList.SetSelectedStyle(textStyle.Foreground(theme.SelectedText).Background(theme.SelectedBackground))
+	v.linksList.SetSecondaryTextColor(theme.Text)
+	v.linksList.SetBackgroundColor(theme.Background)
+	v.linksList.SetHighlightFullLine(true)
+	v.linksList.SetDoneFunc(func() {
+		v.closeLinks()
+		v.app.SetFocus(v.textView)
+	})
+	v.linksList.SetSelectedFunc(func(i int, main, secondary string, r rune) {
+		v.openLink(i)
+	})
+
+	v.listFlex.AddItem(v.list, 0, 1, true)
+	v.listFlex.SetBorder(true)
+	v.listFlex.SetBackgroundColor(theme.Background)
+	v.listFlex.SetBorderColor(theme.FocusedBorder)
+	v.textFlex.AddItem(v.textView, 0, 1, false)
+	v.textFlex.SetBorder(true)
+	v.textFlex.SetBackgroundColor(theme.Background)
+	v.textFlex.SetBorderColor(theme.Border)
+
+	v.layout.ListShare = v.layout.resize(0)
+	if v.layout.Vertical {
+		v.flex.SetDirection(tview.FlexRow)
+	}
+	v.flex.AddItem(v.listFlex, 0, v.layout.ListShare, true)
+	if !options.noPreview {
+		v.flex.AddItem(v.textFlex, 0, 100-v.layout.ListShare, false)
+	}
+
+	v.footer.SetTextColor(theme.Border)
+	v.footer.SetBackgroundColor(theme.Background)
+	v.root.AddItem(v.flex, 0, 1, true)
+	v.root.AddItem(v.footer, 1, 0, false)
+	// The footer follows the focus, so it is filled in just before drawing
+	v.app.SetBeforeDrawFunc(func(tcell.Screen) bool {
+		text := v.failure
+		if text == "" {
+			text = v.notice
 		}
-	}()
+		if text == "" {
+			text = v.keys()
+		}
+		v.footer.SetText(text)
+		return false
+	})
+	v.app.SetInputCapture(v.handleKey)
+	return v
+}
+
+// showError shows the error in the footer, since the terminal belongs to the
+// app while it runs.
+func (v *appView) showError(err error) {
+	v.failure = "[red]" + tview.Escape(err.Error()) + "[white]"
+}
 
-	toggleBorder := func(ps ...*tview.Box) {
-		if listFlex.HasFocus() {
-			listFlex.SetBorderColor(tcell.ColorGreen)
-			textFlex.SetBorderColor(tcell.ColorGray)
-			return
+// keys returns the keys of the pane with focus, for the footer. It is called
+// while drawing, when the app can't be asked what has focus.
+func (v *appView) keys() string {
+	var keys []string
+	switch {
+	case v.list.HasFocus():
+		keys = append(keys, "enter read", "o open")
+		if v.options.stars != nil {
+			keys = append(keys, "s star", "S star with note")
+		}
+		if v.options.queue != nil {
+			keys = append(keys, "q queue")
+		}
+		if v.options.tags != nil {
+			keys = append(keys, "t tag")
+		}
+		keys = append(keys, "i info")
+		if v.options.reload != nil {
+			keys = append(keys, "r reload")
+		}
+		if v.options.onExit != nil {
+			keys = append(keys, "e exit and print")
+		}
+		keys = append(keys, "< > resize")
+	case v.textView.HasFocus():
+		keys = append(keys, "/ search")
+		if v.matches > 0 {
+			keys = append(keys, "n N next and previous match")
+		}
+		keys = append(keys, "L links", "f full screen", "esc back")
+	case v.linksList.HasFocus():
+		keys = append(keys, "enter open")
+		if v.options.queue != nil {
+			keys = append(keys, "q queue")
 		}
-		textFlex.SetBorderColor(tcell.ColorGreen)
-		listFlex.SetBorderColor(tcell.ColorGray)
+		keys = append(keys, "esc back")
 	}
-	textView.SetDoneFunc(func(key tcell.Key) {
-		app.SetFocus(list)
-		toggleBorder()
-	})
+	return strings.Join(keys, "  ")
+}
 
-	list.SetHighlightFullLine(true)
+// resize shares the screen between the panes as the layout says, or gives it
+// all to the pane while reading full screen.
+func (v *appView) resize() {
+	if v.options.noPreview {
+		return
+	}
+	if v.fullScreen {
+		v.flex.ResizeItem(v.listFlex, 0, 0)
+		v.flex.ResizeItem(v.textFlex, 0, 1)
+		return
+	}
+	v.flex.ResizeItem(v.listFlex, 0, v.layout.ListShare)
+	v.flex.ResizeItem(v.textFlex, 0, 100-v.layout.ListShare)
+}
 
-	var b *Browser
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		var err error
-		b, err = NewBrowser()
+// format writes the item for the list, in colour unless the theme is
+// monochrome.
+func (v *appView) format(item FeedItem) string {
+	if v.theme.Monochrome {
+		return FormatItem(item, NoColours())
+	}
+	return formatFeedInteractive(item)
+}
+
+// displayed returns the item as the display options show it.
+func (v *appView) displayed(item FeedItem) FeedItem {
+	for _, o := range v.options.display {
+		item = o(item)
+	}
+	return item
+}
+
+func channelKey(source, channel string) string {
+	return source + "\n" + channel
+}
+
+// rankOf returns the position of the feed in the feed order, after the others
+// if it isn't in it.
+func (v *appView) rankOf(url string) int {
+	for rank, u := range v.order {
+		if u == url {
+			return rank
+		}
+	}
+	return len(v.order)
+}
+
+// item returns the item shown at i in the list, if there is one.
+func (v *appView) item(i int) (FeedItem, bool) {
+	v.shownMu.Lock()
+	defer v.shownMu.Unlock()
+	if i >= len(v.shown) {
+		return FeedItem{}, false
+	}
+	return v.shown[i], true
+}
+
+// receive shows the feeds as they arrive, or once they have all arrived if the
+// options say so, until they are reloaded.
+func (v *appView) receive(feeds <-chan *Feed, gen int) {
+	var arrived []*Feed
+	var arrivedItems []FeedItem
+	for feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		v.shownMu.Lock()
+		stale := gen != v.generation
+		v.shownMu.Unlock()
+		if stale {
+			continue
+		}
+		if v.options.noBrowser && v.options.description == nil {
+			v.keepDescriptions(feed)
+		}
+		feedItems := UnpackFeed(feed, v.options.filters...)
+		if v.options.onArrival != nil {
+			v.options.onArrival(feedItems)
+		}
+		if v.options.whenArrived {
+			arrived = append(arrived, feed)
+			arrivedItems = append(arrivedItems, feedItems...)
+			continue
+		}
+		feedItems = v.mode(feedItems)
+		// The list is only changed by the app's own goroutine
+		feed := feed
+		v.app.QueueUpdateDraw(func() {
+			v.show(feed, feedItems, gen)
+		})
+	}
+	if v.options.whenArrived {
+		feedItems := v.mode(arrivedItems)
+		v.app.QueueUpdateDraw(func() {
+			v.showAll(arrived, feedItems, gen)
+		})
+	}
+}
+
+// keepDescriptions keeps the descriptions of the feed's items, to be shown
+// when they are opened.
+func (v *appView) keepDescriptions(feed *Feed) {
+	newFeedItem := newFeedItemCreator(feed)
+	v.descriptionsMu.Lock()
+	defer v.descriptionsMu.Unlock()
+	for _, item := range feed.Channel.Items {
+		feedItem, err := newFeedItem(item)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, err.Error())
-			os.Exit(1)
+			continue
 		}
-	}()
+		if len(item.Content) > 0 {
+			v.descriptions[feedItem.ID] = item.Content
+		} else {
+			v.descriptions[feedItem.ID] = item.Description
+		}
+	}
+}
+
+// show inserts the items of the feed into the list after those of the feeds
+// which come before it, unless the feeds have been reloaded since.
+func (v *appView) show(feed *Feed, feedItems []FeedItem, gen int) {
+	v.shownMu.Lock()
+	defer v.shownMu.Unlock()
+	if gen != v.generation {
+		return
+	}
+	currentPosition := v.list.GetCurrentItem()
+	v.channels[channelKey(feed.source(), feed.Channel.Title)] = feed
+	rank := v.rankOf(feed.URL)
+	var i int
+	for r, count := range v.counts {
+		if r <= rank {
+			i += count
+		}
+	}
+	start := i
+	for _, item := range feedItems {
+		v.list.InsertItem(i, v.format(v.displayed(item)), "", 0, nil)
+		v.shown = append(v.shown[:i], append([]FeedItem{item}, v.shown[i:]...)...)
+		i++
+	}
+	inserted := i - start
+	v.counts[rank] += inserted
+	// Keep the cursor on the same item
+	if start <= currentPosition && v.list.GetItemCount() > inserted {
+		currentPosition += inserted
+	}
+	v.list.SetCurrentItem(currentPosition)
+}
+
+// showAll shows the items of the feeds in the order given, unless the feeds
+// have been reloaded since.
+func (v *appView) showAll(feeds []*Feed, feedItems []FeedItem, gen int) {
+	v.shownMu.Lock()
+	defer v.shownMu.Unlock()
+	if gen != v.generation {
+		return
+	}
+	for _, feed := range feeds {
+		v.channels[channelKey(feed.source(), feed.Channel.Title)] = feed
+	}
+	for _, item := range feedItems {
+		v.list.AddItem(v.format(v.displayed(item)), "", 0, nil)
+	}
+	v.shown = feedItems
+}
+
+// reloadFeeds replaces the list with the feeds given by the reload option.
+func (v *appView) reloadFeeds() {
+	v.notice = ""
+	urls, feeds, err := v.options.reload()
+	if err != nil {
+		v.showError(err)
+		return
+	}
+	v.shownMu.Lock()
+	v.generation++
+	gen := v.generation
+	v.order = urls
+	v.shown = nil
+	v.counts = make(map[int]int)
+	v.channels = make(map[string]*Feed)
+	v.list.Clear()
+	v.shownMu.Unlock()
+	v.descriptionsMu.Lock()
+	v.descriptions = make(map[string][]byte)
+	v.descriptionsMu.Unlock()
+	go v.receive(feeds, gen)
+}
+
+// prefetch fetches the pages of the first items shown into the articles.
+func (v *appView) prefetch() {
+	var links []string
+	v.shownMu.Lock()
+	for _, item := range v.shown {
+		if len(links) >= v.options.prefetch {
+			break
+		}
+		if len(item.Links) == 0 || (v.options.prefetchOnly != nil && !v.options.prefetchOnly(item)) {
+			continue
+		}
+		links = append(links, item.Links[0])
+	}
+	v.shownMu.Unlock()
+	v.ready.Wait()
+	Prefetch(links, v.options.articles, v.browser.NewPage)
+}
+
+// toggleBorder borders the pane with focus, and without the preview, shows it
+// in place of the other.
+func (v *appView) toggleBorder() {
+	focused, other := v.textFlex, v.listFlex
+	if v.listFlex.HasFocus() {
+		focused, other = v.listFlex, v.textFlex
+	}
+	focused.SetBorderColor(v.theme.FocusedBorder)
+	other.SetBorderColor(v.theme.Border)
+	if v.fullScreen && focused == v.listFlex {
+		v.fullScreen = false
+		v.resize()
+	}
+	if v.options.noPreview {
+		v.flex.RemoveItem(other)
+		v.flex.RemoveItem(focused)
+		v.flex.AddItem(focused, 0, 1, true)
+	}
+}
+
+func (v *appView) canonicalOf(link string) string {
+	v.canonicalMu.Lock()
+	defer v.canonicalMu.Unlock()
+	return v.canonical[link]
+}
+
+// entryOf records the item with the given link for the history, stars, tags
+// and queue.
+func (v *appView) entryOf(item FeedItem, link string) HistoryEntry {
+	return HistoryEntry{
+		ID:        item.ID,
+		Time:      time.Now(),
+		Title:     item.Title,
+		Link:      link,
+		Canonical: v.canonicalOf(link),
+		Feed:      item.Source(),
+		Published: item.PublishTime,
+	}
+}
+
+func (v *appView) showMatch(i int) {
+	v.match = i
+	v.textView.Highlight(matchRegion(v.match))
+	v.textView.ScrollToHighlight()
+	v.textFlex.SetTitle(fmt.Sprintf(" %d of %d ", v.match+1, v.matches))
+}
+
+// search marks the matches of the term in the article, highlighting the
+// first.
+func (v *appView) search(term string) {
+	if v.searched == "" {
+		v.searched = v.textView.GetText(false)
+	}
+	var marked string
+	marked, v.matches = markMatches(v.searched, term)
+	v.textView.SetRegions(true)
+	v.textView.SetText(marked)
+	if v.matches == 0 {
+		v.textView.Highlight()
+		v.textFlex.SetTitle(fmt.Sprintf(" No matches for %s ", tview.Escape(term)))
+		return
+	}
+	v.showMatch(0)
+}
+
+func (v *appView) clearSearch() {
+	if v.searched == "" {
+		return
+	}
+	v.searched, v.matches = "", 0
+	v.textView.Highlight()
+	v.textView.SetRegions(false)
+	v.textFlex.SetTitle("")
+}
+
+func (v *appView) closeLinks() {
+	if !v.showingLinks {
+		return
+	}
+	v.showingLinks = false
+	v.textFlex.RemoveItem(v.linksList)
+	v.textFlex.AddItem(v.textView, 0, 1, false)
+	v.textFlex.SetTitle(v.textTitle)
+}
+
+// showLinks lists the links of the article in place of its text.
+func (v *appView) showLinks() {
+	if v.showingLinks || v.reading == "" {
+		return
+	}
+	if len(v.readingLinks) == 0 {
+		v.textFlex.SetTitle(" No links ")
+		return
+	}
+	v.linksList.Clear()
+	for i, link := range v.readingLinks {
+		v.linksList.AddItem(fmt.Sprintf("%d. %s", i+1, tview.Escape(link.Text)), tview.Escape(link.URL), 0, nil)
+	}
+	v.showingLinks = true
+	v.textTitle = v.textFlex.GetTitle()
+	v.textFlex.SetTitle(fmt.Sprintf(" %s ", plural(len(v.readingLinks), "link")))
+	v.textFlex.RemoveItem(v.textView)
+	v.textFlex.AddItem(v.linksList, 0, 1, true)
+	v.app.SetFocus(v.linksList)
+}
+
+// openLink opens the link outside of the app.
+func (v *appView) openLink(i int) {
+	if i >= len(v.readingLinks) {
+		return
+	}
+	link := v.readingLinks[i]
+	err := v.options.opener.Open(FeedItem{Title: link.Text}, link.URL)
+	if err != nil {
+		v.textFlex.SetTitle(fmt.Sprintf(" %s ", tview.Escape(err.Error())))
+	}
+}
+
+// queueLink adds the link to the reading queue, from the article's feed.
+func (v *appView) queueLink(i int) {
+	if i >= len(v.readingLinks) {
+		return
+	}
+	link := v.readingLinks[i]
+	entry := HistoryEntry{Time: time.Now(), Title: link.Text, Link: link.URL, Feed: v.readingItem.Source()}
+	err := WriteQueueEntry(v.options.queue, QueueEntry{HistoryEntry: entry})
+	if err != nil {
+		v.showError(err)
+		return
+	}
+	main, secondary := v.linksList.GetItemText(i)
+	if !strings.Contains(main, queuedMarker) {
+		v.linksList.SetItemText(i, queuedMarker+main, secondary)
+	}
+}
+
+func (v *appView) leaveArticle() {
+	v.closeLinks()
+	if v.reading != "" {
+		v.offsets[v.reading], _ = v.textView.GetScrollOffset()
+		v.reading = ""
+	}
+	v.clearSearch()
+}
 
-	list.SetSelectedFunc(func(i int, main, secondary string, r rune) {
-		if secondary == "" {
-			return
+// read shows the item at i in the right pane, recording it as opened.
+func (v *appView) read(i int) {
+	item, found := v.item(i)
+	// Title cards have no link
+	if !found || len(item.Links) == 0 {
+		return
+	}
+	link := item.Links[0]
+	v.leaveArticle()
+	v.textView.Clear()
+	fmt.Fprintln(v.textView, link)
+	fmt.Fprintf(v.textView, "\n")
+	var page io.Reader
+	var pageLinks []PageLink
+	var err error
+	if v.options.noBrowser {
+		v.descriptionsMu.Lock()
+		description := v.descriptions[item.ID]
+		v.descriptionsMu.Unlock()
+		if v.options.description != nil {
+			description, err = v.options.description(item)
+		}
+		page = strings.NewReader(DescriptionText(description))
+		pageLinks = DescriptionLinks(description, link)
+	} else {
+		var p *Page
+		var kept bool
+		if v.options.articles != nil {
+			p, kept = v.options.articles.Get(link)
+		}
+		if !kept {
+			if v.browser == nil {
+				v.ready.Wait()
+			}
+			p, err = v.browser.NewPage(link)
+			if err == nil && v.options.articles != nil {
+				v.options.articles.Put(link, p)
+			}
+		}
+		if p != nil && p.Canonical != "" && p.Canonical != link {
+			v.canonicalMu.Lock()
+			v.canonical[link] = p.Canonical
+			v.canonicalMu.Unlock()
+		}
+		if p != nil {
+			pageLinks = p.Links
+		}
+		page = p
+	}
+	v.opened(i, item, link)
+	if err != nil {
+		fmt.Fprintf(v.textView, err.Error())
+		return
+	}
+	io.Copy(v.textView, page)
+	v.app.SetFocus(v.textView)
+	v.reading, v.readingItem, v.readingLinks = link, item, pageLinks
+	v.textView.ScrollTo(v.offsets[link], 0)
+	v.toggleBorder()
+}
+
+// opened records the item at i as opened at the link.
+func (v *appView) opened(i int, item FeedItem, link string) {
+	if v.options.history != nil {
+		err := WriteHistory(v.options.history, v.entryOf(item, link))
+		if err != nil {
+			v.showError(err)
+		}
+	}
+	if v.options.onOpen == nil {
+		return
+	}
+	err := v.options.onOpen(item)
+	if err != nil {
+		v.showError(err)
+		return
+	}
+	main, secondary := v.list.GetItemText(i)
+	if !strings.Contains(main, doneMarker) {
+		v.list.SetItemText(i, doneMarker+main, secondary)
+	}
+}
+
+func (v *appView) writeStar(star Star) error {
+	v.starsMu.Lock()
+	defer v.starsMu.Unlock()
+	return WriteStar(v.options.stars, star)
+}
+
+// starItem stars the item at i with the note, saving it to the wayback
+// machine in the background if the stars option says to.
+func (v *appView) starItem(i int, note string) {
+	item, found := v.item(i)
+	if !found || len(item.Links) == 0 {
+		return
+	}
+	star := Star{HistoryEntry: v.entryOf(item, item.Links[0]), Note: note}
+	err := v.writeStar(star)
+	if err != nil {
+		v.showError(err)
+		return
+	}
+	main, secondary := v.list.GetItemText(i)
+	if !strings.Contains(main, starMarker) {
+		v.list.SetItemText(i, starMarker+main, secondary)
+	}
+	if !v.options.saveToWayback {
+		return
+	}
+	v.saving.Add(1)
+	go func() {
+		defer v.saving.Done()
+		// The canonical page is archived rather than a mirror
+		link := star.Link
+		if star.Canonical != "" {
+			link = star.Canonical
 		}
-		if b == nil {
-			wg.Wait()
+		snapshot, err := SaveToWayback(link)
+		if err != nil {
+			star.SaveError = err.Error()
 		}
-		textView.Clear()
-		fmt.Fprintln(textView, secondary)
-		fmt.Fprintf(textView, "\n")
-		page, err := b.NewPage(secondary)
+		star.Snapshot = snapshot
+		err = v.writeStar(star)
 		if err != nil {
-			fmt.Fprintf(textView, err.Error())
-			return
+			// Not waited on since the app may have stopped by now
+			go v.app.QueueUpdateDraw(func() {
+				v.showError(err)
+			})
+		}
+	}()
+}
+
+func (v *appView) queueItem(i int) {
+	item, found := v.item(i)
+	if !found || len(item.Links) == 0 {
+		return
+	}
+	err := WriteQueueEntry(v.options.queue, QueueEntry{HistoryEntry: v.entryOf(item, item.Links[0])})
+	if err != nil {
+		v.showError(err)
+		return
+	}
+	main, secondary := v.list.GetItemText(i)
+	if !strings.Contains(main, queuedMarker) {
+		v.list.SetItemText(i, queuedMarker+main, secondary)
+	}
+}
+
+// openItem opens the item outside of the app, recording it as opened.
+func (v *appView) openItem(i int) {
+	item, found := v.item(i)
+	if !found || len(item.Links) == 0 {
+		return
+	}
+	err := v.options.opener.Open(item, item.Links[0])
+	if err != nil {
+		v.leaveArticle()
+		v.textView.Clear()
+		fmt.Fprint(v.textView, tview.Escape(err.Error()))
+		return
+	}
+	v.opened(i, item, item.Links[0])
+}
+
+// prompt asks for text in place of the footer, passing it to done unless it is
+// empty or the prompt is cancelled.
+func (v *appView) prompt(label string, done func(string)) {
+	focused := v.app.GetFocus()
+	v.input.SetLabel(label)
+	v.input.SetText("")
+	v.input.SetDoneFunc(func(key tcell.Key) {
+		v.root.RemoveItem(v.input)
+		v.root.AddItem(v.footer, 1, 0, false)
+		v.app.SetFocus(focused)
+		if text := strings.TrimSpace(v.input.GetText()); key == tcell.KeyEnter && text != "" {
+			done(text)
 		}
-		io.Copy(textView, page)
-		app.SetFocus(textView)
-		textView.ScrollToBeginning()
-		toggleBorder()
 	})
+	v.root.RemoveItem(v.footer)
+	v.root.AddItem(v.input, 1, 0, true)
+	v.app.SetFocus(v.input)
+}
 
-	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		switch event.Key() {
-		case tcell.KeyCtrlQ, tcell.KeyCtrlC:
-			app.Stop()
-		case tcell.KeyRight:
-			if app.GetFocus() != textView {
-				app.SetFocus(textView)
-				toggleBorder()
+// tagItem applies the changes to the tags of the item at i, e.g. "-later" to
+// remove the tag later.
+func (v *appView) tagItem(i int, changes []string) {
+	v.shownMu.Lock()
+	if i >= len(v.shown) {
+		v.shownMu.Unlock()
+		return
+	}
+	item := v.shown[i]
+	item.Tags = applyTags(append([]string(nil), item.Tags...), changes)
+	v.shown[i] = item
+	v.shownMu.Unlock()
+	if len(item.Links) == 0 {
+		return
+	}
+	err := WriteTagEntry(v.options.tags, TagEntry{HistoryEntry: v.entryOf(item, item.Links[0]), Tags: changes})
+	if err != nil {
+		v.showError(err)
+	}
+	main, secondary := v.list.GetItemText(i)
+	text := v.format(v.displayed(item))
+	// Markers are kept in front of the new text
+	for _, marker := range []string{queuedMarker, starMarker, doneMarker} {
+		if strings.Contains(main, marker) {
+			text = marker + text
+		}
+	}
+	v.list.SetItemText(i, text, secondary)
+}
+
+// showInfo shows what the feed of the item says about itself.
+func (v *appView) showInfo(i int) {
+	v.shownMu.Lock()
+	var feed *Feed
+	if i < len(v.shown) {
+		feed = v.channels[channelKey(v.shown[i].Feed, v.shown[i].Channel)]
+	}
+	v.shownMu.Unlock()
+	if feed == nil {
+		return
+	}
+	var info strings.Builder
+	WriteFeedInfo(&info, feed, time.Now())
+	v.leaveArticle()
+	v.textView.Clear()
+	fmt.Fprint(v.textView, tview.Escape(info.String()))
+	v.textView.ScrollToBeginning()
+	if v.options.noPreview {
+		v.app.SetFocus(v.textView)
+		v.toggleBorder()
+	}
+}
+
+// handleKey handles the keys of the pane with focus, passing on the rest.
+func (v *appView) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	v.failure = ""
+	focus := v.app.GetFocus()
+	if focus == v.input {
+		return event
+	}
+	switch event.Key() {
+	case tcell.KeyRune:
+		switch focus {
+		case v.list:
+			if v.handleListKey(event.Rune()) {
 				return nil
 			}
-		case tcell.KeyLeft:
-			if app.GetFocus() != listFlex {
-				app.SetFocus(listFlex)
-				toggleBorder()
+		case v.textView:
+			if v.handleTextKey(event.Rune()) {
 				return nil
 			}
-		case tcell.KeyDown:
-			focus := app.GetFocus()
-			_, isList := focus.(*tview.List)
-			if isList {
-				n := list.GetItemCount() - 1
-				currentItem := list.GetCurrentItem()
-				if n == currentItem {
-					// Swallow the event to stop jumping to the start
-					return nil
-				}
+		case v.linksList:
+			if v.handleLinksKey(event.Rune()) {
+				return nil
 			}
-		case tcell.KeyUp:
-			focus := app.GetFocus()
-			_, isList := focus.(*tview.List)
-			if isList {
-				currentItem := list.GetCurrentItem()
-				if currentItem == 0 {
-					// Swallow the event to stop jumping to the end
-					return nil
-				}
+		}
+		if event.Rune() == '<' || event.Rune() == '>' {
+			steps := -1
+			if event.Rune() == '>' {
+				steps = 1
 			}
+			v.layout.ListShare = v.layout.resize(steps)
+			v.resize()
+			return nil
+		}
+	case tcell.KeyCtrlQ, tcell.KeyCtrlC:
+		v.app.Stop()
+	case tcell.KeyRight:
+		var pane tview.Primitive = v.textView
+		if v.showingLinks {
+			pane = v.linksList
+		}
+		if focus != pane {
+			v.app.SetFocus(pane)
+			v.toggleBorder()
+			return nil
+		}
+	case tcell.KeyLeft:
+		if focus != v.listFlex {
+			v.app.SetFocus(v.listFlex)
+			v.toggleBorder()
+			return nil
+		}
+	case tcell.KeyDown:
+		if focus == v.list && v.list.GetCurrentItem() == v.list.GetItemCount()-1 {
+			// Swallow the event to stop jumping to the start
+			return nil
+		}
+	case tcell.KeyUp:
+		if focus == v.list && v.list.GetCurrentItem() == 0 {
+			// Swallow the event to stop jumping to the end
+			return nil
+		}
+	}
+	return event
+}
 
+// handleListKey handles the key pressed on the list, returning whether it was
+// one of its keys.
+func (v *appView) handleListKey(r rune) bool {
+	i := v.list.GetCurrentItem()
+	switch {
+	case r == 's' && v.options.stars != nil:
+		v.starItem(i, "")
+	case r == 'S' && v.options.stars != nil:
+		v.prompt("Note: ", func(note string) {
+			v.starItem(i, note)
+		})
+	case r == 'q' && v.options.queue != nil:
+		v.queueItem(i)
+	case r == 't' && v.options.tags != nil:
+		v.prompt("Tags (-tag removes): ", func(text string) {
+			v.tagItem(i, strings.Fields(text))
+		})
+	case r == 'o':
+		v.openItem(i)
+	case r == 'i':
+		v.showInfo(i)
+	case r == 'r' && v.options.reload != nil:
+		v.reloadFeeds()
+	case r == 'e' && v.options.onExit != nil:
+		v.exiting = true
+		v.app.Stop()
+	default:
+		return false
+	}
+	return true
+}
+
+// handleTextKey handles the key pressed while reading an article, returning
+// whether it was one of its keys.
+func (v *appView) handleTextKey(r rune) bool {
+	switch {
+	case r == 'f':
+		v.fullScreen = !v.fullScreen
+		v.resize()
+	case r == 'L':
+		v.showLinks()
+	case r == '/':
+		v.prompt("Search: ", v.search)
+	case (r == 'n' || r == 'N') && v.matches > 0:
+		step := 1
+		if r == 'N' {
+			step = v.matches - 1
 		}
-		return event
-	})
-	app.SetRoot(flex, true)
-	return app.Run()
+		v.showMatch((v.match + step) % v.matches)
+	default:
+		return false
+	}
+	return true
+}
+
+// handleLinksKey handles the key pressed on the links of an article,
+// returning whether it was one of its keys.
+func (v *appView) handleLinksKey(r rune) bool {
+	switch {
+	case r == 'o':
+		v.openLink(v.linksList.GetCurrentItem())
+	case r == 'q' && v.options.queue != nil:
+		v.queueLink(v.linksList.GetCurrentItem())
+	default:
+		return false
+	}
+	return true
 }
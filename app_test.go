@@ -0,0 +1,756 @@
+package rss
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// waitForText waits for the text to be shown on the screen.
+func waitForText(t *testing.T, screen tcell.Screen, text string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(ScreenText(screen), text) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%q not shown, screen is:\n%s", text, ScreenText(screen))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunAppDemo(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, 3, len(feeds))
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette", "https://demo.example/terminal", "https://demo.example/releases"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), order)
+	}()
+	waitForText(t, screen, "Ranging over functions")
+	waitForText(t, screen, "Lantern 2.4.0")
+	waitForText(t, screen, "A faster cd with directory bookmarks")
+	waitForText(t, screen, "updated 12m ago")
+
+	// The first item is below a blank line and the header of its feed
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Iterators are coming to Go")
+
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'i', tcell.ModNone)
+	waitForText(t, screen, "Weekly notes on writing Go")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+}
+
+func TestRunAppLowVision(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 40)
+	done := make(chan error, 1)
+	go func() {
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithTheme(HighContrastTheme), WithSpacedRows(), WithoutPreview())
+	}()
+	waitForText(t, screen, "Lantern 2.4.0")
+
+	// Each item is followed by a blank line
+	lines := strings.Split(ScreenText(screen), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "Lantern 2.4.0") {
+			assertEqual(t, "", strings.Trim(lines[i+1], "║ "))
+		}
+	}
+
+	// Items are shown in place of the list
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Iterators are coming to Go")
+	assertEqual(t, false, strings.Contains(ScreenText(screen), "Lantern 2.4.0"))
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	waitForText(t, screen, "Lantern 2.4.0")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+}
+
+// rowOf waits for the text to be shown on a row of the screen satisfying ok,
+// returning the row.
+func rowOf(t *testing.T, screen tcell.Screen, text string, ok func(row int) bool) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for row, line := range strings.Split(ScreenText(screen), "\n") {
+			if strings.Contains(line, text) && ok(row) {
+				return row
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%q not shown where expected, screen is:\n%s", text, ScreenText(screen))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRunAppLayout(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 40)
+	done := make(chan error, 1)
+	go func() {
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithLayout(Layout{Vertical: true}))
+	}()
+	waitForText(t, screen, "Lantern 2.4.0")
+	anywhere := func(int) bool { return true }
+
+	// The item is shown below the list, which has the top half
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	rowOf(t, screen, "Iterators are coming to Go", func(row int) bool { return row >= 20 })
+
+	// The list is hidden while reading full screen
+	screen.InjectKey(tcell.KeyRune, 'f', tcell.ModNone)
+	rowOf(t, screen, "Iterators are coming to Go", func(row int) bool { return row < 5 })
+	assertEqual(t, false, strings.Contains(ScreenText(screen), "Lantern 2.4.0"))
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	rowOf(t, screen, "Lantern 2.4.0", anywhere)
+
+	// The list shrinks to 30% of the screen
+	for i := 0; i < 4; i++ {
+		screen.InjectKey(tcell.KeyRune, '<', tcell.ModNone)
+	}
+	rowOf(t, screen, "Iterators are coming to Go", func(row int) bool { return row >= 12 && row < 20 })
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+}
+
+func TestRunAppScrollMemory(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The pane is too small for the whole of an item
+	screen.SetSize(60, 8)
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), order)
+	}()
+	waitForText(t, screen, "Ranging over")
+	// waitForScreen waits for the screen to show exactly the text
+	waitForScreen := func(text string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for ScreenText(screen) != text {
+			if time.Now().After(deadline) {
+				t.Fatalf("screen is:\n%s\nexpected:\n%s", ScreenText(screen), text)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "gazette.example")
+	time.Sleep(100 * time.Millisecond)
+	top := ScreenText(screen)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	time.Sleep(100 * time.Millisecond)
+	scrolled := ScreenText(screen)
+	assertEqual(t, false, scrolled == top)
+
+	// Another item opens at its top
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Six months")
+
+	// Going back to the first carries on where it was left
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyUp, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForScreen(scrolled)
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+}
+
+func TestRunAppSearch(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), order)
+	}()
+	waitForText(t, screen, "Ranging over")
+	typeText := func(text string) {
+		for _, r := range text {
+			screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+		}
+		screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	}
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Iterators are coming to Go")
+
+	// "in" is in coming, walking and paging
+	screen.InjectKey(tcell.KeyRune, '/', tcell.ModNone)
+	waitForText(t, screen, "Search:")
+	typeText("IN")
+	waitForText(t, screen, " 1 of 3 ")
+	waitForText(t, screen, "n N next and previous match")
+	screen.InjectKey(tcell.KeyRune, 'n', tcell.ModNone)
+	waitForText(t, screen, " 2 of 3 ")
+	screen.InjectKey(tcell.KeyRune, 'N', tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'N', tcell.ModNone)
+	waitForText(t, screen, " 3 of 3 ")
+	// The text is unchanged by the marks
+	waitForText(t, screen, "Iterators are coming to Go")
+
+	screen.InjectKey(tcell.KeyRune, '/', tcell.ModNone)
+	typeText("rust")
+	waitForText(t, screen, " No matches for rust ")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+}
+
+func TestRunAppLinks(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	var queue bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithQueue(&queue), order)
+	}()
+	waitForText(t, screen, "Structured logging")
+
+	// The first item has no links
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Iterators are coming to Go")
+	screen.InjectKey(tcell.KeyRune, 'L', tcell.ModNone)
+	waitForText(t, screen, " No links ")
+
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Six months")
+	screen.InjectKey(tcell.KeyRune, 'L', tcell.ModNone)
+	waitForText(t, screen, " 2 links ")
+	waitForText(t, screen, "1. log/slog")
+	waitForText(t, screen, "https://pkg.go.dev/log/slog")
+	// Relative links are resolved against the item's link
+	waitForText(t, screen, "2. our post on table tests")
+	waitForText(t, screen, "https://gazette.example/2024/04/table-tests")
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	waitForText(t, screen, "+ 2. our post on table tests")
+
+	// Going back shows the article again
+	screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	waitForText(t, screen, "Six months")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	queued, err := ReadQueue(&queue)
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(queued))
+	assertEqual(t, "https://gazette.example/2024/04/table-tests", queued[0].Link)
+	assertEqual(t, "The Gopher Gazette", queued[0].Feed)
+}
+
+func TestRunAppShowWhenArrived(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	// The newest two items of every feed, not of each of them
+	var expected []string
+	for _, item := range ByScore(GetFeedItems(feeds))[:2] {
+		expected = append(expected, item.Title)
+	}
+	mode := Limited(ByScore, func() Filter { return MaxItems(2) })
+	var shown []string
+	onExit := OnExit(func(feedItems []FeedItem) {
+		for _, item := range feedItems {
+			shown = append(shown, item.Title)
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		done <- RunApp(SendFeeds(feeds), mode, WithScreen(screen), WithoutBrowser(), ShowWhenArrived(), onExit)
+	}()
+	waitForText(t, screen, expected[1])
+
+	screen.InjectKey(tcell.KeyRune, 'e', tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	assertEqual(t, expected, shown)
+}
+
+func TestRunAppOnArrival(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	skip := func(item FeedItem) bool {
+		return !strings.Contains(item.Title, "Structured logging")
+	}
+	expected := GetFeedItems(feeds, skip)
+	var mu sync.Mutex
+	arrived := make(map[string]bool)
+	onArrival := OnArrival(func(feedItems []FeedItem) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, item := range feedItems {
+			arrived[item.ID] = true
+		}
+	})
+	done := make(chan error, 1)
+	go func() {
+		// Every feed has arrived once the items are shown
+		done <- RunApp(SendFeeds(feeds), ReverseChronological, WithScreen(screen), WithoutBrowser(), WithFilters(skip), ShowWhenArrived(), onArrival)
+	}()
+	waitForText(t, screen, ReverseChronological(expected)[0].Title)
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// The items are passed on once they have been filtered
+	assertEqual(t, len(expected), len(arrived))
+	for _, item := range expected {
+		assertEqual(t, true, arrived[item.ID])
+	}
+}
+
+func TestRunAppStarNote(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	var stars bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithStars(&stars, false), order)
+	}()
+	// The footer shows the keys of the list
+	waitForText(t, screen, "S star with note")
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'S', tcell.ModNone)
+	waitForText(t, screen, "Note:")
+	for _, r := range "talk" {
+		screen.InjectKey(tcell.KeyRune, r, tcell.ModNone)
+	}
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "* ")
+	// The footer comes back once the note is given
+	waitForText(t, screen, "S star with note")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	starred, err := ReadStars(&stars)
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(starred))
+	assertEqual(t, "talk", starred[0].Note)
+}
+
+func TestRunAppWaitsForWayback(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Location", "/web/20240501000000/"+strings.TrimPrefix(r.URL.Path, "/save/"))
+	}))
+	defer server.Close()
+	defer func(api string) { waybackSaveAPI = api }(waybackSaveAPI)
+	waybackSaveAPI = server.URL + "/save/"
+
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	var stars bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithStars(&stars, true), order)
+	}()
+	waitForText(t, screen, "Structured logging")
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 's', tcell.ModNone)
+	waitForText(t, screen, "* ")
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	// The app doesn't return while the page is being saved
+	select {
+	case <-done:
+		t.Fatal("app returned before the star was saved")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	starred, err := ReadStars(&stars)
+	assertEqual(t, nil, err)
+	// The star is written again with its snapshot
+	assertEqual(t, 1, len(starred))
+	assertEqual(t, "https://web.archive.org/web/20240501000000/"+starred[0].Link, starred[0].Snapshot)
+}
+
+// failingWriter fails every write, like a full disk.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("no space left on device")
+}
+
+func TestRunAppWriteError(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithQueue(failingWriter{}), order)
+	}()
+	waitForText(t, screen, "Structured logging")
+
+	// The error is shown below the panes until the next key
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyRune, 'q', tcell.ModNone)
+	waitForText(t, screen, "no space left on device")
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	waitForText(t, screen, "q queue")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+}
+
+func TestRunAppOnOpen(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	var popped []string
+	pop := OnOpen(func(item FeedItem) error {
+		if strings.Contains(item.Title, "Structured logging") {
+			return errors.New("no space left on device")
+		}
+		popped = append(popped, item.Title)
+		return nil
+	})
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), pop, order)
+	}()
+	waitForText(t, screen, "Structured logging")
+
+	// Items are marked as done once they have been passed on
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Iterators are coming to Go")
+	waitForText(t, screen, "✓ ")
+
+	// The error is shown if that fails
+	screen.InjectKey(tcell.KeyLeft, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "no space left on device")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	assertEqual(t, 1, len(popped))
+}
+
+func TestRunAppErrors(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	var errs ErrorLog
+	var once sync.Once
+	// The mode fails as a plugin would while the items are shown
+	mode := func(feedItems []FeedItem) []FeedItem {
+		once.Do(func() {
+			errs.Add(errors.New("plugin summarise: exit status 1"))
+		})
+		return Grouped(feedItems)
+	}
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), mode, WithScreen(screen), WithoutBrowser(), WithErrors(&errs), order)
+	}()
+	waitForText(t, screen, "plugin summarise: exit status 1")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	// Errors are kept for the caller once the app has stopped
+	errs.Add(errors.New("late"))
+	assertEqual(t, 1, len(errs.Errors()))
+}
+
+func TestRunAppStoredDescriptions(t *testing.T) {
+	feeds, err := DemoFeeds(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen := tcell.NewSimulationScreen("UTF-8")
+	err = screen.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	screen.SetSize(160, 30)
+	var loaded []string
+	load := func(item FeedItem) ([]byte, error) {
+		loaded = append(loaded, item.ID)
+		return []byte("<p>Loaded from the store</p>"), nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		order := WithFeedOrder([]string{"https://demo.example/gazette"})
+		done <- RunApp(SendFeeds(feeds), Grouped, WithScreen(screen), WithoutBrowser(), WithStoredDescriptions(load), order)
+	}()
+	waitForText(t, screen, "Structured logging")
+
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+	screen.InjectKey(tcell.KeyEnter, 0, tcell.ModNone)
+	waitForText(t, screen, "Loaded from the store")
+
+	screen.InjectKey(tcell.KeyCtrlC, 0, tcell.ModNone)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("app didn't stop")
+	}
+	assertEqual(t, 1, len(loaded))
+}
+
+func TestDemoFeedsDates(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	feeds, err := DemoFeeds(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var newest time.Time
+	for _, feed := range feeds {
+		for _, item := range feed.Channel.Items {
+			published, err := ParseDate(item.PubDate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if published.After(newest) {
+				newest = published
+			}
+		}
+	}
+	assertEqual(t, now.Add(-12*time.Minute), newest.UTC())
+}
@@ -0,0 +1,140 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultArchiveService = "https://archive.is/"
+
+// waybackTimeout bounds each lookup of a snapshot on the Wayback Machine,
+// since they are made while feeds are unpacked.
+const waybackTimeout = 5 * time.Second
+
+var waybackAPI = "https://archive.org/wayback/available"
+
+// ArchiveOptions configures how links to paywalled articles are archived.
+type ArchiveOptions struct {
+	// Service is prefixed to paywalled links e.g. "https://archive.ph/" or
+	// "https://web.archive.org/web/". Defaults to archive.is.
+	Service string `yaml:"service"`
+	// Paywalls are the prefixes of the URLs of feeds whose links are
+	// paywalled.
+	Paywalls []string `yaml:"paywalls"`
+	// Wayback looks up the closest snapshot of each paywalled link on the
+	// Wayback Machine and links directly to it when there is one, falling
+	// back to the service otherwise.
+	Wayback bool `yaml:"wayback"`
+}
+
+// Archive links the items of paywalled feeds to their archived copies as
+// they are unpacked. Items are still identified by their original links.
+func Archive(opts ArchiveOptions) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.archiver = newArchiver(opts)
+	}
+}
+
+// archiver archives the links of paywalled feeds, remembering the snapshots
+// found on the Wayback Machine so that each link is only looked up once.
+type archiver struct {
+	options ArchiveOptions
+	client  *http.Client
+
+	mu        sync.Mutex
+	snapshots map[string]string
+	// unavailable is set once a lookup fails, after which the service is
+	// used rather than waiting on lookups which are likely to fail too.
+	unavailable bool
+}
+
+func newArchiver(opts ArchiveOptions) *archiver {
+	if opts.Service == "" {
+		opts.Service = defaultArchiveService
+	}
+	if !strings.HasSuffix(opts.Service, "/") {
+		opts.Service += "/"
+	}
+	return &archiver{
+		options:   opts,
+		client:    &http.Client{Transport: client.Transport, Timeout: waybackTimeout},
+		snapshots: make(map[string]string),
+	}
+}
+
+// paywalled returns whether the links of the feed with the given URL are
+// archived.
+func (a *archiver) paywalled(feedURL string) bool {
+	if a == nil {
+		return false
+	}
+	for _, pw := range a.options.Paywalls {
+		if strings.HasPrefix(feedURL, pw) {
+			return true
+		}
+	}
+	return false
+}
+
+// link returns the archived copy of the link.
+func (a *archiver) link(link string) string {
+	if !a.options.Wayback {
+		return a.options.Service + link
+	}
+	a.mu.Lock()
+	snapshot, found := a.snapshots[link]
+	unavailable := a.unavailable
+	a.mu.Unlock()
+	if !found && !unavailable {
+		var err error
+		snapshot, err = waybackSnapshot(a.client, link)
+		a.mu.Lock()
+		if err != nil {
+			a.unavailable = true
+		} else {
+			a.snapshots[link] = snapshot
+		}
+		a.mu.Unlock()
+	}
+	if snapshot != "" {
+		return snapshot
+	}
+	return a.options.Service + link
+}
+
+// WaybackSnapshot returns the URL of the closest snapshot of the link on the
+// Wayback Machine, or an empty string if it has never been archived.
+func WaybackSnapshot(link string) (string, error) {
+	return waybackSnapshot(client, link)
+}
+
+func waybackSnapshot(c *http.Client, link string) (string, error) {
+	resp, err := c.Get(fmt.Sprintf("%s?url=%s", waybackAPI, url.QueryEscape(link)))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var availability struct {
+		ArchivedSnapshots struct {
+			Closest struct {
+				Available bool   `json:"available"`
+				URL       string `json:"url"`
+			} `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&availability)
+	if err != nil {
+		return "", err
+	}
+	closest := availability.ArchivedSnapshots.Closest
+	if !closest.Available {
+		return "", nil
+	}
+	return closest.URL, nil
+}
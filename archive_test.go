@@ -0,0 +1,58 @@
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestArchiveLink(t *testing.T) {
+	var lookups int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		if r.URL.Query().Get("url") != "https://example.com/archived" {
+			fmt.Fprint(w, `{"archived_snapshots": {}}`)
+			return
+		}
+		fmt.Fprint(w, `{"archived_snapshots": {"closest": {"available": true, "url": "http://web.archive.org/web/2022/https://example.com/archived"}}}`)
+	}))
+	defer server.Close()
+	waybackAPI = server.URL
+
+	a := newArchiver(ArchiveOptions{Service: "https://archive.ph"})
+	assertEqual(t, "https://archive.ph/https://example.com/archived", a.link("https://example.com/archived"))
+	assertEqual(t, int32(0), atomic.LoadInt32(&lookups))
+
+	a = newArchiver(ArchiveOptions{Service: "https://archive.ph", Wayback: true})
+	assertEqual(t, "http://web.archive.org/web/2022/https://example.com/archived", a.link("https://example.com/archived"))
+	assertEqual(t, "https://archive.ph/https://example.com/new", a.link("https://example.com/new"))
+	// Snapshots are only looked up once
+	assertEqual(t, "http://web.archive.org/web/2022/https://example.com/archived", a.link("https://example.com/archived"))
+	assertEqual(t, int32(2), atomic.LoadInt32(&lookups))
+
+	feed := &Feed{URL: "https://paywalled.com/rss", archiver: newArchiver(ArchiveOptions{Paywalls: []string{"https://paywalled.com"}})}
+	item, err := NewFeedItem(feed, Item{Title: "Article", Link: "https://paywalled.com/article"})
+	assertEqual(t, nil, err)
+	assertEqual(t, "https://archive.is/https://paywalled.com/article", item.Links[0])
+	// The item is identified by its original link
+	unarchived, err := NewFeedItem(&Feed{URL: feed.URL}, Item{Title: "Article", Link: "https://paywalled.com/article"})
+	assertEqual(t, nil, err)
+	assertEqual(t, unarchived.ID, item.ID)
+}
+
+func TestArchiveUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	waybackAPI = server.URL
+
+	a := newArchiver(ArchiveOptions{Wayback: true})
+	assertEqual(t, "https://archive.is/https://example.com/1", a.link("https://example.com/1"))
+	// Once a lookup fails the rest of the links aren't looked up
+	server.Close()
+	assertEqual(t, "https://archive.is/https://example.com/2", a.link("https://example.com/2"))
+	assertEqual(t, true, a.unavailable)
+}
@@ -0,0 +1,88 @@
+package rss
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// arxivTitleSuffix is the identifier at the end of titles in arXiv's
+	// older feeds e.g. "Title. (arXiv:2211.00001v1 [cs.LG])".
+	arxivTitleSuffix = regexp.MustCompile(`\.?\s*\(arXiv:[^)]*\)\s*$`)
+	// arxivTitlePrefix is an identifier at the start of a title e.g.
+	// "arXiv:2401.00001v1 Title".
+	arxivTitlePrefix = regexp.MustCompile(`^\s*arXiv:\d{4}\.\d{4,5}(v\d+)?[:\s]\s*`)
+	// arxivAnnouncement precedes the abstract in descriptions e.g.
+	// "arXiv:2401.00001v1 Announce Type: new Abstract: ...".
+	arxivAnnouncement = regexp.MustCompile(`(?s)^\s*arXiv:\S+\s+Announce Type:\s*\S+\s*Abstract:\s*`)
+)
+
+// isArxiv reports whether the link is to arXiv.
+func isArxiv(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "arxiv.org" || strings.HasSuffix(host, ".arxiv.org")
+}
+
+// cleanArxiv tidies up an item from an arXiv feed: identifiers are removed
+// from its title and abstract, and the authors, which arXiv lists as links in
+// a single creator, are split up.
+func cleanArxiv(item Item) Item {
+	item.Title = strings.TrimSpace(arxivTitlePrefix.ReplaceAllString(arxivTitleSuffix.ReplaceAllString(item.Title, ""), ""))
+	item.Description = arxivAnnouncement.ReplaceAll(item.Description, nil)
+	var authors []string
+	for _, creator := range item.Creators {
+		authors = append(authors, arxivAuthors(creator)...)
+	}
+	item.Creators = authors
+	return item
+}
+
+func arxivAuthors(creator string) []string {
+	text := html.UnescapeString(htmlTag.ReplaceAllString(creator, ""))
+	var authors []string
+	for _, author := range strings.Split(text, ",") {
+		author = strings.Join(strings.Fields(author), " ")
+		if author != "" {
+			authors = append(authors, author)
+		}
+	}
+	return authors
+}
+
+// ArxivPDF links items from arXiv to their PDFs rather than their abstracts.
+func ArxivPDF() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		if len(item.Links) == 0 || !isArxiv(item.Links[0]) || !strings.Contains(item.Links[0], "/abs/") {
+			return item
+		}
+		links := append([]string{}, item.Links...)
+		links[0] = strings.Replace(links[0], "/abs/", "/pdf/", 1)
+		item.Links = links
+		return item
+	}
+}
+
+// maxAuthors is the number of authors shown before the rest are abbreviated.
+const maxAuthors = 3
+
+// ShowAuthors appends the authors of items to their titles e.g. "Title — A,
+// B, C et al.".
+func ShowAuthors() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		if len(item.Authors) == 0 {
+			return item
+		}
+		authors := strings.Join(item.Authors, ", ")
+		if len(item.Authors) > maxAuthors {
+			authors = strings.Join(item.Authors[:maxAuthors], ", ") + " et al."
+		}
+		item.Title = item.Title + " — " + authors
+		return item
+	}
+}
@@ -0,0 +1,74 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanArxiv(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		doc         string
+		title       string
+		authors     []string
+		description string
+	}{
+		{
+			name: "current",
+			doc: `<rss xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>cs.LG updates on arXiv.org</title>
+<item><title>Attention Is Still All You Need</title><link>https://arxiv.org/abs/2401.00001</link><guid>oai:arXiv.org:2401.00001v1</guid>
+<description>arXiv:2401.00001v1 Announce Type: new 
+Abstract: We revisit attention.</description>
+<dc:creator>Ada Lovelace, Alan Turing, Grace Hopper, Edsger Dijkstra</dc:creator></item></channel></rss>`,
+			title:       "Attention Is Still All You Need",
+			authors:     []string{"Ada Lovelace", "Alan Turing", "Grace Hopper", "Edsger Dijkstra"},
+			description: "We revisit attention.",
+		},
+		{
+			name: "legacy",
+			doc: `<rss xmlns:dc="http://purl.org/dc/elements/1.1/"><channel><title>cs.LG updates on arXiv.org</title>
+<item><title>Attention Is Still All You Need. (arXiv:2211.00001v1 [cs.LG])</title><link>http://arxiv.org/abs/2211.00001</link>
+<description>&lt;p&gt;We revisit attention.&lt;/p&gt;</description>
+<dc:creator> &lt;a href="http://arxiv.org/find/cs/1/au:+Lovelace_A/0/1/0/all/0/1"&gt;Ada Lovelace&lt;/a&gt;, &lt;a href="http://arxiv.org/find/cs/1/au:+Turing_A/0/1/0/all/0/1"&gt;Alan Turing&lt;/a&gt;</dc:creator></item></channel></rss>`,
+			title:       "Attention Is Still All You Need",
+			authors:     []string{"Ada Lovelace", "Alan Turing"},
+			description: "<p>We revisit attention.</p>",
+		},
+		{
+			name:        "prefixed",
+			doc:         `<rss><channel><item><title>arXiv:2401.00002v2 Attention Again</title><link>https://arxiv.org/abs/2401.00002</link></item></channel></rss>`,
+			title:       "Attention Again",
+			description: "",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			channels, err := decodeRSS(strings.NewReader(tc.doc), fetchOptions{})
+			assertEqual(t, nil, err)
+			feeds := newFeeds("https://rss.arxiv.org/rss/cs.LG", channels, fetchOptions{})
+			feedItems := UnpackFeed(feeds[0])
+			assertEqual(t, 1, len(feedItems))
+			assertEqual(t, tc.title, feedItems[0].Title)
+			assertEqual(t, tc.authors, feedItems[0].Authors)
+			assertEqual(t, tc.description, string(feeds[0].Channel.Items[0].Description))
+		})
+	}
+}
+
+func TestArxivDisplayOptions(t *testing.T) {
+	t.Parallel()
+	item := FeedItem{
+		Title:   "Attention",
+		Links:   []string{"https://arxiv.org/abs/2401.00001"},
+		Authors: []string{"Ada Lovelace", "Alan Turing", "Grace Hopper", "Edsger Dijkstra"},
+	}
+	assertEqual(t, "https://arxiv.org/pdf/2401.00001", ArxivPDF()(item).Links[0])
+	assertEqual(t, "https://arxiv.org/abs/2401.00001", item.Links[0])
+	assertEqual(t, "https://example.com/abs/1", ArxivPDF()(FeedItem{Links: []string{"https://example.com/abs/1"}}).Links[0])
+	assertEqual(t, "Attention — Ada Lovelace, Alan Turing, Grace Hopper et al.", ShowAuthors()(item).Title)
+	item.Authors = item.Authors[:2]
+	assertEqual(t, "Attention — Ada Lovelace, Alan Turing", ShowAuthors()(item).Title)
+}
@@ -0,0 +1,165 @@
+package rss
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Backup writes the files to w as a gzipped tarball. The files are keyed by
+// their name in the tarball and those which don't exist are left out. Names
+// ending in a slash are folders, whose files are written below the name.
+func Backup(w io.Writer, files map[string]string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedKeys(files) {
+		var err error
+		if strings.HasSuffix(name, "/") {
+			err = backupFolder(tw, name, files[name])
+		} else {
+			err = backupFile(tw, name, files[name])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err := tw.Close()
+	if err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// backupFolder writes the files in the folder to the tarball below the name.
+func backupFolder(tw *tar.Writer, name, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) && path == dir {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return backupFile(tw, name+filepath.ToSlash(rel), path)
+	})
+}
+
+// backupFile writes the file to the tarball with the name, keeping its mode.
+func backupFile(tw *tar.Writer, name, path string) error {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    int64(info.Mode().Perm()),
+		Size:    int64(len(b)),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
+
+// restoredFile is a file read from a backup, to be written to path.
+type restoredFile struct {
+	path     string
+	mode     fs.FileMode
+	contents []byte
+}
+
+// Restore writes the files in a backup made by Backup to the paths they are
+// keyed by, with the modes they had. Files not in files, or in the folders in
+// it, are not restored, and existing files are only replaced if overwrite is
+// true. Nothing is written unless every file can be restored.
+func Restore(r io.Reader, files map[string]string, overwrite bool) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	restored := make(map[string]restoredFile)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		path, found := restorePath(files, header.Name)
+		if !found || header.Typeflag != tar.TypeReg {
+			return fmt.Errorf("unexpected file in backup: %s", header.Name)
+		}
+		_, err = os.Stat(path)
+		if err == nil && !overwrite {
+			return fmt.Errorf("%s already exists", path)
+		}
+		contents, err := io.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		restored[header.Name] = restoredFile{path: path, mode: fs.FileMode(header.Mode).Perm(), contents: contents}
+	}
+
+	for _, name := range sortedKeys(restored) {
+		file := restored[name]
+		err := os.MkdirAll(filepath.Dir(file.path), fs.ModePerm)
+		if err != nil {
+			return err
+		}
+		err = WriteFileAtomic(file.path, file.contents, file.mode)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restorePath returns the path of the file with the name in a backup, within
+// the folders in files if it isn't one of them itself.
+func restorePath(files map[string]string, name string) (string, bool) {
+	if path, found := files[name]; found && !strings.HasSuffix(name, "/") {
+		return path, true
+	}
+	for folder, dir := range files {
+		if !strings.HasSuffix(folder, "/") || !strings.HasPrefix(name, folder) {
+			continue
+		}
+		// Names can't climb out of the folder
+		rel := strings.TrimPrefix(name, folder)
+		if !fs.ValidPath(rel) || rel == "." {
+			return "", false
+		}
+		return filepath.Join(dir, filepath.FromSlash(rel)), true
+	}
+	return "", false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package rss
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRestore(t *testing.T) {
+	files := func(dir string) map[string]string {
+		return map[string]string{
+			"urls.txt":    filepath.Join(dir, "urls.txt"),
+			"config.yaml": filepath.Join(dir, "config.yaml"),
+			"history":     filepath.Join(dir, "state", "history"),
+		}
+	}
+	src := t.TempDir()
+	os.Mkdir(filepath.Join(src, "state"), 0755)
+	os.WriteFile(filepath.Join(src, "urls.txt"), []byte("https://example.com/rss\n"), 0644)
+	os.WriteFile(filepath.Join(src, "state", "history"), []byte("{}\n"), 0644)
+	os.WriteFile(filepath.Join(src, "other"), []byte("not backed up"), 0644)
+
+	var backup bytes.Buffer
+	err := Backup(&backup, files(src))
+	assertEqual(t, nil, err)
+
+	dst := filepath.Join(t.TempDir(), ".rss")
+	err = Restore(bytes.NewReader(backup.Bytes()), files(dst), false)
+	assertEqual(t, nil, err)
+	entries, err := os.ReadDir(dst)
+	assertEqual(t, nil, err)
+	assertEqual(t, 2, len(entries))
+	urls, err := os.ReadFile(filepath.Join(dst, "urls.txt"))
+	assertEqual(t, nil, err)
+	assertEqual(t, "https://example.com/rss\n", string(urls))
+
+	// Existing files are kept unless overwriting
+	historyPath := filepath.Join(dst, "state", "history")
+	os.WriteFile(historyPath, []byte("newer\n"), 0644)
+	err = Restore(bytes.NewReader(backup.Bytes()), files(dst), false)
+	assertEqual(t, true, err != nil)
+	history, _ := os.ReadFile(historyPath)
+	assertEqual(t, "newer\n", string(history))
+
+	err = Restore(bytes.NewReader(backup.Bytes()), files(dst), true)
+	assertEqual(t, nil, err)
+	history, _ = os.ReadFile(historyPath)
+	assertEqual(t, "{}\n", string(history))
+}
+
+func TestBackupRestoreFolder(t *testing.T) {
+	files := func(dir string) map[string]string {
+		return map[string]string{
+			"checkpoints.json": filepath.Join(dir, "checkpoints.json"),
+			"stored/":          filepath.Join(dir, "stored"),
+		}
+	}
+	src := t.TempDir()
+	os.MkdirAll(filepath.Join(src, "stored", "example.com"), 0755)
+	os.WriteFile(filepath.Join(src, "checkpoints.json"), []byte("{}\n"), 0600)
+	os.WriteFile(filepath.Join(src, "stored", "example.com", "feed.json"), []byte("[]\n"), 0644)
+
+	var backup bytes.Buffer
+	err := Backup(&backup, files(src))
+	assertEqual(t, nil, err)
+
+	dst := t.TempDir()
+	err = Restore(bytes.NewReader(backup.Bytes()), files(dst), false)
+	assertEqual(t, nil, err)
+	stored, err := os.ReadFile(filepath.Join(dst, "stored", "example.com", "feed.json"))
+	assertEqual(t, nil, err)
+	assertEqual(t, "[]\n", string(stored))
+	// Files keep their modes
+	info, err := os.Stat(filepath.Join(dst, "checkpoints.json"))
+	assertEqual(t, nil, err)
+	assertEqual(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestRestoreUnexpectedFile(t *testing.T) {
+	var backup bytes.Buffer
+	gz := gzip.NewWriter(&backup)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "../evil", Mode: 0644, Size: 1})
+	tw.Write([]byte("x"))
+	tw.Close()
+	gz.Close()
+
+	dir := t.TempDir()
+	err := Restore(&backup, map[string]string{"urls.txt": filepath.Join(dir, "urls.txt"), "stored/": filepath.Join(dir, "stored")}, true)
+	assertEqual(t, true, err != nil)
+	_, err = os.Stat(filepath.Join(dir, "..", "evil"))
+	assertEqual(t, true, os.IsNotExist(err))
+}
@@ -36,26 +36,56 @@ func (b *Browser) Stop() {
 	b.pw.Stop()
 }
 
+// defaultTextWidth is the width lines of text are wrapped at.
+const defaultTextWidth = 72
+
+// TextOption configures how the text of pages is written.
+type TextOption func(*textOptions)
+
+type textOptions struct {
+	width int
+}
+
+// TextWidth wraps lines of text at the first space after width characters,
+// rather than 72. Lines aren't wrapped if width is zero.
+func TextWidth(width int) TextOption {
+	return func(to *textOptions) {
+		to.width = width
+	}
+}
+
 // WriteText fetches the page at the given URL and writes its text to the given
 // Writer.
-func (b *Browser) WriteText(url string, w io.Writer) error {
+func (b *Browser) WriteText(url string, w io.Writer, opts ...TextOption) error {
+	options := textOptions{width: defaultTextWidth}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	_, err := b.writeText(url, w, options.width)
+	return err
+}
+
+// writeText writes the text of the page in reader view, wrapping it at width,
+// and returns the links in it.
+func (b *Browser) writeText(url string, w io.Writer, width int) ([]PageLink, error) {
 	page, err := b.b.NewPage()
 	if err != nil {
-		return fmt.Errorf("could not create page: %v", err)
+		return nil, fmt.Errorf("could not create page: %v", err)
 	}
 	_, err = page.Goto(fmt.Sprintf("about:reader?url=%s", url))
 	if err != nil {
-		return fmt.Errorf("could not goto: %v", err)
+		return nil, fmt.Errorf("could not goto: %v", err)
 	}
 	// Need to wait for the reader mode to take effect
 	time.Sleep(1 * time.Second)
 
 	entries, err := page.QuerySelectorAll("div[class='moz-reader-content reader-show-element']")
 	if err != nil {
-		return fmt.Errorf("could not get entries: %v", err)
+		return nil, fmt.Errorf("could not get entries: %v", err)
 	}
 
-	wrapLines := newLineWrapper(72)
+	var links []PageLink
+	wrapLines := newLineWrapper(width)
 	for _, entry := range entries {
 		titleElement, err := entry.QuerySelector("h3")
 		if err != nil {
@@ -85,74 +115,63 @@ func (b *Browser) WriteText(url string, w io.Writer) error {
 				fmt.Fprintf(w, "\t%s\n", strings.TrimSpace(line))
 			}
 			fmt.Fprintf(w, "\n")
+			anchors, err := bodyElement.QuerySelectorAll("a[href]")
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			for _, anchor := range anchors {
+				href, err := anchor.GetAttribute("href")
+				if err != nil {
+					continue
+				}
+				text, _ := anchor.TextContent()
+				links = appendLink(links, url, href, text)
+			}
 		}
 	}
-	return nil
+	return links, nil
 }
 
 type Page struct {
 	*bytes.Buffer
+	// Canonical is the canonical URL declared by the page, or the URL it
+	// ended up at if it doesn't declare one. It is empty if the page couldn't
+	// be fetched directly.
+	Canonical string
+	// Links are the hyperlinks in the text of the page.
+	Links []PageLink
 }
 
 func (b *Browser) NewPage(url string) (*Page, error) {
-	page, err := b.b.NewPage()
-	if err != nil {
-		return nil, fmt.Errorf("could not create page: %v", err)
-	}
-	_, err = page.Goto(fmt.Sprintf("about:reader?url=%s", url))
-	if err != nil {
-		return nil, fmt.Errorf("could not goto: %v", err)
-	}
-	// Need to wait for the reader mode to take effect
-	time.Sleep(1 * time.Second)
-
-	entries, err := page.QuerySelectorAll("div[class='moz-reader-content reader-show-element']")
-	if err != nil {
-		return nil, fmt.Errorf("could not get entries: %v", err)
-	}
+	// The reader view drops the head of the page so the canonical URL is
+	// fetched alongside it
+	canonical := make(chan string, 1)
+	go func() {
+		u, err := FetchCanonicalURL(url)
+		if err != nil {
+			u = ""
+		}
+		canonical <- u
+	}()
 
-	wrapLines := newLineWrapper(72)
 	var p []byte
 	w := bytes.NewBuffer(p)
-	for _, entry := range entries {
-		titleElement, err := entry.QuerySelector("h3")
-		if err != nil {
-			fmt.Println(err)
-			continue
-		}
-		if titleElement != nil {
-			title, err := titleElement.TextContent()
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-			fmt.Fprintf(w, title)
-		}
-		bodyElements, err := entry.QuerySelectorAll("p")
-		if err != nil {
-			fmt.Println(err)
-			continue
-		}
-		for _, bodyElement := range bodyElements {
-			body, err := bodyElement.TextContent()
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-			for _, line := range wrapLines(body) {
-				fmt.Fprintf(w, "\t%s\n", strings.TrimSpace(line))
-			}
-			fmt.Fprintf(w, "\n")
-		}
+	links, err := b.writeText(url, w, defaultTextWidth)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Page{w}, nil
+	return &Page{Buffer: w, Canonical: <-canonical, Links: links}, nil
 
 }
 
 func newLineWrapper(softLimit int) func(string) []string {
 	return func(body string) []string {
 		var result []string
+		if softLimit <= 0 && len(body) > 0 {
+			return append(result, body)
+		}
 		for len(body) > softLimit {
 			delimiter := body[softLimit]
 			lineBreakIdx := softLimit
@@ -0,0 +1,26 @@
+package rss
+
+import "testing"
+
+func TestLineWrapper(t *testing.T) {
+	body := "The quick brown fox jumps over the lazy dog"
+	tests := []struct {
+		name     string
+		width    int
+		body     string
+		expected []string
+	}{
+		{name: "Wrapped", width: 10, body: body, expected: []string{"The quick brown", " fox jumps", " over the lazy", " dog"}},
+		{name: "Wide", width: 100, body: body, expected: []string{body}},
+		{name: "Unwrapped", width: 0, body: body, expected: []string{body}},
+		{name: "Empty", width: 0, body: "", expected: nil},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assertEqual(t, tc.expected, newLineWrapper(tc.width)(tc.body))
+		})
+	}
+}
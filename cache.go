@@ -0,0 +1,71 @@
+package rss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache keeps the responses of feeds on disk for a short time, so that
+// commands run one after another don't fetch every feed again. Responses are
+// kept rather than decoded feeds since how they are decoded depends on the
+// options of each command.
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewCache returns a cache in the directory, which is created if need be,
+// holding responses for the given time.
+func NewCache(dir string, ttl time.Duration) (*Cache, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{dir: dir, ttl: ttl}, nil
+}
+
+// WithCache reads feeds from the cache while they are fresh, and caches them
+// when they are fetched.
+func WithCache(c *Cache) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.cache = c
+	}
+}
+
+func (c *Cache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16]))
+}
+
+// Age returns how long ago the response for the url was cached, and whether it
+// is still fresh. Returns false if it isn't cached.
+func (c *Cache) Age(url string) (time.Duration, bool, bool) {
+	info, err := os.Stat(c.path(url))
+	if err != nil {
+		return 0, false, false
+	}
+	age := time.Since(info.ModTime())
+	return age, age <= c.ttl, true
+}
+
+// get returns the cached response for the url if it is still fresh.
+func (c *Cache) get(url string) ([]byte, bool) {
+	path := c.path(url)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > c.ttl {
+		return nil, false
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// put caches the response for the url.
+func (c *Cache) put(url string, body []byte) error {
+	return WriteFileAtomic(c.path(url), body, 0644)
+}
@@ -0,0 +1,56 @@
+package rss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/broken" {
+			w.Write([]byte("<rss><channel><item>"))
+			return
+		}
+		w.Write([]byte(`<rss><channel><title>Feed</title><item><title>One</title><link>https://example.com/1</link></item></channel></rss>`))
+	}))
+	defer server.Close()
+
+	cache, err := NewCache(t.TempDir(), time.Hour)
+	assertEqual(t, nil, err)
+
+	var report FetchReport
+	_, _, cached := cache.Age(server.URL)
+	assertEqual(t, false, cached)
+	feeds := GetFeeds([]string{server.URL}, WithCache(cache), ReportTo(&report))
+	assertEqual(t, 1, len(GetFeedItems(feeds)))
+	assertEqual(t, 0, report.Cached)
+	_, fresh, cached := cache.Age(server.URL)
+	assertEqual(t, true, fresh && cached)
+
+	// The second fetch is served from the cache
+	report = FetchReport{}
+	feeds = GetFeeds([]string{server.URL}, WithCache(cache), ReportTo(&report))
+	assertEqual(t, 1, len(GetFeedItems(feeds)))
+	assertEqual(t, 1, report.Cached)
+	assertEqual(t, 1, requests)
+
+	// Responses which fail to decode aren't cached
+	GetFeeds([]string{server.URL + "/broken"}, WithCache(cache))
+	GetFeeds([]string{server.URL + "/broken"}, WithCache(cache))
+	assertEqual(t, 3, requests)
+
+	// Stale responses are fetched again
+	old := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(cache.path(server.URL), old, old)
+	assertEqual(t, nil, err)
+	age, fresh, _ := cache.Age(server.URL)
+	assertEqual(t, false, fresh)
+	assertEqual(t, true, age > time.Hour)
+	GetFeeds([]string{server.URL}, WithCache(cache))
+	assertEqual(t, 4, requests)
+}
@@ -0,0 +1,200 @@
+package rss
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AzinKhan/functools"
+)
+
+// CanonicalURL returns a key which is the same for URLs of the same feed that
+// differ only in ways which don't matter: http or https, the case of the host,
+// a leading "www.", default ports, trailing slashes and fragments.
+func CanonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	key := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+// ResolveURL follows any redirects from the URL e.g. from feedburner, and
+// returns the URL they end at.
+func ResolveURL(raw string) (string, error) {
+	req, err := http.NewRequest(http.MethodHead, raw, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return resp.Request.URL.String(), nil
+}
+
+// PageCanonicalURL returns the canonical URL declared in the head of the HTML
+// page in r, with <link rel="canonical"> or else the og:url meta property, so
+// that AMP pages and mirrors can be identified with the original. Relative URLs
+// are resolved against base. Returns an empty string if there isn't one.
+func PageCanonicalURL(r io.Reader, base string) string {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var canonical, ogURL string
+	// The canonical URL can only be given in the head
+head:
+	for canonical == "" {
+		token, err := d.Token()
+		if err != nil {
+			break
+		}
+		if end, ok := token.(xml.EndElement); ok && strings.EqualFold(end.Name.Local, "head") {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := make(map[string]string)
+		for _, attr := range start.Attr {
+			attrs[strings.ToLower(attr.Name.Local)] = attr.Value
+		}
+		switch strings.ToLower(start.Name.Local) {
+		case "link":
+			for _, rel := range strings.Fields(attrs["rel"]) {
+				if strings.EqualFold(rel, "canonical") {
+					canonical = attrs["href"]
+				}
+			}
+		case "meta":
+			if attrs["property"] == "og:url" && ogURL == "" {
+				ogURL = attrs["content"]
+			}
+		case "body":
+			break head
+		}
+	}
+	if canonical == "" {
+		canonical = ogURL
+	}
+	if canonical == "" {
+		return ""
+	}
+	u, err := url.Parse(strings.TrimSpace(canonical))
+	if err != nil {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return u.String()
+	}
+	return b.ResolveReference(u).String()
+}
+
+// FetchCanonicalURL fetches the page at the link and returns its canonical URL,
+// or the URL it ends up at after any redirects if it doesn't declare one.
+func FetchCanonicalURL(link string) (string, error) {
+	resp, err := client.Get(link)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	final := resp.Request.URL.String()
+	canonical := PageCanonicalURL(resp.Body, final)
+	if canonical == "" {
+		return final, nil
+	}
+	return canonical, nil
+}
+
+// DuplicateSubscriptions groups the URLs of subscriptions which are for the
+// same feed, in the order they appear. Only groups with more than one URL are
+// returned. If resolve is not nil it is used to find where each URL ends up
+// first, so that redirects are caught too.
+func DuplicateSubscriptions(subs []Subscription, resolve func(string) (string, error)) [][]string {
+	urls := make([]string, len(subs))
+	for i, sub := range subs {
+		urls[i] = sub.URL
+	}
+	if resolve != nil {
+		// URLs which can't be resolved are kept as they are
+		urls = functools.MapAsync(func(u string) string {
+			final, err := resolve(u)
+			if err != nil {
+				return u
+			}
+			return final
+		}, urls)
+	}
+
+	var keys []string
+	groups := make(map[string][]string)
+	for i, sub := range subs {
+		key := CanonicalURL(urls[i])
+		if _, found := groups[key]; !found {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], sub.URL)
+	}
+	var duplicates [][]string
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, groups[key])
+		}
+	}
+	return duplicates
+}
+
+// FindSubscription returns the subscription for the same feed as the URL, if
+// there is one.
+func FindSubscription(subs []Subscription, u string) (Subscription, bool) {
+	key := CanonicalURL(u)
+	for _, sub := range subs {
+		if CanonicalURL(sub.URL) == key {
+			return sub, true
+		}
+	}
+	return Subscription{}, false
+}
+
+// MergeDuplicates removes the subscriptions which are duplicates of earlier
+// ones as found by DuplicateSubscriptions, adding their tags to the one kept.
+func MergeDuplicates(subs []Subscription, duplicates [][]string) []Subscription {
+	keep := make(map[string]string)
+	for _, group := range duplicates {
+		for _, u := range group[1:] {
+			keep[u] = group[0]
+		}
+	}
+	var merged []Subscription
+	indices := make(map[string]int)
+	for _, sub := range subs {
+		if first, found := keep[sub.URL]; found {
+			if i, found := indices[first]; found {
+				for _, tag := range sub.Tags {
+					if !merged[i].HasTag(tag) {
+						merged[i].Tags = append(merged[i].Tags, tag)
+					}
+				}
+				continue
+			}
+		}
+		indices[sub.URL] = len(merged)
+		merged = append(merged, sub)
+	}
+	return merged
+}
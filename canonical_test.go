@@ -0,0 +1,112 @@
+package rss
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		same bool
+	}{
+		{name: "Scheme", a: "http://example.com/rss", b: "https://example.com/rss", same: true},
+		{name: "Trailing slash", a: "https://example.com/feed/", b: "https://example.com/feed", same: true},
+		{name: "Host case and www", a: "https://WWW.Example.com/rss", b: "https://example.com/rss", same: true},
+		{name: "Default port and fragment", a: "https://example.com:443/rss#top", b: "https://example.com/rss", same: true},
+		{name: "Different path", a: "https://example.com/rss", b: "https://example.com/atom", same: false},
+		{name: "Different query", a: "https://example.com/?feed=rss", b: "https://example.com/?feed=atom", same: false},
+		{name: "Other port", a: "https://example.com:8443/rss", b: "https://example.com/rss", same: false},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assertEqual(t, tc.same, CanonicalURL(tc.a) == CanonicalURL(tc.b))
+		})
+	}
+}
+
+func TestDuplicateSubscriptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/feedburner" {
+			http.Redirect(w, r, "/rss", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	subs := []Subscription{
+		{URL: server.URL + "/rss", Tags: []string{"news"}},
+		{URL: "https://example.com/rss"},
+		{URL: server.URL + "/feedburner", Tags: []string{"news", "pinned"}},
+		{URL: "http://example.com/rss/"},
+	}
+	duplicates := DuplicateSubscriptions(subs, nil)
+	assertEqual(t, [][]string{{"https://example.com/rss", "http://example.com/rss/"}}, duplicates)
+
+	// Only resolve the URLs of the test server
+	resolve := func(u string) (string, error) {
+		if !strings.HasPrefix(u, server.URL) {
+			return u, errors.New("not resolved")
+		}
+		return ResolveURL(u)
+	}
+	duplicates = DuplicateSubscriptions(subs, resolve)
+	assertEqual(t, [][]string{
+		{server.URL + "/rss", server.URL + "/feedburner"},
+		{"https://example.com/rss", "http://example.com/rss/"},
+	}, duplicates)
+
+	existing, found := FindSubscription(subs, "https://www.example.com/rss/")
+	assertEqual(t, true, found)
+	assertEqual(t, "https://example.com/rss", existing.URL)
+	_, found = FindSubscription(subs, "https://example.org/rss")
+	assertEqual(t, false, found)
+
+	merged := MergeDuplicates(subs, duplicates)
+	assertEqual(t, []Subscription{
+		{URL: server.URL + "/rss", Tags: []string{"news", "pinned"}},
+		{URL: "https://example.com/rss"},
+	}, merged)
+}
+
+func TestPageCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		expected string
+	}{
+		{
+			name:     "Link",
+			page:     `<html><head><meta property="og:url" content="https://example.com/og"><link rel="canonical" href="https://example.com/post"></head><body></body></html>`,
+			expected: "https://example.com/post",
+		},
+		{
+			name:     "Open Graph",
+			page:     `<!DOCTYPE html><html><head><meta charset="utf-8"><meta property="og:url" content="https://example.com/post"></head></html>`,
+			expected: "https://example.com/post",
+		},
+		{
+			name:     "Relative",
+			page:     `<html><head><LINK REL="Canonical" HREF="/post"></head></html>`,
+			expected: "https://example.com/post",
+		},
+		{
+			name:     "Only in head",
+			page:     `<html><head><title>AMP &amp; more</title></head><body><link rel="canonical" href="https://example.com/post"></body></html>`,
+			expected: "",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			result := PageCanonicalURL(strings.NewReader(tc.page), "https://example.com/amp/post")
+			assertEqual(t, tc.expected, result)
+		})
+	}
+}
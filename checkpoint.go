@@ -0,0 +1,84 @@
+package rss
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// maxCheckpointIDs is the number of items a checkpoint remembers by ID.
+const maxCheckpointIDs = 500
+
+// Checkpoint records the items output by a run, so that the next run only
+// outputs those which are new e.g. for cron jobs appending to a log.
+type Checkpoint struct {
+	// Newest is when the newest item output was published.
+	Newest time.Time `json:"newest"`
+	// IDs are of the items which can't be told apart from new ones by time:
+	// those published at Newest, undated or dated in the future.
+	IDs []string `json:"ids,omitempty"`
+}
+
+// checkpointKey identifies the item by its ID, or else its link.
+func checkpointKey(item FeedItem) string {
+	if item.ID != "" || len(item.Links) == 0 {
+		return item.ID
+	}
+	return item.Links[0]
+}
+
+// Filter passes items which were published no earlier than the newest item of
+// the checkpoint, or are undated, and which it hasn't recorded.
+func (c Checkpoint) Filter() Filter {
+	ids := make(map[string]struct{}, len(c.IDs))
+	for _, id := range c.IDs {
+		ids[id] = struct{}{}
+	}
+	return func(item FeedItem) bool {
+		if _, found := ids[checkpointKey(item)]; found {
+			return false
+		}
+		return item.PublishTime.IsZero() || !item.PublishTime.Before(c.Newest)
+	}
+}
+
+// Update returns the checkpoint after the items were output as of now.
+func (c Checkpoint) Update(feedItems []FeedItem, now time.Time) Checkpoint {
+	next := Checkpoint{Newest: c.Newest, IDs: append([]string(nil), c.IDs...)}
+	// Items dated in the future don't move the checkpoint, which would hide
+	// everything until then
+	dated := func(item FeedItem) bool {
+		return !item.PublishTime.IsZero() && !item.PublishTime.After(now)
+	}
+	for _, item := range feedItems {
+		if dated(item) && item.PublishTime.After(next.Newest) {
+			next.Newest = item.PublishTime
+		}
+	}
+	for _, item := range feedItems {
+		// Skip the headers added by display modes
+		if len(item.Links) == 0 {
+			continue
+		}
+		if !dated(item) || item.PublishTime.Equal(next.Newest) {
+			next.IDs = append(next.IDs, checkpointKey(item))
+		}
+	}
+	if len(next.IDs) > maxCheckpointIDs {
+		next.IDs = next.IDs[len(next.IDs)-maxCheckpointIDs:]
+	}
+	return next
+}
+
+// WriteCheckpoints writes the checkpoints, keyed by the name of what they are
+// for, to w.
+func WriteCheckpoints(w io.Writer, checkpoints map[string]Checkpoint) error {
+	return json.NewEncoder(w).Encode(checkpoints)
+}
+
+// ReadCheckpoints reads checkpoints written by WriteCheckpoints.
+func ReadCheckpoints(r io.Reader) (map[string]Checkpoint, error) {
+	checkpoints := make(map[string]Checkpoint)
+	err := json.NewDecoder(r).Decode(&checkpoints)
+	return checkpoints, err
+}
@@ -0,0 +1,75 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)
+	item := func(id string, published time.Time) FeedItem {
+		return FeedItem{ID: id, Title: id, PublishTime: published, Links: []string{"https://example.com/" + id}}
+	}
+	first := []FeedItem{
+		// A header added by Grouped
+		{Title: "Example"},
+		item("old", now.Add(-3*time.Hour)),
+		item("newest", now.Add(-time.Hour)),
+		item("same time", now.Add(-time.Hour)),
+		item("undated", time.Time{}),
+		item("future", now.Add(24*time.Hour)),
+	}
+	checkpoint := Checkpoint{}.Update(first, now)
+	assertEqual(t, Checkpoint{Newest: now.Add(-time.Hour), IDs: []string{"newest", "same time", "undated", "future"}}, checkpoint)
+
+	tests := []struct {
+		item     FeedItem
+		expected bool
+	}{
+		{item: item("old", now.Add(-3*time.Hour)), expected: false},
+		{item: item("late", now.Add(-2*time.Hour)), expected: false},
+		{item: item("newest", now.Add(-time.Hour)), expected: false},
+		{item: item("undated", time.Time{}), expected: false},
+		{item: item("future", now.Add(24*time.Hour)), expected: false},
+		// Items published at the same time as the newest are told apart by ID
+		{item: item("another at the same time", now.Add(-time.Hour)), expected: true},
+		{item: item("new", now.Add(-time.Minute)), expected: true},
+		{item: item("new undated", time.Time{}), expected: true},
+		{item: FeedItem{Title: "No ID", Links: []string{"https://example.com/no-id"}}, expected: true},
+	}
+	filter := checkpoint.Filter()
+	for _, tc := range tests {
+		assertEqual(t, tc.expected, filter(tc.item))
+	}
+
+	// Newer items move the checkpoint on
+	next := checkpoint.Update([]FeedItem{item("new", now.Add(-time.Minute))}, now)
+	assertEqual(t, now.Add(-time.Minute), next.Newest)
+	assertEqual(t, false, next.Filter()(item("new", now.Add(-time.Minute))))
+	assertEqual(t, false, next.Filter()(item("another at the same time", now.Add(-time.Hour))))
+}
+
+func TestCheckpointLimit(t *testing.T) {
+	t.Parallel()
+	var feedItems []FeedItem
+	for i := 0; i < maxCheckpointIDs+10; i++ {
+		feedItems = append(feedItems, FeedItem{ID: string(rune('a' + i%26)), Links: []string{"link"}})
+	}
+	checkpoint := Checkpoint{}.Update(feedItems, time.Now())
+	assertEqual(t, maxCheckpointIDs, len(checkpoint.IDs))
+}
+
+func TestReadCheckpoints(t *testing.T) {
+	t.Parallel()
+	checkpoints := map[string]Checkpoint{
+		"feed": {Newest: time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC), IDs: []string{"1"}},
+	}
+	var buf bytes.Buffer
+	err := WriteCheckpoints(&buf, checkpoints)
+	assertEqual(t, nil, err)
+	read, err := ReadCheckpoints(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, checkpoints, read)
+}
@@ -1,124 +1,2246 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"text/tabwriter"
 	"time"
 
+	"filippo.io/age"
 	"github.com/AzinKhan/rss"
 )
 
 const (
-	feedsDir  = ".rss"
-	feedsFile = "urls.txt"
+	feedsDir       = ".rss"
+	feedsFile      = "urls.txt"
+	lastRunFile    = "lastrun"
+	linksFile      = "links"
+	cacheDir       = "cache"
+	articlesDir    = "articles"
+	snapshotFile   = "snapshot.json"
+	lastDigestFile = "lastdigest"
+	checkpointFile = "checkpoints.json"
+	postedFile     = "posted.json"
+	notifiedFile   = "notified.json"
+	matrixFile     = "matrix.json"
+	serveStateFile = "serve.json"
+	storedDir      = "stored"
+	historyFile    = rss.HistoryState
+	configFile     = "config.yaml"
+	starsFile      = rss.StarsState
+	tagsFile       = rss.TagsState
+	queueFile      = rss.QueueState
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Expected a subcommand")
+	err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+}
+
+// env holds the paths, config and state shared by the commands.
+type env struct {
+	homeDir        string
+	feedsDirPath   string
+	configFilepath string
+	// sets are the -set key=value overrides of the config
+	sets          []string
+	feedsFilepath string
+	config        rss.Config
+	state         rss.Store
+	history       stateFile
+	stars         stateFile
+	tags          stateFile
+	queue         stateFile
+	subs          []rss.Subscription
+	urls          []string
+}
+
+// run runs the command given on the command line.
+func run() error {
+	if len(os.Args) < 2 {
+		return errors.New("Expected a subcommand")
+	}
+
+	// The demo needs no feeds or config, so that it works anywhere
+	if os.Args[1] == "-demo" || os.Args[1] == "--demo" {
+		return demo()
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	feedsDirPath := path.Join(homeDir, feedsDir)
+	configFilepath := path.Join(feedsDirPath, configFile)
+	if envConfig := os.Getenv("RSS_CONFIG"); envConfig != "" {
+		configFilepath = expandHome(homeDir, envConfig)
+	}
+	// The last run is kept with the config since it is particular to this
+	// machine
+	lastRunFilepath := path.Join(feedsDirPath, lastRunFile)
+	snapshotFilepath := path.Join(feedsDirPath, snapshotFile)
+	lastDigestFilepath := path.Join(feedsDirPath, lastDigestFile)
+	checkpointFilepath := path.Join(feedsDirPath, checkpointFile)
+	postedFilepath := path.Join(feedsDirPath, postedFile)
+	matrixFilepath := path.Join(feedsDirPath, matrixFile)
+
+	// The config is validated before it is loaded in case loading fails
+	if os.Args[1] == "config" {
+		if len(os.Args) < 3 || os.Args[2] != "validate" {
+			return errors.New("Expected 'rss config validate'")
+		}
+		valid, err := validateConfig(configFilepath)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return fmt.Errorf("%s is invalid", configFilepath)
+		}
+		return nil
+	}
+
+	var sets []string
+	os.Args, sets = extractSets(os.Args)
+	config, err := loadOverriddenConfig(configFilepath, sets)
+	if err != nil {
+		return err
+	}
+	stateDirPath := feedsDirPath
+	if config.StateDir != "" {
+		stateDirPath, err = initStateDir(homeDir, config.StateDir)
+		if err != nil {
+			return err
+		}
+	}
+	feedsFilepath := path.Join(stateDirPath, feedsFile)
+	historyFilepath := path.Join(stateDirPath, historyFile)
+	starsFilepath := path.Join(stateDirPath, starsFile)
+	tagsFilepath := path.Join(stateDirPath, tagsFile)
+	queueFilepath := path.Join(stateDirPath, queueFile)
+	storedDirPath := path.Join(stateDirPath, storedDir)
+	identity, err := loadIdentity(homeDir, config.Encryption.Identity)
+	if err != nil {
+		return fmt.Errorf("error reading identity: %s", err.Error())
+	}
+	var state rss.Store = rss.NewFileStore(stateDirPath, rss.WithFeedsFolder(storedDirPath), rss.WithIdentity(identity))
+	if s3 := config.Storage.S3; s3.Bucket != "" {
+		state, err = rss.NewS3Store(s3.Endpoint, s3.Region, s3.Bucket,
+			rss.WithS3Prefix(s3.Prefix),
+			rss.WithS3Credentials(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("AWS_SESSION_TOKEN")),
+			rss.WithS3Identity(identity))
+		if err != nil {
+			return err
+		}
+	}
+	if redis := config.Storage.Redis; redis.URL != "" {
+		opts := []rss.RedisOption{rss.WithRedisIdentity(identity)}
+		if redis.Prefix != "" {
+			opts = append(opts, rss.WithRedisPrefix(redis.Prefix))
+		}
+		// Feeds are still stored on disk or in the bucket
+		state, err = rss.NewRedisStore(redis.URL, state, opts...)
+		if err != nil {
+			return err
+		}
+	}
+	history := stateFile{store: state, name: historyFile}
+	stars := stateFile{store: state, name: starsFile}
+	tags := stateFile{store: state, name: tagsFile}
+	queue := stateFile{store: state, name: queueFile}
+
+	// These work without any feeds so that state can be moved to a new
+	// machine
+	switch os.Args[1] {
+	case "backup", "restore":
+		files := map[string]string{
+			feedsFile:      feedsFilepath,
+			configFile:     configFilepath,
+			historyFile:    historyFilepath,
+			starsFile:      starsFilepath,
+			tagsFile:       tagsFilepath,
+			queueFile:      queueFilepath,
+			lastRunFile:    lastRunFilepath,
+			checkpointFile: checkpointFilepath,
+			// The feeds kept by 'rss store'
+			storedDir + "/": storedDirPath,
+		}
+		return backupOrRestore(os.Args[1], files, os.Args[2:])
+	case "read":
+		return readArticle(os.Args[2:])
+	}
+
+	var subs []rss.Subscription
+	if envURLs, found := os.LookupEnv("RSS_URLS"); found {
+		// Feeds given in the environment replace the feeds file, e.g. in
+		// containers. They are separated by commas or new lines.
+		subs = rss.ReadSubscriptions(strings.NewReader(strings.ReplaceAll(envURLs, ",", "\n")))
+	} else {
+		f, err := os.Open(feedsFilepath)
+		// A client of a daemon needs no feeds of its own
+		if err != nil && !(errors.Is(err, os.ErrNotExist) && usesRemote(config, os.Args)) {
+			fmt.Fprintf(os.Stderr, "No feeds file found, creating one at %s\n", feedsFilepath)
+			// If the file doesn't exist then create it.
+			// If the error is something else then exit.
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			err = os.MkdirAll(stateDirPath, fs.ModePerm)
+			if err != nil {
+				return err
+			}
+			f, err = os.Create(feedsFilepath)
+			if err != nil {
+				return err
+			}
+			f.Close()
+			fmt.Fprintf(os.Stderr, "Run 'rss edit' command to add your first url(s)\n")
+			return nil
+		}
+		if f != nil {
+			subs = rss.ReadSubscriptions(f)
+			f.Close()
+		}
+	}
+	var urls []string
+	for _, sub := range rss.Ordered(subs) {
+		if !sub.Disabled {
+			urls = append(urls, sub.URL)
+		}
+	}
+
+	var interactive bool
+	flag.BoolVar(&interactive, "i", false, "Enable interactive mode")
+
+	flag.Parse()
+
+	command := os.Args[1]
+	if interactive {
+		command = os.Args[2]
+	}
+	switch command {
+	case "edit":
+		return editFeedsFile(feedsFilepath)
+	case "manage":
+		return rss.RunManager(subs, func(subs []rss.Subscription) error {
+			return saveSubscriptions(feedsFilepath, subs)
+		})
+	case "add":
+		return addSubscription(feedsFilepath, subs, os.Args[2:])
+	case "check":
+		return checkSubscriptions(feedsFilepath, subs, os.Args[2:])
+	case "history":
+		return showHistory(history, os.Args[2:])
+	case "stars":
+		return showStars(stars, os.Args[2:])
+	case "queue":
+		return showQueue(queue, history, stars, interactive, config.WaybackSave)
+	case "tag":
+		return tagItem(tags, history, os.Args[2:])
+	case "tagged":
+		return showTagged(tags, os.Args[2:])
+	case "note":
+		return noteStar(stars, os.Args[2:])
+	case "discover-related":
+		return discoverRelated(urls, config.Names())
+	case "status":
+		return showStatus(snapshotFilepath, history, os.Args[2:])
+	case "stats":
+		return showStats(history, urls, config.Names())
+	case "digest":
+		return digest(urls, config, lastDigestFilepath, stars, os.Args[2:])
+	case "post":
+		return post(subs, config, postedFilepath, matrixFilepath, history, os.Args[2:])
+	case "store":
+		return store(urls, config, state, storedDirPath, identity, os.Args[2:])
+	case "export-site":
+		return exportSite(state, storedDirPath, identity, history, stars, os.Args[2:])
+	case "info":
+		return info(subs, config, path.Join(feedsDirPath, cacheDir), os.Args[2:])
+	case "export-ics":
+		return exportICS(subs, config, os.Args[2:])
+	case "serve":
+		// The config and feeds are read again when reloaded
+		reload := func() ([]string, rss.Config, error) {
+			config, err := loadOverriddenConfig(configFilepath, sets)
+			if err != nil {
+				return nil, config, err
+			}
+			urls, err := readURLs(feedsFilepath)
+			return urls, config, err
+		}
+		return serve(urls, config, feedsFilepath, path.Join(feedsDirPath, serveStateFile), reload, os.Args[2:])
+	case "open":
+		return openLinks(history, config.LinkOpener(subs), os.Args[2:])
+	}
+	return runDisplay(env{
+		homeDir:        homeDir,
+		feedsDirPath:   feedsDirPath,
+		configFilepath: configFilepath,
+		sets:           sets,
+		feedsFilepath:  feedsFilepath,
+		config:         config,
+		state:          state,
+		history:        history,
+		stars:          stars,
+		tags:           tags,
+		queue:          queue,
+		subs:           subs,
+		urls:           urls,
+	}, command, interactive)
+}
+
+// runDisplay fetches the feeds and displays their items with the display
+// command, in interactive mode if interactive is set.
+func runDisplay(e env, command string, interactive bool) error {
+	homeDir, feedsDirPath, configFilepath, feedsFilepath := e.homeDir, e.feedsDirPath, e.configFilepath, e.feedsFilepath
+	config, state, subs, urls := e.config, e.state, e.subs, e.urls
+	history, stars, tags, queue := e.history, e.stars, e.tags, e.queue
+	lastRunFilepath := path.Join(feedsDirPath, lastRunFile)
+	snapshotFilepath := path.Join(feedsDirPath, snapshotFile)
+	checkpointFilepath := path.Join(feedsDirPath, checkpointFile)
+	notifiedFilepath := path.Join(feedsDirPath, notifiedFile)
+
+	var displayMode rss.DisplayMode
+	itemFilter := rss.MaxItemsPerChannel
+	var catchUp, pick, releases bool
+	switch command {
+	case "feed":
+		displayMode = rss.ReverseChronological
+		itemFilter = rss.MaxItems
+	case "pick":
+		if len(os.Args) > 2 && os.Args[2] == "-open" {
+			return openPicked(history, config.LinkOpener(subs), os.Args[3:])
+		}
+		displayMode = rss.ReverseChronological
+		itemFilter = rss.MaxItems
+		pick = true
+	case "group":
+		displayMode = rss.Grouped
+	case "releases":
+		displayMode = rss.Releases
+		releases = true
+	case "select":
+		urls = []string{selectSingleFeed(urls)}
+		displayMode = rss.ReverseChronological
+	case "catchup":
+		displayMode = rss.ReverseChronological
+		itemFilter = rss.MaxItems
+		catchUp = true
+	default:
+		return fmt.Errorf("Unknown command %s", command)
+	}
+
+	var maxHours, maxItems, maxRead, titleWidth, minPoints, prefetch, listShare int
+	var highlight, expand, timeZone, dateFormat, tag, itemTag, sanitize, languages, future, remote, remoteToken, themeName, layoutName, profile string
+	var showReadTime, showPoints, showAuthors, showSummaries, arxivPDF, shuffle, stream, byScore, recommend, resolveLinks, footer, noCache, dataSaver, lazyDescriptions, accessible, spacedRows, hidePreview, sinceLastRun bool
+	var timeout time.Duration
+	args := flag.NewFlagSet("display", flag.ExitOnError)
+	if config.MaxAge == 0 {
+		config.MaxAge = 24
+	}
+	args.IntVar(&maxHours, "max", config.MaxAge, "Max age of items (hours)")
+	args.IntVar(&maxItems, "limit", config.Limit, "Max items per channel")
+	args.StringVar(&highlight, "highlight", "", "Colour titles containing keywords e.g. go=red,security=yellow")
+	args.BoolVar(&showReadTime, "readtime", false, "Show the estimated read time of items")
+	args.IntVar(&maxRead, "maxread", 0, "Max estimated read time of items (minutes)")
+	args.BoolVar(&showPoints, "points", false, "Show the points and comment counts of items from sites which give them e.g. hn://front")
+	args.IntVar(&minPoints, "min-points", 0, "Only show items with at least this many points, from sites which give them")
+	args.BoolVar(&showAuthors, "authors", false, "Show the authors of items from feeds which name them")
+	args.BoolVar(&showSummaries, "summaries", false, "Show the summaries given to items by plugins")
+	args.BoolVar(&arxivPDF, "arxiv-pdf", config.ArxivPDF, "Link items from arXiv to their PDFs rather than their abstracts")
+	args.BoolVar(&shuffle, "shuffle", false, fmt.Sprintf("Show a random sample of items, as many as -limit or else %d (feed command only)", defaultSampleSize))
+	args.StringVar(&tag, "tag", "", "Only show items from feeds with the given tag")
+	args.StringVar(&itemTag, "item-tag", "", "Only show items with the given tag, given by hand or by rules")
+	args.StringVar(&expand, "expand", "", "Show the new items from the named feed (catchup command only)")
+	args.StringVar(&timeZone, "tz", config.TimeZone, "Show dates in the given time zone e.g. Local, UTC, Europe/London")
+	args.BoolVar(&byScore, "byscore", false, "Order items by the score given by rules")
+	args.BoolVar(&recommend, "recommend", false, "Show unread items most likely to interest you first, based on your history")
+	args.BoolVar(&stream, "stream", false, "Write each line immediately using fixed-width columns")
+	args.IntVar(&titleWidth, "width", 0, "Max width of titles when streaming, longer titles are truncated")
+	args.StringVar(&dateFormat, "date", config.DateFormat, "Date layout: default, iso, iso-time, short, weekday, us or a Go time layout")
+	args.BoolVar(&accessible, "accessible", config.Accessible, "Write plain lines for screen readers, labelling each part and leaving out colours")
+	args.StringVar(&languages, "lang", strings.Join(config.Languages, ","), "Only show items in the given languages e.g. en,de")
+	args.BoolVar(&sinceLastRun, "since-last-run", false, "Only show items newer than those shown by the last run with this flag and -profile, e.g. from cron")
+	args.StringVar(&profile, "profile", command, "Name of what -since-last-run remembers the items shown by, so that several jobs don't interfere")
+	args.BoolVar(&footer, "footer", false, "Print the number of items shown and feeds which failed after the items")
+	args.StringVar(&future, "future", config.FutureItems, "How to handle items dated in the future: show, hide or clamp")
+	args.StringVar(&sanitize, "sanitize", config.Sanitize, "Clean up titles: none, normalize (control and zero-width characters) or strip (emoji too)")
+	args.BoolVar(&dataSaver, "data-saver", config.DataSaver, "Save data on metered connections: limit the size of feeds, don't resolve links and show descriptions instead of pages")
+	args.BoolVar(&lazyDescriptions, "lazy-descriptions", config.LazyDescriptions, "Drop descriptions while fetching and read them from the feeds kept by 'rss store' when opened")
+	args.IntVar(&prefetch, "prefetch", config.Prefetch, "Fetch the pages of this many unread items in the background in interactive mode, keeping them to read offline")
+	args.StringVar(&themeName, "theme", config.Theme, "Colours of interactive mode: default or high-contrast")
+	args.BoolVar(&spacedRows, "spaced", config.SpacedRows, "Leave a blank line below each item in interactive mode")
+	args.BoolVar(&hidePreview, "no-preview", config.HidePreview, "Hide the right pane in interactive mode, showing items in place of the list when opened")
+	args.StringVar(&layoutName, "layout", config.Layout, "Layout of interactive mode: horizontal, vertical or list")
+	args.IntVar(&listShare, "list-share", config.ListShare, "Percentage of the screen given to the list in interactive mode, 50 by default")
+	args.DurationVar(&timeout, "timeout", 0, "Stop fetching feeds after this long e.g. 20s, showing those which have arrived")
+	args.BoolVar(&noCache, "no-cache", false, "Fetch every feed rather than reusing those fetched recently")
+	args.BoolVar(&resolveLinks, "resolve", config.Redirects.Resolve, "Replace links through redirectors e.g. feedproxy with where they end up")
+	args.StringVar(&remote, "remote", config.Remote.URL, "URL of a daemon run by the serve command to get items from instead of fetching the feeds e.g. https://home.example:8080")
+	args.StringVar(&remoteToken, "token", config.Remote.Token, "Token of the daemon given with -remote")
+	argv := os.Args[2:]
+	if interactive {
+		argv = os.Args[3:]
+	}
+	args.Parse(argv)
+	maxAge := time.Duration(maxHours) * time.Hour
+	if dataSaver {
+		// Resolving links makes a request for each of them
+		resolveLinks = false
+	}
+
+	if tag != "" && command != "select" {
+		urls = nil
+		for _, sub := range rss.Ordered(subs) {
+			if !sub.Disabled && sub.HasTag(tag) {
+				urls = append(urls, sub.URL)
+			}
+		}
+	}
+
+	// Resolved links are cached with the last run since they are only an
+	// optimisation
+	linksFilepath := path.Join(feedsDirPath, linksFile)
+	var resolver *rss.LinkResolver
+	if resolveLinks {
+		resolver = loadLinkResolver(linksFilepath, config.Redirects.Hosts)
+	}
+
+	var filters []rss.Filter
+	if catchUp {
+		lastRun, err := readLastRun(lastRunFilepath)
+		if err == nil {
+			maxAge = time.Since(lastRun)
+		}
+		if expand != "" {
+			filters = append(filters, rss.FromFeeds(expand))
+		}
+	}
+	var checkpoints map[string]rss.Checkpoint
+	if sinceLastRun {
+		var err error
+		checkpoints, err = readCheckpoints(checkpointFilepath)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, checkpoints[profile].Filter())
+	}
+	rules, err := rss.CompileRules(config.Rules)
+	if err != nil {
+		return fmt.Errorf("error in config rules: %s", err.Error())
+	}
+	titles, err := config.TitleTransforms()
+	if err != nil {
+		return fmt.Errorf("error in config feeds: %s", err.Error())
+	}
+
+	blockLinks, err := compileBlockLinks(config.BlockLinks)
+	if err != nil {
+		return err
+	}
+	filters = append(filters, rss.OldestItem(maxAge), blockLinks, rss.DropJunk(config.Junk))
+	if minPoints > 0 {
+		filters = append(filters, rss.MinPoints(minPoints))
+	}
+	if maxRead > 0 {
+		filters = append(filters, rss.MaxReadTime(time.Duration(maxRead)*time.Minute))
+	}
+	if languages != "" {
+		filters = append(filters, rss.Language(strings.Split(languages, ",")...))
+	}
+	filters = append(filters, rules.Filters...)
+	// errs collects the failures of modules, plugins and scripts, which are
+	// printed after the items rather than among them, or shown by the app
+	errs := &rss.ErrorLog{}
+	var wasmScores []rss.DisplayOption
+	for _, p := range config.WasmFilters {
+		module, err := rss.LoadWasm(expandHome(homeDir, p), errs)
+		if err != nil {
+			return err
+		}
+		defer module.Close()
+		filters = append(filters, module.Filter())
+		wasmScores = append(wasmScores, module.Score())
+	}
+	filters = append(filters, rss.Deduplicate(), rss.DeduplicateContent())
+	annotations := append(rules.Display, wasmScores...)
+	notify := rules.Notify
+	if releases {
+		fallback, constraints, err := config.ReleaseConstraints()
+		if err != nil {
+			return fmt.Errorf("error in config releases: %s", err.Error())
+		}
+		notify = rss.Or(notify, rss.ReleaseNotify(fallback, constraints))
+	}
+	tagged, err := readTags(tags)
+	if err != nil {
+		return err
+	}
+	if len(tagged) > 0 {
+		annotations = append(annotations, rss.WithItemTags(tagged))
+	}
+	if recommend {
+		entries, err := readHistory(history)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, rss.Unread(entries))
+		annotations = append(annotations, rss.Recommend(entries))
+		byScore = true
+	}
+	if byScore {
+		displayMode = rss.ByScore
+	}
+	// wholeMode is set when the display mode needs every item at once,
+	// rather than those of each feed as it arrives in interactive mode
+	var wholeMode bool
+	if shuffle && command == "feed" {
+		// Sample after shuffling rather than taking the first items found
+		displayMode = sample(maxItems)
+		wholeMode = true
+	} else if byScore {
+		// Keep the highest scored items rather than the first items found
+		displayMode = rss.Limited(displayMode, func() rss.Filter { return itemFilter(maxItems) })
+		wholeMode = true
+	} else {
+		// Limits must come last so that only items which are displayed count
+		filters = append(filters, itemFilter(maxItems))
+	}
+	if itemTag != "" {
+		// Tags are only known once items have been annotated
+		displayMode = rss.Only(displayMode, rss.HasTag(itemTag))
+	}
+	if pinned := pinnedFeeds(subs, config.Names()); len(pinned) > 0 && !interactive && !pick {
+		// Interactive mode already shows pinned feeds first, and picking
+		// needs every line to be an item
+		displayMode = rss.PinnedFirst(displayMode, pinned...)
+	}
+	// Rules are applied before the display mode so that it can use scores
+	displayMode = rss.Annotated(displayMode, annotations...)
+	plugins, err := config.Plugins()
+	if err != nil {
+		return fmt.Errorf("error in config feeds: %s", err.Error())
+	}
+	if len(plugins) > 0 {
+		// Plugins go first so that rules see e.g. translated titles
+		displayMode = rss.Enriched(displayMode, plugins, errs)
+	}
+	scripts, scriptsByURL, err := loadScripts(homeDir, config, errs)
+	if err != nil {
+		return err
+	}
+	if len(scripts) > 0 {
+		// Scripts are cheap, so hide items before they reach plugins
+		displayMode = rss.Scripted(displayMode, scripts)
+	}
+
+	ageColours := rss.DefaultAgeColours
+	if len(config.AgeColours) > 0 {
+		ageColours, err = parseAgeColours(config.AgeColours)
+		if err != nil {
+			return err
+		}
+	}
+	displayOpts := []rss.DisplayOption{rss.ColourByAge(time.Now(), ageColours)}
+	sanitizeMode, err := rss.ParseSanitizeMode(sanitize)
+	if err != nil {
+		return err
+	}
+	sanitizeModes, err := config.SanitizeModes()
+	if err != nil {
+		return fmt.Errorf("error in config feeds: %s", err.Error())
+	}
+	displayOpts = append(displayOpts, rss.SanitizeTitles(sanitizeMode, sanitizeModes))
+	if len(scripts) > 0 {
+		displayOpts = append(displayOpts, rss.ScriptedTitles(scripts))
+	}
+	keywords := make(map[string]rss.Colour)
+	for keyword, colourName := range config.Highlight {
+		keywords[keyword], err = rss.ParseColour(colourName)
+		if err != nil {
+			return err
+		}
+	}
+	if highlight != "" {
+		// Keywords given on the command line take precedence over the config
+		err = parseHighlights(highlight, keywords)
+		if err != nil {
+			return err
+		}
+	}
+	if len(keywords) > 0 {
+		displayOpts = append(displayOpts, rss.HighlightKeywords(keywords))
+	}
+	if showReadTime {
+		displayOpts = append(displayOpts, rss.ShowReadTime())
+	}
+	if showPoints {
+		displayOpts = append(displayOpts, rss.ShowPoints())
+	}
+	if showAuthors {
+		displayOpts = append(displayOpts, rss.ShowAuthors())
+	}
+	if showSummaries {
+		displayOpts = append(displayOpts, rss.ShowSummaries())
+	}
+	if arxivPDF {
+		displayOpts = append(displayOpts, rss.ArxivPDF())
+	}
+	if timeZone != "" {
+		loc, err := time.LoadLocation(timeZone)
+		if err != nil {
+			return err
+		}
+		displayOpts = append(displayOpts, rss.InLocation(loc))
+	}
+	if dateFormat != "" {
+		displayOpts = append(displayOpts, rss.DateLayout(dateFormat))
+	}
+	if accessible {
+		displayOpts = append(displayOpts, rss.Accessible())
+	}
+	if noColour() {
+		displayOpts = append(displayOpts, rss.WithoutColour())
+	}
+
+	futurePolicy, err := rss.ParseFuturePolicy(future)
+	if err != nil {
+		return err
+	}
+	theme, err := rss.ParseTheme(themeName)
+	if err != nil {
+		return err
+	}
+	layout, err := rss.ParseLayout(layoutName, listShare)
+	if err != nil {
+		return err
+	}
+	// Old items are dropped while decoding to save holding them in memory
+	var report rss.FetchReport
+	fetchOpts := []rss.FetchOption{rss.SkipOlderThan(maxAge), rss.Rename(config.Names()), rss.TransformTitles(titles), rss.FutureItems(futurePolicy), rss.Archive(config.Archive), rss.ReportTo(&report)}
+	if len(scriptsByURL) > 0 {
+		fetchOpts = append(fetchOpts, rss.OnFetchError(func(url string, err error) {
+			if script, found := scriptsByURL[url]; found {
+				script.OnFetchError(url, err)
+			}
+		}))
+	}
+	if !showReadTime && maxRead == 0 && languages == "" && !(dataSaver && interactive && !lazyDescriptions) {
+		// Descriptions are only needed to estimate read times, detect
+		// languages and be read instead of pages, unless they are read
+		// from the store
+		fetchOpts = append(fetchOpts, rss.DropDescriptions())
+	}
+	if resolver != nil {
+		fetchOpts = append(fetchOpts, rss.ResolveLinks(resolver))
+	}
+	if dataSaver {
+		fetchOpts = append(fetchOpts, rss.LimitBody(rss.DataSaverBodyLimit))
+	}
+	if timeout > 0 {
+		fetchOpts = append(fetchOpts, rss.Deadline(time.Now().Add(timeout)))
+	}
+	if !noCache {
+		// The cache is kept with the last run since it is particular to this
+		// machine
+		cache, err := openCache(path.Join(feedsDirPath, cacheDir), config.CacheTTL)
+		if err != nil {
+			return err
+		}
+		if cache != nil {
+			fetchOpts = append(fetchOpts, rss.WithCache(cache))
+		}
+	}
+
+	// getFeeds fetches the feeds, showing those which have arrived rather
+	// than exiting if interrupted. Interrupts exit as usual once the feeds
+	// have been fetched.
+	getFeeds := func() ([]*rss.Feed, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		defer signal.Stop(interrupts)
+		go func() {
+			select {
+			case <-interrupts:
+				fmt.Fprintln(os.Stderr, "Interrupted, showing the feeds fetched so far")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		if remote == "" {
+			return rss.GetFeeds(urls, append(fetchOpts, rss.WithContext(ctx))...), nil
+		}
+		return rss.FetchRemote(remote, remoteToken, append(fetchOpts, rss.WithContext(ctx))...)
+	}
+	// getFeedsAsync sends the feeds as they arrive, from the daemon when
+	// given one
+	getFeedsAsync := func(urls []string, opts ...rss.FetchOption) <-chan *rss.Feed {
+		if remote == "" {
+			return rss.GetFeedsAsync(urls, opts...)
+		}
+		return remoteFeedsAsync(remote, remoteToken, opts...)
+	}
+
+	// displayed holds the items shown, when they are all known up front
+	var displayed []rss.FeedItem
+	switch {
+	case catchUp && expand == "":
+		var feeds []*rss.Feed
+		feeds, err = getFeeds()
+		if err != nil {
+			break
+		}
+		feedItems := rss.GetFeedItems(feeds, filters...)
+		err = rss.DisplaySummary(os.Stdout, feedItems, colourOptions()...)
+		if err == nil {
+			err = notifyAll(feedItems, notify, notifiedFilepath)
+		}
+	case interactive:
+		var historyWriter, starsWriter, tagsWriter, queueWriter io.WriteCloser
+		historyWriter, err = history.appender()
+		if err != nil {
+			break
+		}
+		defer historyWriter.Close()
+		starsWriter, err = stars.appender()
+		if err != nil {
+			break
+		}
+		defer starsWriter.Close()
+		tagsWriter, err = tags.appender()
+		if err != nil {
+			break
+		}
+		defer tagsWriter.Close()
+		feedsCh := getFeedsAsync(urls, fetchOpts...)
+		var exitItems []rss.FeedItem
+		printOnExit := rss.OnExit(func(feedItems []rss.FeedItem) {
+			exitItems = feedItems
+		})
+		queueWriter, err = queue.appender()
+		if err != nil {
+			break
+		}
+		defer queueWriter.Close()
+		appOpts := []rss.AppOption{rss.WithFeedOrder(urls), rss.WithFilters(filters...), rss.WithDisplayOptions(displayOpts...), rss.WithHistory(historyWriter), rss.WithStars(starsWriter, config.WaybackSave), rss.WithTags(tagsWriter), rss.WithQueue(queueWriter), printOnExit, rss.WithTheme(theme), rss.WithLayout(layout), rss.WithOpener(config.LinkOpener(subs)), rss.WithErrors(errs)}
+		if spacedRows {
+			appOpts = append(appOpts, rss.WithSpacedRows())
+		}
+		if wholeMode {
+			appOpts = append(appOpts, rss.ShowWhenArrived())
+		}
+		if hidePreview {
+			appOpts = append(appOpts, rss.WithoutPreview())
+		}
+		// Feeds arrive one at a time, but those of a reload may overlap
+		var notifyMu sync.Mutex
+		appOpts = append(appOpts, rss.OnArrival(func(feedItems []rss.FeedItem) {
+			notifyMu.Lock()
+			defer notifyMu.Unlock()
+			errs.Add(notifyAll(feedItems, notify, notifiedFilepath))
+		}))
+		if command != "select" {
+			// The feeds are fetched afresh with the new names and titles
+			// when the feeds file or config change
+			reload := func() ([]string, <-chan *rss.Feed, error) {
+				config, err := loadOverriddenConfig(configFilepath, e.sets)
+				if err != nil {
+					return nil, nil, err
+				}
+				titles, err := config.TitleTransforms()
+				if err != nil {
+					return nil, nil, fmt.Errorf("error in config feeds: %s", err.Error())
+				}
+				subs, err := readSubscriptions(feedsFilepath)
+				if err != nil {
+					return nil, nil, err
+				}
+				urls := enabledURLs(subs, tag)
+				opts := append(append([]rss.FetchOption{}, fetchOpts...), rss.Rename(config.Names()), rss.TransformTitles(titles))
+				if timeout > 0 {
+					opts = append(opts, rss.Deadline(time.Now().Add(timeout)))
+				}
+				return urls, getFeedsAsync(urls, opts...), nil
+			}
+			appOpts = append(appOpts, rss.WithReload([]string{feedsFilepath, configFilepath}, reload))
+		}
+		if dataSaver {
+			appOpts = append(appOpts, rss.WithoutBrowser())
+			if lazyDescriptions {
+				appOpts = append(appOpts, rss.WithStoredDescriptions(func(item rss.FeedItem) ([]byte, error) {
+					return rss.StoredDescription(state, item)
+				}))
+			}
+		} else {
+			var prefetchOpt rss.AppOption
+			prefetchOpt, err = prefetchOption(path.Join(feedsDirPath, articlesDir), history, prefetch)
+			if err != nil {
+				break
+			}
+			appOpts = append(appOpts, prefetchOpt)
+		}
+		err = interactiveDisplay(feedsCh, displayMode, appOpts...)
+		if err == nil && exitItems != nil {
+			_, err = display(exitItems, unchanged, displayOpts...)
+		}
+	case pick:
+		var feeds []*rss.Feed
+		feeds, err = getFeeds()
+		if err != nil {
+			break
+		}
+		feedItems := rss.GetFeedItems(feeds, filters...)
+		displayed = displayMode(feedItems)
+		for _, item := range displayed {
+			for _, o := range displayOpts {
+				item = o(item)
+			}
+			fmt.Println(rss.FormatPick(item))
+		}
+		err = notifyAll(feedItems, notify, notifiedFilepath)
+	case stream:
+		var feeds []*rss.Feed
+		feeds, err = getFeeds()
+		if err != nil {
+			break
+		}
+		feedItems := rss.GetFeedItems(feeds, filters...)
+		displayed, err = displayStreaming(feedItems, displayMode, titleWidth, displayOpts...)
+		if err == nil && footer {
+			err = rss.WriteFooter(os.Stdout, displayed, &report)
+		}
+		if err == nil {
+			err = notifyAll(feedItems, notify, notifiedFilepath)
+		}
+	default:
+		var feeds []*rss.Feed
+		feeds, err = getFeeds()
+		if err != nil {
+			break
+		}
+		feedItems := rss.GetFeedItems(feeds, filters...)
+		displayed, err = display(feedItems, displayMode, displayOpts...)
+		if err == nil && footer {
+			err = rss.WriteFooter(os.Stdout, displayed, &report)
+		}
+		if err == nil {
+			err = notifyAll(feedItems, notify, notifiedFilepath)
+		}
+	}
+	for _, failure := range errs.Errors() {
+		fmt.Fprintln(os.Stderr, failure)
+	}
+	if err != nil {
+		return err
+	}
+	if resolver != nil && resolver.Changed() {
+		err = saveLinkResolver(linksFilepath, resolver)
+		if err != nil {
+			return err
+		}
+	}
+	if displayed != nil && !catchUp {
+		err = writeSnapshot(snapshotFilepath, rss.NewSnapshot(displayed, time.Now()))
+		if err != nil {
+			return err
+		}
+	}
+	if sinceLastRun && displayed != nil {
+		checkpoints[profile] = checkpoints[profile].Update(displayed, time.Now())
+		err = writeCheckpoints(checkpointFilepath, checkpoints)
+		if err != nil {
+			return err
+		}
+	}
+	if !catchUp {
+		// Catching up only previews what is new so it doesn't count as a run
+		err = writeLastRun(lastRunFilepath, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discoverRelated lists the feeds in the blogrolls of the feeds which aren't
+// subscribed to yet.
+func discoverRelated(urls []string, names map[string]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	for _, suggestion := range rss.DiscoverRelated(rss.GetFeeds(urls, rss.Rename(names)), urls) {
+		fmt.Fprintf(w, "%s\t%s\t(via %s)\n", suggestion.Title, suggestion.URL, suggestion.Via)
+	}
+	return w.Flush()
+}
+
+// showHistory displays the items which have been opened, most recent first,
+// optionally only those matching a search query.
+func showHistory(history stateFile, argv []string) error {
+	var search string
+	args := flag.NewFlagSet("history", flag.ExitOnError)
+	args.StringVar(&search, "search", "", "Only show items containing the query")
+	args.Parse(argv)
+
+	entries, err := readHistory(history)
+	if err != nil {
+		return err
+	}
+	if search != "" {
+		entries = rss.SearchHistory(entries, search)
+	}
+	feedItems := make([]rss.FeedItem, 0, len(entries))
+	for _, entry := range entries {
+		feedItems = append(feedItems, entry.FeedItem())
+	}
+	_, err = display(feedItems, rss.ReverseChronological)
+	return err
+}
+
+// readStars reads the starred items, of which there may be none yet.
+func readStars(starsFile stateFile) ([]rss.Star, error) {
+	return starsFile.store.Stars()
+}
+
+// showStars displays the starred items, most recently starred first, along
+// with their notes and any copies saved on the Wayback Machine.
+func showStars(starsFile stateFile, argv []string) error {
+	var ids bool
+	args := flag.NewFlagSet("stars", flag.ExitOnError)
+	args.BoolVar(&ids, "ids", false, "Show the IDs of items, for adding notes to them")
+	args.Parse(argv)
+
+	stars, err := readStars(starsFile)
+	if err != nil {
+		return err
+	}
+	feedItems := make([]rss.FeedItem, 0, len(stars))
+	for _, star := range stars {
+		item := star.FeedItem()
+		if ids && len(star.ID) >= 8 {
+			item.Title = star.ID[:8] + " " + item.Title
+		}
+		if star.Snapshot != "" {
+			item.Links = append(item.Links, star.Snapshot)
+		}
+		feedItems = append(feedItems, item)
+	}
+	_, err = display(feedItems, rss.ReverseChronological)
+	return err
+}
+
+// showQueue displays the items in the reading queue in the order they were
+// added. In interactive mode, items are popped from the queue as they are
+// opened.
+func showQueue(queueFile, history, starsFile stateFile, interactive, waybackSave bool) error {
+	r, err := queueFile.read()
+	if err != nil {
+		return err
+	}
+	queue, err := rss.ReadQueue(r)
+	if err != nil {
+		return err
+	}
+	if !interactive {
+		feedItems := make([]rss.FeedItem, len(queue))
+		for i, entry := range queue {
+			feedItems[i] = entry.FeedItem()
+			feedItems[i].PublishTime = entry.Published
+		}
+		_, err = display(feedItems, unchanged)
+		return err
+	}
+	if len(queue) == 0 {
+		fmt.Println("The queue is empty")
+		return nil
+	}
+
+	var historyWriter, starsWriter, queueWriter io.WriteCloser
+	historyWriter, err = history.appender()
+	if err != nil {
+		return err
+	}
+	defer historyWriter.Close()
+	starsWriter, err = starsFile.appender()
+	if err != nil {
+		return err
+	}
+	defer starsWriter.Close()
+	queueWriter, err = queueFile.appender()
+	if err != nil {
+		return err
+	}
+	defer queueWriter.Close()
+	pop := rss.OnOpen(func(item rss.FeedItem) error {
+		entry := rss.QueueEntry{HistoryEntry: rss.HistoryEntry{Time: time.Now(), Link: item.Links[0]}, Popped: true}
+		return rss.WriteQueueEntry(queueWriter, entry)
+	})
+	feeds := make(chan *rss.Feed, 1)
+	feeds <- rss.QueueFeed(queue)
+	close(feeds)
+	return interactiveDisplay(feeds, unchanged, rss.WithHistory(historyWriter), rss.WithStars(starsWriter, waybackSave), pop)
+}
+
+// readTags reads the tags file, which may not exist yet.
+func readTags(tagsFile stateFile) ([]rss.TaggedItem, error) {
+	r, err := tagsFile.read()
+	if err != nil {
+		return nil, err
+	}
+	return rss.ReadTags(r)
+}
+
+// tagItem adds tags to the item with the given link, or removes those starting
+// with "-". The item's title and feed are taken from the history if it has
+// been opened.
+func tagItem(tagsFile, history stateFile, argv []string) error {
+	if len(argv) < 2 {
+		return errors.New("usage: rss tag <link> <tag>... (-tag removes a tag)")
+	}
+	entry := rss.TagEntry{HistoryEntry: rss.HistoryEntry{Link: argv[0]}, Tags: argv[1:]}
+	entries, err := readHistory(history)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Link == argv[0] || e.Canonical == argv[0] {
+			entry.HistoryEntry = e
+		}
+	}
+	entry.Time = time.Now()
+	w, err := tagsFile.appender()
+	if err != nil {
+		return err
+	}
+	err = rss.WriteTagEntry(w, entry)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// showTagged displays the items given the tag by hand, or every tagged item,
+// most recently tagged first.
+func showTagged(tagsFile stateFile, argv []string) error {
+	if len(argv) > 1 {
+		return errors.New("usage: rss tagged [tag]")
+	}
+	tagged, err := readTags(tagsFile)
+	if err != nil {
+		return err
+	}
+	var feedItems []rss.FeedItem
+	for _, item := range tagged {
+		if len(argv) == 1 && !rss.HasTag(argv[0])(rss.FeedItem{Tags: item.Tags}) {
+			continue
+		}
+		feedItem := item.FeedItem()
+		feedItem.Tags = item.Tags
+		feedItems = append(feedItems, feedItem)
+	}
+	_, err = display(feedItems, rss.ReverseChronological)
+	return err
+}
+
+// noteStar adds a note to a starred item, given by the start of its ID or its
+// link, replacing any note it had.
+func noteStar(starsFile stateFile, argv []string) error {
+	if len(argv) != 2 || strings.TrimSpace(argv[1]) == "" {
+		return errors.New("usage: rss note <id or link> <note>")
+	}
+	stars, err := readStars(starsFile)
+	if err != nil {
+		return err
+	}
+	star, err := rss.FindStar(stars, argv[0])
+	if err != nil {
+		return err
+	}
+	star.Note = strings.TrimSpace(argv[1])
+	w, err := starsFile.appender()
+	if err != nil {
+		return err
+	}
+	err = rss.WriteStar(w, star)
+	if err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// showStats displays statistics on reading habits. The feeds are fetched in
+// order to find the ones which have never been read.
+func showStats(history stateFile, urls []string, names map[string]string) error {
+	entries, err := readHistory(history)
+	if err != nil {
+		return err
+	}
+	var titles []string
+	for _, feed := range rss.GetFeeds(urls, rss.Rename(names)) {
+		if feed == nil {
+			continue
+		}
+		titles = append(titles, feed.Title())
+	}
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+	err = rss.DisplayStats(w, rss.NewStats(entries, titles), colourOptions()...)
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// shutdownTimeout is how long requests being served are given to finish when
+// the server is stopped.
+const shutdownTimeout = 10 * time.Second
+
+// serve polls the feeds and serves their items over HTTP until interrupted or
+// terminated, when the requests being served are finished and its state is
+// saved. On SIGHUP the config and feeds are reloaded, keeping the listener.
+func serve(urls []string, config rss.Config, feedsFilepath, statePath string, reload func() ([]string, rss.Config, error), argv []string) error {
+	var addr, callback, token, cert, key string
+	var interval int
+	if config.Serve.Addr == "" {
+		config.Serve.Addr = "localhost:8080"
+	}
+	args := flag.NewFlagSet("serve", flag.ExitOnError)
+	args.StringVar(&addr, "addr", config.Serve.Addr, "Address to listen on")
+	args.StringVar(&token, "token", config.Serve.Token, "Bearer token required to use the API")
+	args.StringVar(&cert, "cert", config.Serve.Cert, "Certificate file to serve over TLS with")
+	args.StringVar(&key, "key", config.Serve.Key, "Key file to serve over TLS with")
+	args.IntVar(&interval, "interval", 15, "How often to poll feeds (minutes)")
+	args.StringVar(&callback, "callback", "", "Public URL of the server, for WebSub hubs and rssCloud services to push updates to")
+	args.Parse(argv)
+
+	// A token given as a flag isn't replaced by the config's on reload
+	tokenFlag := false
+	args.Visit(func(f *flag.Flag) {
+		tokenFlag = tokenFlag || f.Name == "token"
+	})
+	opts, err := serveOptions(config)
+	if err != nil {
+		return err
+	}
+	server, err := rss.NewServer(urls, append(opts,
+		rss.PollEvery(time.Duration(interval)*time.Minute),
+		rss.WithCallbackURL(callback),
+		rss.WithToken(token),
+		rss.WithStateFile(statePath),
+	)...)
+	if err != nil {
+		return err
+	}
+	if (cert == "") != (key == "") {
+		return errors.New("both -cert and -key are needed to serve over TLS")
+	}
+	if token == "" && !isLoopback(addr) {
+		fmt.Fprintf(os.Stderr, "Warning: serving on %s without a token, anyone who can reach it can read your feeds\n", addr)
+	}
+
+	// reloadServer reads the config and feeds again, for the server to use
+	reloadServer := func() error {
+		urls, config, err := reload()
+		if err != nil {
+			return err
+		}
+		opts, err := serveOptions(config)
+		if err != nil {
+			return err
+		}
+		if !tokenFlag {
+			opts = append(opts, rss.WithToken(config.Serve.Token))
+		}
+		server.Reload(urls, opts...)
+		fmt.Fprintf(os.Stderr, "Reloaded %d feeds\n", len(urls))
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangups:
+			}
+			err := reloadServer()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Not reloading: %s\n", err.Error())
+			}
+		}
+	}()
+
+	if telegram := config.Serve.Telegram; telegram.Token != "" {
+		bot := rss.NewTelegramBot(telegram.Token, telegram.Chats, server,
+			rss.OnAddFeed(func(url string, tags []string) error {
+				if _, found := os.LookupEnv("RSS_URLS"); found {
+					return errors.New("the feeds are given in $RSS_URLS, so can't be added to")
+				}
+				subs, err := readSubscriptions(feedsFilepath)
+				if err != nil {
+					return err
+				}
+				err = addFeed(feedsFilepath, subs, rss.Subscription{URL: url, Tags: tags}, false)
+				if err != nil {
+					return err
+				}
+				return reloadServer()
+			}),
+			rss.ListFeeds(func() ([]string, error) {
+				return readURLs(feedsFilepath)
+			}),
+		)
+		go bot.Run(ctx)
+	}
+
+	polling := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(polling)
+	}()
+	// Requests share the context so that event streams end on shutdown
+	httpServer := &http.Server{Addr: addr, Handler: server, BaseContext: func(net.Listener) context.Context { return ctx }}
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		timeout, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		shutdown <- httpServer.Shutdown(timeout)
+	}()
+	if cert != "" {
+		err = httpServer.ListenAndServeTLS(cert, key)
+	} else {
+		err = httpServer.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	err = <-shutdown
+	// A poll in progress is finished before the state is saved
+	<-polling
+	if saveErr := server.Save(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// serveOptions returns the options for the server set in the config, which are
+// applied again when it is reloaded.
+func serveOptions(config rss.Config) ([]rss.ServerOption, error) {
+	fetchOpts, err := configFetchOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := configFilters(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Languages) == 0 {
+		// Descriptions are only needed to detect languages
+		fetchOpts = append(fetchOpts, rss.DropDescriptions())
+	}
+	return []rss.ServerOption{rss.WithFetchOptions(fetchOpts...), rss.WithServerFilters(filters...)}, nil
+}
+
+// readURLs returns the URLs of the enabled feeds, from $RSS_URLS or else the
+// feeds file.
+func readURLs(feedsFilepath string) ([]string, error) {
+	subs, err := readSubscriptions(feedsFilepath)
+	return enabledURLs(subs, ""), err
+}
+
+// readSubscriptions reads the feeds from $RSS_URLS or else the feeds file.
+func readSubscriptions(feedsFilepath string) ([]rss.Subscription, error) {
+	if envURLs, found := os.LookupEnv("RSS_URLS"); found {
+		return rss.ReadSubscriptions(strings.NewReader(strings.ReplaceAll(envURLs, ",", "\n"))), nil
+	}
+	f, err := os.Open(feedsFilepath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return rss.ReadSubscriptions(f), nil
+}
+
+// enabledURLs returns the URLs of the feeds which are enabled and, if a tag is
+// given, have it.
+func enabledURLs(subs []rss.Subscription, tag string) []string {
+	var urls []string
+	for _, sub := range rss.Ordered(subs) {
+		if !sub.Disabled && (tag == "" || sub.HasTag(tag)) {
+			urls = append(urls, sub.URL)
+		}
+	}
+	return urls
+}
+
+// store fetches the feeds and adds their items to those stored by previous
+// runs, keeping an archive of everything they have published.
+func store(urls []string, config rss.Config, state rss.Store, folder string, identity *age.X25519Identity, argv []string) error {
+	var pages int
+	args := flag.NewFlagSet("store", flag.ExitOnError)
+	args.StringVar(&folder, "dir", folder, "Folder to store the feeds in")
+	args.IntVar(&pages, "pages", 0, "Also store up to this many older pages of feeds which haven't been stored before, if they are paged or archived")
+	args.Parse(argv)
+	args.Visit(func(f *flag.Flag) {
+		if f.Name == "dir" {
+			state = rss.NewFileStore(folder, rss.WithFeedsFolder(folder), rss.WithIdentity(identity))
+		}
+	})
+
+	fetchOpts, err := configFetchOptions(config)
+	if err != nil {
+		return err
+	}
+	var report rss.FetchReport
+	fetchOpts = append(fetchOpts, rss.ReportTo(&report))
+	// Only new feeds are paged, since older items are already stored for
+	// the rest
+	stored := make(map[string]bool)
+	if pages > 0 {
+		feeds, err := state.LoadFeeds()
+		if err != nil {
+			return err
+		}
+		for _, feed := range feeds {
+			stored[feed.URL] = true
+		}
+	}
+	var current, added []string
+	for _, url := range urls {
+		if pages > 0 && !stored[url] {
+			added = append(added, url)
+		} else {
+			current = append(current, url)
+		}
+	}
+	feeds := rss.GetFeeds(current, fetchOpts...)
+	for _, url := range added {
+		paged, err := rss.FetchPages(url, pages, fetchOpts...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		feeds = append(feeds, paged...)
+	}
+	err = rss.SaveAll(state, feeds)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stored %d feeds in %s\n", len(feeds), state)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d feeds failed to fetch", report.Failed)
+	}
+	return nil
+}
+
+// exportSite renders the feeds stored by the store command as a static HTML
+// site in the given directory, with the notes on starred items.
+func exportSite(state rss.Store, folder string, identity *age.X25519Identity, history, starsFile stateFile, argv []string) error {
+	var read bool
+	args := flag.NewFlagSet("export-site", flag.ExitOnError)
+	args.StringVar(&folder, "dir", folder, "Folder the feeds were stored in")
+	args.BoolVar(&read, "read", false, "Only include items which have been opened, from the history")
+	args.Parse(argv)
+	if args.NArg() != 1 {
+		return errors.New("usage: rss export-site [-read] <dir>")
+	}
+	args.Visit(func(f *flag.Flag) {
+		if f.Name == "dir" {
+			state = rss.NewFileStore(folder, rss.WithFeedsFolder(folder), rss.WithIdentity(identity))
+		}
+	})
+
+	feeds, err := state.LoadFeeds()
+	if err != nil {
+		return err
+	}
+	if len(feeds) == 0 {
+		return fmt.Errorf("no feeds stored in %s, run 'rss store' first", state)
+	}
+	var filters []rss.Filter
+	if read {
+		entries, err := readHistory(history)
+		if err != nil {
+			return err
+		}
+		unread := rss.Unread(entries)
+		filters = append(filters, func(item rss.FeedItem) bool { return !unread(item) })
+	}
+	stars, err := readStars(starsFile)
+	if err != nil {
+		return err
+	}
+	return rss.ExportSite(args.Arg(0), feeds, filters, rss.WithNotes(stars))
+}
+
+// info fetches a single feed, given by its URL or name, and prints what it says
+// about itself, its items, how long it took to fetch and whether it was cached
+// beforehand. The cache isn't used, so that the fetch is timed.
+func info(subs []rss.Subscription, config rss.Config, cacheDir string, argv []string) error {
+	args := flag.NewFlagSet("info", flag.ExitOnError)
+	args.Parse(argv)
+	if args.NArg() != 1 {
+		return errors.New("usage: rss info <url|name>")
+	}
+	url, err := findFeed(subs, config.Names(), args.Arg(0))
+	if err != nil {
+		return err
+	}
+	fetchOpts, err := configFetchOptions(config)
+	if err != nil {
+		return err
+	}
+	cache, err := openCache(cacheDir, config.CacheTTL)
+	if err != nil {
+		return err
+	}
+	cacheStatus := "disabled"
+	if cache != nil {
+		cacheStatus = "not cached"
+		if age, fresh, cached := cache.Age(url); cached {
+			cacheStatus = fmt.Sprintf("cached %s ago (stale)", age.Round(time.Second))
+			if fresh {
+				cacheStatus = fmt.Sprintf("cached %s ago (fresh)", age.Round(time.Second))
+			}
+		}
+	}
+
+	start := time.Now()
+	feeds, err := rss.FetchFeeds(url, fetchOpts...)
+	latency := time.Since(start)
+	if err != nil {
+		return err
+	}
+	for i, feed := range feeds {
+		if i > 0 {
+			fmt.Println()
+		}
+		err = rss.WriteFeedInfo(os.Stdout, feed, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	err = rss.WriteInfoField(os.Stdout, "Fetched in", latency.Round(time.Millisecond).String())
+	if err != nil {
+		return err
+	}
+	return rss.WriteInfoField(os.Stdout, "Cache", cacheStatus)
+}
+
+// findFeed returns the URL of the feed with the given URL or name. URLs which
+// aren't subscribed to are returned as they are.
+func findFeed(subs []rss.Subscription, names map[string]string, feed string) (string, error) {
+	for _, sub := range subs {
+		if sub.URL == feed {
+			return sub.URL, nil
+		}
+	}
+	for url, name := range names {
+		if strings.EqualFold(name, feed) {
+			return url, nil
+		}
+	}
+	if strings.Contains(feed, "://") {
+		return feed, nil
+	}
+	return "", fmt.Errorf("no feed with the URL or name %q", feed)
+}
+
+// exportICS writes the items of feeds which have dates in their titles or
+// descriptions, e.g. meetups, as an iCalendar file.
+func exportICS(subs []rss.Subscription, config rss.Config, argv []string) error {
+	var tag, output string
+	var upcoming bool
+	args := flag.NewFlagSet("export-ics", flag.ExitOnError)
+	args.StringVar(&tag, "tag", "", "Only export items from feeds with the given tag e.g. events")
+	args.StringVar(&output, "o", "", "File to write the calendar to instead of stdout")
+	args.BoolVar(&upcoming, "upcoming", false, "Only export events which haven't happened yet")
+	args.Parse(argv)
+
+	var urls []string
+	for _, sub := range rss.Ordered(subs) {
+		if !sub.Disabled && (tag == "" || sub.HasTag(tag)) {
+			urls = append(urls, sub.URL)
+		}
+	}
+	fetchOpts, err := configFetchOptions(config)
+	if err != nil {
+		return err
+	}
+	filters, err := configFilters(config)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	events := rss.Events(rss.GetFeeds(urls, fetchOpts...), append(filters, rss.Deduplicate())...)
+	if upcoming {
+		// All day events are kept until the end of the day
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		var future []rss.Event
+		for _, event := range events {
+			if !event.Start.Before(today) {
+				future = append(future, event)
+			}
+		}
+		events = future
+	}
+	if len(events) == 0 {
+		return errors.New("no items with dates found")
+	}
+
+	w := os.Stdout
+	if output != "" {
+		w, err = os.Create(output)
+		if err != nil {
+			return err
+		}
+	}
+	err = rss.WriteICS(w, events, now)
+	if output == "" {
+		return err
+	}
+	if err != nil {
+		w.Close()
+		return err
+	}
+	err = w.Close()
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "Wrote %d events to %s\n", len(events), output)
+	}
+	return err
+}
+
+// postInterval is how long to wait between posts, since chat services limit
+// how quickly messages can be posted.
+const postInterval = 500 * time.Millisecond
+
+// post posts the items which haven't been posted before to a chat service,
+// oldest first, e.g. when run from cron to bridge feeds to a channel.
+func post(subs []rss.Subscription, config rss.Config, postedFilepath, matrixFilepath string, history stateFile, argv []string) error {
+	var target, tag string
+	var backfill, threads bool
+	args := flag.NewFlagSet("post", flag.ExitOnError)
+	args.StringVar(&target, "target", os.Getenv("RSS_POST_TARGET"), "Where to post items: slack://<webhook>, discord://<webhook> or matrix://<token>@<homeserver>/<room>")
+	args.StringVar(&tag, "tag", "", "Only post items from feeds with the given tag")
+	args.BoolVar(&backfill, "backfill", false, "Post the items already in the feeds the first time a target is used, rather than only recording them")
+	args.BoolVar(&threads, "threads", false, "Post the items of each feed in a thread of its own (Matrix only)")
+	args.Parse(argv)
+	if target == "" || args.NArg() != 0 {
+		return errors.New("usage: rss post -target <slack://...|discord://...|matrix://...>")
+	}
+	poster, err := rss.ParseTarget(target)
+	if err != nil {
+		return err
+	}
+	follower, following := poster.(rss.Follower)
+	if threads && !following {
+		return errors.New("-threads needs a matrix:// target")
+	}
+	if following {
+		rooms, err := readMatrixRooms(matrixFilepath)
+		if err != nil {
+			return err
+		}
+		follower = follower.Follow(rooms.Room(target), threads)
+		poster = follower
+		// The room is saved however posting goes, since its threads and
+		// the reactions read can't be told apart from new ones otherwise
+		defer func() {
+			err := writeMatrixRooms(matrixFilepath, rooms)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+		err = markReacted(follower, history)
+		if err != nil {
+			return err
+		}
+	}
+	posted, err := readPosted(postedFilepath)
+	if err != nil {
+		return err
+	}
+	fetchOpts, err := configFetchOptions(config)
+	if err != nil {
+		return err
+	}
+	filters, err := configFilters(config)
+	if err != nil {
+		return err
+	}
+	maxAge := time.Duration(config.MaxAge) * time.Hour
+	if maxAge == 0 {
+		maxAge = 24 * time.Hour
+	}
+	feeds := rss.GetFeeds(enabledURLs(subs, tag), append(fetchOpts, rss.SkipOlderThan(maxAge))...)
+	filters = append(filters, posted.Filter(target), rss.Deduplicate())
+	feedItems := rss.ReverseChronological(rss.GetFeedItems(feeds, filters...))
+
+	if !posted.Has(target) && !backfill {
+		// A new target isn't flooded with everything already in the feeds
+		posted.Add(target, feedItems...)
+		fmt.Fprintf(os.Stderr, "Recorded %d items without posting them, pass -backfill to post them\n", len(feedItems))
+		return writePosted(postedFilepath, posted)
+	}
+	var count int
+	for i := len(feedItems) - 1; i >= 0; i-- {
+		if count > 0 {
+			time.Sleep(postInterval)
+		}
+		err = poster.Post(feedItems[i])
+		if err != nil {
+			break
+		}
+		posted.Add(target, feedItems[i])
+		count++
+	}
+	// The items posted before any failure are still recorded
+	writeErr := writePosted(postedFilepath, posted)
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// markReacted records the items reacted to in the room as read.
+func markReacted(follower rss.Follower, history stateFile) error {
+	read, err := follower.Reacted()
+	if err != nil || len(read) == 0 {
+		return err
+	}
+	historyWriter, err := history.appender()
+	if err != nil {
+		return err
+	}
+	for _, entry := range read {
+		err = rss.WriteHistory(historyWriter, entry)
+		if err != nil {
+			historyWriter.Close()
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Marked %d items reacted to as read\n", len(read))
+	return historyWriter.Close()
+}
+
+func readMatrixRooms(filepath string) (rss.MatrixRooms, error) {
+	f, err := os.Open(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(rss.MatrixRooms), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return rss.ReadMatrixRooms(f)
+}
+
+func writeMatrixRooms(filepath string, rooms rss.MatrixRooms) error {
+	err := os.MkdirAll(path.Dir(filepath), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = rss.WriteMatrixRooms(&buf, rooms)
+	if err != nil {
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, buf.Bytes(), 0644)
+}
+
+func readPosted(filepath string) (rss.Posted, error) {
+	f, err := os.Open(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(rss.Posted), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return rss.ReadPosted(f)
+}
+
+func writePosted(filepath string, posted rss.Posted) error {
+	err := os.MkdirAll(path.Dir(filepath), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = rss.WritePosted(&buf, posted)
+	if err != nil {
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, buf.Bytes(), 0644)
+}
+
+// digest writes the items published since the last digest to stdout or a
+// webhook, without touching the terminal, so that it can run on a schedule
+// e.g. in a container. Given an address to listen on, it repeats at an
+// interval instead of exiting and reports its health at /healthz.
+func digest(urls []string, config rss.Config, lastDigestFilepath string, starsFile stateFile, argv []string) error {
+	var format, webhook, listen string
+	var every int
+	args := flag.NewFlagSet("digest", flag.ExitOnError)
+	args.StringVar(&format, "format", "text", "Format of the digest: text or json")
+	args.StringVar(&webhook, "webhook", "", "URL to post the digest to as JSON instead of writing it to stdout")
+	args.StringVar(&listen, "listen", "", "Address to serve /healthz on, repeating the digest instead of exiting")
+	args.IntVar(&every, "every", 24*60, "How often to repeat the digest with -listen (minutes)")
+	args.Parse(argv)
+
+	fetchOpts, err := configFetchOptions(config)
+	if err != nil {
+		return err
+	}
+	filters, err := configFilters(config)
+	if err != nil {
+		return err
+	}
+	maxAge := time.Duration(config.MaxAge) * time.Hour
+	if maxAge == 0 {
+		maxAge = 24 * time.Hour
+	}
+
+	run := func() error {
+		now := time.Now()
+		since, err := readLastRun(lastDigestFilepath)
+		if err != nil || now.Sub(since) > maxAge {
+			// The first digest, or one after a long gap, only covers the max
+			// age
+			since = now.Add(-maxAge)
+		}
+		feeds := rss.GetFeeds(urls, append(fetchOpts, rss.SkipOlderThan(now.Sub(since)))...)
+		// Filters are copied so that each run deduplicates afresh
+		runFilters := append(append([]rss.Filter{}, filters...), rss.Deduplicate(), rss.DeduplicateContent())
+		// Stars are read each run so that notes added since are included
+		stars, err := readStars(starsFile)
+		if err != nil {
+			return err
+		}
+		feedItems := rss.Annotated(unchanged, rss.WithNotes(stars))(rss.GetFeedItems(feeds, runFilters...))
+		d := rss.NewDigest(feedItems, since, now)
+		if webhook != "" {
+			err = rss.PostDigest(webhook, d)
+		} else {
+			err = rss.WriteDigest(os.Stdout, d, format)
+		}
+		if err != nil {
+			return err
+		}
+		return writeLastRun(lastDigestFilepath, now)
+	}
+	if listen == "" {
+		return run()
+	}
+
+	var mu sync.Mutex
+	var lastDigest time.Time
+	var lastErr error
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		status := struct {
+			LastDigest time.Time `json:"last_digest"`
+			Error      string    `json:"error,omitempty"`
+		}{LastDigest: lastDigest}
+		if lastErr != nil {
+			status.Error = lastErr.Error()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	httpServer := &http.Server{Addr: listen, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	running := make(chan struct{})
+	go func() {
+		defer close(running)
+		ticker := time.NewTicker(time.Duration(every) * time.Minute)
+		defer ticker.Stop()
+		for {
+			err := run()
+			mu.Lock()
+			lastErr = err
+			if err == nil {
+				lastDigest = time.Now()
+			}
+			mu.Unlock()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	err = httpServer.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// A digest in progress is finished so that the last run is recorded
+	<-running
+	return nil
+}
+
+// noColour returns whether output to the terminal is left uncoloured, as asked
+// for with NO_COLOR by the convention of https://no-color.org
+func noColour() bool {
+	return os.Getenv("RSS_NO_COLOR") != "" || os.Getenv("NO_COLOR") != ""
+}
+
+// colourOptions returns the options formatting output to the terminal.
+func colourOptions() []rss.FormatOption {
+	if noColour() {
+		return []rss.FormatOption{rss.NoColours()}
+	}
+	return nil
+}
+
+// configFetchOptions returns the options for fetching feeds set in the config,
+// for the commands which don't take display flags.
+func configFetchOptions(config rss.Config) ([]rss.FetchOption, error) {
+	titles, err := config.TitleTransforms()
+	if err != nil {
+		return nil, fmt.Errorf("error in config feeds: %s", err.Error())
+	}
+	future, err := rss.ParseFuturePolicy(config.FutureItems)
+	if err != nil {
+		return nil, err
+	}
+	return []rss.FetchOption{rss.Rename(config.Names()), rss.TransformTitles(titles), rss.FutureItems(future), rss.Archive(config.Archive)}, nil
+}
+
+// configFilters returns the filters set in the config, for the commands which
+// don't take display flags.
+func configFilters(config rss.Config) ([]rss.Filter, error) {
+	rules, err := rss.CompileRules(config.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("error in config rules: %s", err.Error())
+	}
+	blockLinks, err := compileBlockLinks(config.BlockLinks)
+	if err != nil {
+		return nil, err
+	}
+	filters := []rss.Filter{blockLinks, rss.DropJunk(config.Junk)}
+	if len(config.Languages) > 0 {
+		filters = append(filters, rss.Language(config.Languages...))
+	}
+	return append(filters, rules.Filters...), nil
+}
+
+// isLoopback returns true if addr can only be reached from this machine.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// readArticle writes the text of the page at a link to stdout, as shown in
+// interactive mode, e.g. to pipe into less.
+func readArticle(argv []string) error {
+	args := flag.NewFlagSet("read", flag.ExitOnError)
+	var width int
+	args.IntVar(&width, "width", 72, "Wrap lines after this many characters, or not at all if 0")
+	args.Parse(argv)
+	// Flags may come after the link too
+	var link string
+	if args.NArg() > 0 {
+		link = args.Arg(0)
+		args.Parse(args.Args()[1:])
+	}
+	if link == "" || args.NArg() != 0 {
+		return errors.New("usage: rss read <link> [-width 72]")
+	}
+	b, err := rss.NewBrowser()
+	if err != nil {
+		return err
+	}
+	defer b.Stop()
+	w := bufio.NewWriter(os.Stdout)
+	err = b.WriteText(link, w, rss.TextWidth(width))
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// backupOrRestore bundles the state files into an archive, or unpacks one.
+func backupOrRestore(command string, files map[string]string, argv []string) error {
+	var overwrite bool
+	args := flag.NewFlagSet(command, flag.ExitOnError)
+	args.BoolVar(&overwrite, "force", false, "Replace existing files when restoring")
+	args.Parse(argv)
+	if args.NArg() != 1 {
+		return fmt.Errorf("usage: rss %s <file.tar.gz>", command)
+	}
+	if command == "restore" {
+		f, err := os.Open(args.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return rss.Restore(f, files, overwrite)
+	}
+	f, err := os.Create(args.Arg(0))
+	if err != nil {
+		return err
+	}
+	err = rss.Backup(f, files)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readHistory reads the history file, which may not exist yet.
+func readHistory(history stateFile) ([]rss.HistoryEntry, error) {
+	r, err := history.read()
+	if err != nil {
+		return nil, err
+	}
+	return rss.ReadHistory(r)
+}
+
+// stateFile is a state of records which are only ever appended to, such as the
+// history, kept in the store.
+type stateFile struct {
+	store rss.Store
+	name  string
+}
+
+// read returns the records, which may not exist yet.
+func (sf stateFile) read() (io.Reader, error) {
+	return sf.store.ReadState(sf.name)
+}
+
+// appender opens the state for records to be appended to it.
+func (sf stateFile) appender() (io.WriteCloser, error) {
+	return sf.store.AppendState(sf.name)
+}
+
+// loadIdentity reads the age identity used to encrypt the state files, if one
+// is configured.
+func loadIdentity(homeDir, filepath string) (*age.X25519Identity, error) {
+	if filepath == "" {
+		return nil, nil
+	}
+	f, err := os.Open(expandHome(homeDir, filepath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return rss.LoadIdentity(f, os.Getenv("RSS_PASSPHRASE"))
+}
+
+// desktopTarget is what desktop notifications are recorded as posted to.
+const desktopTarget = "notify-send"
+
+// notifyAll sends a desktop notification for each item passing the filter,
+// unless one has been sent for it before as recorded in the notified file.
+// Notifications are best effort, so failures to send them are ignored.
+func notifyAll(feedItems []rss.FeedItem, notify rss.Filter, notifiedFilepath string) error {
+	var matched []rss.FeedItem
+	for _, item := range feedItems {
+		if notify(item) {
+			matched = append(matched, item)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	notified, err := readPosted(notifiedFilepath)
+	if err != nil {
+		return err
+	}
+	unnotified := notified.Filter(desktopTarget)
+	var sent []rss.FeedItem
+	for _, item := range matched {
+		if !unnotified(item) {
+			continue
+		}
+		exec.Command("notify-send", item.Source(), item.Title).Run()
+		sent = append(sent, item)
+	}
+	if len(sent) == 0 {
+		return nil
+	}
+	notified.Add(desktopTarget, sent...)
+	return writePosted(notifiedFilepath, notified)
+}
 
-	homeDir, err := os.UserHomeDir()
+// showStatus prints a one line summary of the items shown by the last run,
+// for status bars. It doesn't fetch the feeds so that it is quick.
+func showStatus(snapshotFilepath string, history stateFile, argv []string) error {
+	var format string
+	args := flag.NewFlagSet("status", flag.ExitOnError)
+	args.StringVar(&format, "format", "{unread} unread", "Summary with {unread}, {total}, {newest}, {feed} and {age} replaced")
+	args.Parse(argv)
+
+	var snapshot rss.Snapshot
+	f, err := os.Open(snapshotFilepath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Nothing has been shown yet
+	case err != nil:
+		return err
+	default:
+		defer f.Close()
+		snapshot, err = rss.ReadSnapshot(f)
+		if err != nil {
+			return err
+		}
+	}
+	entries, err := readHistory(history)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
-		os.Exit(1)
+		return err
 	}
+	fmt.Println(rss.FormatStatus(format, snapshot, entries, time.Now()))
+	return nil
+}
 
-	feedsDirPath := path.Join(homeDir, feedsDir)
-	feedsFilepath := path.Join(feedsDirPath, feedsFile)
+// writeSnapshot records the items shown in the file for the status command.
+func writeSnapshot(filepath string, snapshot rss.Snapshot) error {
+	err := os.MkdirAll(path.Dir(filepath), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = rss.WriteSnapshot(&buf, snapshot)
+	if err != nil {
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, buf.Bytes(), 0644)
+}
 
-	f, err := os.Open(feedsFilepath)
+// readCheckpoints returns the checkpoints recorded in the given file, or none
+// if there isn't one yet.
+func readCheckpoints(filepath string) (map[string]rss.Checkpoint, error) {
+	f, err := os.Open(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]rss.Checkpoint), nil
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "No feeds file found, creating one at %s\n", feedsFilepath)
-		// If the file doesn't exist then create it.
-		// If the error is something else then exit.
-		if !errors.Is(err, os.ErrNotExist) {
-			fmt.Fprintf(os.Stderr, err.Error())
-			os.Exit(1)
+		return nil, err
+	}
+	defer f.Close()
+	return rss.ReadCheckpoints(f)
+}
+
+func writeCheckpoints(filepath string, checkpoints map[string]rss.Checkpoint) error {
+	err := os.MkdirAll(path.Dir(filepath), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	err = rss.WriteCheckpoints(&buf, checkpoints)
+	if err != nil {
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, buf.Bytes(), 0644)
+}
+
+// readLastRun returns the time recorded in the given file.
+func readLastRun(filepath string) (time.Time, error) {
+	b, err := os.ReadFile(filepath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+}
+
+// writeLastRun records the given time in the file. The directory is created
+// if need be, since the feeds may have been given in the environment.
+func writeLastRun(filepath string, t time.Time) error {
+	err := os.MkdirAll(path.Dir(filepath), fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, []byte(t.Format(time.RFC3339)), 0644)
+}
+
+// openLinks opens the links given as arguments or on stdin with the opener,
+// choosing its command by the tags given, and records them in the history.
+func openLinks(history stateFile, opener rss.Opener, argv []string) error {
+	var tags string
+	args := flag.NewFlagSet("open", flag.ExitOnError)
+	args.StringVar(&tags, "tag", "", "Comma separated tags choosing the command from openers in the config")
+	args.Parse(argv)
+
+	links := args.Args()
+	if len(links) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			links = append(links, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	var item rss.FeedItem
+	if tags != "" {
+		item.Tags = strings.Split(tags, ",")
+	}
+	historyWriter, err := history.appender()
+	if err != nil {
+		return err
+	}
+	defer historyWriter.Close()
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		if link == "" {
+			continue
 		}
-		err = os.Mkdir(feedsDirPath, fs.ModePerm)
+		err = opener.Open(item, link)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, err.Error())
-			os.Exit(1)
+			return err
 		}
-		f, err = os.Create(feedsFilepath)
+		err = rss.WriteHistory(historyWriter, rss.HistoryEntry{Time: time.Now(), Link: link})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, err.Error())
-			os.Exit(1)
+			return err
 		}
-		fmt.Fprintf(os.Stderr, "Run 'rss edit' command to add your first url(s)\n")
-		os.Exit(0)
 	}
-	defer f.Close()
-	urls := rss.GetURLs(f)
-
-	var displayMode rss.DisplayMode
-	itemFilter := rss.MaxItemsPerChannel
-
-	var interactive bool
-	flag.BoolVar(&interactive, "i", false, "Enable interactive mode")
-
-	flag.Parse()
+	return nil
+}
 
-	command := os.Args[1]
-	if interactive {
-		command = os.Args[2]
+// openPicked opens the links of the lines chosen from the output of 'rss
+// pick', given as arguments or else read from stdin, and records them in the
+// history. Nothing being chosen is not an error.
+func openPicked(history stateFile, opener rss.Opener, argv []string) error {
+	lines := argv
+	if len(lines) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
 	}
-	switch command {
-	case "edit":
-		err := editFeedsFile(feedsFilepath)
+	historyWriter, err := history.appender()
+	if err != nil {
+		return err
+	}
+	defer historyWriter.Close()
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		title, link, err := rss.ParsePick(line)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, err.Error())
-			os.Exit(1)
+			return err
+		}
+		// Lines only give the title and link, so only the opener's default
+		// command applies to them
+		err = opener.Open(rss.FeedItem{Title: title}, link)
+		if err != nil {
+			return err
+		}
+		err = rss.WriteHistory(historyWriter, rss.HistoryEntry{Time: time.Now(), Title: title, Link: link})
+		if err != nil {
+			return err
 		}
-		return
-	case "feed":
-		displayMode = rss.ReverseChronological
-		itemFilter = rss.MaxItems
-	case "group":
-		displayMode = rss.Grouped
-	case "select":
-		urls = []string{selectSingleFeed(urls)}
-		displayMode = rss.ReverseChronological
-	default:
-		fmt.Printf("Unknown command %s\n", command)
-		os.Exit(1)
 	}
+	return nil
+}
 
-	var maxHours, maxItems int
-	args := flag.NewFlagSet("display", flag.ExitOnError)
-	args.IntVar(&maxHours, "max", 24, "Max age of items (hours)")
-	args.IntVar(&maxItems, "limit", 0, "Max items per channel")
-	argv := os.Args[2:]
-	if interactive {
-		argv = os.Args[3:]
+// compileBlockLinks returns the filter blocking links matching the patterns,
+// checking them first since BlockLinks panics on invalid ones.
+func compileBlockLinks(patterns []string) (rss.Filter, error) {
+	for _, pattern := range patterns {
+		_, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error in config block_links: %s", err.Error())
+		}
 	}
-	args.Parse(argv)
-	maxAge := time.Duration(maxHours) * time.Hour
+	return rss.BlockLinks(patterns...), nil
+}
 
-	filters := []rss.Filter{rss.OldestItem(maxAge), rss.Deduplicate(), itemFilter(maxItems)}
+// loadLinkResolver returns a resolver for the default redirectors and the
+// given hosts, with the links resolved by previous runs. A missing or
+// unreadable cache only means links are resolved again.
+func loadLinkResolver(filepath string, hosts []string) *rss.LinkResolver {
+	resolver := rss.NewLinkResolver(append(rss.DefaultRedirectors, hosts...))
+	f, err := os.Open(filepath)
+	if err != nil {
+		return resolver
+	}
+	defer f.Close()
+	resolver.Load(f)
+	return resolver
+}
 
-	if interactive {
-		feedsCh := rss.GetFeedsAsync(urls)
-		err = interactiveDisplay(feedsCh, displayMode, rss.WithFilters(filters...))
-	} else {
-		feeds := rss.GetFeeds(urls)
-		feedItems := rss.GetFeedItems(feeds, filters...)
-		now := time.Now()
-		err = display(feedItems, displayMode, rss.ColourAfter(now.Add(-2*time.Hour)))
+// saveLinkResolver writes the resolved links to the file for the next run.
+func saveLinkResolver(filepath string, resolver *rss.LinkResolver) error {
+	err := os.MkdirAll(path.Dir(filepath), fs.ModePerm)
+	if err != nil {
+		return err
 	}
+	var buf bytes.Buffer
+	err = resolver.Save(&buf)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, err.Error())
-		os.Exit(1)
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, buf.Bytes(), 0644)
+}
+
+// defaultSampleSize is the number of items sampled by -shuffle without a limit.
+const defaultSampleSize = 10
+
+// sample returns a display mode which shuffles the items and keeps at most n
+// of them, or defaultSampleSize if n is zero.
+func sample(n int) rss.DisplayMode {
+	if n <= 0 {
+		n = defaultSampleSize
+	}
+	return func(feedItems []rss.FeedItem) []rss.FeedItem {
+		feedItems = rss.Shuffled(feedItems)
+		if len(feedItems) > n {
+			feedItems = feedItems[:n]
+		}
+		return feedItems
 	}
 }
 
@@ -165,6 +2287,204 @@ func printSelection(urls []string) {
 	fmt.Fprintf(os.Stdout, builder.String())
 }
 
+// parseHighlights parses a comma-separated list of keyword=colour pairs into
+// the given map.
+func parseHighlights(s string, keywords map[string]rss.Colour) error {
+	for _, pair := range strings.Split(s, ",") {
+		keyword, colourName, found := strings.Cut(pair, "=")
+		if !found || keyword == "" {
+			return fmt.Errorf("invalid highlight %q, expected keyword=colour", pair)
+		}
+		colour, err := rss.ParseColour(colourName)
+		if err != nil {
+			return err
+		}
+		keywords[keyword] = colour
+	}
+	return nil
+}
+
+// parseAgeColours parses the age thresholds given in the config.
+func parseAgeColours(raw map[string]string) ([]rss.AgeColour, error) {
+	ageColours := make([]rss.AgeColour, 0, len(raw))
+	for rawAge, colourName := range raw {
+		age, err := time.ParseDuration(rawAge)
+		if err != nil {
+			return nil, err
+		}
+		var colour rss.Colour
+		if colourName != "default" {
+			colour, err = rss.ParseColour(colourName)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ageColours = append(ageColours, rss.AgeColour{Age: age, Colour: colour})
+	}
+	return ageColours, nil
+}
+
+// extractSets removes the -set key=value overrides of config options from
+// the arguments, returning them separately since they apply to every command.
+func extractSets(argv []string) ([]string, []string) {
+	var rest, sets []string
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		switch {
+		case (arg == "-set" || arg == "--set") && i+1 < len(argv):
+			sets = append(sets, argv[i+1])
+			i++
+		case strings.HasPrefix(arg, "-set="), strings.HasPrefix(arg, "--set="):
+			_, set, _ := strings.Cut(arg, "=")
+			sets = append(sets, set)
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, sets
+}
+
+// loadConfig reads the config file, which is optional.
+// validateConfig reports any problems with the config file. Returns true if
+// there are none.
+func validateConfig(filepath string) (bool, error) {
+	f, err := os.Open(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("No config found at %s\n", filepath)
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	problems, err := rss.ValidateConfig(f)
+	if err != nil {
+		return false, fmt.Errorf("%s: %s", filepath, err.Error())
+	}
+	for _, problem := range problems {
+		fmt.Printf("%s:%d: %s\n", filepath, problem.Line, problem.Message)
+	}
+	return len(problems) == 0, nil
+}
+
+// loadOverriddenConfig loads the config, which is overridden by the
+// environment, then by -set key=value on the command line, then by the flags
+// for particular options. It is loaded this way again when reloaded.
+func loadOverriddenConfig(filepath string, sets []string) (rss.Config, error) {
+	config, err := loadConfig(filepath)
+	if err != nil {
+		return config, fmt.Errorf("error reading config: %s\nRun 'rss config validate' for details", err.Error())
+	}
+	err = config.ApplyEnv(os.LookupEnv)
+	if err != nil {
+		return config, err
+	}
+	for _, set := range sets {
+		key, value, _ := strings.Cut(set, "=")
+		err = config.Set(key, value)
+		if err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
+
+func loadConfig(filepath string) (rss.Config, error) {
+	f, err := os.Open(filepath)
+	if errors.Is(err, os.ErrNotExist) {
+		return rss.Config{}, nil
+	}
+	if err != nil {
+		return rss.Config{}, err
+	}
+	defer f.Close()
+	return rss.LoadConfig(f)
+}
+
+// initStateDir returns the path of the state directory, creating it if need
+// be. Git is told to merge the history, stars, tags and queue by keeping the
+// lines from both sides, since they are only ever appended to.
+func initStateDir(homeDir, dir string) (string, error) {
+	dir = expandHome(homeDir, dir)
+	err := os.MkdirAll(dir, fs.ModePerm)
+	if err != nil {
+		return "", err
+	}
+	attributes := path.Join(dir, ".gitattributes")
+	_, err = os.Stat(attributes)
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.WriteFile(attributes, []byte(historyFile+" merge=union\n"+starsFile+" merge=union\n"+tagsFile+" merge=union\n"+queueFile+" merge=union\n"), 0644)
+	}
+	return dir, err
+}
+
+// expandHome replaces a leading "~/" in the path with the home directory.
+func expandHome(homeDir, p string) string {
+	if strings.HasPrefix(p, "~/") {
+		return path.Join(homeDir, p[2:])
+	}
+	return p
+}
+
+// addSubscription adds a feed to the feeds file, unless it is already
+// subscribed to under another URL.
+func addSubscription(filepath string, subs []rss.Subscription, argv []string) error {
+	var force bool
+	args := flag.NewFlagSet("add", flag.ExitOnError)
+	args.BoolVar(&force, "force", false, "Add the feed even if it looks like a duplicate")
+	args.Parse(argv)
+	if args.NArg() == 0 {
+		return errors.New("usage: rss add <url> [tags...]")
+	}
+	return addFeed(filepath, subs, rss.Subscription{URL: args.Arg(0), Tags: args.Args()[1:]}, force)
+}
+
+// addFeed adds the subscription to the feeds file, unless it is already
+// subscribed to and force isn't given.
+func addFeed(filepath string, subs []rss.Subscription, sub rss.Subscription, force bool) error {
+	if !force {
+		existing, found := rss.FindSubscription(subs, sub.URL)
+		if !found {
+			// Catch redirects e.g. from feedburner to the feed itself
+			if resolved, err := rss.ResolveURL(sub.URL); err == nil {
+				existing, found = rss.FindSubscription(subs, resolved)
+			}
+		}
+		if found {
+			return fmt.Errorf("already subscribed as %s, use -force to add it anyway", existing.URL)
+		}
+	}
+	return saveSubscriptions(filepath, append(subs, sub))
+}
+
+// checkSubscriptions reports subscriptions which are for the same feed,
+// following redirects, optionally merging them.
+func checkSubscriptions(filepath string, subs []rss.Subscription, argv []string) error {
+	var merge bool
+	args := flag.NewFlagSet("check", flag.ExitOnError)
+	args.BoolVar(&merge, "merge", false, "Keep only the first of each set of duplicates")
+	args.Parse(argv)
+
+	duplicates := rss.DuplicateSubscriptions(subs, rss.ResolveURL)
+	for _, group := range duplicates {
+		fmt.Printf("Duplicate feeds: %s\n", strings.Join(group, ", "))
+	}
+	if !merge || len(duplicates) == 0 {
+		return nil
+	}
+	return saveSubscriptions(filepath, rss.MergeDuplicates(subs, duplicates))
+}
+
+// saveSubscriptions replaces the feeds file with the given subscriptions.
+func saveSubscriptions(filepath string, subs []rss.Subscription) error {
+	var buf bytes.Buffer
+	err := rss.WriteSubscriptions(&buf, subs)
+	if err != nil {
+		return err
+	}
+	return rss.WriteFileAtomic(filepath, buf.Bytes(), 0644)
+}
+
 func editFeedsFile(filepath string) error {
 	cmd := exec.Command("vim", filepath)
 	cmd.Stdin = os.Stdin
@@ -173,19 +2493,173 @@ func editFeedsFile(filepath string) error {
 	return cmd.Run()
 }
 
-func display(feedItems []rss.FeedItem, mode rss.DisplayMode, opts ...rss.DisplayOption) error {
+func display(feedItems []rss.FeedItem, mode rss.DisplayMode, opts ...rss.DisplayOption) ([]rss.FeedItem, error) {
+	// The mode is applied up front so that the items displayed can be
+	// returned
+	feedItems = mode(feedItems)
 	w := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-	err := rss.Display(w, feedItems, mode, opts...)
+	err := rss.Display(w, feedItems, unchanged, opts...)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	err = w.Flush()
+	if err != nil {
+		return nil, err
+	}
+	return feedItems, nil
+}
+
+// pinnedFeeds returns the feeds with the pinned tag, by their name or else
+// their URL.
+func pinnedFeeds(subs []rss.Subscription, names map[string]string) []string {
+	var pinned []string
+	for _, sub := range subs {
+		if sub.Disabled || !sub.Pinned() {
+			continue
+		}
+		if name, found := names[sub.URL]; found {
+			pinned = append(pinned, name)
+		} else {
+			pinned = append(pinned, sub.URL)
+		}
+	}
+	return pinned
+}
+
+// unchanged is a display mode which leaves the items as they are.
+func unchanged(feedItems []rss.FeedItem) []rss.FeedItem {
+	return feedItems
+}
+
+// displayStreaming writes the items using fixed-width columns computed up front
+// so that each line can be written immediately rather than buffered.
+func displayStreaming(feedItems []rss.FeedItem, mode rss.DisplayMode, titleWidth int, opts ...rss.DisplayOption) ([]rss.FeedItem, error) {
+	feedItems = mode(feedItems)
+	widths := rss.ColumnWidths(feedItems, opts...)
+	if titleWidth > 0 && len(widths) > 1 && widths[1] > titleWidth {
+		widths[1] = titleWidth
+	}
+	w := rss.NewColumnWriter(os.Stdout, widths...)
+	err := rss.Display(w, feedItems, unchanged, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return feedItems, w.Flush()
+}
+
+// demo runs interactive mode on canned feeds, without fetching anything or
+// starting the browser, e.g. to try it out or take screenshots. Nothing done
+// in the demo is recorded.
+func demo() error {
+	feeds, err := rss.DemoFeeds(time.Now())
 	if err != nil {
 		return err
 	}
-	return nil
+	return interactiveDisplay(rss.SendFeeds(feeds), rss.Grouped,
+		rss.WithoutBrowser(), rss.WithStars(io.Discard, false), rss.WithTags(io.Discard), rss.WithQueue(io.Discard))
 }
 
 func interactiveDisplay(feeds <-chan *rss.Feed, mode rss.DisplayMode, opts ...rss.AppOption) error {
 	return rss.RunApp(feeds, mode, opts...)
 }
+
+// articlesMaxAge is how long the pages of items are kept for offline reading.
+const articlesMaxAge = 7 * 24 * time.Hour
+
+// prefetchOption keeps the pages opened in interactive mode in dir, and
+// prefetches the pages of the first n unread items.
+func prefetchOption(dir string, history stateFile, n int) (rss.AppOption, error) {
+	articles, err := rss.NewArticles(dir, articlesMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	var unread rss.Filter
+	if n > 0 {
+		entries, err := readHistory(history)
+		if err != nil {
+			return nil, err
+		}
+		unread = rss.Unread(entries)
+	}
+	return rss.WithPrefetch(articles, n, unread), nil
+}
+
+// usesRemote returns true if items are got from a daemon, given in the config
+// or with -remote.
+func usesRemote(config rss.Config, argv []string) bool {
+	if config.Remote.URL != "" {
+		return true
+	}
+	for _, arg := range argv {
+		if arg == "-remote" || arg == "--remote" || strings.HasPrefix(arg, "-remote=") || strings.HasPrefix(arg, "--remote=") {
+			return true
+		}
+	}
+	return false
+}
+
+// loadScripts loads the Lua scripts of feeds, keyed by the feed's name or else
+// its URL, and by its URL. Feeds sharing a script share its state. The
+// failures of hooks are added to errs.
+func loadScripts(homeDir string, config rss.Config, errs *rss.ErrorLog) (map[string]*rss.LuaScript, map[string]*rss.LuaScript, error) {
+	byFeed := make(map[string]*rss.LuaScript)
+	byURL := make(map[string]*rss.LuaScript)
+	loaded := make(map[string]*rss.LuaScript)
+	for url, feed := range config.Feeds {
+		if feed.Script == "" {
+			continue
+		}
+		p := expandHome(homeDir, feed.Script)
+		script, found := loaded[p]
+		if !found {
+			var err error
+			script, err = rss.LoadLuaScript(p, errs)
+			if err != nil {
+				return nil, nil, err
+			}
+			loaded[p] = script
+		}
+		byURL[url] = script
+		if feed.Name != "" {
+			byFeed[feed.Name] = script
+		} else {
+			byFeed[url] = script
+		}
+	}
+	return byFeed, byURL, nil
+}
+
+// remoteFeedsAsync gets the feeds from the daemon in the background, reporting
+// an error rather than exiting since the app is running.
+func remoteFeedsAsync(remote, token string, opts ...rss.FetchOption) <-chan *rss.Feed {
+	feedsCh := make(chan *rss.Feed)
+	go func() {
+		defer close(feedsCh)
+		feeds, err := rss.FetchRemote(remote, token, opts...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, err.Error())
+			return
+		}
+		for _, feed := range feeds {
+			feedsCh <- feed
+		}
+	}()
+	return feedsCh
+}
+
+// openCache returns the cache of fetched feeds in dir, or nil if the ttl
+// turns it off.
+func openCache(dir, ttl string) (*rss.Cache, error) {
+	d := 5 * time.Minute
+	if ttl != "" {
+		var err error
+		d, err = time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache_ttl: %w", err)
+		}
+	}
+	if d <= 0 {
+		return nil, nil
+	}
+	return rss.NewCache(dir, d)
+}
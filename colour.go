@@ -3,6 +3,7 @@ package rss
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 type Colour string
@@ -20,6 +21,38 @@ const (
 	white  Colour = "\033[97m"
 )
 
+// Exported colours for use in display options.
+const (
+	Red    = red
+	Green  = green
+	Yellow = yellow
+	Blue   = blue
+	Purple = purple
+	Cyan   = cyan
+	Gray   = gray
+	White  = white
+)
+
+var colourNames = map[string]Colour{
+	"red":    red,
+	"green":  green,
+	"yellow": yellow,
+	"blue":   blue,
+	"purple": purple,
+	"cyan":   cyan,
+	"gray":   gray,
+	"white":  white,
+}
+
+// ParseColour returns the Colour with the given name e.g. "red".
+func ParseColour(name string) (Colour, error) {
+	c, ok := colourNames[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("unknown colour %q", name)
+	}
+	return c, nil
+}
+
 type colourizer interface {
 	colourize(string, Colour) string
 }
@@ -34,17 +67,52 @@ type stringWriter interface {
 	WriteString(string) (int, error)
 }
 
-type formatOption func(*formatSettings)
+// FormatOption configures how FormatItem writes an item.
+type FormatOption func(*formatSettings)
 
-func includeLinks(include bool) formatOption {
+// IncludeLinks writes the links of the item after its title.
+func IncludeLinks(include bool) FormatOption {
 	return func(fs *formatSettings) {
 		fs.includeLinks = include
 	}
 }
 
-func setColourizer(c colourizer) formatOption {
+// ANSIColours colours the text with ANSI escape codes, which is the default.
+func ANSIColours() FormatOption {
+	return func(fs *formatSettings) {
+		fs.colourizer = colourizeFunc(colourizeANSI)
+	}
+}
+
+// TviewColours colours the text with tview's colour tags, as in interactive
+// mode.
+func TviewColours() FormatOption {
 	return func(fs *formatSettings) {
-		fs.colourizer = c
+		fs.colourizer = colourizeFunc(colourizeInteractive)
+	}
+}
+
+// NoColours leaves the text uncoloured.
+func NoColours() FormatOption {
+	return func(fs *formatSettings) {
+		fs.colourizer = colourizeFunc(func(s string, _ Colour) string { return s })
+	}
+}
+
+// WithoutColour leaves items uncoloured when they are formatted, e.g. for
+// terminals without colour.
+func WithoutColour() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		item.uncoloured = true
+		return item
+	}
+}
+
+// FormatAt writes how long ago feeds were updated as of now, rather than the
+// time the item is formatted.
+func FormatAt(now time.Time) FormatOption {
+	return func(fs *formatSettings) {
+		fs.now = now
 	}
 }
 
@@ -52,28 +120,54 @@ type formatSettings struct {
 	title        Colour
 	date         Colour
 	link         Colour
+	tag          Colour
 	colourizer   colourizer
 	includeLinks bool
+	now          time.Time
 }
 
-func formatFeed(fi FeedItem, opts ...formatOption) string {
+// colourizerOf returns the colourizer chosen by the options, which colours
+// text with ANSI escape codes unless they say otherwise.
+func colourizerOf(opts []FormatOption) colourizer {
+	settings := &formatSettings{colourizer: colourizeFunc(colourizeANSI)}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	return settings.colourizer
+}
+
+// FormatItem returns the item as a line of text, with its date, title, tags
+// and note, and its links if included, separated by tabs. The text is coloured
+// with ANSI escape codes unless the options say otherwise.
+func FormatItem(fi FeedItem, opts ...FormatOption) string {
 	// Set some defaults
 	settings := &formatSettings{
 		title:        green,
 		date:         yellow,
 		link:         blue,
-		colourizer:   colourizeFunc(colourize),
+		tag:          purple,
+		colourizer:   colourizeFunc(colourizeANSI),
 		includeLinks: false,
 	}
 	for _, opt := range opts {
 		opt(settings)
 	}
+	if settings.now.IsZero() {
+		settings.now = time.Now()
+	}
+	if fi.accessible {
+		return formatAccessible(fi, settings)
+	}
 
 	c := settings.colourizer
 
 	builder := &strings.Builder{}
 	if !fi.PublishTime.IsZero() {
-		date := fi.PublishTime.Format(outputTimeLayout)
+		layout := outputTimeLayout
+		if fi.dateLayout != "" {
+			layout = fi.dateLayout
+		}
+		date := fi.PublishTime.Format(layout)
 		builder.WriteString(fmt.Sprintf("%s:", c.colourize(date, settings.date)))
 	}
 
@@ -81,11 +175,27 @@ func formatFeed(fi FeedItem, opts ...formatOption) string {
 		// This is one of the items acting as a title card for the feed so
 		// colour its title green.
 		fi.Title = c.colourize(fi.Title, settings.title)
+	} else if fi.colour != "" {
+		fi.Title = c.colourize(fi.Title, fi.colour)
 	}
 	builder.WriteString(fmt.Sprintf("\t%s", fi.Title))
+	if !fi.updated.IsZero() {
+		// Feeds dated in the future were updated just now
+		since := settings.now.Sub(fi.updated)
+		if since < 0 {
+			since = 0
+		}
+		builder.WriteString(" " + c.colourize("— updated "+shortDuration(since)+" ago", gray))
+	}
+	for _, tag := range fi.Tags {
+		builder.WriteString(fmt.Sprintf(" %s", c.colourize("#"+tag, settings.tag)))
+	}
+	if fi.Note != "" {
+		builder.WriteString(fmt.Sprintf(" %s", c.colourize("("+fi.Note+")", gray)))
+	}
 	if settings.includeLinks {
 		for _, link := range fi.Links {
-			builder.WriteString(fmt.Sprintf("\t%s", colourize(link, blue)))
+			builder.WriteString(fmt.Sprintf("\t%s", c.colourize(link, settings.link)))
 		}
 	}
 	builder.WriteString("\n")
@@ -93,10 +203,10 @@ func formatFeed(fi FeedItem, opts ...formatOption) string {
 }
 
 func formatFeedInteractive(fi FeedItem) string {
-	return formatFeed(fi, setColourizer(colourizeFunc(colourizeInteractive)))
+	return FormatItem(fi, TviewColours())
 }
 
-func colourize(text string, c Colour) string {
+func colourizeANSI(text string, c Colour) string {
 	return fmt.Sprintf("%s%s%s", c, text, reset)
 }
 
@@ -0,0 +1,154 @@
+package rss
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// ColumnWriter writes lines of tab-separated cells as fixed-width columns.
+// Unlike a tabwriter, which buffers all of its output in order to compute the
+// column widths, each line is written as soon as it is complete.
+type ColumnWriter struct {
+	w      io.Writer
+	widths []int
+	buf    []byte
+}
+
+// NewColumnWriter returns a ColumnWriter which pads the cells of each line to
+// the given widths, truncating cells which are too long. Cells beyond the
+// given widths are written as they are.
+func NewColumnWriter(w io.Writer, widths ...int) *ColumnWriter {
+	return &ColumnWriter{w: w, widths: widths}
+}
+
+// Write writes any complete lines in p, buffering the remainder until the
+// rest of the line is written or Flush is called.
+func (cw *ColumnWriter) Write(p []byte) (int, error) {
+	cw.buf = append(cw.buf, p...)
+	for {
+		i := bytes.IndexByte(cw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		err := cw.writeLine(string(cw.buf[:i]))
+		if err != nil {
+			return 0, err
+		}
+		cw.buf = cw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any incomplete line.
+func (cw *ColumnWriter) Flush() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	err := cw.writeLine(string(cw.buf))
+	cw.buf = cw.buf[:0]
+	return err
+}
+
+func (cw *ColumnWriter) writeLine(line string) error {
+	cells := strings.Split(line, "\t")
+	builder := &strings.Builder{}
+	for i, cell := range cells {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+		if i >= len(cw.widths) || i == len(cells)-1 {
+			// Don't pad the final cell
+			builder.WriteString(cell)
+			continue
+		}
+		width := cw.widths[i]
+		cell = truncateVisible(cell, width)
+		builder.WriteString(cell)
+		builder.WriteString(strings.Repeat(" ", width-visibleLen(cell)))
+	}
+	builder.WriteString("\n")
+	_, err := io.WriteString(cw.w, builder.String())
+	return err
+}
+
+// ColumnWidths returns the widths of the columns needed to fit every item,
+// once the display options are applied, without truncation.
+func ColumnWidths(feedItems []FeedItem, opts ...DisplayOption) []int {
+	var widths []int
+	for _, item := range feedItems {
+		for _, o := range opts {
+			item = o(item)
+		}
+		line := strings.TrimSuffix(item.Format(), "\n")
+		for i, cell := range strings.Split(line, "\t") {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := visibleLen(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	return widths
+}
+
+// visibleLen returns the number of characters in s which are displayed i.e.
+// excluding colour codes.
+func visibleLen(s string) int {
+	return utf8.RuneCountInString(stripColours(s))
+}
+
+func stripColours(s string) string {
+	builder := &strings.Builder{}
+	for len(s) > 0 {
+		if code := colourCodeLen(s); code > 0 {
+			s = s[code:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		builder.WriteRune(r)
+		s = s[size:]
+	}
+	return builder.String()
+}
+
+// truncateVisible shortens s to at most n displayed characters, keeping any
+// colour codes intact.
+func truncateVisible(s string, n int) string {
+	if visibleLen(s) <= n {
+		return s
+	}
+	builder := &strings.Builder{}
+	var count int
+	for len(s) > 0 {
+		if code := colourCodeLen(s); code > 0 {
+			builder.WriteString(s[:code])
+			s = s[code:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		if count < n-1 {
+			builder.WriteRune(r)
+		} else if count == n-1 {
+			builder.WriteRune('…')
+		}
+		count++
+	}
+	return builder.String()
+}
+
+// colourCodeLen returns the length of the colour code at the start of s, or
+// zero if there isn't one.
+func colourCodeLen(s string) int {
+	if !strings.HasPrefix(s, "\033[") {
+		return 0
+	}
+	end := strings.IndexByte(s, 'm')
+	if end < 0 {
+		return 0
+	}
+	return end + 1
+}
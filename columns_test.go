@@ -0,0 +1,35 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestColumnWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewColumnWriter(&buf, 5, 8)
+
+	_, err := cw.Write([]byte("date:\ta title\tlink\n\tA much longer title"))
+	assertEqual(t, nil, err)
+	// The first line is written without waiting for a flush
+	assertEqual(t, "date: a title  link\n", buf.String())
+
+	err = cw.Flush()
+	assertEqual(t, nil, err)
+	assertEqual(t, "date: a title  link\n      A much longer title\n", buf.String())
+}
+
+func TestTruncateVisible(t *testing.T) {
+	coloured := colourizeANSI("abcdef", green)
+	result := truncateVisible(coloured, 4)
+	assertEqual(t, 4, visibleLen(result))
+	assertEqual(t, colourizeANSI("abc…", green), result)
+}
+
+func TestColumnWidths(t *testing.T) {
+	feedItems := []FeedItem{
+		{Title: "Feed"},
+		{Title: colourizeANSI("Coloured", red), Links: []string{"link"}},
+	}
+	assertEqual(t, []int{0, 8, 4}, ColumnWidths(feedItems))
+}
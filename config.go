@@ -0,0 +1,329 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds the user's preferences. Each field can be overridden for a
+// single invocation by an environment variable, see ApplyEnv, or on the
+// command line, which takes precedence.
+type Config struct {
+	// MaxAge is the age in hours of the oldest items shown.
+	MaxAge int `yaml:"max_age"`
+	// Limit is the number of items shown from each channel, or in total for
+	// the feed command. Zero is no limit.
+	Limit int `yaml:"limit"`
+	// DateFormat is the layout used to display dates. Either one of the
+	// named layouts e.g. "iso", or a Go time layout e.g. "02 Jan".
+	DateFormat string `yaml:"date_format"`
+	// Accessible writes items for screen readers, with each part of a line
+	// labelled and no colours.
+	Accessible bool `yaml:"accessible"`
+	// TimeZone is the zone dates are displayed in e.g. "Local".
+	TimeZone string `yaml:"time_zone"`
+	// Highlight maps keywords to the colour of titles containing them.
+	Highlight map[string]string `yaml:"highlight"`
+	// AgeColours maps item ages e.g. "6h" to the colour of items at least
+	// that old. The colour "default" leaves items uncoloured.
+	AgeColours map[string]string `yaml:"age_colours"`
+	// Rules hide, highlight, tag, score or notify about matching items.
+	Rules []Rule `yaml:"rules"`
+	// WasmFilters are paths of WebAssembly modules whose filter and score
+	// functions are applied to items. See WasmModule.
+	WasmFilters []string `yaml:"wasm_filters"`
+	// Archive configures how paywalled links are archived.
+	Archive ArchiveOptions `yaml:"archive"`
+	// BlockLinks are regular expressions matching the links of items to
+	// hide e.g. "/sponsored/".
+	BlockLinks []string `yaml:"block_links"`
+	// DataSaver saves data on metered connections: feeds are only read up
+	// to a limit, links aren't resolved and interactive mode shows the
+	// descriptions of items instead of fetching their pages.
+	DataSaver bool `yaml:"data_saver"`
+	// LazyDescriptions drops the descriptions of items while fetching, and
+	// reads them from the stored feeds when items are opened in data saver
+	// mode, to save memory.
+	LazyDescriptions bool `yaml:"lazy_descriptions"`
+	// CacheTTL is how long fetched feeds are reused by later commands e.g.
+	// "5m". Defaults to five minutes, and "0" turns the cache off.
+	CacheTTL string `yaml:"cache_ttl"`
+	// Releases is the version constraint a release must meet to be notified
+	// about by the releases command e.g. ">=1.0, <2". Prereleases are never
+	// notified about.
+	Releases string `yaml:"releases"`
+	// Prefetch is the number of unread items whose pages are fetched in the
+	// background in interactive mode, so that they open instantly and can
+	// be read offline.
+	Prefetch int `yaml:"prefetch"`
+	// Theme colours interactive mode, either "default" or "high-contrast".
+	Theme string `yaml:"theme"`
+	// SpacedRows leaves a blank line below each item in interactive mode.
+	SpacedRows bool `yaml:"spaced_rows"`
+	// HidePreview gives the whole screen to the list in interactive mode,
+	// showing items in its place when they are opened.
+	HidePreview bool `yaml:"hide_preview"`
+	// Layout arranges interactive mode: "horizontal" puts the list beside
+	// the pane showing items, "vertical" above it, and "list" shows items
+	// in place of the list, as HidePreview does.
+	Layout string `yaml:"layout"`
+	// ListShare is the percentage of the screen given to the list in
+	// interactive mode, from 10 to 90. Defaults to 50.
+	ListShare int `yaml:"list_share"`
+	// Opener is the command opening links outside of interactive mode's
+	// pane, with 'o' or rss pick -open, e.g. "firefox --private-window %s".
+	// %s is replaced by the link, which is otherwise added to the end.
+	// Defaults to the desktop's handler for links.
+	Opener string `yaml:"opener"`
+	// Openers maps tags of feeds or items to the commands opening their
+	// links instead of Opener e.g. "video: mpv %s".
+	Openers map[string]string `yaml:"openers"`
+	// ArxivPDF links items from arXiv to their PDFs rather than their
+	// abstracts.
+	ArxivPDF bool `yaml:"arxiv_pdf"`
+	// FutureItems is how items dated in the future are handled: "show",
+	// "hide" or "clamp" to the time they were fetched.
+	FutureItems string `yaml:"future_items"`
+	// Junk selects the heuristics used to hide junk items.
+	Junk JunkOptions `yaml:"junk"`
+	// Languages restricts items to those in the given languages e.g. "en",
+	// as far as their language can be told.
+	Languages []string `yaml:"languages"`
+	// Sanitize cleans up the titles of items for display: "normalize"
+	// removes control and zero-width characters, and "strip" also removes
+	// emoji.
+	Sanitize string `yaml:"sanitize"`
+	// Redirects configures the resolution of links through redirects.
+	Redirects RedirectOptions `yaml:"redirects"`
+	// WaybackSave saves starred items to the Wayback Machine.
+	WaybackSave bool `yaml:"wayback_save"`
+	// Feeds holds settings for individual feeds, keyed by their URL.
+	Feeds map[string]FeedConfig `yaml:"feeds"`
+	// StateDir is a directory to keep the feeds, history and stars in
+	// instead of alongside the config, e.g. a git repository or a folder
+	// synced between machines. A leading "~/" is the home directory.
+	StateDir string `yaml:"state_dir"`
+	// Encryption configures the encryption of the history and stars.
+	Encryption EncryptionConfig `yaml:"encryption"`
+	// Serve configures the HTTP server run by the serve command.
+	Serve ServeConfig `yaml:"serve"`
+	// Storage configures where the stored feeds, history and stars are kept
+	// instead of the state folder.
+	Storage StorageConfig `yaml:"storage"`
+	// Remote is a daemon run by the serve command to get items from instead
+	// of fetching the feeds.
+	Remote RemoteConfig `yaml:"remote"`
+}
+
+// RemoteConfig configures a daemon to get items from.
+type RemoteConfig struct {
+	// URL is where the daemon is served e.g. "https://home.example:8080".
+	// The feeds are fetched locally unless it is set.
+	URL string `yaml:"url"`
+	// Token is the daemon's token, if it has one.
+	Token string `yaml:"token"`
+}
+
+// StorageConfig configures where the stored feeds, history and stars are
+// kept.
+type StorageConfig struct {
+	// S3 keeps them in a bucket on S3 or a service compatible with it.
+	S3 S3Config `yaml:"s3"`
+	// Redis keeps the history, stars, tags and queue in Redis, where they
+	// are shared straight away between machines.
+	Redis RedisConfig `yaml:"redis"`
+}
+
+// RedisConfig configures a Redis to keep the history, stars, tags and queue
+// in.
+type RedisConfig struct {
+	// URL is e.g. "redis://:password@localhost:6379/0", or "rediss://" for
+	// TLS. Redis is only used if it is set.
+	URL string `yaml:"url"`
+	// Prefix is put before the name of every key, and defaults to "rss:".
+	Prefix string `yaml:"prefix"`
+}
+
+// S3Config configures a bucket to keep the stored feeds and state in. The
+// credentials are read from $AWS_ACCESS_KEY_ID, $AWS_SECRET_ACCESS_KEY and
+// $AWS_SESSION_TOKEN.
+type S3Config struct {
+	// Bucket is the name of the bucket, which is only used if it is set.
+	Bucket string `yaml:"bucket"`
+	// Endpoint is the URL of the service, which defaults to S3 itself.
+	Endpoint string `yaml:"endpoint"`
+	// Region defaults to us-east-1.
+	Region string `yaml:"region"`
+	// Prefix is put before the name of every object e.g. "rss/".
+	Prefix string `yaml:"prefix"`
+}
+
+// EncryptionConfig configures the encryption of the history and stars.
+type EncryptionConfig struct {
+	// Identity is the path of an age identity file which the history and
+	// stars are encrypted to. If the file is itself encrypted with a
+	// passphrase, the passphrase is read from $RSS_PASSPHRASE.
+	Identity string `yaml:"identity"`
+}
+
+// ServeConfig configures the HTTP server run by the serve command.
+type ServeConfig struct {
+	// Addr is the address to listen on. Defaults to localhost:8080.
+	Addr string `yaml:"addr"`
+	// Token is required as a bearer token by the API when set.
+	Token string `yaml:"token"`
+	// Cert and Key are the paths of the certificate and key used to serve
+	// over TLS.
+	Cert string `yaml:"cert"`
+	Key  string `yaml:"key"`
+	// Telegram runs a bot pushing new items to chats and managing the
+	// feeds from them.
+	Telegram TelegramConfig `yaml:"telegram"`
+}
+
+// TelegramConfig configures the Telegram bot run by the serve command.
+type TelegramConfig struct {
+	// Token is the bot's token, given by BotFather.
+	Token string `yaml:"token"`
+	// Chats are the IDs of the chats the bot talks to. Anyone else is told
+	// the ID of their chat, to add it here.
+	Chats []int64 `yaml:"chats"`
+}
+
+// FeedConfig holds the settings for a single feed.
+type FeedConfig struct {
+	// Name is displayed instead of the title of the feed's channel.
+	Name string `yaml:"name"`
+	// Titles configures how the titles of the feed's items are rewritten.
+	Titles TitleOptions `yaml:"titles"`
+	// Sanitize overrides the sanitize mode for the feed.
+	Sanitize string `yaml:"sanitize"`
+	// Releases overrides the version constraint for the feed's releases.
+	Releases string `yaml:"releases"`
+	// Plugins are commands, with their arguments separated by spaces, run
+	// in turn to enrich the feed's items. See CommandPlugin.
+	Plugins []string `yaml:"plugins"`
+	// Script is the path of a Lua script with hooks for the feed. See
+	// LuaScript.
+	Script string `yaml:"script"`
+}
+
+// Names returns the names given to feeds, keyed by their URL.
+func (c Config) Names() map[string]string {
+	names := make(map[string]string)
+	for url, feed := range c.Feeds {
+		if feed.Name != "" {
+			names[url] = feed.Name
+		}
+	}
+	return names
+}
+
+// TitleTransforms compiles the title transforms of feeds, keyed by their URL.
+func (c Config) TitleTransforms() (map[string]TitleTransform, error) {
+	transforms := make(map[string]TitleTransform)
+	for url, feed := range c.Feeds {
+		t := feed.Titles
+		if !t.StripSiteName && len(t.Replace) == 0 && t.MaxLength == 0 {
+			continue
+		}
+		transform, err := t.Compile()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", url, err)
+		}
+		transforms[url] = transform
+	}
+	return transforms, nil
+}
+
+// SanitizeModes returns the sanitize mode for each feed which overrides the
+// global one, keyed by the feed's name or else its URL as they are displayed.
+func (c Config) SanitizeModes() (map[string]SanitizeMode, error) {
+	modes := make(map[string]SanitizeMode)
+	for url, feed := range c.Feeds {
+		if feed.Sanitize == "" {
+			continue
+		}
+		mode, err := ParseSanitizeMode(feed.Sanitize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", url, err)
+		}
+		if feed.Name != "" {
+			url = feed.Name
+		}
+		modes[url] = mode
+	}
+	return modes, nil
+}
+
+// Plugins returns the plugins enriching the items of feeds, keyed by the
+// feed's name or else its URL as they are displayed.
+func (c Config) Plugins() (map[string][]Plugin, error) {
+	plugins := make(map[string][]Plugin)
+	for url, feed := range c.Feeds {
+		key := url
+		if feed.Name != "" {
+			key = feed.Name
+		}
+		for _, command := range feed.Plugins {
+			plugin, err := NewCommandPlugin(command)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", url, err)
+			}
+			plugins[key] = append(plugins[key], plugin)
+		}
+	}
+	return plugins, nil
+}
+
+// LinkOpener returns the opener of links, with the tags of the subscriptions
+// keyed by the feed's name or else its URL as they are displayed.
+func (c Config) LinkOpener(subs []Subscription) Opener {
+	names := c.Names()
+	feedTags := make(map[string][]string)
+	for _, sub := range subs {
+		key := sub.URL
+		if name, found := names[sub.URL]; found {
+			key = name
+		}
+		feedTags[key] = sub.Tags
+	}
+	return Opener{Command: c.Opener, Tags: c.Openers, FeedTags: feedTags}
+}
+
+// ReleaseConstraints returns the version constraint for releases, and those of
+// feeds which override it keyed by the feed's name or else its URL.
+func (c Config) ReleaseConstraints() (VersionConstraint, map[string]VersionConstraint, error) {
+	fallback, err := ParseVersionConstraint(c.Releases)
+	if err != nil {
+		return nil, nil, err
+	}
+	constraints := make(map[string]VersionConstraint)
+	for url, feed := range c.Feeds {
+		if feed.Releases == "" {
+			continue
+		}
+		constraint, err := ParseVersionConstraint(feed.Releases)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", url, err)
+		}
+		if feed.Name != "" {
+			url = feed.Name
+		}
+		constraints[url] = constraint
+	}
+	return fallback, constraints, nil
+}
+
+// LoadConfig reads the config from r. An empty config is valid.
+func LoadConfig(r io.Reader) (Config, error) {
+	var config Config
+	err := yaml.NewDecoder(r).Decode(&config)
+	if errors.Is(err, io.EOF) {
+		return config, nil
+	}
+	return config, err
+}
@@ -0,0 +1,59 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	raw := `
+date_format: iso
+time_zone: Local
+highlight:
+  go: red
+`
+	config, err := LoadConfig(strings.NewReader(raw))
+	assertEqual(t, nil, err)
+	assertEqual(t, Config{
+		DateFormat: "iso",
+		TimeZone:   "Local",
+		Highlight:  map[string]string{"go": "red"},
+	}, config)
+
+	config, err = LoadConfig(strings.NewReader(""))
+	assertEqual(t, nil, err)
+	assertEqual(t, Config{}, config)
+}
+
+func TestConfigLinkOpener(t *testing.T) {
+	config := Config{
+		Opener:  "firefox %s",
+		Openers: map[string]string{"video": "mpv %s"},
+		Feeds:   map[string]FeedConfig{"https://example.com/videos.xml": {Name: "Videos"}},
+	}
+	opener := config.LinkOpener([]Subscription{
+		{URL: "https://example.com/videos.xml", Tags: []string{"video"}},
+		{URL: "https://example.org/videos.xml", Tags: []string{"video"}},
+	})
+	// Items know the feed by the name it was renamed to
+	name, _ := opener.command(FeedItem{Feed: "Videos"}, "https://example.com/1")
+	assertEqual(t, "mpv", name)
+	name, _ = opener.command(FeedItem{Feed: "https://example.org/videos.xml"}, "https://example.org/1")
+	assertEqual(t, "mpv", name)
+	name, _ = opener.command(FeedItem{Feed: "https://example.com/videos.xml"}, "https://example.com/1")
+	assertEqual(t, "firefox", name)
+}
+
+func TestConfigPlugins(t *testing.T) {
+	config := Config{Feeds: map[string]FeedConfig{
+		"https://example.com/rss": {Name: "Example", Plugins: []string{"summarise --short", "translate"}},
+		"https://example.org/rss": {Plugins: []string{"score"}},
+		"https://example.net/rss": {Name: "Plain"},
+	}}
+	plugins, err := config.Plugins()
+	assertEqual(t, nil, err)
+	assertEqual(t, map[string][]Plugin{
+		"Example":                 {&CommandPlugin{Command: []string{"summarise", "--short"}}, &CommandPlugin{Command: []string{"translate"}}},
+		"https://example.org/rss": {&CommandPlugin{Command: []string{"score"}}},
+	}, plugins)
+}
@@ -0,0 +1,35 @@
+package rss
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// DataSaverBodyLimit is the most read from each feed in data saver mode.
+const DataSaverBodyLimit = 256 * 1024
+
+var htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6]|blockquote|pre)>`)
+
+// DescriptionText converts the HTML description of an item into plain text,
+// wrapped and indented in the same way as pages fetched by the browser.
+func DescriptionText(description []byte) string {
+	if len(strings.TrimSpace(string(description))) == 0 {
+		return "No description\n"
+	}
+	text := htmlBreak.ReplaceAllString(string(description), "\n")
+	text = html.UnescapeString(htmlTag.ReplaceAllString(text, ""))
+	wrapLines := newLineWrapper(72)
+	var b strings.Builder
+	for _, paragraph := range strings.Split(text, "\n") {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		if paragraph == "" {
+			continue
+		}
+		for _, line := range wrapLines(paragraph) {
+			b.WriteString("\t" + strings.TrimSpace(line) + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
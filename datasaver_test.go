@@ -0,0 +1,52 @@
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLimitBody(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<rss><channel><title>Big</title>")
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(w, "<item><title>Item %d</title><link>https://example.com/%d</link><description>%s</description></item>", i, i, strings.Repeat("x", 1000))
+		}
+		fmt.Fprint(w, "</channel></rss>")
+	}))
+	defer server.Close()
+
+	feeds, err := FetchFeeds(server.URL, LimitBody(10*1024))
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(feeds))
+	// Only the items read whole before the limit are kept
+	assertEqual(t, 9, len(feeds[0].Channel.Items))
+	assertEqual(t, "Item 8", feeds[0].Channel.Items[8].Title)
+
+	feeds, err = FetchFeeds(server.URL)
+	assertEqual(t, nil, err)
+	assertEqual(t, 100, len(feeds[0].Channel.Items))
+}
+
+func TestDescriptionText(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name        string
+		description string
+		expected    string
+	}{
+		{name: "Paragraphs", description: "<p>One &amp; two</p><p>Three<br/>four</p>", expected: "\tOne & two\n\n\tThree\n\n\tfour\n\n"},
+		{name: "Plain text", description: "Just   some text", expected: "\tJust some text\n\n"},
+		{name: "Empty", description: " ", expected: "No description\n"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assertEqual(t, tc.expected, DescriptionText([]byte(tc.description)))
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package rss
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type fetchOptions struct {
+	maxAge           time.Duration
+	maxItems         int
+	dropDescriptions bool
+	maxBody          int64
+	names            map[string]string
+	titles           map[string]TitleTransform
+	future           FuturePolicy
+	report           *FetchReport
+	cache            *Cache
+	deadline         time.Time
+	ctx              context.Context
+	onError          func(url string, err error)
+	archiver         *archiver
+	resolver         *LinkResolver
+}
+
+// FetchOption configures how feeds are fetched and decoded.
+type FetchOption func(*fetchOptions)
+
+// SkipOlderThan discards items older than maxAge while the feed is being
+// decoded, so that they are never held in memory. Items with dates which
+// cannot be parsed are kept.
+func SkipOlderThan(maxAge time.Duration) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.maxAge = maxAge
+	}
+}
+
+// StopAfter stops decoding a channel once n items have been kept from it.
+// Passing zero in results in no limit.
+func StopAfter(n int) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.maxItems = n
+	}
+}
+
+// ErrTimedOut is the error for feeds which were still being fetched at the
+// deadline.
+var ErrTimedOut = errors.New("timed out")
+
+// Deadline stops fetching feeds at the given time, so that one slow host
+// can't hold up the rest. Feeds still being fetched then fail with
+// ErrTimedOut. The zero time results in no deadline.
+func Deadline(t time.Time) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.deadline = t
+	}
+}
+
+// ErrCancelled is the error for feeds which were still being fetched when the
+// context given with WithContext was cancelled.
+var ErrCancelled = errors.New("cancelled")
+
+// WithContext stops fetching feeds when ctx is done, e.g. on an interrupt, so
+// that the feeds which have arrived can be shown. Feeds still being fetched
+// then fail with ErrCancelled.
+func WithContext(ctx context.Context) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.ctx = ctx
+	}
+}
+
+// OnFetchError calls fn with the URL of each feed which can't be fetched or
+// decoded, and the error. Cancelling isn't counted as failing. Feeds are
+// fetched in parallel, so fn may be called concurrently.
+func OnFetchError(fn func(url string, err error)) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.onError = fn
+	}
+}
+
+// LimitBody stops reading the response of a feed after n bytes, keeping the
+// items decoded before then, e.g. to save data on metered connections.
+// Passing zero in results in no limit.
+func LimitBody(n int64) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.maxBody = n
+	}
+}
+
+// DropDescriptions discards the descriptions and content of items while the
+// feed is being decoded, since they can be large and are not needed unless the
+// read time of items is estimated.
+func DropDescriptions() FetchOption {
+	return func(fo *fetchOptions) {
+		fo.dropDescriptions = true
+	}
+}
+
+// Rename gives feeds names to display instead of the titles of their
+// channels, keyed by the URL of the feed.
+func Rename(names map[string]string) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.names = names
+	}
+}
+
+func newFetchOptions(opts ...FetchOption) fetchOptions {
+	var options fetchOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+// slashNamespace is the namespace of the slash module, which gives the number of
+// comments on items.
+const slashNamespace = "http://purl.org/rss/1.0/modules/slash/"
+
+// decodeRSS reads an RSS document token by token, rather than unmarshaling it
+// in one go, so that unwanted items can be dropped as soon as they are read
+// and the rest of a channel skipped once enough items have been found. There
+// is one RSS per channel in the document, with any items outside of a channel
+// collected into a channel of their own.
+func decodeRSS(r io.Reader, options fetchOptions) ([]RSS, error) {
+	var root xml.Name
+	var channels []RSS
+	// loose holds the index of the channel for items outside of a channel
+	loose := -1
+	parseDate := newDateParser(time.Now())
+	keep := func(item Item) bool {
+		if options.maxAge == 0 {
+			return true
+		}
+		pubTime, err := parseDate(item.PubDate)
+		if err != nil {
+			// Leave it for the error to be reported when unpacking
+			return true
+		}
+		return time.Since(pubTime) <= options.maxAge
+	}
+
+	d := xml.NewDecoder(r)
+	var depth int
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return channels, nil
+		}
+		if err != nil {
+			return channels, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			depth--
+			continue
+		case xml.StartElement:
+			switch {
+			case depth == 0 && strings.EqualFold(t.Name.Local, "rss"):
+				root = t.Name
+				depth++
+			case depth == 1 && strings.EqualFold(t.Name.Local, "channel"):
+				channels = append(channels, RSS{XMLName: root, Channel: Channel{XMLName: t.Name}})
+				depth++
+			case depth == 0:
+				return channels, fmt.Errorf("expected element type <rss> but have <%s>", t.Name.Local)
+			case depth == 1 && strings.EqualFold(t.Name.Local, "item"):
+				if loose < 0 {
+					loose = len(channels)
+					channels = append(channels, RSS{XMLName: root})
+				}
+				channel := &channels[loose].Channel
+				if options.maxItems > 0 && len(channel.Items) >= options.maxItems {
+					// Items outside of a channel have no end to skip to
+					err = d.Skip()
+				} else {
+					_, err = decodeChannelElement(d, t, channel, keep, options)
+				}
+				if err != nil {
+					return channels, err
+				}
+			case depth == 2:
+				channel := &channels[len(channels)-1].Channel
+				done, err := decodeChannelElement(d, t, channel, keep, options)
+				if err != nil {
+					return channels, err
+				}
+				if done {
+					// Skip the rest of the channel
+					err := d.Skip()
+					if err != nil {
+						return channels, err
+					}
+					depth--
+				}
+			default:
+				err := d.Skip()
+				if err != nil {
+					return channels, err
+				}
+			}
+		}
+	}
+}
+
+// ParseFeed decodes the RSS document read from r as though it had been fetched
+// from the URL, applying the options which affect decoding e.g. Rename or
+// SkipOlderThan. Each channel in the document is a feed of its own.
+func ParseFeed(r io.Reader, url string, opts ...FetchOption) ([]*Feed, error) {
+	options := newFetchOptions(opts...)
+	channels, err := decodeRSS(r, options)
+	if err != nil {
+		return nil, err
+	}
+	return newFeeds(url, channels, options), nil
+}
+
+// relLink returns the relation and URL of the element if it is a link with
+// them e.g. <atom:link rel="blogroll" href="..."/>.
+func relLink(start xml.StartElement) (string, string, bool) {
+	if start.Name.Local != "link" {
+		return "", "", false
+	}
+	var rel, href string
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "rel":
+			rel = attr.Value
+		case "href":
+			href = attr.Value
+		}
+	}
+	return rel, href, rel != "" && href != ""
+}
+
+// decodeChannelElement decodes an element found directly within the channel
+// into it. Returns true once the channel has enough items.
+func decodeChannelElement(d *xml.Decoder, start xml.StartElement, channel *Channel, keep func(Item) bool, options fetchOptions) (bool, error) {
+	if strings.EqualFold(start.Name.Local, "item") {
+		item, err := decodeItem(d, start)
+		if err != nil {
+			return false, err
+		}
+		if keep(item) {
+			if options.dropDescriptions {
+				item.Description = nil
+				item.Content = nil
+			}
+			channel.Items = append(channel.Items, item)
+		}
+		return options.maxItems > 0 && len(channel.Items) >= options.maxItems, nil
+	}
+
+	if rel, href, ok := relLink(start); ok {
+		switch rel {
+		case "blogroll":
+			channel.Blogrolls = append(channel.Blogrolls, href)
+		case "hub":
+			channel.Hub = href
+		case "self":
+			channel.Self = href
+		case "next":
+			channel.Next = href
+		case "prev-archive":
+			channel.PrevArchive = href
+		}
+		return false, d.Skip()
+	}
+	if strings.EqualFold(start.Name.Local, "image") {
+		if start.Name.Space == "" {
+			channel.Image = &Image{}
+			return false, d.DecodeElement(channel.Image, &start)
+		}
+		// Images in other namespaces, e.g. <itunes:image href="...">, are
+		// used if the channel has no <image>
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "href" && channel.Image == nil {
+				channel.Image = &Image{URL: attr.Value}
+			}
+		}
+		return false, d.Skip()
+	}
+	if start.Name.Local == "blogroll" {
+		var blogroll string
+		err := d.DecodeElement(&blogroll, &start)
+		if err != nil {
+			return false, err
+		}
+		channel.Blogrolls = append(channel.Blogrolls, strings.TrimSpace(blogroll))
+		return false, nil
+	}
+
+	var field interface{}
+	switch strings.ToLower(start.Name.Local) {
+	case "title":
+		field = &channel.Title
+	case "link":
+		field = &channel.Link
+	case "description":
+		field = &channel.Description
+	case "generator":
+		field = &channel.Generator
+	case "language":
+		field = &channel.Language
+	case "lastbuilddate", "updated", "date":
+		field = &channel.LastBuildDate
+	case "copyright", "rights":
+		field = &channel.Copyright
+	case "managingeditor":
+		field = &channel.ManagingEditor
+	case "cloud":
+		field = &channel.Cloud
+	}
+	return false, decodeField(d, start, field)
+}
+
+// decodeItem decodes an item element, matching the names of its elements
+// case-insensitively and falling back to elements in other namespaces, e.g.
+// <dc:title>, for feeds which do not use the standard ones.
+func decodeItem(d *xml.Decoder, start xml.StartElement) (Item, error) {
+	item := Item{XMLName: start.Name}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return item, err
+		}
+		switch t := token.(type) {
+		case xml.EndElement:
+			return item, nil
+		case xml.StartElement:
+			var field interface{}
+			switch strings.ToLower(t.Name.Local) {
+			case "title":
+				field = &item.Title
+			case "link":
+				field = &item.Link
+			case "pubdate", "date":
+				field = &item.PubDate
+			case "guid", "identifier":
+				field = &item.GUID
+			case "comments":
+				if t.Name.Space == slashNamespace {
+					// A count which isn't a number is ignored rather than
+					// failing the feed
+					var count string
+					err := d.DecodeElement(&count, &t)
+					if err != nil {
+						return item, err
+					}
+					item.CommentCount, _ = strconv.Atoi(strings.TrimSpace(count))
+					continue
+				}
+				field = &item.Comments
+			case "creator":
+				var creator string
+				err := d.DecodeElement(&creator, &t)
+				if err != nil {
+					return item, err
+				}
+				if creator = strings.TrimSpace(creator); creator != "" {
+					item.Creators = append(item.Creators, creator)
+				}
+				continue
+			case "description":
+				field = &item.Description
+			case "encoded":
+				field = &item.Content
+			}
+			err := decodeField(d, t, field)
+			if err != nil {
+				return item, err
+			}
+		}
+	}
+}
+
+// decodeField decodes the element into field. Elements in a namespace are only
+// decoded into a *string or *[]byte if it has not been set, so that the
+// standard elements take precedence whatever their order.
+func decodeField(d *xml.Decoder, start xml.StartElement, field interface{}) error {
+	var set bool
+	switch f := field.(type) {
+	case nil:
+		return d.Skip()
+	case *string:
+		set = *f != ""
+	case *[]byte:
+		set = len(*f) > 0
+	}
+	if start.Name.Space != "" && set {
+		return d.Skip()
+	}
+	return d.DecodeElement(field, &start)
+}
@@ -0,0 +1,310 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDocument(n int, now time.Time) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0"?><rss version="2.0"><channel>`)
+	b.WriteString(`<title>Planet</title><link>https://example.com</link><description>An aggregator</description>`)
+	b.WriteString(`<image><title>Logo</title><url>https://example.com/logo.png</url></image>`)
+	for i := 0; i < n; i++ {
+		pubDate := now.Add(-time.Duration(i) * time.Hour).Format(time.RFC1123Z)
+		fmt.Fprintf(&b, `<item><title>Item %d</title><link>https://example.com/%d</link><pubDate>%s</pubDate><description>Body %d</description></item>`, i, i, pubDate, i)
+	}
+	b.WriteString(`</channel></rss>`)
+	return []byte(b.String())
+}
+
+func TestDecodeRSSMatchesUnmarshal(t *testing.T) {
+	doc := testDocument(5, time.Now())
+
+	var expected RSS
+	err := xml.Unmarshal(doc, &expected)
+	assertEqual(t, nil, err)
+
+	result, err := decodeRSS(bytes.NewReader(doc), fetchOptions{})
+	assertEqual(t, nil, err)
+	assertEqual(t, []RSS{expected}, result)
+}
+
+func TestDecodeRSSLimits(t *testing.T) {
+	doc := testDocument(10, time.Now())
+
+	result, err := decodeRSS(bytes.NewReader(doc), newFetchOptions(SkipOlderThan(150*time.Minute)))
+	assertEqual(t, nil, err)
+	assertEqual(t, 3, len(result[0].Channel.Items))
+	assertEqual(t, "Planet", result[0].Channel.Title)
+
+	result, err = decodeRSS(bytes.NewReader(doc), newFetchOptions(StopAfter(4)))
+	assertEqual(t, nil, err)
+	assertEqual(t, 4, len(result[0].Channel.Items))
+
+	result, err = decodeRSS(bytes.NewReader(doc), newFetchOptions(DropDescriptions()))
+	assertEqual(t, nil, err)
+	assertEqual(t, 10, len(result[0].Channel.Items))
+	for _, item := range result[0].Channel.Items {
+		assertEqual(t, 0, len(item.Description))
+	}
+}
+
+func TestDecodeRSSMultipleChannels(t *testing.T) {
+	doc := `<rss>
+<channel><title>A</title><item><title>a1</title></item><item><title>a2</title></item><item><title>a3</title></item></channel>
+<item><title>loose1</title></item>
+<channel><title>B</title><item><title>b1</title></item></channel>
+<item><title>loose2</title></item>
+<item><title>loose3</title></item>
+</rss>`
+	result, err := decodeRSS(strings.NewReader(doc), newFetchOptions(StopAfter(2)))
+	assertEqual(t, nil, err)
+
+	var titles [][]string
+	for _, rss := range result {
+		channel := []string{rss.Channel.Title}
+		for _, item := range rss.Channel.Items {
+			channel = append(channel, item.Title)
+		}
+		titles = append(titles, channel)
+	}
+	assertEqual(t, [][]string{{"A", "a1", "a2"}, {"", "loose1", "loose2"}, {"B", "b1"}}, titles)
+}
+
+func TestDecodeRSSTolerant(t *testing.T) {
+	doc := `<RSS xmlns:dc="http://purl.org/dc/elements/1.1/"><Channel><TITLE>Shouty</TITLE>
+<Item><Title>Upper</Title><Link>https://example.com/1</Link><PubDate>Mon, 02 Jan 2006 15:04:05 -0700</PubDate></Item>
+<item><dc:title>Dublin Core</dc:title><link>https://example.com/2</link><dc:date>2006-01-02T15:04:05Z</dc:date></item>
+<item><dc:title>Ignored</dc:title><title>Standard</title></item>
+<item><title>Standard</title><dc:title>Ignored</dc:title></item>
+</Channel></RSS>`
+	result, err := decodeRSS(strings.NewReader(doc), fetchOptions{})
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(result))
+
+	channel := result[0].Channel
+	assertEqual(t, "Shouty", channel.Title)
+	var titles []string
+	for _, item := range channel.Items {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, []string{"Upper", "Dublin Core", "Standard", "Standard"}, titles)
+	assertEqual(t, "https://example.com/1", channel.Items[0].Link)
+
+	item, err := NewFeedItem(&Feed{RSS: result[0]}, channel.Items[1])
+	assertEqual(t, nil, err)
+	assertEqual(t, true, item.PublishTime.Equal(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)))
+}
+
+func TestDecodeRSSNotRSS(t *testing.T) {
+	_, err := decodeRSS(strings.NewReader(`<feed></feed>`), fetchOptions{})
+	assertEqual(t, true, err != nil)
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	doc := testDocument(5000, time.Now())
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var rss RSS
+		xml.NewDecoder(bytes.NewReader(doc)).Decode(&rss)
+	}
+}
+
+func BenchmarkDecodeRSS(b *testing.B) {
+	doc := testDocument(5000, time.Now())
+	options := newFetchOptions(SkipOlderThan(24*time.Hour), StopAfter(10))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeRSS(bytes.NewReader(doc), options)
+	}
+}
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata with the results of the tests")
+
+// goldenFeed is what the golden files record of a feed parsed from a fixture.
+type goldenFeed struct {
+	Title          string       `json:"title"`
+	Link           string       `json:"link,omitempty"`
+	Description    string       `json:"description,omitempty"`
+	Language       string       `json:"language,omitempty"`
+	Generator      string       `json:"generator,omitempty"`
+	LastBuildDate  string       `json:"last_build_date,omitempty"`
+	Copyright      string       `json:"copyright,omitempty"`
+	ManagingEditor string       `json:"managing_editor,omitempty"`
+	Image          string       `json:"image,omitempty"`
+	Hub            string       `json:"hub,omitempty"`
+	Self           string       `json:"self,omitempty"`
+	Next           string       `json:"next,omitempty"`
+	PrevArchive    string       `json:"prev_archive,omitempty"`
+	Cloud          string       `json:"cloud,omitempty"`
+	Blogrolls      []string     `json:"blogrolls,omitempty"`
+	Items          []goldenItem `json:"items"`
+}
+
+type goldenItem struct {
+	ID    string   `json:"id,omitempty"`
+	Title string   `json:"title"`
+	Links []string `json:"links,omitempty"`
+	// Published is empty for items without a date, which are given the time
+	// they are fetched.
+	Published string   `json:"published,omitempty"`
+	Authors   []string `json:"authors,omitempty"`
+	Comments  int      `json:"comments,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	ReadTime  string   `json:"read_time,omitempty"`
+	// Error is why the item can't be shown, e.g. a date which can't be
+	// parsed.
+	Error string `json:"error,omitempty"`
+}
+
+func newGoldenFeed(feed *Feed) goldenFeed {
+	channel := feed.Channel
+	g := goldenFeed{
+		Title:          channel.Title,
+		Link:           channel.Link,
+		Description:    channel.Description,
+		Language:       channel.Language,
+		Generator:      channel.Generator,
+		LastBuildDate:  channel.LastBuildDate,
+		Copyright:      channel.Copyright,
+		ManagingEditor: channel.ManagingEditor,
+		Hub:            channel.Hub,
+		Self:           channel.Self,
+		Next:           channel.Next,
+		PrevArchive:    channel.PrevArchive,
+		Blogrolls:      channel.Blogrolls,
+		Items:          []goldenItem{},
+	}
+	if channel.Image != nil {
+		g.Image = channel.Image.URL
+	}
+	if channel.Cloud.Domain != "" {
+		g.Cloud = fmt.Sprintf("%s://%s:%s%s", channel.Cloud.Protocol, channel.Cloud.Domain, channel.Cloud.Port, channel.Cloud.Path)
+	}
+	newFeedItem := newFeedItemCreator(feed)
+	for _, item := range channel.Items {
+		feedItem, err := newFeedItem(item)
+		if err != nil {
+			g.Items = append(g.Items, goldenItem{Title: item.Title, Error: err.Error()})
+			continue
+		}
+		gi := goldenItem{
+			ID:       feedItem.ID,
+			Title:    feedItem.Title,
+			Links:    feedItem.Links,
+			Authors:  feedItem.Authors,
+			Comments: feedItem.Comments,
+			Language: feedItem.Language,
+		}
+		if !feedItem.undated {
+			gi.Published = feedItem.PublishTime.UTC().Format(time.RFC3339)
+		}
+		if feedItem.ReadTime > 0 {
+			gi.ReadTime = feedItem.ReadTime.String()
+		}
+		g.Items = append(g.Items, gi)
+	}
+	return g
+}
+
+// TestParseFeedGolden parses each feed in testdata/feeds and compares what it
+// finds with the JSON file of the same name. Run with -update to write the
+// JSON for new fixtures, and check it by eye.
+func TestParseFeedGolden(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "feeds", "*.xml"))
+	assertEqual(t, nil, err)
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures found")
+	}
+	for _, fixture := range fixtures {
+		fixture := fixture
+		name := strings.TrimSuffix(filepath.Base(fixture), ".xml")
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(fixture)
+			assertEqual(t, nil, err)
+			defer f.Close()
+			feeds, err := ParseFeed(f, "https://fixtures.example.com/"+name+".xml")
+			assertEqual(t, nil, err)
+			result := make([]goldenFeed, len(feeds))
+			for i, feed := range feeds {
+				result[i] = newGoldenFeed(feed)
+			}
+			var buf bytes.Buffer
+			encoder := json.NewEncoder(&buf)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(result)
+			assertEqual(t, nil, err)
+			b := buf.Bytes()
+
+			golden := strings.TrimSuffix(fixture, ".xml") + ".json"
+			if *update {
+				err = os.WriteFile(golden, b, 0644)
+				assertEqual(t, nil, err)
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s, run the tests with -update to create it", err.Error())
+			}
+			if !bytes.Equal(expected, b) {
+				t.Errorf("parsing %s doesn't match %s, got:\n%s", fixture, golden, b)
+			}
+		})
+	}
+}
+
+// FuzzParseFeed checks that no document, however malformed, crashes parsing
+// or the things done with the feeds parsed, and that they can be stored and
+// read back.
+func FuzzParseFeed(f *testing.F) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "feeds", "*.xml"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		b, err := os.ReadFile(fixture)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add(testDocument(3, time.Date(2022, 11, 1, 9, 0, 0, 0, time.UTC)))
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		feeds, err := ParseFeed(bytes.NewReader(b), "https://example.com/feed")
+		if err != nil {
+			return
+		}
+		for _, feed := range feeds {
+			newFeedItem := newFeedItemCreator(feed)
+			for _, item := range feed.Channel.Items {
+				newFeedItem(item)
+			}
+			WriteFeedInfo(io.Discard, feed, now)
+			Events([]*Feed{feed})
+
+			stored, err := xml.Marshal(storedFeed{Version: "2.0", URL: feed.URL, Channels: []Channel{feed.Channel}})
+			if err != nil {
+				t.Fatalf("error storing feed: %s", err)
+			}
+			var read storedFeed
+			err = xml.Unmarshal(stored, &read)
+			if err != nil {
+				t.Fatalf("error reading stored feed: %s\n%s", err, stored)
+			}
+			assertEqual(t, 1, len(read.Channels))
+			assertEqual(t, len(feed.Channel.Items), len(read.Channels[0].Items))
+		}
+	})
+}
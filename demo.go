@@ -0,0 +1,92 @@
+package rss
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// demoFiles are canned feeds for trying out the app without the network.
+//
+//go:embed demo/*.xml
+var demoFiles embed.FS
+
+// DemoFeeds returns the canned demo feeds, with their dates moved so that the
+// newest item was published a few minutes before now.
+func DemoFeeds(now time.Time) ([]*Feed, error) {
+	paths, err := fs.Glob(demoFiles, "demo/*.xml")
+	if err != nil {
+		return nil, err
+	}
+	var feeds []*Feed
+	for _, p := range paths {
+		f, err := demoFiles.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseFeed(f, "https://demo.example/"+strings.TrimSuffix(path.Base(p), ".xml"))
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, parsed...)
+	}
+
+	var newest time.Time
+	for _, feed := range feeds {
+		for _, item := range feed.Channel.Items {
+			published, err := ParseDate(item.PubDate)
+			if err == nil && published.After(newest) {
+				newest = published
+			}
+		}
+	}
+	shift := now.Add(-12 * time.Minute).Sub(newest)
+	move := func(date string) string {
+		t, err := ParseDate(date)
+		if err != nil || t.IsZero() {
+			return date
+		}
+		return t.Add(shift).Format(time.RFC1123Z)
+	}
+	for _, feed := range feeds {
+		feed.Channel.LastBuildDate = move(feed.Channel.LastBuildDate)
+		for i, item := range feed.Channel.Items {
+			feed.Channel.Items[i].PubDate = move(item.PubDate)
+		}
+	}
+	return feeds, nil
+}
+
+// SendFeeds returns a channel from which the feeds can be received, e.g. to
+// run the app with feeds which have already been fetched.
+func SendFeeds(feeds []*Feed) <-chan *Feed {
+	ch := make(chan *Feed, len(feeds))
+	for _, feed := range feeds {
+		ch <- feed
+	}
+	close(ch)
+	return ch
+}
+
+// ScreenText returns the text shown on the screen, one line to a row with
+// trailing spaces removed, e.g. to check what the app shows in tests.
+func ScreenText(screen tcell.Screen) string {
+	width, height := screen.Size()
+	lines := make([]string, height)
+	for y := 0; y < height; y++ {
+		var line strings.Builder
+		for x := 0; x < width; {
+			mainc, combc, _, w := screen.GetContent(x, y)
+			line.WriteRune(mainc)
+			line.WriteString(string(combc))
+			x += w
+		}
+		lines[y] = strings.TrimRight(line.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
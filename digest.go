@@ -0,0 +1,88 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Digest summarises the items published since the last digest, for sending
+// on a schedule.
+type Digest struct {
+	Time  time.Time      `json:"time"`
+	Since time.Time      `json:"since"`
+	Items []SnapshotItem `json:"items"`
+}
+
+// NewDigest collects the items into a digest of those published since the
+// given time, newest first.
+func NewDigest(feedItems []FeedItem, since, now time.Time) Digest {
+	var recent []FeedItem
+	for _, item := range feedItems {
+		if item.PublishTime.After(since) {
+			recent = append(recent, item)
+		}
+	}
+	return Digest{Time: now, Since: since, Items: NewSnapshot(ReverseChronological(recent), now).Items}
+}
+
+// Text formats the digest as plain text, with the items grouped by feed in
+// the order their newest items were published.
+func (d Digest) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s since %s\n", plural(len(d.Items), "new item"), d.Since.Format(time.RFC1123))
+	var feeds []string
+	byFeed := make(map[string][]SnapshotItem)
+	for _, item := range d.Items {
+		if _, found := byFeed[item.Feed]; !found {
+			feeds = append(feeds, item.Feed)
+		}
+		byFeed[item.Feed] = append(byFeed[item.Feed], item)
+	}
+	for _, feed := range feeds {
+		fmt.Fprintf(&b, "\n%s\n", feed)
+		for _, item := range byFeed[feed] {
+			fmt.Fprintf(&b, "- %s\n  %s\n", item.Title, item.Link)
+			if item.Note != "" {
+				fmt.Fprintf(&b, "  Note: %s\n", item.Note)
+			}
+		}
+	}
+	return b.String()
+}
+
+// WriteDigest writes the digest to w as "text" or "json".
+func WriteDigest(w io.Writer, d Digest, format string) error {
+	switch format {
+	case "", "text":
+		_, err := io.WriteString(w, d.Text())
+		return err
+	case "json":
+		return json.NewEncoder(w).Encode(d)
+	}
+	return fmt.Errorf("unknown digest format %q, expected text or json", format)
+}
+
+// PostDigest sends the digest to the webhook as JSON. The text of the digest
+// is included as "text", which chat services such as Slack display.
+func PostDigest(webhook string, d Digest) error {
+	body, err := json.Marshal(struct {
+		Text string `json:"text"`
+		Digest
+	}{Text: d.Text(), Digest: d})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDigest(t *testing.T) {
+	now := time.Date(2022, 11, 5, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+	feedItems := []FeedItem{
+		{Title: "Old", Links: []string{"https://example.com/old"}, Feed: "Example", PublishTime: now.Add(-48 * time.Hour)},
+		{Title: "First", Links: []string{"https://example.com/1"}, Feed: "Example", PublishTime: now.Add(-3 * time.Hour)},
+		{Title: "Other", Links: []string{"https://example.org/1"}, Feed: "Other", PublishTime: now.Add(-2 * time.Hour)},
+		{Title: "Second", Links: []string{"https://example.com/2"}, Feed: "Example", PublishTime: now.Add(-time.Hour)},
+	}
+	digest := NewDigest(feedItems, since, now)
+	assertEqual(t, 3, len(digest.Items))
+	expected := `3 new items since Fri, 04 Nov 2022 12:00:00 UTC
+
+Example
+- Second
+  https://example.com/2
+- First
+  https://example.com/1
+
+Other
+- Other
+  https://example.org/1
+`
+	var buf bytes.Buffer
+	err := WriteDigest(&buf, digest, "text")
+	assertEqual(t, nil, err)
+	assertEqual(t, expected, buf.String())
+
+	var posted struct {
+		Text  string         `json:"text"`
+		Items []SnapshotItem `json:"items"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewDecoder(r.Body).Decode(&posted)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	err = PostDigest(server.URL, digest)
+	assertEqual(t, nil, err)
+	assertEqual(t, expected, posted.Text)
+	assertEqual(t, digest.Items, posted.Items)
+
+	err = WriteDigest(&buf, digest, "html")
+	assertEqual(t, true, err != nil)
+}
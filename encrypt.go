@@ -0,0 +1,164 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// encryptedPrefix marks lines encrypted by EncryptLines, so that they can be
+// told apart from lines written before encryption was turned on.
+const encryptedPrefix = "age:"
+
+// LoadIdentity reads an age identity file, as made by age-keygen. The file may
+// itself be encrypted with a passphrase e.g. by age -p, in which case the
+// passphrase is needed to decrypt it.
+func LoadIdentity(r io.Reader, passphrase string) (*age.X25519Identity, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(b, []byte("age-encryption.org/")) {
+		if passphrase == "" {
+			return nil, errors.New("the identity is encrypted but no passphrase was given")
+		}
+		scrypt, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		decrypted, err := age.Decrypt(bytes.NewReader(b), scrypt)
+		if err != nil {
+			return nil, fmt.Errorf("decrypting identity: %s", err.Error())
+		}
+		b, err = io.ReadAll(decrypted)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return age.ParseX25519Identity(line)
+	}
+	return nil, errors.New("no identity found")
+}
+
+// EncryptLines returns a writer which encrypts each line written to it
+// separately before writing it to w. Lines are only written once they are
+// complete, so files of records such as the history can still be appended to
+// and merged line by line.
+func EncryptLines(w io.Writer, recipient age.Recipient) io.Writer {
+	return &lineEncrypter{w: w, recipient: recipient}
+}
+
+type lineEncrypter struct {
+	w         io.Writer
+	recipient age.Recipient
+	partial   []byte
+}
+
+func (le *lineEncrypter) Write(p []byte) (int, error) {
+	le.partial = append(le.partial, p...)
+	for {
+		i := bytes.IndexByte(le.partial, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		var ciphertext bytes.Buffer
+		encrypter, err := age.Encrypt(&ciphertext, le.recipient)
+		if err != nil {
+			return 0, err
+		}
+		_, err = encrypter.Write(le.partial[:i])
+		if err != nil {
+			return 0, err
+		}
+		err = encrypter.Close()
+		if err != nil {
+			return 0, err
+		}
+		line := encryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext.Bytes()) + "\n"
+		_, err = io.WriteString(le.w, line)
+		if err != nil {
+			return 0, err
+		}
+		le.partial = le.partial[i+1:]
+	}
+}
+
+// DecryptLines reads the lines written by EncryptLines from r and returns them
+// decrypted. Lines which aren't encrypted are returned as they are.
+func DecryptLines(r io.Reader, identity age.Identity) (io.Reader, error) {
+	var plaintext bytes.Buffer
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, encryptedPrefix) {
+			plaintext.WriteString(line + "\n")
+			continue
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, encryptedPrefix))
+		if err != nil {
+			return nil, err
+		}
+		decrypted, err := age.Decrypt(bytes.NewReader(ciphertext), identity)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(&plaintext, decrypted)
+		if err != nil {
+			return nil, err
+		}
+		plaintext.WriteByte('\n')
+	}
+	return &plaintext, scanner.Err()
+}
+
+// ageHeader begins every document encrypted by age.
+const ageHeader = "age-encryption.org/v1\n"
+
+// EncryptDocument encrypts the whole of b to the identity, for documents such
+// as stored feeds which are rewritten rather than appended to. A nil identity
+// leaves b as it is.
+func EncryptDocument(b []byte, identity *age.X25519Identity) ([]byte, error) {
+	if identity == nil {
+		return b, nil
+	}
+	var ciphertext bytes.Buffer
+	encrypter, err := age.Encrypt(&ciphertext, identity.Recipient())
+	if err != nil {
+		return nil, err
+	}
+	_, err = encrypter.Write(b)
+	if err != nil {
+		return nil, err
+	}
+	err = encrypter.Close()
+	return ciphertext.Bytes(), err
+}
+
+// DecryptDocument decrypts a document written by EncryptDocument. Documents
+// which aren't encrypted, e.g. those written before the identity was given,
+// are returned as they are.
+func DecryptDocument(b []byte, identity *age.X25519Identity) ([]byte, error) {
+	if !bytes.HasPrefix(b, []byte(ageHeader)) {
+		return b, nil
+	}
+	if identity == nil {
+		return nil, errors.New("the document is encrypted but no identity was given")
+	}
+	r, err := age.Decrypt(bytes.NewReader(b), identity)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
@@ -0,0 +1,71 @@
+package rss
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+func TestEncryptLines(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assertEqual(t, nil, err)
+
+	// Plaintext written before encryption was turned on is kept
+	var buf bytes.Buffer
+	now := time.Now().UTC().Truncate(time.Second)
+	err = WriteHistory(&buf, HistoryEntry{Time: now, Title: "Before"})
+	assertEqual(t, nil, err)
+	w := EncryptLines(&buf, identity.Recipient())
+	err = WriteHistory(w, HistoryEntry{Time: now, Title: "Secret"})
+	assertEqual(t, nil, err)
+	err = WriteHistory(w, HistoryEntry{Time: now, Title: "Another"})
+	assertEqual(t, nil, err)
+	assertEqual(t, false, strings.Contains(buf.String(), "Secret"))
+	assertEqual(t, 3, strings.Count(buf.String(), "\n"))
+
+	r, err := DecryptLines(&buf, identity)
+	assertEqual(t, nil, err)
+	entries, err := ReadHistory(r)
+	assertEqual(t, nil, err)
+	var titles []string
+	for _, entry := range entries {
+		titles = append(titles, entry.Title)
+	}
+	assertEqual(t, []string{"Before", "Secret", "Another"}, titles)
+
+	other, _ := age.GenerateX25519Identity()
+	var encrypted bytes.Buffer
+	WriteHistory(EncryptLines(&encrypted, identity.Recipient()), HistoryEntry{Title: "Secret"})
+	_, err = DecryptLines(&encrypted, other)
+	assertEqual(t, true, err != nil)
+}
+
+func TestLoadIdentity(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	assertEqual(t, nil, err)
+	file := "# created: today\n" + identity.String() + "\n"
+
+	loaded, err := LoadIdentity(strings.NewReader(file), "")
+	assertEqual(t, nil, err)
+	assertEqual(t, identity.String(), loaded.String())
+
+	recipient, err := age.NewScryptRecipient("hunter2")
+	assertEqual(t, nil, err)
+	recipient.SetWorkFactor(10)
+	var protected bytes.Buffer
+	w, err := age.Encrypt(&protected, recipient)
+	assertEqual(t, nil, err)
+	w.Write([]byte(file))
+	w.Close()
+
+	_, err = LoadIdentity(bytes.NewReader(protected.Bytes()), "")
+	assertEqual(t, true, err != nil)
+	_, err = LoadIdentity(bytes.NewReader(protected.Bytes()), "wrong")
+	assertEqual(t, true, err != nil)
+	loaded, err = LoadIdentity(bytes.NewReader(protected.Bytes()), "hunter2")
+	assertEqual(t, nil, err)
+	assertEqual(t, identity.String(), loaded.String())
+}
@@ -0,0 +1,65 @@
+package rss
+
+import "sync"
+
+// maxLoggedErrors is the number of errors an ErrorLog keeps, the oldest being
+// dropped first.
+const maxLoggedErrors = 100
+
+// ErrorLog collects the errors of scripts, plugins and modules, which run
+// while items are shown, so that the caller can report them where they will be
+// seen rather than them being printed over the app. A nil log drops them.
+type ErrorLog struct {
+	mu     sync.Mutex
+	errs   []error
+	notify func(error)
+}
+
+// Add records the error, or passes it on if the log is being watched.
+func (l *ErrorLog) Add(err error) {
+	if l == nil || err == nil {
+		return
+	}
+	l.mu.Lock()
+	notify := l.notify
+	if notify == nil {
+		l.errs = append(l.errs, err)
+		if len(l.errs) > maxLoggedErrors {
+			l.errs = l.errs[len(l.errs)-maxLoggedErrors:]
+		}
+	}
+	l.mu.Unlock()
+	if notify != nil {
+		notify(err)
+	}
+}
+
+// Errors returns the errors recorded, oldest first.
+func (l *ErrorLog) Errors() []error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]error(nil), l.errs...)
+}
+
+// Watch passes the errors recorded so far, and each one added from now on, to
+// fn instead of keeping them. A nil fn goes back to keeping them.
+func (l *ErrorLog) Watch(fn func(error)) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.notify = fn
+	if fn == nil {
+		l.mu.Unlock()
+		return
+	}
+	errs := l.errs
+	l.errs = nil
+	l.mu.Unlock()
+	for _, err := range errs {
+		fn(err)
+	}
+}
@@ -0,0 +1,39 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorLog(t *testing.T) {
+	t.Parallel()
+	var log ErrorLog
+	for i := 0; i < maxLoggedErrors+1; i++ {
+		log.Add(fmt.Errorf("error %d", i))
+	}
+	log.Add(nil)
+	errs := log.Errors()
+	assertEqual(t, maxLoggedErrors, len(errs))
+	// The oldest are dropped
+	assertEqual(t, "error 1", errs[0].Error())
+
+	// Watching passes on those recorded, then each as it is added
+	var watched []string
+	log.Watch(func(err error) {
+		watched = append(watched, err.Error())
+	})
+	log.Add(errors.New("watched"))
+	assertEqual(t, maxLoggedErrors+1, len(watched))
+	assertEqual(t, "watched", watched[len(watched)-1])
+	assertEqual(t, 0, len(log.Errors()))
+
+	log.Watch(nil)
+	log.Add(errors.New("kept"))
+	assertEqual(t, 1, len(log.Errors()))
+
+	// A nil log drops errors
+	var none *ErrorLog
+	none.Add(errors.New("dropped"))
+	assertEqual(t, 0, len(none.Errors()))
+}
@@ -0,0 +1,86 @@
+package rss
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteFeedInfo writes what the feed's channel says about itself, one field to
+// a line e.g. "Copyright:       © 2024 Someone", followed by how many items it
+// has and the dates of the newest and oldest. Fields the channel doesn't give
+// are left out.
+func WriteFeedInfo(w io.Writer, feed *Feed, now time.Time) error {
+	channel := feed.Channel
+	description := html.UnescapeString(htmlTag.ReplaceAllString(channel.Description, " "))
+	fields := [][2]string{
+		{"Title", feed.Title()},
+		{"URL", feed.URL},
+		{"Link", channel.Link},
+		{"Description", strings.Join(strings.Fields(description), " ")},
+		{"Language", channel.Language},
+		{"Generator", channel.Generator},
+	}
+	if built, ok := feed.LastBuilt(); ok {
+		fields = append(fields, [2]string{"Last built", built.Format(outputTimeLayout+" 15:04") + " (" + shortDuration(now.Sub(built)) + " ago)"})
+	} else {
+		// Kept as it is so that dates which can't be parsed are seen
+		fields = append(fields, [2]string{"Last built", channel.LastBuildDate})
+	}
+	if channel.Image != nil {
+		fields = append(fields, [2]string{"Image", channel.Image.URL})
+	}
+	fields = append(fields,
+		[2]string{"Copyright", channel.Copyright},
+		[2]string{"Managing editor", channel.ManagingEditor},
+		[2]string{"Items", strconv.Itoa(len(channel.Items))},
+	)
+	newest, oldest := itemDates(feed)
+	if !newest.IsZero() {
+		fields = append(fields,
+			[2]string{"Newest item", newest.Format(outputTimeLayout+" 15:04") + " (" + shortDuration(now.Sub(newest)) + " ago)"},
+			[2]string{"Oldest item", oldest.Format(outputTimeLayout+" 15:04") + " (" + shortDuration(now.Sub(oldest)) + " ago)"},
+		)
+	}
+	for _, field := range fields {
+		err := WriteInfoField(w, field[0], field[1])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteInfoField writes a line of information about a feed, aligned with
+// those written by WriteFeedInfo. Nothing is written if the value is empty.
+func WriteInfoField(w io.Writer, name, value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%-16s %s\n", name+":", value)
+	return err
+}
+
+// itemDates returns the dates of the newest and oldest items in the feed which
+// are dated.
+func itemDates(feed *Feed) (time.Time, time.Time) {
+	parseDate := newDateParser(time.Time{})
+	var newest, oldest time.Time
+	for _, item := range feed.Channel.Items {
+		published, err := parseDate(item.PubDate)
+		if err != nil || published.IsZero() {
+			continue
+		}
+		if newest.IsZero() || published.After(newest) {
+			newest = published
+		}
+		if oldest.IsZero() || published.Before(oldest) {
+			oldest = published
+		}
+	}
+	return newest, oldest
+}
@@ -0,0 +1,87 @@
+package rss
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChannelMetadata(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name, body string
+		image      *Image
+	}{
+		{
+			name:  "image",
+			body:  `<image><url>https://example.com/logo.png</url><title>Example</title><link>https://example.com</link></image>`,
+			image: &Image{URL: "https://example.com/logo.png", Title: "Example", Link: "https://example.com"},
+		},
+		{
+			name:  "itunes image",
+			body:  `<itunes:image href="https://example.com/cover.jpg"/>`,
+			image: &Image{URL: "https://example.com/cover.jpg"},
+		},
+		{
+			name:  "image takes precedence",
+			body:  `<itunes:image href="https://example.com/cover.jpg"/><image><url>https://example.com/logo.png</url></image>`,
+			image: &Image{URL: "https://example.com/logo.png"},
+		},
+		{
+			name: "no image",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			body := `<rss xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel><title>T</title>
+<copyright>© 2024 Example</copyright><managingEditor>editor@example.com (Ed)</managingEditor>` + tc.body + `</channel></rss>`
+			channels, err := decodeRSS(strings.NewReader(body), newFetchOptions())
+			assertEqual(t, nil, err)
+			channel := channels[0].Channel
+			assertEqual(t, tc.image, channel.Image)
+			assertEqual(t, "© 2024 Example", channel.Copyright)
+			assertEqual(t, "editor@example.com (Ed)", channel.ManagingEditor)
+		})
+	}
+}
+
+func TestWriteFeedInfo(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	feed := &Feed{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{
+		Title:          "Example",
+		Link:           "https://example.com",
+		Description:    "<p>News &amp; views</p>",
+		LastBuildDate:  "Tue, 05 Mar 2024 09:00:00 +0000",
+		Image:          &Image{URL: "https://example.com/logo.png"},
+		ManagingEditor: "editor@example.com",
+		Items: []Item{
+			{Title: "New", PubDate: "Tue, 05 Mar 2024 11:30:00 +0000"},
+			{Title: "Undated"},
+			{Title: "Old", PubDate: "Sat, 02 Mar 2024 12:00:00 +0000"},
+		},
+	}}}
+	var buf bytes.Buffer
+	err := WriteFeedInfo(&buf, feed, now)
+	assertEqual(t, nil, err)
+	assertEqual(t, `Title:           Example
+URL:             https://example.com/feed
+Link:            https://example.com
+Description:     News & views
+Last built:      2024/03/05 09:00 (3h ago)
+Image:           https://example.com/logo.png
+Managing editor: editor@example.com
+Items:           3
+Newest item:     2024/03/05 11:30 (30m ago)
+Oldest item:     2024/03/02 12:00 (3d ago)
+`, buf.String())
+
+	// Dates which can't be parsed are shown as they are
+	feed.Channel.LastBuildDate = "yesterday"
+	buf.Reset()
+	err = WriteFeedInfo(&buf, feed, now)
+	assertEqual(t, nil, err)
+	assertEqual(t, true, strings.Contains(buf.String(), "Last built:      yesterday\n"))
+}
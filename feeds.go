@@ -1,15 +1,23 @@
 package rss
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AzinKhan/functools"
@@ -20,26 +28,111 @@ const (
 )
 
 var (
-	dateFormats = []string{time.RFC1123, time.RFC1123Z, "Mon, 2 Jan 2006 15:04:05 MST"}
+	// dateFormats are tried in order. RFC 3339 is for <dc:date>.
+	dateFormats = []string{time.RFC1123, time.RFC1123Z, "Mon, 2 Jan 2006 15:04:05 MST", time.RFC3339}
 	client      = http.DefaultClient
-	paywalls    = []string{}
 )
 
 type FeedItem struct {
+	// ID identifies the item stably across fetches. It is derived from the
+	// item's GUID, or its link and title if it has none.
+	ID          string
 	Title       string
 	PublishTime time.Time
 	Links       []string
-	Feed        string
-	Channel     string
+	// Feed identifies the source the item was fetched from: the name given
+	// to it in the config, or otherwise its URL.
+	Feed string
+	// Channel is the title of the channel the item was published in.
+	Channel string
+	// ReadTime is the estimated time to read the item's content. Zero if
+	// the feed did not provide any.
+	ReadTime time.Duration
+	// Language is the ISO 639-1 code of the language the item is written
+	// in, if it could be told.
+	Language string
+	// Tags label the item e.g. from rules.
+	Tags []string
+	// Score ranks the item, higher scores being more interesting.
+	Score int
+	// Note is the reader's note on the item, if it was starred with one.
+	Note string
+	// Summary sums up the item, if a plugin gave it one.
+	Summary string
+	// Points and Comments are the score and number of comments given to
+	// the item by the site it was posted to, if it provides them.
+	Points   int
+	Comments int
+	// Authors wrote the item, if the feed names them.
+	Authors []string
+	// channelLink is the link to the home page of the item's channel.
+	channelLink string
+	// colour is applied to the title when the item is formatted.
+	colour Colour
+	// dateLayout overrides the layout of the publish time when the item is
+	// formatted.
+	dateLayout string
+	// undated items had no date in the feed, so were given the time they were
+	// fetched.
+	undated bool
+	// channelUpdated is when the item's channel last changed, if it says.
+	channelUpdated time.Time
+	// updated is when the feed of a title card was last updated, shown after
+	// its title.
+	updated time.Time
+	// accessible items are formatted for screen readers.
+	accessible bool
+	// uncoloured items are formatted without colour.
+	uncoloured bool
 }
 
 func (fi FeedItem) Format() string {
-	return formatFeed(fi, includeLinks(true))
+	if fi.uncoloured {
+		return FormatItem(fi, IncludeLinks(true), NoColours())
+	}
+	return FormatItem(fi, IncludeLinks(true))
 }
 
 type Feed struct {
 	URL string
+	// Name overrides the title of the channel when displaying the feed.
+	Name string
 	RSS
+	// transformTitle rewrites the titles of the feed's items, if set.
+	transformTitle TitleTransform
+	// future is the policy for the feed's items dated in the future.
+	future FuturePolicy
+	// archiver archives the links of the feed's items if it is paywalled.
+	archiver *archiver
+	// resolver resolves the links of the feed's items through redirects, if
+	// set.
+	resolver *LinkResolver
+}
+
+// Title returns the name given to the feed, or the title of its channel if it
+// has none.
+func (f *Feed) Title() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return f.Channel.Title
+}
+
+// LastBuilt returns when the feed's channel says it last changed, if it says.
+func (f *Feed) LastBuilt() (time.Time, bool) {
+	if f.Channel.LastBuildDate == "" {
+		return time.Time{}, false
+	}
+	t, err := newDateParser(time.Time{})(f.Channel.LastBuildDate)
+	return t, err == nil
+}
+
+// source returns the name given to the feed, or its URL if it has none.
+func (f *Feed) source() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return f.URL
 }
 
 type RSS struct {
@@ -54,7 +147,47 @@ type Channel struct {
 	Description string   `xml:"description"`
 	Generator   string   `xml:"generator"`
 	Language    string   `xml:"language"`
-	Items       []Item   `xml:"item"`
+	// LastBuildDate is when the channel last changed, from <lastBuildDate>
+	// or Atom's <updated>.
+	LastBuildDate string `xml:"lastBuildDate,omitempty"`
+	Copyright     string `xml:"copyright,omitempty"`
+	// ManagingEditor is the email address of who is responsible for the
+	// channel's content, often followed by their name.
+	ManagingEditor string `xml:"managingEditor,omitempty"`
+	// Image is the channel's logo, if it has one.
+	Image *Image `xml:"image,omitempty"`
+	Items []Item `xml:"item"`
+	// Blogrolls are the URLs of OPML files listing feeds related to this
+	// one, advertised with <link rel="blogroll"> or <source:blogroll>.
+	Blogrolls []string `xml:"-"`
+	// Hub is the URL of the WebSub hub advertised with <link rel="hub">,
+	// and Self the URL of the feed it publishes updates for.
+	Hub  string `xml:"-"`
+	Self string `xml:"-"`
+	// Next is the URL of the next page of a paged feed, and PrevArchive the
+	// URL of the previous archive of an archived feed (RFC 5005), both
+	// holding older items.
+	Next        string `xml:"-"`
+	PrevArchive string `xml:"-"`
+	// Cloud is where to register for notifications of updates with the
+	// rssCloud protocol, if the feed supports it.
+	Cloud Cloud `xml:"cloud"`
+}
+
+// Image is a channel's logo, linking to its site.
+type Image struct {
+	URL   string `xml:"url"`
+	Title string `xml:"title,omitempty"`
+	Link  string `xml:"link,omitempty"`
+}
+
+// Cloud describes the rssCloud service notifying subscribers of updates.
+type Cloud struct {
+	Domain            string `xml:"domain,attr"`
+	Port              string `xml:"port,attr"`
+	Path              string `xml:"path,attr"`
+	RegisterProcedure string `xml:"registerProcedure,attr"`
+	Protocol          string `xml:"protocol,attr"`
 }
 
 type Item struct {
@@ -66,6 +199,21 @@ type Item struct {
 	// Comments provide a link to a dedicated comments page e.g. hackernews
 	Comments    string `xml:"comments"`
 	Description []byte `xml:"description"`
+	// Content holds the full body of the item if the feed uses the content
+	// module i.e. content:encoded
+	Content []byte `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	// CommentCount is the number of comments on the item, from the slash
+	// module i.e. slash:comments, or a source's API.
+	CommentCount int `xml:"-"`
+	// Points is the score given to the item by a source's API e.g. Hacker
+	// News.
+	Points int `xml:"-"`
+	// Creators are the authors of the item, from the Dublin Core module i.e.
+	// dc:creator.
+	Creators []string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	// id is the item's ID if it is already known, e.g. for items from a
+	// daemon, so that it is kept rather than derived again.
+	id string
 }
 
 type DisplayMode func([]FeedItem) []FeedItem
@@ -75,7 +223,109 @@ type DisplayOption func(FeedItem) FeedItem
 func ColourAfter(t time.Time) DisplayOption {
 	return func(item FeedItem) FeedItem {
 		if item.PublishTime.After(t) {
-			item.Title = colourize(item.Title, cyan)
+			item.colour = cyan
+		}
+		return item
+	}
+}
+
+var dateLayouts = map[string]string{
+	"default":  outputTimeLayout,
+	"iso":      "2006-01-02",
+	"iso-time": time.RFC3339,
+	"short":    "02 Jan",
+	"weekday":  "Mon 02 Jan",
+	"us":       "01/02/2006",
+}
+
+// DateLayout sets the layout used to format the publish times of items. The
+// name of one of the predefined layouts may be given, e.g. "iso" or
+// "weekday", otherwise it is taken to be a Go time layout.
+func DateLayout(layout string) DisplayOption {
+	if named, ok := dateLayouts[layout]; ok {
+		layout = named
+	}
+	return func(item FeedItem) FeedItem {
+		item.dateLayout = layout
+		return item
+	}
+}
+
+// AgeColour colours items which are at least Age old.
+type AgeColour struct {
+	Age    time.Duration
+	Colour Colour
+}
+
+// DefaultAgeColours colours items by freshness: cyan under 2 hours, green
+// under 6 hours, uncoloured under 3 days and gray after that. Each band starts
+// where the one before it ends.
+var DefaultAgeColours = []AgeColour{
+	{Age: 0, Colour: cyan},
+	{Age: 2 * time.Hour, Colour: green},
+	{Age: 6 * time.Hour, Colour: ""},
+	{Age: 72 * time.Hour, Colour: gray},
+}
+
+// ColourByAge colours items according to their age at the given time, using
+// the colour of the oldest threshold the item has reached. An empty colour
+// leaves the item uncoloured. Items which have already been coloured e.g. by
+// a rule are left as they are.
+func ColourByAge(now time.Time, thresholds []AgeColour) DisplayOption {
+	sorted := make([]AgeColour, len(thresholds))
+	copy(sorted, thresholds)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Age < sorted[j].Age
+	})
+	return func(item FeedItem) FeedItem {
+		if item.colour != "" {
+			return item
+		}
+		age := now.Sub(item.PublishTime)
+		for i := len(sorted) - 1; i >= 0; i-- {
+			if age >= sorted[i].Age || i == 0 {
+				item.colour = sorted[i].Colour
+				break
+			}
+		}
+		return item
+	}
+}
+
+// InLocation converts the publish times of items into the given location so
+// that they are displayed consistently e.g. in local time. Otherwise each item
+// keeps the time zone of its feed, which can put it on a different date.
+func InLocation(loc *time.Location) DisplayOption {
+	return func(item FeedItem) FeedItem {
+		item.PublishTime = item.PublishTime.In(loc)
+		return item
+	}
+}
+
+// HighlightKeywords colours the titles of items which contain any of the given
+// keywords. Matching is case-insensitive and on whole words only. Where a title
+// matches several keywords, the alphabetically first keyword takes precedence.
+func HighlightKeywords(keywords map[string]Colour) DisplayOption {
+	words := make([]string, 0, len(keywords))
+	for word := range keywords {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	patterns := make([]*regexp.Regexp, 0, len(words))
+	for _, word := range words {
+		patterns = append(patterns, regexp.MustCompile(`(?i)(^|\W)`+regexp.QuoteMeta(word)+`($|\W)`))
+	}
+	return func(item FeedItem) FeedItem {
+		if len(item.Links) == 0 {
+			// Title cards are coloured separately
+			return item
+		}
+		for i, pattern := range patterns {
+			if pattern.MatchString(item.Title) {
+				item.colour = keywords[words[i]]
+				break
+			}
 		}
 		return item
 	}
@@ -88,22 +338,92 @@ func ReverseChronological(feedItems []FeedItem) []FeedItem {
 	return feedItems
 }
 
+// Annotated returns a display mode which applies the display options to each
+// item before the items are passed to mode, so that mode can use what they
+// set e.g. scores.
+func Annotated(mode DisplayMode, opts ...DisplayOption) DisplayMode {
+	return func(feedItems []FeedItem) []FeedItem {
+		for i := range feedItems {
+			for _, o := range opts {
+				feedItems[i] = o(feedItems[i])
+			}
+		}
+		return mode(feedItems)
+	}
+}
+
+// ByScore orders the items by score, highest first, and then by publish time.
+func ByScore(feedItems []FeedItem) []FeedItem {
+	sort.SliceStable(feedItems, func(i, j int) bool {
+		if feedItems[i].Score == feedItems[j].Score {
+			return feedItems[i].PublishTime.After(feedItems[j].PublishTime)
+		}
+		return feedItems[i].Score > feedItems[j].Score
+	})
+	return feedItems
+}
+
+// Limited returns the items shown by the mode which pass a new limit, so that
+// limits count the items in the order they are shown rather than the order of
+// the feeds, e.g. the highest scored items.
+func Limited(mode DisplayMode, limit func() Filter) DisplayMode {
+	return func(feedItems []FeedItem) []FeedItem {
+		filter := limit()
+		var kept []FeedItem
+		for _, item := range mode(feedItems) {
+			if filter(item) {
+				kept = append(kept, item)
+			}
+		}
+		return kept
+	}
+}
+
+// Shuffled returns the items in a random order.
+func Shuffled(feedItems []FeedItem) []FeedItem {
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r.Shuffle(len(feedItems), func(i, j int) {
+		feedItems[i], feedItems[j] = feedItems[j], feedItems[i]
+	})
+	return feedItems
+}
+
+// Source returns a readable name for where the item came from: the name of
+// its feed if it was given one, otherwise the title of its channel.
+func (fi FeedItem) Source() string {
+	if fi.Channel == "" || !strings.Contains(fi.Feed, "://") {
+		return fi.Feed
+	}
+	return fi.Channel
+}
+
+// Grouped groups the items by feed and channel, in the order each first
+// appears, with a title card for each showing when the feed was last updated,
+// so that stale feeds stand out. Within each group the items are in reverse
+// chronological order.
 func Grouped(feedItems []FeedItem) []FeedItem {
-	itemsByFeed := make(map[string][]FeedItem)
+	type group struct{ feed, channel string }
+	itemsByGroup := make(map[group][]FeedItem)
+	var groups []group
 	for _, item := range feedItems {
-		existing := itemsByFeed[item.Feed]
+		g := group{item.Feed, item.Channel}
+		existing, found := itemsByGroup[g]
+		if !found {
+			groups = append(groups, g)
+		}
 		existing = append(existing, item)
-		itemsByFeed[item.Feed] = existing
+		itemsByGroup[g] = existing
 	}
 
-	result := make([]FeedItem, 0, len(itemsByFeed))
-	for feed, items := range itemsByFeed {
+	result := make([]FeedItem, 0, len(itemsByGroup))
+	for _, g := range groups {
+		items := itemsByGroup[g]
 		if len(items) == 0 {
 			continue
 		}
 		// Create a title-only item for the feed itself
 		result = append(result, FeedItem{})
-		result = append(result, FeedItem{Title: feed})
+		result = append(result, FeedItem{Title: items[0].Source(), updated: lastUpdated(items)})
 		for _, item := range ReverseChronological(items) {
 			result = append(result, item)
 		}
@@ -111,6 +431,24 @@ func Grouped(feedItems []FeedItem) []FeedItem {
 	return result
 }
 
+// lastUpdated returns when the items' feed was last updated: the date of the
+// newest item, or if none are dated, when the channel says it last changed.
+func lastUpdated(feedItems []FeedItem) time.Time {
+	var updated, channelUpdated time.Time
+	for _, item := range feedItems {
+		if !item.undated && item.PublishTime.After(updated) {
+			updated = item.PublishTime
+		}
+		if item.channelUpdated.After(channelUpdated) {
+			channelUpdated = item.channelUpdated
+		}
+	}
+	if updated.IsZero() {
+		return channelUpdated
+	}
+	return updated
+}
+
 // Display writes the feed items to the given writer in the provided display
 // mode. Returns any error encountered by writing to w.
 func Display(w io.Writer, feedItems []FeedItem, displayMode DisplayMode, opts ...DisplayOption) error {
@@ -126,6 +464,23 @@ func Display(w io.Writer, feedItems []FeedItem, displayMode DisplayMode, opts ..
 	return nil
 }
 
+// DisplaySummary writes the number of items from each feed to the given
+// writer, with the feeds with the most items first.
+func DisplaySummary(w io.Writer, feedItems []FeedItem, opts ...FormatOption) error {
+	c := colourizerOf(opts)
+	counts := make(map[string]int)
+	for _, item := range feedItems {
+		counts[item.Source()]++
+	}
+	for _, feed := range sortedByCount(counts) {
+		_, err := fmt.Fprintf(w, "%s: %d new\n", c.colourize(feed, green), counts[feed])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type Filter func(FeedItem) bool
 
 type Filters []Filter
@@ -141,10 +496,56 @@ func (fs Filters) Apply(fi FeedItem) bool {
 	return true
 }
 
+// And passes items which pass all of the filters. Filters are applied in order
+// and stop at the first which fails, so stateful filters after it won't see
+// the item.
+func And(filters ...Filter) Filter {
+	return Filters(filters).Apply
+}
+
+// Or passes items which pass any of the filters. Filters are applied in order
+// and stop at the first which passes.
+func Or(filters ...Filter) Filter {
+	return func(item FeedItem) bool {
+		for _, f := range filters {
+			if f(item) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not passes items which fail the filter.
+func Not(f Filter) Filter {
+	return func(item FeedItem) bool {
+		return !f(item)
+	}
+}
+
+// SyncFilter makes a stateful filter safe to use from multiple goroutines by
+// only allowing one call at a time. The stateful filters provided by this
+// package, such as Deduplicate and MaxItems, are already wrapped.
+func SyncFilter(f Filter) Filter {
+	var mu sync.Mutex
+	return func(item FeedItem) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return f(item)
+	}
+}
+
 // Deduplicate ensures that each feed item only appears in the output once.
+// Items are identified by their ID, or by their links if they have none.
 func Deduplicate() Filter {
+	ids := make(map[string]struct{})
 	urls := make(map[string]struct{})
-	return func(item FeedItem) bool {
+	return SyncFilter(func(item FeedItem) bool {
+		if item.ID != "" {
+			_, found := ids[item.ID]
+			ids[item.ID] = struct{}{}
+			return !found
+		}
 		for _, link := range item.Links {
 			_, found := urls[link]
 			if found {
@@ -153,6 +554,40 @@ func Deduplicate() Filter {
 			urls[link] = struct{}{}
 		}
 		return true
+	})
+}
+
+// FromFeeds only lets through items from the named feeds. Names are matched
+// case-insensitively against the feed's name or URL and its channel's title.
+func FromFeeds(names ...string) Filter {
+	return func(item FeedItem) bool {
+		for _, name := range names {
+			if strings.EqualFold(item.Feed, name) || strings.EqualFold(item.Channel, name) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// BlockLinks filters out items with a link matching any of the regular
+// expressions e.g. "/sponsored/" or `^https://deals\.`. It panics if a pattern
+// is invalid, so patterns from users should be checked with regexp.Compile
+// first.
+func BlockLinks(patterns ...string) Filter {
+	blocked := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		blocked[i] = regexp.MustCompile(pattern)
+	}
+	return func(item FeedItem) bool {
+		for _, link := range item.Links {
+			for _, b := range blocked {
+				if b.MatchString(link) {
+					return false
+				}
+			}
+		}
+		return true
 	}
 }
 
@@ -171,12 +606,12 @@ func MaxItemsPerChannel(n int) Filter {
 		return func(FeedItem) bool { return true }
 	}
 	counts := make(map[string]int)
-	return func(item FeedItem) bool {
+	return SyncFilter(func(item FeedItem) bool {
 		channelCount := counts[item.Channel]
 		channelCount++
 		counts[item.Channel] = channelCount
 		return channelCount <= n
-	}
+	})
 }
 
 // MaxItems enforces a limit on the total number of items in the result.
@@ -187,21 +622,45 @@ func MaxItems(n int) Filter {
 	}
 
 	count := 0
-	return func(item FeedItem) bool {
+	return SyncFilter(func(item FeedItem) bool {
 		count++
 		return count <= n
-	}
+	})
 }
 
 // GetFeedItems unpacks the items within the given feeds, applying filters if
-// given.
+// given. The feeds are unpacked in parallel but the filters are applied
+// serially, in the order of the feeds, since they are often stateful.
 func GetFeedItems(feeds []*Feed, filters ...Filter) []FeedItem {
-	feedItems := make([]FeedItem, 0, len(feeds))
-	for _, feed := range feeds {
+	unpacked := make([][]FeedItem, len(feeds))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				unpacked[i] = UnpackFeed(feeds[i])
+			}
+		}()
+	}
+	for i, feed := range feeds {
 		if feed == nil {
 			continue
 		}
-		feedItems = append(feedItems, UnpackFeed(feed, filters...)...)
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	fs := Filters(filters)
+	feedItems := make([]FeedItem, 0, len(feeds))
+	for _, items := range unpacked {
+		for _, item := range items {
+			if fs.Apply(item) {
+				feedItems = append(feedItems, item)
+			}
+		}
 	}
 	return feedItems
 }
@@ -213,6 +672,9 @@ func UnpackFeed(feed *Feed, filters ...Filter) []FeedItem {
 	feedItems := make([]FeedItem, 0, len(feed.Channel.Items))
 	for _, item := range feed.Channel.Items {
 		feedItem, err := newFeedItem(item)
+		if errors.Is(err, ErrFutureItem) {
+			continue
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, err.Error())
 			continue
@@ -230,79 +692,195 @@ func UnpackFeed(feed *Feed, filters ...Filter) []FeedItem {
 // GetURLs reads the given Reader and returns a list of the urls from which
 // feeds can be fetched.
 func GetURLs(r io.Reader) []string {
-	scanner := bufio.NewScanner(r)
 	var urls []string
-	for scanner.Scan() {
-		url := scanner.Text()
-		if strings.HasPrefix(url, "#") {
-			// Commented out url
+	for _, sub := range ReadSubscriptions(r) {
+		if sub.Disabled {
 			continue
 		}
-		urls = append(urls, url)
+		urls = append(urls, sub.URL)
 	}
 	return urls
 }
 
 // GetFeeds makes requests to the hosts in parallel and collects the results
-// into a slice.
-func GetFeeds(urls []string) []*Feed {
-	return functools.MapAsync(getFeed, urls)
+// into a slice, with a feed for each channel in the order of the urls.
+func GetFeeds(urls []string, opts ...FetchOption) []*Feed {
+	var feeds []*Feed
+	for _, fetched := range functools.MapAsync(feedGetter(opts...), urls) {
+		feeds = append(feeds, fetched...)
+	}
+	return feeds
 }
 
 // GetFeedsAsync makes requests to the hosts in parallel and writes the results
-// to the returned channel as they are received.
-func GetFeedsAsync(urls []string) <-chan *Feed {
-	return functools.MapChan(getFeed, urls)
+// to the returned channel as they are received, with a feed for each channel.
+func GetFeedsAsync(urls []string, opts ...FetchOption) <-chan *Feed {
+	fetchedCh := functools.MapChan(feedGetter(opts...), urls)
+	feedsCh := make(chan *Feed)
+	go func() {
+		defer close(feedsCh)
+		for fetched := range fetchedCh {
+			for _, feed := range fetched {
+				feedsCh <- feed
+			}
+		}
+	}()
+	return feedsCh
 }
 
-func getFeed(url string) *Feed {
-	resp, err := client.Get(url)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error getting %s: %s", url, err.Error())
-		return nil
+func feedGetter(opts ...FetchOption) func(string) []*Feed {
+	return func(url string) []*Feed {
+		feeds, err := FetchFeeds(url, opts...)
+		if errors.Is(err, ErrCancelled) {
+			// Cancelling is reported once rather than for every feed
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, err.Error())
+			return nil
+		}
+		return feeds
+	}
+}
+
+// FetchFeeds makes a request to the url and decodes the feeds in the
+// response, one for each channel in the document.
+func FetchFeeds(url string, opts ...FetchOption) ([]*Feed, error) {
+	options := newFetchOptions(opts...)
+	parent := options.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if !options.deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(parent, options.deadline)
+	}
+	defer cancel()
+	feeds, cached, err := fetchFeeds(ctx, url, options)
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("error getting %s: %w", url, ErrTimedOut)
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = fmt.Errorf("error getting %s: %w", url, ErrCancelled)
+	}
+	options.report.record(err, cached)
+	if err != nil && options.onError != nil && !errors.Is(err, ErrCancelled) {
+		options.onError(url, err)
+	}
+	return feeds, err
+}
+
+func fetchFeeds(ctx context.Context, url string, options fetchOptions) ([]*Feed, bool, error) {
+	if source, u, found := sourceOf(url); found {
+		channels, err := source(ctx, u, options)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting %s: %s", url, err.Error())
+		}
+		return newFeeds(url, channels, options), false, nil
+	}
+	var body io.Reader
+	var cached, fetched []byte
+	var limited *io.LimitedReader
+	if options.cache != nil {
+		cached, _ = options.cache.get(url)
+	}
+	if cached != nil {
+		body = bytes.NewReader(cached)
+	} else {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting %s: %s", url, err.Error())
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting %s: %s", url, err.Error())
+		}
+		defer resp.Body.Close()
+		body = resp.Body
+		if options.maxBody > 0 {
+			limited = &io.LimitedReader{R: resp.Body, N: options.maxBody}
+			body = limited
+		}
+		if options.cache != nil && resp.StatusCode == http.StatusOK {
+			// The whole response is read so that it can be cached once it
+			// has been decoded
+			fetched, err = io.ReadAll(body)
+			if err != nil {
+				return nil, false, fmt.Errorf("error getting %s: %s", url, err.Error())
+			}
+			body = bytes.NewReader(fetched)
+		}
+	}
+	channels, err := decodeRSS(body, options)
+	truncated := limited != nil && limited.N == 0
+	if err != nil && truncated && len(channels) > 0 {
+		// The items before the limit are kept
+		err = nil
 	}
-	defer resp.Body.Close()
-	var rss RSS
-	err = xml.NewDecoder(resp.Body).Decode(&rss)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error unmarshaling body from %s: %s", url, err.Error())
-		return nil
+		return nil, false, fmt.Errorf("error unmarshaling body from %s: %s", url, err.Error())
 	}
-	return &Feed{url, rss}
+	if fetched != nil && !truncated {
+		// Failing to cache only means fetching again next time
+		options.cache.put(url, fetched)
+	}
+	return newFeeds(url, channels, options), cached != nil, nil
 }
 
-func linkFormatter(feed *Feed) func(Item) string {
-	var hasPaywall bool
-	for _, pw := range paywalls {
-		if strings.HasPrefix(feed.URL, pw) {
-			hasPaywall = true
-			break
+func newFeeds(url string, channels []RSS, options fetchOptions) []*Feed {
+	feeds := make([]*Feed, 0, len(channels))
+	arxiv := isArxiv(url)
+	for _, rss := range channels {
+		if arxiv {
+			for i, item := range rss.Channel.Items {
+				rss.Channel.Items[i] = cleanArxiv(item)
+			}
 		}
+		feeds = append(feeds, &Feed{URL: url, Name: options.names[url], RSS: rss, transformTitle: options.titles[url], future: options.future, archiver: options.archiver, resolver: options.resolver})
 	}
+	return feeds
+}
+
+func linkFormatter(feed *Feed) func(Item) string {
+	// Links from sources' APIs aren't tracked, and need their queries e.g.
+	// for Hacker News discussions
+	_, _, fromSource := sourceOf(feed.URL)
 	return func(item Item) string {
-		link := item.Link
+		link := strings.TrimSpace(item.Link)
 		if link == "" {
-			link = item.GUID
+			link = strings.TrimSpace(item.GUID)
 		}
+		// Resolve redirects first so that the final link is cleaned up too
+		link = feed.resolver.Resolve(link)
 		// Clear query params since they're usually just for tracking
 		u, err := url.Parse(link)
 		if err != nil {
 			return err.Error()
 		}
-		u.RawQuery = ""
-
-		link = u.String()
-		// Add archive to paywalled links
-		if hasPaywall {
-			return fmt.Sprintf("https://archive.is/%s", link)
+		if !fromSource {
+			u.RawQuery = ""
 		}
-		return link
+
+		return u.String()
 	}
 }
 
+// NewFeedItem converts an item from the given feed into a FeedItem in the same
+// way as UnpackFeed: tracking query parameters are removed from its link,
+// paywalled links are archived and its publish date is parsed. Items without
+// a publish date are given the current time. When converting many items from
+// the same feed, UnpackFeed is more efficient.
+func NewFeedItem(feed *Feed, item Item) (FeedItem, error) {
+	return newFeedItemCreator(feed)(item)
+}
+
 func newFeedItemCreator(feed *Feed) func(Item) (FeedItem, error) {
-	parseDate := newDateParser(time.Now())
+	now := time.Now()
+	parseDate := newDateParser(now)
 	formatLink := linkFormatter(feed)
+	paywalled := feed.archiver.paywalled(feed.URL)
+	channelUpdated, _ := feed.LastBuilt()
 	return func(item Item) (FeedItem, error) {
 		links := []string{formatLink(item)}
 		if item.Comments != "" {
@@ -312,16 +890,67 @@ func newFeedItemCreator(feed *Feed) func(Item) (FeedItem, error) {
 		if err != nil {
 			return FeedItem{}, err
 		}
-		return FeedItem{
+		feedItem := FeedItem{
 			Title:       item.Title,
 			Links:       links,
 			PublishTime: pubTime,
-			Feed:        feed.Channel.Title,
+			Feed:        feed.source(),
 			Channel:     feed.Channel.Title,
-		}, nil
+			ReadTime:    estimateReadTime(item),
+			Points:      item.Points,
+			Comments:    item.CommentCount,
+			Authors:     item.Creators,
+			Language:    itemLanguage(item, feed.Channel),
+			channelLink: feed.Channel.Link,
+
+			undated:        item.PubDate == "",
+			channelUpdated: channelUpdated,
+		}
+		feedItem.ID = itemID(feed, item, feedItem)
+		// Titles are transformed after the ID is found so that changing the
+		// transforms doesn't change which items have been seen
+		if feed.transformTitle != nil {
+			feedItem.Title = feed.transformTitle(feedItem.Title, feed.Channel.Title)
+		}
+		if pubTime.After(now.Add(futureTolerance)) {
+			switch feed.future {
+			case FutureHide:
+				return FeedItem{}, ErrFutureItem
+			case FutureClamp:
+				feedItem.PublishTime = now
+			}
+		}
+		// Links are archived after the ID is found so that the item is
+		// identified by its original link
+		if paywalled {
+			feedItem.Links[0] = feed.archiver.link(feedItem.Links[0])
+		}
+		return feedItem, nil
 	}
 }
 
+// itemID returns a stable identifier for the item. GUIDs are only unique
+// within a feed so they are combined with the feed's URL. Items without a GUID
+// are identified by their title and their link as the feed gives it, since
+// the query stripped from links shown often names the item e.g. ?v=ID.
+func itemID(feed *Feed, item Item, feedItem FeedItem) string {
+	if item.id != "" {
+		return item.id
+	}
+	key := feed.URL + "\n" + strings.TrimSpace(item.GUID)
+	if strings.TrimSpace(item.GUID) == "" {
+		key = strings.TrimSpace(feedItem.Title) + "\n" + strings.TrimSpace(item.Link)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ParseDate parses the date of an item or channel in any of the layouts found
+// in feeds. An empty date is the zero time.
+func ParseDate(date string) (time.Time, error) {
+	return newDateParser(time.Time{})(date)
+}
+
 func newDateParser(defaultTime time.Time) func(string) (time.Time, error) {
 	return func(rawDate string) (time.Time, error) {
 		if rawDate == "" {
@@ -1,7 +1,12 @@
 package rss
 
 import (
+	"bytes"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -143,6 +148,60 @@ func TestFilterMultiple(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:   "Deduplicate by ID",
+			filter: Deduplicate(),
+			cases: []testcase{
+				{
+					expected: true,
+					item: FeedItem{
+						ID:    "1",
+						Links: []string{"link1"},
+					},
+				},
+				{
+					expected: true,
+					item: FeedItem{
+						ID:    "2",
+						Links: []string{"link1"},
+					},
+				},
+				{
+					expected: false,
+					item: FeedItem{
+						ID:    "1",
+						Links: []string{"link2"},
+					},
+				},
+			},
+		},
+		{
+			name:   "Deduplicate content",
+			filter: DeduplicateContent(),
+			cases: []testcase{
+				{
+					expected: true,
+					item: FeedItem{
+						Title: "Some  title",
+						Links: []string{"https://www.example.com/post/"},
+					},
+				},
+				{
+					expected: false,
+					item: FeedItem{
+						Title: "some title",
+						Links: []string{"http://example.com/post"},
+					},
+				},
+				{
+					expected: true,
+					item: FeedItem{
+						Title: "Another title",
+						Links: []string{"http://example.com/post"},
+					},
+				},
+			},
+		},
 	}
 
 	t.Parallel()
@@ -159,6 +218,353 @@ func TestFilterMultiple(t *testing.T) {
 	}
 }
 
+func TestFilterCombinators(t *testing.T) {
+	pass := func(FeedItem) bool { return true }
+	fail := func(FeedItem) bool { return false }
+	testcases := []struct {
+		name     string
+		filter   Filter
+		expected bool
+	}{
+		{name: "And all pass", filter: And(pass, pass), expected: true},
+		{name: "And one fails", filter: And(pass, fail), expected: false},
+		{name: "And empty", filter: And(), expected: true},
+		{name: "Or one passes", filter: Or(fail, pass), expected: true},
+		{name: "Or all fail", filter: Or(fail, fail), expected: false},
+		{name: "Or empty", filter: Or(), expected: false},
+		{name: "Not", filter: Not(fail), expected: true},
+		{name: "Nested", filter: Or(And(pass, fail), Not(Or(fail))), expected: true},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			assertEqual(t, tc.expected, tc.filter(FeedItem{}))
+		})
+	}
+}
+
+func TestBlockLinks(t *testing.T) {
+	block := BlockLinks("/sponsored/", `^https://deals\.`)
+	testcases := []struct {
+		name     string
+		links    []string
+		expected bool
+	}{
+		{name: "Path", links: []string{"https://example.com/sponsored/post"}, expected: false},
+		{name: "Subdomain", links: []string{"https://deals.example.com/post"}, expected: false},
+		{name: "Comments", links: []string{"https://example.com/post", "https://example.com/sponsored/comments"}, expected: false},
+		{name: "Allowed", links: []string{"https://example.com/post"}, expected: true},
+		{name: "No links", expected: true},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			assertEqual(t, tc.expected, block(FeedItem{Links: tc.links}))
+		})
+	}
+}
+
+func TestStatefulFiltersConcurrent(t *testing.T) {
+	filters := Filters{Deduplicate(), DeduplicateContent(), MaxItemsPerChannel(50), MaxItems(100)}
+	var passed int64
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				item := FeedItem{
+					Title:   fmt.Sprintf("%d-%d", i, j),
+					Links:   []string{fmt.Sprintf("https://example.com/%d/%d", i, j)},
+					Channel: fmt.Sprint(i),
+				}
+				if filters.Apply(item) {
+					atomic.AddInt64(&passed, 1)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	assertEqual(t, int64(100), passed)
+}
+
+func TestHighlightKeywords(t *testing.T) {
+	highlight := HighlightKeywords(map[string]Colour{
+		"go":       Red,
+		"security": Yellow,
+	})
+	testcases := []struct {
+		name     string
+		item     FeedItem
+		expected Colour
+	}{
+		{
+			name:     "Matches keyword case-insensitively",
+			item:     FeedItem{Title: "Go 1.20 released", Links: []string{"link"}},
+			expected: Red,
+		},
+		{
+			name:     "Does not match part of a word",
+			item:     FeedItem{Title: "Google announces something", Links: []string{"link"}},
+			expected: "",
+		},
+		{
+			name:     "First keyword alphabetically wins",
+			item:     FeedItem{Title: "Security fixes in Go", Links: []string{"link"}},
+			expected: Red,
+		},
+		{
+			name:     "Title cards are ignored",
+			item:     FeedItem{Title: "Go blog"},
+			expected: "",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			result := highlight(tc.item)
+			assertEqual(t, tc.expected, result.colour)
+		})
+	}
+}
+
+func TestInLocation(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database unavailable")
+	}
+	testcases := []struct {
+		name     string
+		publish  time.Time
+		loc      *time.Location
+		expected string
+	}{
+		{
+			name:     "Late evening moves to the next day in UTC",
+			publish:  time.Date(2022, 11, 1, 23, 30, 0, 0, newYork),
+			loc:      time.UTC,
+			expected: "2022/11/02",
+		},
+		{
+			name:     "Just after midnight UTC moves to the previous day",
+			publish:  time.Date(2022, 11, 2, 0, 15, 0, 0, time.UTC),
+			loc:      newYork,
+			expected: "2022/11/01",
+		},
+		{
+			name:     "Same zone is unchanged",
+			publish:  time.Date(2022, 11, 2, 0, 0, 0, 0, time.UTC),
+			loc:      time.UTC,
+			expected: "2022/11/02",
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			item := InLocation(tc.loc)(FeedItem{PublishTime: tc.publish})
+			assertEqual(t, tc.expected, item.PublishTime.Format(outputTimeLayout))
+			assertEqual(t, true, item.PublishTime.Equal(tc.publish))
+		})
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	item := FeedItem{
+		Title:       "Title",
+		PublishTime: time.Date(2022, 11, 4, 9, 0, 0, 0, time.UTC),
+		Links:       []string{"link"},
+	}
+	testcases := []struct {
+		layout   string
+		expected string
+	}{
+		{layout: "iso", expected: "2022-11-04:\tTitle\n"},
+		{layout: "weekday", expected: "Fri 04 Nov:\tTitle\n"},
+		{layout: "Jan 2", expected: "Nov 4:\tTitle\n"},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.layout, func(t *testing.T) {
+			result := FormatItem(DateLayout(tc.layout)(item), NoColours())
+			assertEqual(t, tc.expected, result)
+		})
+	}
+}
+
+func TestColourByAge(t *testing.T) {
+	now := time.Now()
+	colourByAge := ColourByAge(now, DefaultAgeColours)
+	testcases := []struct {
+		name     string
+		age      time.Duration
+		expected Colour
+	}{
+		{name: "Future item", age: -time.Hour, expected: Cyan},
+		{name: "Under 2 hours", age: time.Hour, expected: Cyan},
+		{name: "Under 6 hours", age: 2 * time.Hour, expected: Green},
+		{name: "Under 24 hours", age: 6 * time.Hour, expected: ""},
+		{name: "Under 3 days", age: 48 * time.Hour, expected: ""},
+		{name: "Just under 3 days", age: 72*time.Hour - time.Minute, expected: ""},
+		{name: "Exactly 3 days", age: 72 * time.Hour, expected: Gray},
+		{name: "Over 3 days", age: 80 * time.Hour, expected: Gray},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			item := colourByAge(FeedItem{PublishTime: now.Add(-tc.age)})
+			assertEqual(t, tc.expected, item.colour)
+		})
+	}
+}
+
+func TestGetFeedItems(t *testing.T) {
+	now := time.Now().Format(time.RFC1123Z)
+	var feeds []*Feed
+	for i := 0; i < 20; i++ {
+		channel := Channel{Title: fmt.Sprintf("Feed %d", i)}
+		for j := 0; j < 3; j++ {
+			channel.Items = append(channel.Items, Item{
+				Title:   fmt.Sprintf("Item %d-%d", i, j),
+				Link:    fmt.Sprintf("https://example.com/%d/%d", i, j),
+				PubDate: now,
+			})
+		}
+		feeds = append(feeds, &Feed{RSS: RSS{Channel: channel}}, nil)
+	}
+
+	feedItems := GetFeedItems(feeds, MaxItemsPerChannel(2))
+
+	assertEqual(t, 40, len(feedItems))
+	// Items keep the order of the feeds despite being unpacked in parallel
+	for i, item := range feedItems {
+		assertEqual(t, fmt.Sprintf("Item %d-%d", i/2, i%2), item.Title)
+	}
+}
+
+func TestNewFeedItem(t *testing.T) {
+	feed := &Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Example"}}}
+	item := Item{
+		Title:    "Title",
+		Link:     "https://example.com/post?utm_source=rss",
+		PubDate:  "Mon, 02 Jan 2006 15:04:05 -0700",
+		Comments: "https://example.com/post#comments",
+	}
+
+	result, err := NewFeedItem(feed, item)
+	assertEqual(t, nil, err)
+	assertEqual(t, "Title", result.Title)
+	assertEqual(t, []string{"https://example.com/post", "https://example.com/post#comments"}, result.Links)
+	assertEqual(t, true, result.PublishTime.Equal(time.Date(2006, 1, 2, 22, 4, 5, 0, time.UTC)))
+	assertEqual(t, "https://example.com/rss", result.Feed)
+	assertEqual(t, "Example", result.Channel)
+	assertEqual(t, "Example", result.Source())
+
+	// Without a GUID the ID comes from the link and title
+	assertEqual(t, 32, len(result.ID))
+	withGUID, err := NewFeedItem(feed, Item{Title: "Title", Link: item.Link, GUID: "1"})
+	assertEqual(t, nil, err)
+	otherFeed := &Feed{URL: "https://example.org/rss"}
+	otherGUID, err := NewFeedItem(otherFeed, Item{Title: "Title", Link: item.Link, GUID: "1"})
+	assertEqual(t, nil, err)
+	assertEqual(t, false, withGUID.ID == result.ID)
+	assertEqual(t, false, withGUID.ID == otherGUID.ID)
+	// Items with the same title are told apart by their GUIDs, or by the
+	// queries of their links without them
+	video, err := NewFeedItem(feed, Item{Title: "Title", Link: "https://www.youtube.com/watch?v=1", GUID: "yt:video:1"})
+	assertEqual(t, nil, err)
+	otherVideo, err := NewFeedItem(feed, Item{Title: "Title", Link: "https://www.youtube.com/watch?v=1", GUID: "yt:video:2"})
+	assertEqual(t, nil, err)
+	assertEqual(t, false, video.ID == otherVideo.ID)
+	video, err = NewFeedItem(feed, Item{Title: "Title", Link: "https://www.youtube.com/watch?v=1"})
+	assertEqual(t, nil, err)
+	otherVideo, err = NewFeedItem(feed, Item{Title: "Title", Link: "https://www.youtube.com/watch?v=2"})
+	assertEqual(t, nil, err)
+	assertEqual(t, false, video.ID == otherVideo.ID)
+
+	feed.Name = "Renamed"
+	renamed, err := NewFeedItem(feed, item)
+	assertEqual(t, nil, err)
+	assertEqual(t, "Renamed", renamed.Feed)
+	assertEqual(t, "Example", renamed.Channel)
+	assertEqual(t, "Renamed", renamed.Source())
+
+	_, err = NewFeedItem(feed, Item{PubDate: "yesterday"})
+	assertEqual(t, true, err != nil)
+}
+
+func TestGroupedKeepsFeedOrder(t *testing.T) {
+	now := time.Now()
+	feedItems := []FeedItem{
+		{Feed: "C", Title: "c1", Links: []string{"c1"}, PublishTime: now},
+		{Feed: "A", Title: "a1", Links: []string{"a1"}, PublishTime: now.Add(-time.Hour)},
+		{Feed: "A", Title: "a2", Links: []string{"a2"}, PublishTime: now},
+		{Feed: "B", Title: "b1", Links: []string{"b1"}, PublishTime: now},
+	}
+	var titles []string
+	for _, item := range Grouped(feedItems) {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, []string{"", "C", "c1", "", "A", "a2", "a1", "", "B", "b1"}, titles)
+}
+
+func TestGroupedBySource(t *testing.T) {
+	now := time.Now()
+	feedItems := []FeedItem{
+		{Feed: "https://a.com/rss", Channel: "Blog", Title: "a1", Links: []string{"a1"}, PublishTime: now},
+		{Feed: "https://b.com/rss", Channel: "Blog", Title: "b1", Links: []string{"b1"}, PublishTime: now},
+		{Feed: "Renamed", Channel: "Blog", Title: "c1", Links: []string{"c1"}, PublishTime: now},
+	}
+	var titles []string
+	for _, item := range Grouped(feedItems) {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, []string{"", "Blog", "a1", "", "Blog", "b1", "", "Renamed", "c1"}, titles)
+	assertEqual(t, true, FromFeeds("blog")(feedItems[0]))
+	assertEqual(t, true, FromFeeds("https://b.com/rss")(feedItems[1]))
+	assertEqual(t, false, FromFeeds("Renamed")(feedItems[1]))
+}
+
+func TestDisplaySummary(t *testing.T) {
+	feedItems := []FeedItem{
+		{Feed: "B"},
+		{Feed: "A"},
+		{Feed: "C"},
+		{Feed: "C"},
+	}
+	var buf bytes.Buffer
+	err := DisplaySummary(&buf, feedItems)
+	assertEqual(t, nil, err)
+
+	expected := fmt.Sprintf("%s: 2 new\n%s: 1 new\n%s: 1 new\n", colourizeANSI("C", green), colourizeANSI("A", green), colourizeANSI("B", green))
+	assertEqual(t, expected, buf.String())
+}
+
+func TestDisplaySummaryWithoutColour(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	err := DisplaySummary(&buf, []FeedItem{{Feed: "A"}}, NoColours())
+	assertEqual(t, nil, err)
+	assertEqual(t, "A: 1 new\n", buf.String())
+}
+
 func assertEqual(t *testing.T, expected interface{}, result interface{}) {
 	if reflect.DeepEqual(expected, result) {
 		return
@@ -166,3 +572,105 @@ func assertEqual(t *testing.T, expected interface{}, result interface{}) {
 	t.Fail()
 	t.Logf("Expected %v, got %v", expected, result)
 }
+
+func TestGroupedLastUpdated(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	built := now.Add(-3 * time.Hour).Format(time.RFC1123Z)
+	feeds := []*Feed{
+		{URL: "https://a.com/rss", RSS: RSS{Channel: Channel{Title: "Dated", LastBuildDate: built, Items: []Item{
+			{Title: "old", Link: "https://a.com/1", PubDate: now.Add(-26 * time.Hour).Format(time.RFC1123Z)},
+			{Title: "new", Link: "https://a.com/2", PubDate: now.Add(-2*time.Hour - time.Minute).Format(time.RFC1123Z)},
+		}}}},
+		// Undated items fall back to the build date of the channel
+		{URL: "https://b.com/rss", RSS: RSS{Channel: Channel{Title: "Undated", LastBuildDate: built, Items: []Item{
+			{Title: "b", Link: "https://b.com/1"},
+		}}}},
+		{URL: "https://c.com/rss", RSS: RSS{Channel: Channel{Title: "Unknown", Items: []Item{
+			{Title: "c", Link: "https://c.com/1"},
+		}}}},
+	}
+	var headers []string
+	for _, item := range Grouped(GetFeedItems(feeds)) {
+		if len(item.Links) == 0 && item.Title != "" {
+			headers = append(headers, WithoutColour()(item).Format())
+		}
+	}
+	assertEqual(t, []string{
+		"\tDated — updated 2h ago\n",
+		"\tUndated — updated 3h ago\n",
+		"\tUnknown\n",
+	}, headers)
+}
+
+func TestDecodeLastBuildDate(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name, body string
+	}{
+		{"rss", `<rss><channel><title>T</title><lastBuildDate>Mon, 02 Jan 2006 15:04:05 +0000</lastBuildDate></channel></rss>`},
+		{"updated", `<rss><channel><title>T</title><updated>Mon, 02 Jan 2006 15:04:05 +0000</updated></channel></rss>`},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			channels, err := decodeRSS(strings.NewReader(tc.body), newFetchOptions())
+			assertEqual(t, nil, err)
+			assertEqual(t, "Mon, 02 Jan 2006 15:04:05 +0000", channels[0].Channel.LastBuildDate)
+		})
+	}
+}
+
+// FuzzParseDate checks that dates which parse are read back as the same time
+// when written out again.
+func FuzzParseDate(f *testing.F) {
+	for _, date := range []string{
+		"",
+		"Tue, 01 Nov 2022 09:00:00 GMT",
+		"Tue, 01 Nov 2022 09:00:00 -0500",
+		"Tue, 1 Nov 2022 09:00:00 EST",
+		"2022-11-01T09:00:00.123-05:00",
+		"2022-11-01T09:00:00Z",
+		"Not a date",
+	} {
+		f.Add(date)
+	}
+	f.Fuzz(func(t *testing.T, date string) {
+		parsed, err := ParseDate(date)
+		if err != nil || parsed.Year() < 0 || parsed.Year() > 9999 {
+			return
+		}
+		formatted := parsed.Format(time.RFC3339Nano)
+		reparsed, err := ParseDate(formatted)
+		if err != nil {
+			t.Fatalf("error parsing %q, from %q: %s", formatted, date, err)
+		}
+		if !reparsed.Equal(parsed) {
+			t.Errorf("%q parsed as %s, then %s", date, parsed, reparsed)
+		}
+	})
+}
+
+func TestLimitedByScore(t *testing.T) {
+	t.Parallel()
+	feedItems := []FeedItem{
+		{Title: "Low", Channel: "a", Score: 1},
+		{Title: "High", Channel: "a", Score: 3},
+		{Title: "Middle", Channel: "b", Score: 2},
+		{Title: "Highest", Channel: "b", Score: 4},
+	}
+	titles := func(feedItems []FeedItem) []string {
+		var titles []string
+		for _, item := range feedItems {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+	mode := Limited(ByScore, func() Filter { return MaxItems(2) })
+	assertEqual(t, []string{"Highest", "High"}, titles(mode(append([]FeedItem{}, feedItems...))))
+	// Each time items are shown they are limited afresh
+	assertEqual(t, []string{"Highest", "High"}, titles(mode(append([]FeedItem{}, feedItems...))))
+
+	mode = Limited(ByScore, func() Filter { return MaxItemsPerChannel(1) })
+	assertEqual(t, []string{"Highest", "High"}, titles(mode(append([]FeedItem{}, feedItems...))))
+}
@@ -0,0 +1,94 @@
+package rss
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// FileStore is the Store which keeps each state in a file of its own in a
+// folder, one record to a line, and feeds in a folder beneath it.
+type FileStore struct {
+	dir      string
+	feeds    string
+	identity *age.X25519Identity
+}
+
+type FileStoreOption func(*FileStore)
+
+// WithFeedsFolder saves feeds in the folder rather than the "stored" folder
+// alongside the state.
+func WithFeedsFolder(folder string) FileStoreOption {
+	return func(fs *FileStore) {
+		fs.feeds = folder
+	}
+}
+
+// WithIdentity encrypts each record of the state, and each stored feed, to the
+// identity so that the files can be synced through places which aren't
+// trusted. A nil identity leaves them unencrypted.
+func WithIdentity(identity *age.X25519Identity) FileStoreOption {
+	return func(fs *FileStore) {
+		fs.identity = identity
+	}
+}
+
+// NewFileStore returns the store keeping state in the folder.
+func NewFileStore(dir string, opts ...FileStoreOption) *FileStore {
+	fs := &FileStore{dir: dir, feeds: filepath.Join(dir, "stored")}
+	for _, o := range opts {
+		o(fs)
+	}
+	return fs
+}
+
+// String returns the folder the feeds are stored in.
+func (fs *FileStore) String() string {
+	return fs.feeds
+}
+
+func (fs *FileStore) SaveFeed(feed *Feed) error {
+	return storeFeed(feed, fs.feeds, fs.identity)
+}
+
+func (fs *FileStore) LoadFeeds() ([]*Feed, error) {
+	return loadStored(fs.feeds, fs.identity)
+}
+
+func (fs *FileStore) ReadState(name string) (io.Reader, error) {
+	b, err := ReadFileShared(filepath.Join(fs.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return strings.NewReader(""), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fs.identity == nil {
+		return bytes.NewReader(b), nil
+	}
+	return DecryptLines(bytes.NewReader(b), fs.identity)
+}
+
+func (fs *FileStore) AppendState(name string) (io.WriteCloser, error) {
+	f, err := AppendFile(filepath.Join(fs.dir, name))
+	if err != nil || fs.identity == nil {
+		return f, err
+	}
+	return struct {
+		io.Writer
+		io.Closer
+	}{EncryptLines(f, fs.identity.Recipient()), f}, nil
+}
+
+func (fs *FileStore) Stars() ([]Star, error) {
+	r, err := fs.ReadState(StarsState)
+	if err != nil {
+		return nil, err
+	}
+	return ReadStars(r)
+}
@@ -0,0 +1,128 @@
+package rss
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+func TestFileStoreState(t *testing.T) {
+	t.Parallel()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, identity := range map[string]*age.X25519Identity{"plain": nil, "encrypted": identity} {
+		identity := identity
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			store := NewFileStore(dir, WithIdentity(identity))
+
+			stars, err := store.Stars()
+			assertEqual(t, nil, err)
+			assertEqual(t, 0, len(stars))
+
+			w, err := store.AppendState(StarsState)
+			assertEqual(t, nil, err)
+			star := Star{HistoryEntry: HistoryEntry{ID: "1", Time: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), Title: "One", Link: "https://example.com/1"}, Note: "Read again"}
+			assertEqual(t, nil, WriteStar(w, star))
+			assertEqual(t, nil, w.Close())
+
+			stars, err = store.Stars()
+			assertEqual(t, nil, err)
+			assertEqual(t, []Star{star}, stars)
+
+			b, err := os.ReadFile(filepath.Join(dir, StarsState))
+			assertEqual(t, nil, err)
+			assertEqual(t, identity == nil, strings.Contains(string(b), "Read again"))
+
+			r, err := store.ReadState(HistoryState)
+			assertEqual(t, nil, err)
+			b, err = io.ReadAll(r)
+			assertEqual(t, nil, err)
+			assertEqual(t, "", string(b))
+		})
+	}
+}
+
+func TestFileStoreFeeds(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	feed := &Feed{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Blog", Items: []Item{{Title: "One", GUID: "1"}}}}}
+	assertEqual(t, nil, store.SaveFeed(feed))
+	assertEqual(t, true, IsStored(filepath.Join(dir, "stored"), feed.URL))
+
+	feeds, err := store.LoadFeeds()
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(feeds))
+	assertEqual(t, "Blog", feeds[0].Channel.Title)
+}
+
+func TestFileStoreEncryptedFeeds(t *testing.T) {
+	t.Parallel()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	// Feeds stored before the identity was given are still read
+	feed := &Feed{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Blog", Items: []Item{{Title: "One", GUID: "1"}}}}}
+	assertEqual(t, nil, NewFileStore(dir).SaveFeed(feed))
+	store := NewFileStore(dir, WithIdentity(identity))
+	feed.Channel.Items = []Item{{Title: "Two", GUID: "2"}}
+	assertEqual(t, nil, store.SaveFeed(feed))
+
+	paths, err := filepath.Glob(filepath.Join(dir, "stored", "*.xml"))
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(paths))
+	b, err := os.ReadFile(paths[0])
+	assertEqual(t, nil, err)
+	assertEqual(t, false, strings.Contains(string(b), "Blog"))
+
+	feeds, err := store.LoadFeeds()
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(feeds))
+	assertEqual(t, 2, len(feeds[0].Channel.Items))
+	// They can't be read without the identity
+	_, err = NewFileStore(dir).LoadFeeds()
+	assertEqual(t, true, err != nil)
+}
+
+// failingStore fails to save feeds from one URL.
+type failingStore struct {
+	*FileStore
+	url string
+}
+
+func (fs failingStore) SaveFeed(feed *Feed) error {
+	if feed.URL == fs.url {
+		return errors.New("full")
+	}
+	return fs.FileStore.SaveFeed(feed)
+}
+
+func TestSaveAll(t *testing.T) {
+	t.Parallel()
+	store := failingStore{FileStore: NewFileStore(t.TempDir()), url: "https://broken.example.com/feed"}
+	err := SaveAll(store, []*Feed{
+		{URL: "https://broken.example.com/feed"},
+		{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Blog"}}},
+		{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Links"}}},
+	})
+	var errs StoreErrors
+	assertEqual(t, true, errors.As(err, &errs))
+	assertEqual(t, 1, len(errs))
+	assertEqual(t, "https://broken.example.com/feed", errs[0].URL)
+
+	feeds, err := store.LoadFeeds()
+	assertEqual(t, nil, err)
+	assertEqual(t, 2, len(feeds))
+}
@@ -0,0 +1,29 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// markMatches marks each match of the term in the text, ignoring case, as a
+// region of a tview.TextView so that it can be highlighted. The regions are
+// numbered in order by matchRegion. Returns the marked text and the number of
+// matches.
+func markMatches(text, term string) (string, int) {
+	if term == "" {
+		return text, 0
+	}
+	re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(term))
+	var n int
+	marked := re.ReplaceAllStringFunc(text, func(match string) string {
+		region := matchRegion(n)
+		n++
+		return fmt.Sprintf(`["%s"]%s[""]`, region, match)
+	})
+	return marked, n
+}
+
+// matchRegion is the ID of the region of the i-th match.
+func matchRegion(i int) string {
+	return fmt.Sprintf("match%d", i)
+}
@@ -0,0 +1,49 @@
+package rss
+
+import "testing"
+
+func TestMarkMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		term     string
+		expected string
+		matches  int
+	}{
+		{
+			name:     "Ignoring case",
+			text:     "Go is fun. go on.",
+			term:     "go",
+			expected: `["match0"]Go[""] is fun. ["match1"]go[""] on.`,
+			matches:  2,
+		},
+		{
+			name:     "Special characters",
+			text:     "a.b a*b",
+			term:     "a*b",
+			expected: `a.b ["match0"]a*b[""]`,
+			matches:  1,
+		},
+		{
+			name:     "No matches",
+			text:     "Go is fun.",
+			term:     "rust",
+			expected: "Go is fun.",
+		},
+		{
+			name:     "Empty term",
+			text:     "Go is fun.",
+			expected: "Go is fun.",
+		},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			marked, matches := markMatches(tc.text, tc.term)
+			assertEqual(t, tc.expected, marked)
+			assertEqual(t, tc.matches, matches)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package rss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// Fingerprint identifies the item by its content rather than its GUID or
+// publish time, since some feeds regenerate those every time they publish. It
+// is a hash of the normalized title and link.
+func (fi FeedItem) Fingerprint() string {
+	var link string
+	if len(fi.Links) > 0 {
+		link = normalizeLink(fi.Links[0])
+	}
+	title := strings.ToLower(strings.Join(strings.Fields(fi.Title), " "))
+	sum := sha256.Sum256([]byte(title + "\n" + link))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeLink strips the parts of a link which commonly vary between
+// publishes of the same item: scheme, query, fragment and trailing slashes.
+func normalizeLink(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return link
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return host + strings.TrimRight(u.Path, "/")
+}
+
+// DeduplicateContent ensures that items with the same content only appear in
+// the output once, even if the feed has given them different GUIDs or links
+// which differ only superficially.
+func DeduplicateContent() Filter {
+	seen := make(map[string]struct{})
+	return SyncFilter(func(item FeedItem) bool {
+		fingerprint := item.Fingerprint()
+		_, found := seen[fingerprint]
+		if found {
+			return false
+		}
+		seen[fingerprint] = struct{}{}
+		return true
+	})
+}
@@ -0,0 +1,98 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// FetchReport counts how fetching feeds went, so that it can be checked that
+// feeds aren't failing or filters hiding everything.
+type FetchReport struct {
+	mu sync.Mutex
+	// Fetched is the number of feeds fetched successfully.
+	Fetched int
+	// Failed is the number of feeds which couldn't be fetched or decoded.
+	Failed int
+	// Cached is the number of feeds read from a cache instead of fetched.
+	Cached int
+	// TimedOut is the number of feeds still being fetched at the deadline.
+	TimedOut int
+	// Cancelled is the number of feeds still being fetched when fetching
+	// was cancelled.
+	Cancelled int
+}
+
+// ReportTo records the outcome of fetching each feed in the report.
+func ReportTo(report *FetchReport) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.report = report
+	}
+}
+
+func (r *FetchReport) record(err error, cached bool) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case errors.Is(err, ErrTimedOut):
+		r.TimedOut++
+	case errors.Is(err, ErrCancelled):
+		r.Cancelled++
+	case err != nil:
+		r.Failed++
+	case cached:
+		r.Cached++
+	default:
+		r.Fetched++
+	}
+}
+
+// WriteFooter writes a line summarising the items displayed and how fetching
+// went e.g. "87 items from 23 feeds (4 feeds failed, 2 cached)". Headers added
+// by display modes such as Grouped aren't counted. The report may be nil.
+func WriteFooter(w io.Writer, feedItems []FeedItem, report *FetchReport) error {
+	var count int
+	sources := make(map[string]struct{})
+	for _, item := range feedItems {
+		if len(item.Links) == 0 {
+			continue
+		}
+		count++
+		sources[item.Source()] = struct{}{}
+	}
+	line := fmt.Sprintf("%s from %s", plural(count, "item"), plural(len(sources), "feed"))
+	if report != nil {
+		report.mu.Lock()
+		var notes []string
+		if report.Failed > 0 {
+			notes = append(notes, plural(report.Failed, "feed")+" failed")
+		}
+		if report.TimedOut > 0 {
+			notes = append(notes, fmt.Sprintf("%d timed out", report.TimedOut))
+		}
+		if report.Cancelled > 0 {
+			notes = append(notes, fmt.Sprintf("%d cancelled", report.Cancelled))
+		}
+		if report.Cached > 0 {
+			notes = append(notes, fmt.Sprintf("%d cached", report.Cached))
+		}
+		report.mu.Unlock()
+		if len(notes) > 0 {
+			line += " (" + strings.Join(notes, ", ") + ")"
+		}
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,107 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteFooter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.Write([]byte("<rss><channel><item>"))
+			return
+		}
+		fmt.Fprintf(w, `<rss><channel><title>%s</title><item><title>One</title><link>https://example.com%s/1</link></item><item><title>Two</title><link>https://example.com%s/2</link></item></channel></rss>`, r.URL.Path, r.URL.Path, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var report FetchReport
+	var failed []string
+	onError := OnFetchError(func(url string, err error) {
+		failed = append(failed, url)
+	})
+	feeds := GetFeeds([]string{server.URL + "/a", server.URL + "/b", server.URL + "/broken"}, ReportTo(&report), onError)
+	feedItems := GetFeedItems(feeds)
+	assertEqual(t, []string{server.URL + "/broken"}, failed)
+
+	var buf bytes.Buffer
+	err := WriteFooter(&buf, Grouped(feedItems), &report)
+	assertEqual(t, nil, err)
+	assertEqual(t, "4 items from 2 feeds (1 feed failed)\n", buf.String())
+
+	buf.Reset()
+	err = WriteFooter(&buf, feedItems[:1], nil)
+	assertEqual(t, nil, err)
+	assertEqual(t, "1 item from 1 feed\n", buf.String())
+}
+
+func TestDeadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			// Hangs until the test ends, unless the request is cancelled
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		fmt.Fprintf(w, `<rss><channel><title>Fast</title><item><title>One</title><link>https://example.com/1</link></item></channel></rss>`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var report FetchReport
+	start := time.Now()
+	feeds := GetFeeds([]string{server.URL + "/fast", server.URL + "/slow"}, ReportTo(&report), Deadline(time.Now().Add(200*time.Millisecond)))
+	assertEqual(t, true, time.Since(start) < 5*time.Second)
+	assertEqual(t, 1, len(feeds))
+	assertEqual(t, 1, report.TimedOut)
+	assertEqual(t, 0, report.Failed)
+
+	_, err := FetchFeeds(server.URL+"/slow", Deadline(time.Now().Add(10*time.Millisecond)))
+	assertEqual(t, true, errors.Is(err, ErrTimedOut))
+
+	var buf bytes.Buffer
+	err = WriteFooter(&buf, GetFeedItems(feeds), &report)
+	assertEqual(t, nil, err)
+	assertEqual(t, "1 item from 1 feed (1 timed out)\n", buf.String())
+}
+
+func TestWithContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		fmt.Fprintf(w, `<rss><channel><title>Fast</title><item><title>One</title><link>https://example.com/1</link></item></channel></rss>`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	var report FetchReport
+	feeds := GetFeeds([]string{server.URL + "/fast", server.URL + "/slow"}, ReportTo(&report), WithContext(ctx))
+	assertEqual(t, 1, len(feeds))
+	assertEqual(t, 1, report.Cancelled)
+	assertEqual(t, 0, report.Failed)
+
+	var buf bytes.Buffer
+	err := WriteFooter(&buf, GetFeedItems(feeds), &report)
+	assertEqual(t, nil, err)
+	assertEqual(t, "1 item from 1 feed (1 cancelled)\n", buf.String())
+
+	_, err = FetchFeeds(server.URL+"/fast", WithContext(ctx))
+	assertEqual(t, true, errors.Is(err, ErrCancelled))
+}
@@ -0,0 +1,83 @@
+package rss
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFormatItemGolden formats items showing each part of a line, with each
+// kind of colouring, and compares them with the files in testdata/format so
+// that changes to the output are deliberate. Run with -update to rewrite them,
+// and check the diff by eye.
+func TestFormatItemGolden(t *testing.T) {
+	now := time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)
+	published := time.Date(2024, 3, 5, 16, 0, 0, 0, time.UTC)
+	items := []FeedItem{
+		// A title card, as added by Grouped
+		{Title: "Example Blog", updated: now.Add(-3 * time.Hour)},
+		{Title: "Plain item", PublishTime: published, Links: []string{"https://example.com/1"}},
+		{Title: "Coloured by age", PublishTime: published, Links: []string{"https://example.com/2"}, colour: cyan},
+		{Title: "Two links", PublishTime: published, Links: []string{"https://example.com/3", "https://news.example.com/item?id=3"}},
+		{Title: "Tagged and noted", PublishTime: published, Links: []string{"https://example.com/4"}, Tags: []string{"go", "release"}, Note: "read later"},
+		{Title: "Other layout", PublishTime: published, Links: []string{"https://example.com/5"}, dateLayout: time.RFC3339},
+		{Title: "Undated", Links: []string{"https://example.com/6"}},
+		// Feeds dated in the future were updated just now
+		{Title: "Future Feed", updated: now.Add(time.Hour)},
+	}
+	tests := []struct {
+		name    string
+		opts    []FormatOption
+		display []DisplayOption
+	}{
+		{name: "plain", opts: []FormatOption{NoColours()}},
+		{name: "ansi", opts: []FormatOption{ANSIColours()}},
+		{name: "tview", opts: []FormatOption{TviewColours()}},
+		{name: "links", opts: []FormatOption{NoColours(), IncludeLinks(true)}},
+		{name: "ansi-links", opts: []FormatOption{ANSIColours(), IncludeLinks(true)}},
+		// Colours are left out for screen readers even when asked for
+		{name: "accessible", opts: []FormatOption{ANSIColours()}, display: []DisplayOption{Accessible()}},
+		{name: "accessible-links", opts: []FormatOption{ANSIColours(), IncludeLinks(true)}, display: []DisplayOption{Accessible()}},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			for _, item := range items {
+				for _, opt := range tc.display {
+					item = opt(item)
+				}
+				buf.WriteString(FormatItem(item, append(tc.opts, FormatAt(now))...))
+			}
+			b := buf.Bytes()
+
+			golden := filepath.Join("testdata", "format", tc.name+".txt")
+			if *update {
+				err := os.WriteFile(golden, b, 0644)
+				assertEqual(t, nil, err)
+				return
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%s, run the tests with -update to create it", err.Error())
+			}
+			if !bytes.Equal(expected, b) {
+				t.Errorf("formatting doesn't match %s, got:\n%s", golden, b)
+			}
+		})
+	}
+}
+
+func TestFormatItemDefaultColour(t *testing.T) {
+	item := FeedItem{Title: "Title", PublishTime: time.Date(2024, 3, 5, 16, 0, 0, 0, time.UTC), Links: []string{"https://example.com/1"}}
+	assertEqual(t, FormatItem(item, ANSIColours()), FormatItem(item))
+
+	assertEqual(t, "\x1b[33m2024/03/05\x1b[0m:\tTitle\n", FormatItem(item))
+	assertEqual(t, "2024/03/05:\tTitle\n", FormatItem(item, NoColours()))
+	// Items can be left uncoloured as they are displayed
+	assertEqual(t, "2024/03/05:\tTitle\thttps://example.com/1\n", WithoutColour()(item).Format())
+}
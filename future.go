@@ -0,0 +1,49 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// futureTolerance allows for the clocks of publishers being slightly ahead,
+// so that items published just now aren't treated as being in the future.
+const futureTolerance = 5 * time.Minute
+
+// ErrFutureItem is returned when converting an item which is dated in the
+// future from a feed whose policy is to hide such items.
+var ErrFutureItem = errors.New("item is dated in the future")
+
+// FuturePolicy is how items dated in the future are handled. Left alone they
+// pass any age filter and stay at the top of the list until their date comes.
+type FuturePolicy string
+
+const (
+	// FutureShow shows items with the date they were given.
+	FutureShow FuturePolicy = "show"
+	// FutureHide drops items dated in the future.
+	FutureHide FuturePolicy = "hide"
+	// FutureClamp dates items in the future at the time they were fetched.
+	FutureClamp FuturePolicy = "clamp"
+)
+
+// ParseFuturePolicy returns the policy with the given name. An empty name is
+// FutureShow.
+func ParseFuturePolicy(name string) (FuturePolicy, error) {
+	switch policy := FuturePolicy(strings.ToLower(name)); policy {
+	case "":
+		return FutureShow, nil
+	case FutureShow, FutureHide, FutureClamp:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown policy for future items %q, expected show, hide or clamp", name)
+}
+
+// FutureItems sets the policy for items dated in the future, applied as they
+// are unpacked.
+func FutureItems(policy FuturePolicy) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.future = policy
+	}
+}
@@ -0,0 +1,38 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFutureItems(t *testing.T) {
+	future := time.Now().Add(48 * time.Hour).Format(time.RFC1123Z)
+	soon := time.Now().Add(time.Minute).Format(time.RFC1123Z)
+	testcases := []struct {
+		name     string
+		policy   FuturePolicy
+		pubDate  string
+		expected int
+		// current is whether the item ends up dated around now
+		current bool
+	}{
+		{name: "Show", policy: FutureShow, pubDate: future, expected: 1},
+		{name: "Hide", policy: FutureHide, pubDate: future, expected: 0},
+		{name: "Clamp", policy: FutureClamp, pubDate: future, expected: 1, current: true},
+		{name: "Within tolerance", policy: FutureHide, pubDate: soon, expected: 1, current: true},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			feed := &Feed{future: tc.policy, RSS: RSS{Channel: Channel{Items: []Item{{Title: "Upcoming", PubDate: tc.pubDate}}}}}
+			items := UnpackFeed(feed)
+			assertEqual(t, tc.expected, len(items))
+			if len(items) > 0 {
+				assertEqual(t, tc.current, time.Until(items[0].PublishTime) < time.Hour)
+			}
+		})
+	}
+}
@@ -3,21 +3,28 @@ module github.com/AzinKhan/rss
 go 1.18
 
 require (
+	filippo.io/age v1.0.0
+	github.com/AzinKhan/functools v0.0.0-20221118172207-ecefed8f3a1c
+	github.com/fsnotify/fsnotify v1.6.0
 	github.com/gdamore/tcell/v2 v2.4.1-0.20210905002822-f057f0a857a1
 	github.com/playwright-community/playwright-go v0.2000.0
 	github.com/rivo/tview v0.0.0-20220307222120-9994674d60a8
+	github.com/tetratelabs/wazero v1.2.1
+	github.com/yuin/gopher-lua v1.1.1
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/AzinKhan/functools v0.0.0-20221118172207-ecefed8f3a1c // indirect
 	github.com/danwakefield/fnmatch v0.0.0-20160403171240-cbb64ac3d964 // indirect
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/go-stack/stack v1.8.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c // indirect
-	golang.org/x/term v0.0.0-20210422114643-f5beecf764ed // indirect
-	golang.org/x/text v0.3.6 // indirect
+	golang.org/x/crypto v0.9.0 // indirect
+	golang.org/x/sys v0.10.0 // indirect
+	golang.org/x/term v0.10.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 	gopkg.in/square/go-jose.v2 v2.6.0 // indirect
 )
@@ -0,0 +1,266 @@
+package rss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The daemon's API is also served over gRPC, as described by proto/rss.proto.
+// Calls arrive over the same HTTP/2 connections as the rest of the API, and
+// the few messages there are are encoded by hand rather than generated.
+
+const (
+	grpcService = "/rss.v1.Feeds/"
+	// maxGRPCRequest is the size of the largest request message accepted.
+	maxGRPCRequest = 64 * 1024
+)
+
+// gRPC status codes.
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcUnimplemented   = 12
+	grpcInternal        = 13
+	grpcUnauthenticated = 16
+)
+
+// isGRPC returns true if the request is a gRPC call.
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// serveGRPC answers a gRPC call, with its status in the trailers.
+func (s *Server) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+	code, message := s.grpcCall(w, r)
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", grpcEscape(message))
+	}
+}
+
+func (s *Server) grpcCall(w http.ResponseWriter, r *http.Request) (int, string) {
+	if !s.authorized(r) {
+		return grpcUnauthenticated, "invalid token"
+	}
+	request, err := readGRPCMessage(r.Body)
+	if err != nil {
+		return grpcInvalidArgument, err.Error()
+	}
+	switch r.URL.Path {
+	case grpcService + "ListItems":
+		since, err := decodeListItemsRequest(request)
+		if err != nil {
+			return grpcInvalidArgument, err.Error()
+		}
+		var response []byte
+		for _, item := range s.Items() {
+			if item.PublishTime.After(since) {
+				response = protowire.AppendTag(response, 1, protowire.BytesType)
+				response = protowire.AppendBytes(response, encodeItem(item))
+			}
+		}
+		err = writeGRPCMessage(w, response)
+		if err != nil {
+			return grpcInternal, err.Error()
+		}
+	case grpcService + "WatchItems":
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			return grpcInternal, "streaming unsupported"
+		}
+		// Send the headers once watching, so that no item is missed after
+		// the call is answered
+		stream, stop := s.watch()
+		defer stop()
+		flusher.Flush()
+		for {
+			select {
+			case <-r.Context().Done():
+				return grpcOK, ""
+			case item := <-stream:
+				err = writeGRPCMessage(w, encodeItem(item))
+				if err != nil {
+					return grpcInternal, err.Error()
+				}
+				flusher.Flush()
+			}
+		}
+	default:
+		return grpcUnimplemented, "unknown method " + r.URL.Path
+	}
+	return grpcOK, ""
+}
+
+// readGRPCMessage reads a message prefixed by whether it is compressed and its
+// length. Compression is never asked for, so isn't supported.
+func readGRPCMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	_, err := io.ReadFull(r, prefix[:])
+	if err != nil {
+		return nil, fmt.Errorf("error reading message: %w", err)
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("compressed messages aren't supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxGRPCRequest {
+		return nil, fmt.Errorf("message of %d bytes is too large", length)
+	}
+	message := make([]byte, length)
+	_, err = io.ReadFull(r, message)
+	if err != nil {
+		return nil, fmt.Errorf("error reading message: %w", err)
+	}
+	return message, nil
+}
+
+func writeGRPCMessage(w io.Writer, message []byte) error {
+	prefix := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+	_, err := w.Write(append(prefix, message...))
+	return err
+}
+
+// grpcEscape percent encodes the status message as gRPC requires.
+func grpcEscape(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// decodeListItemsRequest returns the time given in a ListItemsRequest, which
+// is zero if there is none.
+func decodeListItemsRequest(b []byte) (time.Time, error) {
+	var since time.Time
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		var err error
+		since, err = decodeTimestamp(value)
+		return err
+	})
+	return since, err
+}
+
+// decodeTimestamp decodes a google.protobuf.Timestamp.
+func decodeTimestamp(b []byte) (time.Time, error) {
+	var seconds, nanos int64
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if typ != protowire.VarintType {
+			return nil
+		}
+		v, n := protowire.ConsumeVarint(value)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		switch num {
+		case 1:
+			seconds = int64(v)
+		case 2:
+			nanos = int64(int32(v))
+		}
+		return nil
+	})
+	return time.Unix(seconds, nanos).UTC(), err
+}
+
+// consumeFields calls fn with the number, type and encoded value of each field
+// in the message.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		value := b[:n]
+		if typ == protowire.BytesType {
+			value, _ = protowire.ConsumeBytes(value)
+		}
+		err := fn(num, typ, value)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// encodeItem encodes the item as an Item message.
+func encodeItem(item FeedItem) []byte {
+	var b []byte
+	appendString := func(num protowire.Number, s string) {
+		if s != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, s)
+		}
+	}
+	appendInt := func(num protowire.Number, i int64) {
+		if i != 0 {
+			b = protowire.AppendTag(b, num, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(i))
+		}
+	}
+	// Timestamps and durations are both seconds and nanoseconds
+	appendSeconds := func(num protowire.Number, seconds, nanos int64) {
+		var m []byte
+		if seconds != 0 {
+			m = protowire.AppendTag(m, 1, protowire.VarintType)
+			m = protowire.AppendVarint(m, uint64(seconds))
+		}
+		if nanos != 0 {
+			m = protowire.AppendTag(m, 2, protowire.VarintType)
+			m = protowire.AppendVarint(m, uint64(nanos))
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, m)
+	}
+
+	appendString(1, item.ID)
+	appendString(2, item.Title)
+	if !item.PublishTime.IsZero() {
+		appendSeconds(3, item.PublishTime.Unix(), int64(item.PublishTime.Nanosecond()))
+	}
+	for _, link := range item.Links {
+		appendString(4, link)
+	}
+	appendString(5, item.Feed)
+	appendString(6, item.Channel)
+	if item.ReadTime != 0 {
+		appendSeconds(7, int64(item.ReadTime/time.Second), int64(item.ReadTime%time.Second))
+	}
+	appendString(8, item.Language)
+	for _, tag := range item.Tags {
+		appendString(9, tag)
+	}
+	appendInt(10, int64(item.Score))
+	appendInt(11, int64(item.Points))
+	appendInt(12, int64(item.Comments))
+	for _, author := range item.Authors {
+		appendString(13, author)
+	}
+	return b
+}
@@ -0,0 +1,144 @@
+package rss
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func grpcRequest(t *testing.T, api *httptest.Server, method, token string, message []byte) *http.Response {
+	t.Helper()
+	var body bytes.Buffer
+	err := writeGRPCMessage(&body, message)
+	assertEqual(t, nil, err)
+	req, _ := http.NewRequest(http.MethodPost, api.URL+grpcService+method, &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := api.Client().Do(req)
+	assertEqual(t, nil, err)
+	return resp
+}
+
+// decodeTestItem returns the title and feed of an encoded Item.
+func decodeTestItem(t *testing.T, b []byte) (title, feed string) {
+	t.Helper()
+	err := consumeFields(b, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch num {
+		case 2:
+			title = string(value)
+		case 5:
+			feed = string(value)
+		}
+		return nil
+	})
+	assertEqual(t, nil, err)
+	return title, feed
+}
+
+func newGRPCServer(t *testing.T, opts ...ServerOption) (*Server, *httptest.Server) {
+	t.Helper()
+	server, err := NewServer(nil, opts...)
+	assertEqual(t, nil, err)
+	api := httptest.NewUnstartedServer(server)
+	api.EnableHTTP2 = true
+	api.StartTLS()
+	t.Cleanup(api.Close)
+	return server, api
+}
+
+func TestGRPCListItems(t *testing.T) {
+	server, api := newGRPCServer(t)
+	now := time.Now()
+	server.ingest(&Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Listed", Items: []Item{
+		{Title: "Old", Link: "https://example.com/1", PubDate: now.Add(-48 * time.Hour).Format(time.RFC1123Z)},
+		{Title: "New", Link: "https://example.com/2", PubDate: now.Format(time.RFC1123Z)},
+	}}}})
+
+	since := protowire.AppendTag(nil, 1, protowire.VarintType)
+	since = protowire.AppendVarint(since, uint64(now.Add(-time.Hour).Unix()))
+	tests := []struct {
+		name     string
+		request  []byte
+		expected []string
+	}{
+		{name: "All", expected: []string{"New", "Old"}},
+		{
+			name:     "Since",
+			request:  protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), since),
+			expected: []string{"New"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := grpcRequest(t, api, "ListItems", "", tc.request)
+			defer resp.Body.Close()
+			assertEqual(t, "application/grpc", resp.Header.Get("Content-Type"))
+			message, err := readGRPCMessage(resp.Body)
+			assertEqual(t, nil, err)
+			_, err = io.Copy(io.Discard, resp.Body)
+			assertEqual(t, nil, err)
+			assertEqual(t, "0", resp.Trailer.Get("Grpc-Status"))
+
+			var titles []string
+			err = consumeFields(message, func(num protowire.Number, typ protowire.Type, value []byte) error {
+				title, feed := decodeTestItem(t, value)
+				assertEqual(t, "https://example.com/rss", feed)
+				titles = append(titles, title)
+				return nil
+			})
+			assertEqual(t, nil, err)
+			assertEqual(t, tc.expected, titles)
+		})
+	}
+}
+
+func TestGRPCWatchItems(t *testing.T) {
+	server, api := newGRPCServer(t)
+	resp := grpcRequest(t, api, "WatchItems", "", nil)
+	defer resp.Body.Close()
+
+	feed := &Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Live", Items: []Item{{Title: "New", Link: "https://example.com/1"}}}}}
+	server.ingest(feed)
+	message, err := readGRPCMessage(resp.Body)
+	assertEqual(t, nil, err)
+	title, _ := decodeTestItem(t, message)
+	assertEqual(t, "New", title)
+}
+
+func TestGRPCErrors(t *testing.T) {
+	_, api := newGRPCServer(t, WithToken("secret"))
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		expected string
+	}{
+		{name: "No token", method: "ListItems", expected: "16"},
+		{name: "Wrong token", method: "ListItems", token: "wrong", expected: "16"},
+		{name: "Unknown method", method: "DeleteItems", token: "secret", expected: "12"},
+		{name: "Token", method: "ListItems", token: "secret", expected: "0"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := grpcRequest(t, api, tc.method, tc.token, nil)
+			defer resp.Body.Close()
+			_, err := io.Copy(io.Discard, resp.Body)
+			assertEqual(t, nil, err)
+			assertEqual(t, http.StatusOK, resp.StatusCode)
+			assertEqual(t, tc.expected, resp.Trailer.Get("Grpc-Status"))
+		})
+	}
+}
+
+func TestGRPCEscape(t *testing.T) {
+	t.Parallel()
+	assertEqual(t, "100%25 caf%C3%A9%0A", grpcEscape("100% café\n"))
+}
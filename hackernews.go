@@ -0,0 +1,127 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var hackerNewsAPI = "https://hacker-news.firebaseio.com/v0/"
+
+// hackerNewsLists are the lists of stories which can be subscribed to, by the
+// host of the URL e.g. "hn://front".
+var hackerNewsLists = map[string]string{
+	"front": "topstories",
+	"top":   "topstories",
+	"new":   "newstories",
+	"best":  "beststories",
+	"ask":   "askstories",
+	"show":  "showstories",
+}
+
+type hackerNewsItem struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Time        int64  `json:"time"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Text        string `json:"text"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+	Dead        bool   `json:"dead"`
+	Deleted     bool   `json:"deleted"`
+}
+
+// fetchHackerNews fetches stories from Hacker News using its API, which gives
+// their points and number of comments unlike its RSS feed. URLs name the list
+// of stories e.g. "hn://front?points=150", where "front" is the 30 stories on
+// the front page and "points" is the fewest points a story can have. "limit"
+// changes how many stories are taken from the list.
+func fetchHackerNews(ctx context.Context, u *url.URL, options fetchOptions) ([]RSS, error) {
+	list, found := hackerNewsLists[u.Host]
+	if !found {
+		return nil, fmt.Errorf("unknown hacker news list %q", u.Host)
+	}
+	query := u.Query()
+	minPoints, err := queryInt(query, "points", 0)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryInt(query, "limit", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	err = getJSON(ctx, hackerNewsAPI+list+".json", &ids)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	stories := make([]hackerNewsItem, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	// The API has no batch endpoint so a few stories are fetched at a time
+	sem := make(chan struct{}, 8)
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			errs[i] = getJSON(ctx, fmt.Sprintf("%sitem/%d.json", hackerNewsAPI, id), &stories[i])
+		}(i, id)
+	}
+	wg.Wait()
+
+	channel := Channel{Title: "Hacker News", Link: "https://news.ycombinator.com/"}
+	for i, story := range stories {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if story.Dead || story.Deleted || story.Score < minPoints {
+			continue
+		}
+		channel.Items = append(channel.Items, story.item())
+	}
+	return []RSS{{Channel: limitItems(channel, options)}}, nil
+}
+
+func (story hackerNewsItem) item() Item {
+	discussion := fmt.Sprintf("https://news.ycombinator.com/item?id=%d", story.ID)
+	link := story.URL
+	if link == "" {
+		// Ask HN and similar have no link of their own
+		link = discussion
+	}
+	return Item{
+		Title:        story.Title,
+		Link:         link,
+		PubDate:      time.Unix(story.Time, 0).UTC().Format(time.RFC1123Z),
+		GUID:         discussion,
+		Comments:     discussion,
+		Description:  []byte(story.Text),
+		Points:       story.Score,
+		CommentCount: story.Descendants,
+	}
+}
+
+// queryInt returns the integer value of the query parameter, or fallback if it
+// isn't given.
+func queryInt(query url.Values, key string, fallback int) (int, error) {
+	value := query.Get(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,73 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchHackerNews(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/topstories.json":
+			fmt.Fprint(w, "[1, 2, 3, 4]")
+		case "/item/1.json":
+			fmt.Fprint(w, `{"id": 1, "type": "story", "time": 1667293200, "title": "Popular", "url": "https://example.com/popular", "score": 200, "descendants": 42}`)
+		case "/item/2.json":
+			fmt.Fprint(w, `{"id": 2, "type": "story", "time": 1667293200, "title": "Unpopular", "url": "https://example.com/unpopular", "score": 20}`)
+		case "/item/3.json":
+			fmt.Fprint(w, `{"id": 3, "type": "story", "time": 1667293200, "title": "Ask HN: Why?", "text": "Just wondering", "score": 150, "descendants": 7}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(api string) { hackerNewsAPI = api }(hackerNewsAPI)
+	hackerNewsAPI = server.URL + "/"
+
+	feeds, err := FetchFeeds("hn://front?points=150&limit=3")
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(feeds))
+	feedItems := UnpackFeed(feeds[0])
+	assertEqual(t, 2, len(feedItems))
+	assertEqual(t, "Popular", feedItems[0].Title)
+	assertEqual(t, []string{"https://example.com/popular", "https://news.ycombinator.com/item?id=1"}, feedItems[0].Links)
+	assertEqual(t, 200, feedItems[0].Points)
+	assertEqual(t, 42, feedItems[0].Comments)
+	assertEqual(t, "Popular (200 points, 42 comments)", ShowPoints()(feedItems[0]).Title)
+	// Ask HN links to the discussion
+	assertEqual(t, "https://news.ycombinator.com/item?id=3", feedItems[1].Links[0])
+
+	_, err = FetchFeeds("hn://front")
+	assertEqual(t, true, err != nil && strings.Contains(err.Error(), "404"))
+	_, err = FetchFeeds("hn://nope")
+	assertEqual(t, true, err != nil)
+}
+
+func TestSlashComments(t *testing.T) {
+	t.Parallel()
+	doc := `<rss xmlns:slash="http://purl.org/rss/1.0/modules/slash/"><channel><item><title>One</title><comments>https://example.com/1#comments</comments><slash:comments>12</slash:comments></item></channel></rss>`
+	channels, err := decodeRSS(strings.NewReader(doc), fetchOptions{})
+	assertEqual(t, nil, err)
+	item := channels[0].Channel.Items[0]
+	assertEqual(t, "https://example.com/1#comments", item.Comments)
+	assertEqual(t, 12, item.CommentCount)
+}
+
+// FuzzHackerNewsItem checks that no item from Hacker News crashes turning it
+// into a feed item.
+func FuzzHackerNewsItem(f *testing.F) {
+	f.Add([]byte(`{"id": 1, "type": "story", "time": 1667293200, "title": "Popular", "url": "https://example.com/popular", "score": 200, "descendants": 42}`))
+	f.Add([]byte(`{"id": -1, "type": "job", "time": -9223372036854775808, "text": "<p>Hiring</p>"}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var story hackerNewsItem
+		if json.Unmarshal(b, &story) != nil {
+			return
+		}
+		feed := &Feed{URL: "hn://top", RSS: RSS{Channel: Channel{Title: "Hacker News", Items: []Item{story.item()}}}}
+		newFeedItemCreator(feed)(feed.Channel.Items[0])
+	})
+}
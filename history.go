@@ -0,0 +1,80 @@
+package rss
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// HistoryEntry records an item being opened.
+type HistoryEntry struct {
+	ID    string    `json:"id,omitempty"`
+	Time  time.Time `json:"time"`
+	Title string    `json:"title"`
+	Link  string    `json:"link"`
+	// Canonical is the canonical URL of the page at the link, if it was
+	// fetched and differs from the link.
+	Canonical string `json:"canonical,omitempty"`
+	Feed      string `json:"feed"`
+	// Published is when the item itself was published.
+	Published time.Time `json:"published,omitempty"`
+}
+
+// FeedItem converts the entry into a FeedItem, using the time it was opened as
+// the publish time, so that it can be displayed.
+func (he HistoryEntry) FeedItem() FeedItem {
+	return FeedItem{
+		ID:          he.ID,
+		Title:       he.Title,
+		PublishTime: he.Time,
+		Links:       []string{he.Link},
+		Feed:        he.Feed,
+	}
+}
+
+// WriteHistory appends the entry to the history in w as a single line.
+func WriteHistory(w io.Writer, entry HistoryEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadHistory reads all of the history entries from r.
+func ReadHistory(r io.Reader) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry HistoryEntry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// SearchHistory returns the entries whose title, link or feed contain the
+// query, ignoring case.
+func SearchHistory(entries []HistoryEntry, query string) []HistoryEntry {
+	query = strings.ToLower(query)
+	var result []HistoryEntry
+	for _, entry := range entries {
+		for _, field := range []string{entry.Title, entry.Link, entry.Feed} {
+			if strings.Contains(strings.ToLower(field), query) {
+				result = append(result, entry)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,27 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	now := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	entries := []HistoryEntry{
+		{Time: now, Title: "Go 1.20 released", Link: "https://go.dev/blog", Feed: "Go"},
+		{Time: now.Add(time.Hour), Title: "Something else", Link: "https://example.com", Feed: "Example"},
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		err := WriteHistory(&buf, entry)
+		assertEqual(t, nil, err)
+	}
+
+	result, err := ReadHistory(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, entries, result)
+
+	assertEqual(t, entries[:1], SearchHistory(result, "go.DEV"))
+	assertEqual(t, entries[1:], SearchHistory(result, "example"))
+}
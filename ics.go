@@ -0,0 +1,225 @@
+package rss
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is an item announcing something happening on a date, e.g. a meetup
+// or the deadline of a call for papers.
+type Event struct {
+	// UID identifies the event stably across exports, from the item's ID.
+	UID     string
+	Summary string
+	Link    string
+	Feed    string
+	Start   time.Time
+	// AllDay events have no time of day, only a date.
+	AllDay bool
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "sept": time.September, "oct": time.October, "nov": time.November,
+	"dec": time.December,
+}
+
+const monthPattern = `(jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sept?(?:ember)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?)\.?`
+
+var (
+	// isoDate matches e.g. "2024-03-05".
+	isoDate = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	// monthFirstDate matches e.g. "March 5, 2024" or "Mar 5th".
+	monthFirstDate = regexp.MustCompile(`(?i)\b` + monthPattern + `\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s+(\d{4})\b)?`)
+	// dayFirstDate matches e.g. "5 March 2024" or "5th of March".
+	dayFirstDate = regexp.MustCompile(`(?i)\b(\d{1,2})(?:st|nd|rd|th)?\s+(?:of\s+)?` + monthPattern + `\b(?:,?\s+(\d{4})\b)?`)
+	// timeOfDay matches a time following a date e.g. " at 18:30" or ", 6pm".
+	timeOfDay = regexp.MustCompile(`(?i)^[\s,]*(?:at|@|from|-|–)?\s*(\d{1,2})(?::(\d{2}))?\s*([ap])?\.?m?\.?\b`)
+)
+
+// datePatterns find dates in text, with the submatches holding their year,
+// month and day. Years are optional except in ISO dates.
+var datePatterns = []struct {
+	pattern          *regexp.Regexp
+	year, month, day int
+}{
+	{isoDate, 1, 2, 3},
+	{monthFirstDate, 3, 1, 2},
+	{dayFirstDate, 3, 2, 1},
+}
+
+// FindEventDate returns the first date in the text, and whether it is all day
+// because no time of day follows it. Dates without a year are taken to be the next such date on
+// or after published.
+func FindEventDate(text string, published time.Time) (time.Time, bool, bool) {
+	var year, day int
+	var month time.Month
+	start, end := len(text), -1
+	for _, p := range datePatterns {
+		m := p.pattern.FindStringSubmatchIndex(text)
+		if m == nil || m[0] >= start {
+			continue
+		}
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+		year, _ = strconv.Atoi(group(p.year))
+		day, _ = strconv.Atoi(group(p.day))
+		month = parseMonth(group(p.month))
+		start, end = m[0], m[1]
+	}
+	if end < 0 || month < time.January || month > time.December || day < 1 || day > 31 {
+		return time.Time{}, false, false
+	}
+
+	published = published.UTC()
+	inferYear := year == 0
+	if inferYear {
+		year = published.Year()
+	}
+	hour, minute, hasTime := findTimeOfDay(text[end:])
+	date := time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	if date.Day() != day {
+		// e.g. 31 April
+		return time.Time{}, false, false
+	}
+	if inferYear && date.Before(published.Truncate(24*time.Hour)) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, !hasTime, true
+}
+
+func parseMonth(name string) time.Month {
+	if n, err := strconv.Atoi(name); err == nil {
+		return time.Month(n)
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if month, found := months[name]; found {
+		return month
+	}
+	if len(name) >= 3 {
+		return months[name[:3]]
+	}
+	return 0
+}
+
+// findTimeOfDay returns the time at the start of the text, which must have
+// minutes or am/pm so that other numbers aren't mistaken for hours.
+func findTimeOfDay(text string) (int, int, bool) {
+	m := timeOfDay.FindStringSubmatch(text)
+	if m == nil || (m[2] == "" && m[3] == "") {
+		return 0, 0, false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	switch strings.ToLower(m[3]) {
+	case "p":
+		if hour < 12 {
+			hour += 12
+		}
+	case "a":
+		if hour == 12 {
+			hour = 0
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
+
+// Events finds the items in the feeds which announce events, from the first
+// date in their title or else their description. Only items passing the
+// filters are included, and the events are ordered by when they start.
+func Events(feeds []*Feed, filters ...Filter) []Event {
+	fs := Filters(filters)
+	var events []Event
+	for _, feed := range feeds {
+		newFeedItem := newFeedItemCreator(feed)
+		for _, item := range feed.Channel.Items {
+			feedItem, err := newFeedItem(item)
+			if err != nil || !fs.Apply(feedItem) {
+				continue
+			}
+			description := html.UnescapeString(htmlTag.ReplaceAllString(string(item.Description), " "))
+			start, allDay, found := FindEventDate(feedItem.Title, feedItem.PublishTime)
+			if !found {
+				start, allDay, found = FindEventDate(strings.Join(strings.Fields(description), " "), feedItem.PublishTime)
+			}
+			if !found {
+				continue
+			}
+			var link string
+			if len(feedItem.Links) > 0 {
+				link = feedItem.Links[0]
+			}
+			events = append(events, Event{
+				UID:     feedItem.ID + "@rss",
+				Summary: feedItem.Title,
+				Link:    link,
+				Feed:    feedItem.Source(),
+				Start:   start,
+				AllDay:  allDay,
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events
+}
+
+// WriteICS writes the events to w as an iCalendar file which calendars can
+// import. Times are floating, i.e. in the local time of whoever imports them,
+// since feeds rarely give the time zone of events.
+func WriteICS(w io.Writer, events []Event, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		// Lines are folded at 75 octets, without splitting characters
+		for len(s) > 75 {
+			i := 75
+			for i > 0 && s[i]&0xC0 == 0x80 {
+				i--
+			}
+			bw.WriteString(s[:i] + "\r\n")
+			s = " " + s[i:]
+		}
+		bw.WriteString(s + "\r\n")
+	}
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//AzinKhan//rss//EN")
+	line("CALSCALE:GREGORIAN")
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, event := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + icsText(event.UID))
+		line("DTSTAMP:" + stamp)
+		if event.AllDay {
+			line("DTSTART;VALUE=DATE:" + event.Start.Format("20060102"))
+		} else {
+			line("DTSTART:" + event.Start.Format("20060102T150405"))
+		}
+		line("SUMMARY:" + icsText(event.Summary))
+		if event.Link != "" {
+			line("URL:" + event.Link)
+		}
+		line("DESCRIPTION:" + icsText(strings.TrimSpace(event.Link+"\n"+event.Feed)))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// icsText escapes text for a property value.
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
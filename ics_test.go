@@ -0,0 +1,78 @@
+package rss
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindEventDate(t *testing.T) {
+	t.Parallel()
+	published := time.Date(2024, time.November, 20, 9, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		text   string
+		date   time.Time
+		allDay bool
+		found  bool
+	}{
+		{text: "Go meetup: 2024-12-05", date: time.Date(2024, time.December, 5, 0, 0, 0, 0, time.UTC), allDay: true, found: true},
+		{text: "Go meetup on March 5th, 2025 at 6:30pm", date: time.Date(2025, time.March, 5, 18, 30, 0, 0, time.UTC), found: true},
+		{text: "CFP closes 5 January 18:00", date: time.Date(2025, time.January, 5, 18, 0, 0, 0, time.UTC), found: true},
+		{text: "Workshop, Dec. 3 - 9am", date: time.Date(2024, time.December, 3, 9, 0, 0, 0, time.UTC), found: true},
+		{text: "Talks on 21st of November, 12 speakers", date: time.Date(2024, time.November, 21, 0, 0, 0, 0, time.UTC), allDay: true, found: true},
+		{text: "Meetup on Dec 1 (announced 2024-11-01)", date: time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC), allDay: true, found: true},
+		{text: "Not on 31 April 2025"},
+		{text: "No date here"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.text, func(t *testing.T) {
+			t.Parallel()
+			date, allDay, found := FindEventDate(tc.text, published)
+			assertEqual(t, tc.found, found)
+			assertEqual(t, tc.date, date)
+			assertEqual(t, tc.allDay, allDay)
+		})
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	feed := &Feed{URL: "https://example.com/events", RSS: RSS{Channel: Channel{Title: "Meetups", Items: []Item{
+		{Title: "Rust night", Link: "https://example.com/rust", GUID: "1", PubDate: "Wed, 20 Nov 2024 09:00:00 +0000", Description: []byte("<p>Join us on <b>December 12</b> at 19:00</p>")},
+		{Title: "Go meetup, 2024-12-05", Link: "https://example.com/go", GUID: "2", PubDate: "Wed, 20 Nov 2024 09:00:00 +0000"},
+		{Title: "Newsletter", Link: "https://example.com/news", GUID: "3", PubDate: "Wed, 20 Nov 2024 09:00:00 +0000"},
+	}}}}
+	events := Events([]*Feed{feed})
+	assertEqual(t, 2, len(events))
+	assertEqual(t, "Go meetup, 2024-12-05", events[0].Summary)
+	assertEqual(t, true, events[0].AllDay)
+	assertEqual(t, "Rust night", events[1].Summary)
+	assertEqual(t, time.Date(2024, time.December, 12, 19, 0, 0, 0, time.UTC), events[1].Start)
+	assertEqual(t, "Meetups", events[1].Feed)
+
+	var buf bytes.Buffer
+	err := WriteICS(&buf, events, time.Date(2024, time.November, 21, 8, 0, 0, 0, time.UTC))
+	assertEqual(t, nil, err)
+	ics := buf.String()
+	assertEqual(t, true, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assertEqual(t, true, strings.HasSuffix(ics, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assertEqual(t, 2, strings.Count(ics, "BEGIN:VEVENT"))
+	assertEqual(t, true, strings.Contains(ics, "SUMMARY:Go meetup\\, 2024-12-05\r\n"))
+	assertEqual(t, true, strings.Contains(ics, "DTSTART;VALUE=DATE:20241205\r\n"))
+	assertEqual(t, true, strings.Contains(ics, "DTSTART:20241212T190000\r\n"))
+	assertEqual(t, true, strings.Contains(ics, "DTSTAMP:20241121T080000Z\r\n"))
+	assertEqual(t, true, strings.Contains(ics, "DESCRIPTION:https://example.com/rust\\nMeetups\r\n"))
+}
+
+func TestWriteICSFoldsLines(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	err := WriteICS(&buf, []Event{{UID: "1", Summary: strings.Repeat("é", 60), AllDay: true}}, time.Now())
+	assertEqual(t, nil, err)
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assertEqual(t, true, len(line) <= 75)
+	}
+	assertEqual(t, true, strings.Contains(strings.ReplaceAll(buf.String(), "\r\n ", ""), "SUMMARY:"+strings.Repeat("é", 60)+"\r\n"))
+}
@@ -0,0 +1,48 @@
+package rss
+
+import "strings"
+
+// placeholderTitles are given by some feeds to items without a title.
+var placeholderTitles = map[string]bool{
+	"(no title)": true,
+	"no title":   true,
+	"untitled":   true,
+	"(untitled)": true,
+}
+
+// JunkOptions selects the heuristics used to recognise junk items, such as
+// those without a title or daily link dumps.
+type JunkOptions struct {
+	// EmptyTitle drops items without a title, or with a placeholder such
+	// as "(no title)".
+	EmptyTitle bool `yaml:"empty_title"`
+	// MinTitleLength drops items with shorter titles.
+	MinTitleLength int `yaml:"min_title_length"`
+	// FeedTitle drops items titled the same as their feed.
+	FeedTitle bool `yaml:"feed_title"`
+	// ChannelLink drops items which link to the home page of their feed
+	// rather than a page of their own.
+	ChannelLink bool `yaml:"channel_link"`
+}
+
+// DropJunk filters out the items recognised as junk by the selected
+// heuristics.
+func DropJunk(opts JunkOptions) Filter {
+	return func(item FeedItem) bool {
+		title := strings.TrimSpace(item.Title)
+		if opts.EmptyTitle && (title == "" || placeholderTitles[strings.ToLower(title)]) {
+			return false
+		}
+		if opts.MinTitleLength > 0 && len([]rune(title)) < opts.MinTitleLength {
+			return false
+		}
+		if opts.FeedTitle && title != "" && (strings.EqualFold(title, item.Feed) || strings.EqualFold(title, item.Channel)) {
+			return false
+		}
+		if opts.ChannelLink && item.channelLink != "" && len(item.Links) > 0 &&
+			CanonicalURL(item.Links[0]) == CanonicalURL(item.channelLink) {
+			return false
+		}
+		return true
+	}
+}
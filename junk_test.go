@@ -0,0 +1,34 @@
+package rss
+
+import "testing"
+
+func TestDropJunk(t *testing.T) {
+	feed := &Feed{URL: "https://example.com/rss", Name: "Example", RSS: RSS{Channel: Channel{Title: "Example Blog", Link: "https://example.com/"}}}
+	all := JunkOptions{EmptyTitle: true, MinTitleLength: 4, FeedTitle: true, ChannelLink: true}
+	testcases := []struct {
+		name     string
+		options  JunkOptions
+		item     Item
+		expected bool
+	}{
+		{name: "Empty title", options: all, item: Item{Link: "https://example.com/1"}, expected: false},
+		{name: "Placeholder title", options: all, item: Item{Title: "(No title)", Link: "https://example.com/1"}, expected: false},
+		{name: "Short title", options: all, item: Item{Title: "Hi", Link: "https://example.com/1"}, expected: false},
+		{name: "Feed name", options: all, item: Item{Title: "example", Link: "https://example.com/1"}, expected: false},
+		{name: "Channel title", options: all, item: Item{Title: "Example Blog", Link: "https://example.com/1"}, expected: false},
+		{name: "Channel link", options: all, item: Item{Title: "Links for today", Link: "http://example.com"}, expected: false},
+		{name: "Good item", options: all, item: Item{Title: "A real post", Link: "https://example.com/1"}, expected: true},
+		{name: "Disabled", options: JunkOptions{}, item: Item{Link: "https://example.com/"}, expected: true},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			item, err := NewFeedItem(feed, tc.item)
+			assertEqual(t, nil, err)
+			assertEqual(t, tc.expected, DropJunk(tc.options)(item))
+		})
+	}
+}
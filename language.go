@@ -0,0 +1,143 @@
+package rss
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// maxLanguageSample is the number of bytes of an item's description used to
+// detect its language.
+const maxLanguageSample = 2000
+
+// stopwords are common short words which are distinctive of each language.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "for", "with", "it", "on", "are", "was", "this", "you", "how", "what", "why"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "mit", "ein", "eine", "den", "für", "auf", "von", "sich", "auch", "wie", "im"},
+	"fr": {"le", "la", "les", "et", "est", "des", "une", "du", "pour", "dans", "que", "pas", "sur", "avec", "au", "qui", "ce"},
+	"es": {"el", "la", "los", "las", "y", "es", "del", "una", "para", "con", "por", "que", "en", "se", "como", "pero", "más"},
+	"it": {"il", "la", "di", "che", "è", "per", "una", "con", "non", "del", "della", "sono", "gli", "le", "come", "anche", "nel"},
+	"pt": {"o", "a", "os", "as", "do", "da", "em", "que", "não", "uma", "para", "com", "por", "mais", "como", "dos", "das"},
+	"nl": {"de", "het", "een", "en", "van", "is", "niet", "dat", "op", "te", "zijn", "voor", "met", "ook", "er", "maar", "wat"},
+}
+
+// DetectLanguage guesses the language of the text, returning its ISO 639-1
+// code e.g. "en", or an empty string if it can't tell. Languages with their
+// own scripts are told apart by script, and those written in Latin script by
+// their most common words.
+func DetectLanguage(text string) string {
+	scripts := make(map[string]int)
+	var letters int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			scripts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			scripts["zh"]++
+		case unicode.Is(unicode.Hangul, r):
+			scripts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				scripts["uk"]++
+			}
+			scripts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			scripts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			scripts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			scripts["el"]++
+		case unicode.Is(unicode.Thai, r):
+			scripts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			scripts["hi"]++
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+	// Japanese mixes kana with Han characters
+	if scripts["ja"] > 0 {
+		return "ja"
+	}
+	if scripts["uk"] > 0 {
+		return "uk"
+	}
+	for lang, count := range scripts {
+		if lang != "uk" && count*2 > letters {
+			return lang
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	var best, second int
+	var lang string
+	for candidate, common := range stopwords {
+		var score int
+		for _, word := range words {
+			for _, stopword := range common {
+				if word == stopword {
+					score++
+					break
+				}
+			}
+		}
+		switch {
+		case score > best:
+			second = best
+			best, lang = score, candidate
+		case score > second:
+			second = score
+		}
+	}
+	// Too few common words or too close a call to be sure
+	if best < 2 || best == second {
+		return ""
+	}
+	return lang
+}
+
+// itemLanguage detects the language of the item from its title and the start
+// of its description, falling back to the language declared by the channel.
+func itemLanguage(item Item, channel Channel) string {
+	description := item.Description
+	if len(description) > maxLanguageSample {
+		description = description[:maxLanguageSample]
+	}
+	text := item.Title + " " + html.UnescapeString(htmlTag.ReplaceAllString(string(description), " "))
+	if lang := DetectLanguage(text); lang != "" {
+		return lang
+	}
+	return primaryLanguage(channel.Language)
+}
+
+// primaryLanguage returns the language of a tag without its region e.g. "en"
+// for "en-GB".
+func primaryLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	primary, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return primary
+}
+
+// Language only lets through items in the given languages, e.g. "en" or
+// "en-GB", as detected from their title and description. Items whose
+// language can't be told are kept.
+func Language(langs ...string) Filter {
+	allowed := make(map[string]struct{})
+	for _, lang := range langs {
+		allowed[primaryLanguage(lang)] = struct{}{}
+	}
+	return func(item FeedItem) bool {
+		if item.Language == "" {
+			return true
+		}
+		_, found := allowed[item.Language]
+		return found
+	}
+}
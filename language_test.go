@@ -0,0 +1,48 @@
+package rss
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "English", text: "What is new in the latest release of Go", expected: "en"},
+		{name: "German", text: "Warum die Bahn nicht pünktlich ist und was man dagegen tun kann", expected: "de"},
+		{name: "French", text: "Les nouveautés de la version pour les développeurs", expected: "fr"},
+		{name: "Spanish", text: "Las claves del acuerdo para la reforma de las pensiones", expected: "es"},
+		{name: "Japanese", text: "東京でイベントが開催されました", expected: "ja"},
+		{name: "Chinese", text: "北京发布新的经济政策", expected: "zh"},
+		{name: "Russian", text: "Новости технологий за неделю", expected: "ru"},
+		{name: "Ukrainian", text: "Новини України за тиждень", expected: "uk"},
+		{name: "Too short", text: "Kubernetes", expected: ""},
+		{name: "Empty", text: "", expected: ""},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assertEqual(t, tc.expected, DetectLanguage(tc.text))
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	feed := &Feed{RSS: RSS{Channel: Channel{Language: "en-GB"}}}
+	english, err := NewFeedItem(feed, Item{Title: "Release notes", Description: []byte("<p>This is what changed in the release and why</p>")})
+	assertEqual(t, nil, err)
+	german, err := NewFeedItem(feed, Item{Title: "Die Neuigkeiten", Description: []byte("Das ist neu und nicht alt")})
+	assertEqual(t, nil, err)
+	declared, err := NewFeedItem(feed, Item{Title: "Kubernetes"})
+	assertEqual(t, nil, err)
+	assertEqual(t, "en", english.Language)
+	assertEqual(t, "de", german.Language)
+	assertEqual(t, "en", declared.Language)
+
+	filter := Language("en-US", "fr")
+	assertEqual(t, true, filter(english))
+	assertEqual(t, false, filter(german))
+	assertEqual(t, true, filter(declared))
+	assertEqual(t, true, filter(FeedItem{}))
+}
@@ -0,0 +1,67 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// defaultListShare is the percentage of the screen given to the list
+	// unless the layout says otherwise, and minListShare and maxListShare
+	// the least and most it can be given.
+	defaultListShare = 50
+	minListShare     = 10
+	maxListShare     = 90
+	// listShareStep is how much '<' and '>' resize the list by.
+	listShareStep = 5
+)
+
+// Layout arranges the list and the pane showing items in interactive mode.
+type Layout struct {
+	// Vertical puts the pane below the list rather than beside it, which
+	// suits tall terminals.
+	Vertical bool
+	// ListOnly gives the whole screen to the list, showing items in its
+	// place when they are opened, which suits narrow terminals.
+	ListOnly bool
+	// ListShare is the percentage of the screen given to the list, or half
+	// of it if zero.
+	ListShare int
+}
+
+// ParseLayout returns the layout with the given name, either "horizontal",
+// "vertical" or "list", giving the list the percentage of the screen. An empty
+// name is horizontal, and a share of zero is half.
+func ParseLayout(name string, listShare int) (Layout, error) {
+	if listShare != 0 && (listShare < minListShare || listShare > maxListShare) {
+		return Layout{}, fmt.Errorf("list share %d%% should be between %d%% and %d%%", listShare, minListShare, maxListShare)
+	}
+	layout := Layout{ListShare: listShare}
+	switch strings.ToLower(name) {
+	case "", "horizontal":
+	case "vertical":
+		layout.Vertical = true
+	case "list":
+		layout.ListOnly = true
+	default:
+		return Layout{}, fmt.Errorf("unknown layout %q, expected horizontal, vertical or list", name)
+	}
+	return layout, nil
+}
+
+// resize returns the share of the list after growing it by steps of
+// listShareStep, or shrinking it if steps is negative, within its limits.
+func (l Layout) resize(steps int) int {
+	share := l.ListShare
+	if share == 0 {
+		share = defaultListShare
+	}
+	share += steps * listShareStep
+	if share < minListShare {
+		return minListShare
+	}
+	if share > maxListShare {
+		return maxListShare
+	}
+	return share
+}
@@ -0,0 +1,52 @@
+package rss
+
+import "testing"
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		listShare int
+		expected  Layout
+		err       bool
+	}{
+		{name: "", expected: Layout{}},
+		{name: "horizontal", listShare: 40, expected: Layout{ListShare: 40}},
+		{name: "Vertical", expected: Layout{Vertical: true}},
+		{name: "list", expected: Layout{ListOnly: true}},
+		{name: "grid", err: true},
+		{name: "horizontal", listShare: 95, err: true},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			layout, err := ParseLayout(tc.name, tc.listShare)
+			assertEqual(t, tc.err, err != nil)
+			assertEqual(t, tc.expected, layout)
+		})
+	}
+}
+
+func TestLayoutResize(t *testing.T) {
+	tests := []struct {
+		name     string
+		layout   Layout
+		steps    int
+		expected int
+	}{
+		{name: "Default", layout: Layout{}, steps: 0, expected: 50},
+		{name: "Grow", layout: Layout{ListShare: 40}, steps: 1, expected: 45},
+		{name: "Shrink", layout: Layout{}, steps: -2, expected: 40},
+		{name: "Least", layout: Layout{ListShare: 15}, steps: -3, expected: 10},
+		{name: "Most", layout: Layout{ListShare: 85}, steps: 3, expected: 90},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assertEqual(t, tc.expected, tc.layout.resize(tc.steps))
+		})
+	}
+}
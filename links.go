@@ -0,0 +1,60 @@
+package rss
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// PageLink is a hyperlink found in the text of a page or description.
+type PageLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+var htmlAnchor = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+
+// DescriptionLinks returns the hyperlinks in the HTML of an item's description,
+// resolved against the item's link.
+func DescriptionLinks(description []byte, base string) []PageLink {
+	var links []PageLink
+	for _, match := range htmlAnchor.FindAllSubmatch(description, -1) {
+		text := html.UnescapeString(htmlTag.ReplaceAllString(string(match[2]), ""))
+		links = appendLink(links, base, html.UnescapeString(string(match[1])), text)
+	}
+	return links
+}
+
+// appendLink appends the link, resolved against base, unless it is already in
+// the links or isn't to another web page. Links without text are given their
+// URL as text.
+func appendLink(links []PageLink, base, href, text string) []PageLink {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return links
+	}
+	b, err := url.Parse(base)
+	if err == nil {
+		u = b.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return links
+	}
+	// Links within the page, e.g. to its footnotes, aren't worth following
+	page := *u
+	page.Fragment = ""
+	if err == nil && page.String() == b.String() {
+		return links
+	}
+	for _, link := range links {
+		if link.URL == u.String() {
+			return links
+		}
+	}
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		text = u.String()
+	}
+	return append(links, PageLink{Text: text, URL: u.String()})
+}
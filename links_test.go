@@ -0,0 +1,48 @@
+package rss
+
+import "testing"
+
+func TestDescriptionLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		expected    []PageLink
+	}{
+		{
+			name:        "Absolute and relative",
+			description: `<p>See <a href="https://go.dev/doc">the <b>docs</b></a> and <a class="x" href='/2024/05/slog'>our post</a>.</p>`,
+			expected: []PageLink{
+				{Text: "the docs", URL: "https://go.dev/doc"},
+				{Text: "our post", URL: "https://gazette.example/2024/05/slog"},
+			},
+		},
+		{
+			name:        "Escaped",
+			description: `<a href="https://example.com/?a=1&amp;b=2">Tom &amp; Jerry</a>`,
+			expected:    []PageLink{{Text: "Tom & Jerry", URL: "https://example.com/?a=1&b=2"}},
+		},
+		{
+			name:        "Without text",
+			description: `<a href="https://example.com/img"><img src="x.png"></a>`,
+			expected:    []PageLink{{Text: "https://example.com/img", URL: "https://example.com/img"}},
+		},
+		{
+			name:        "Duplicates, mail and footnotes skipped",
+			description: `<a href="https://go.dev">Go</a> <a href="https://go.dev">again</a> <a href="mailto:a@example.com">mail</a> <a href="#fn1">1</a>`,
+			expected:    []PageLink{{Text: "Go", URL: "https://go.dev"}},
+		},
+		{
+			name:        "No links",
+			description: `<p>Nothing to see</p>`,
+		},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			links := DescriptionLinks([]byte(tc.description), "https://gazette.example/2024/05/range-over-func")
+			assertEqual(t, tc.expected, links)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package rss
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var lobstersAPI = "https://lobste.rs/"
+
+type lobstersStory struct {
+	ShortIDURL   string    `json:"short_id_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	Title        string    `json:"title"`
+	URL          string    `json:"url"`
+	Score        int       `json:"score"`
+	CommentCount int       `json:"comment_count"`
+	Description  string    `json:"description"`
+	CommentsURL  string    `json:"comments_url"`
+}
+
+// fetchLobsters fetches stories from lobste.rs using its JSON API, which gives
+// their points and number of comments unlike its RSS feed. URLs name the page
+// of stories e.g. "lobsters://hottest", "lobsters://newest" or
+// "lobsters://t/go" for a tag, with "points" the fewest points a story can
+// have and "limit" the most stories to show.
+func fetchLobsters(ctx context.Context, u *url.URL, options fetchOptions) ([]RSS, error) {
+	page := strings.Trim(u.Host+u.Path, "/")
+	if page == "" {
+		page = "hottest"
+	}
+	minPoints, err := queryInt(u.Query(), "points", 0)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryInt(u.Query(), "limit", 0)
+	if err != nil {
+		return nil, err
+	}
+	var stories []lobstersStory
+	err = getJSON(ctx, lobstersAPI+page+".json", &stories)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := Channel{Title: "Lobsters", Link: lobstersAPI + page}
+	for _, story := range stories {
+		if limit > 0 && len(channel.Items) >= limit {
+			break
+		}
+		if story.Score < minPoints {
+			continue
+		}
+		channel.Items = append(channel.Items, story.item())
+	}
+	return []RSS{{Channel: limitItems(channel, options)}}, nil
+}
+
+func (story lobstersStory) item() Item {
+	link := story.URL
+	if link == "" {
+		link = story.CommentsURL
+	}
+	return Item{
+		Title:        story.Title,
+		Link:         link,
+		PubDate:      story.CreatedAt.Format(time.RFC1123Z),
+		GUID:         story.ShortIDURL,
+		Comments:     story.CommentsURL,
+		Description:  []byte(story.Description),
+		Points:       story.Score,
+		CommentCount: story.CommentCount,
+	}
+}
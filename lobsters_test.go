@@ -0,0 +1,68 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchLobsters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/t/go.json" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"short_id_url": "https://lobste.rs/s/abc", "created_at": "2022-11-01T09:00:00.000-05:00", "title": "Popular", "url": "https://example.com/popular", "score": 40, "comment_count": 12, "comments_url": "https://lobste.rs/s/abc/popular"},
+			{"short_id_url": "https://lobste.rs/s/def", "created_at": "2022-11-01T10:00:00.000-05:00", "title": "Unpopular", "url": "https://example.com/unpopular", "score": 2, "comment_count": 0, "comments_url": "https://lobste.rs/s/def/unpopular"},
+			{"short_id_url": "https://lobste.rs/s/ghi", "created_at": "2022-11-01T11:00:00.000-05:00", "title": "Ask: Why?", "url": "", "score": 25, "comment_count": 3, "description": "<p>Just wondering</p>", "comments_url": "https://lobste.rs/s/ghi/ask_why"}
+		]`)
+	}))
+	defer server.Close()
+	defer func(api string) { lobstersAPI = api }(lobstersAPI)
+	lobstersAPI = server.URL + "/"
+
+	feeds, err := FetchFeeds("lobsters://t/go?points=10")
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(feeds))
+	feedItems := UnpackFeed(feeds[0])
+	assertEqual(t, 2, len(feedItems))
+	assertEqual(t, "Popular", feedItems[0].Title)
+	assertEqual(t, []string{"https://example.com/popular", "https://lobste.rs/s/abc/popular"}, feedItems[0].Links)
+	assertEqual(t, 40, feedItems[0].Points)
+	assertEqual(t, 12, feedItems[0].Comments)
+	// Text posts link to the discussion
+	assertEqual(t, "https://lobste.rs/s/ghi/ask_why", feedItems[1].Links[0])
+
+	feeds, err = FetchFeeds("lobsters://t/go?limit=1")
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(UnpackFeed(feeds[0])))
+
+	_, err = FetchFeeds("lobsters://hottest")
+	assertEqual(t, true, err != nil)
+	_, err = FetchFeeds("lobsters://hottest?points=many")
+	assertEqual(t, true, err != nil)
+}
+
+// FuzzLobstersStories checks that no response from Lobsters crashes turning
+// its stories into items.
+func FuzzLobstersStories(f *testing.F) {
+	f.Add([]byte(`[{"short_id_url": "https://lobste.rs/s/abc", "created_at": "2022-11-01T09:00:00.000-05:00", "title": "Popular", "url": "https://example.com/popular", "score": 40, "comment_count": 12, "comments_url": "https://lobste.rs/s/abc/popular"}]`))
+	f.Add([]byte(`[{"created_at": "0001-01-01T00:00:00Z", "url": "", "description": "<p>Just wondering</p>"}]`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var stories []lobstersStory
+		if json.Unmarshal(b, &stories) != nil {
+			return
+		}
+		feed := &Feed{URL: "lobsters://t/go", RSS: RSS{Channel: Channel{Title: "Lobsters"}}}
+		for _, story := range stories {
+			feed.Channel.Items = append(feed.Channel.Items, story.item())
+		}
+		newFeedItem := newFeedItemCreator(feed)
+		for _, item := range feed.Channel.Items {
+			newFeedItem(item)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package rss
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The storage folder can be written by the daemon and commands run from the
+// shell at the same time. Records are appended under an advisory lock so that
+// they don't interleave, and whole files are replaced by renaming so that they
+// are never read half written.
+
+// AppendFile opens the file, creating it if need be, for records to be
+// appended to it. Each write holds an exclusive lock on the file, so it
+// should be a whole record.
+func AppendFile(path string) (io.WriteCloser, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return lockedFile{f}, nil
+}
+
+type lockedFile struct {
+	*os.File
+}
+
+func (lf lockedFile) Write(p []byte) (int, error) {
+	err := lockFile(lf.File, true)
+	if err != nil {
+		return 0, err
+	}
+	defer unlockFile(lf.File)
+	return lf.File.Write(p)
+}
+
+// ReadFileShared reads the whole file while holding a shared lock on it, so
+// that records being appended are read whole.
+func ReadFileShared(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	err = lockFile(f, false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockFile(f)
+	return io.ReadAll(f)
+}
+
+// WriteFileAtomic replaces the contents of the file by writing them to a
+// temporary file alongside it and renaming that into place. Symlinks are
+// followed so that the file they point to is replaced rather than the link.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(perm)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,80 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAppendFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "history")
+	const writers, records = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Each writer has a file of its own, as separate processes would
+			w, err := AppendFile(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer w.Close()
+			record := strings.Repeat(fmt.Sprint(i), 10000) + "\n"
+			for j := 0; j < records; j++ {
+				_, err = w.Write([]byte(record))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	b, err := ReadFileShared(path)
+	assertEqual(t, nil, err)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(nil, 20000)
+	var lines int
+	for scanner.Scan() {
+		line := scanner.Text()
+		assertEqual(t, strings.Repeat(line[:1], 10000), line)
+		lines++
+	}
+	assertEqual(t, nil, scanner.Err())
+	assertEqual(t, writers*records, lines)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	target := filepath.Join(dir, "urls.txt")
+	link := filepath.Join(dir, "link")
+	err := os.WriteFile(target, []byte("old"), 0644)
+	assertEqual(t, nil, err)
+	err = os.Symlink(target, link)
+	if err != nil {
+		t.Skip("symlinks aren't supported")
+	}
+
+	err = WriteFileAtomic(link, []byte("new"), 0644)
+	assertEqual(t, nil, err)
+	b, err := os.ReadFile(target)
+	assertEqual(t, nil, err)
+	assertEqual(t, "new", string(b))
+	info, err := os.Lstat(link)
+	assertEqual(t, nil, err)
+	assertEqual(t, os.ModeSymlink, info.Mode()&os.ModeSymlink)
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	assertEqual(t, nil, err)
+	assertEqual(t, 2, len(entries))
+}
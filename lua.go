@@ -0,0 +1,187 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// luaTimeout is how long a hook may run for.
+const luaTimeout = time.Second
+
+// LuaScript runs hooks written in Lua for light customisation without
+// plugins. A script defines any of the functions
+//
+//	on_item(item)                called for each item, which may change its
+//	                             title, score and tags, returning false to
+//	                             hide it
+//	on_fetch_error(url, message) called when the feed can't be fetched
+//	format_title(title, item)    returning the title to display instead
+//
+// where item is a table with the fields id, title, feed, channel, links,
+// published (in seconds since the Unix epoch), language, tags, score, points,
+// comments and authors.
+type LuaScript struct {
+	path string
+	errs *ErrorLog
+	// The state can only run one hook at a time
+	mu    sync.Mutex
+	state *lua.LState
+}
+
+// LoadLuaScript runs the script at the path, defining its hooks. Hooks which
+// fail are recorded in errs.
+func LoadLuaScript(path string, errs *ErrorLog) (*LuaScript, error) {
+	state := lua.NewState()
+	err := state.DoFile(path)
+	if err != nil {
+		state.Close()
+		return nil, fmt.Errorf("error loading %s: %w", path, err)
+	}
+	return &LuaScript{path: path, errs: errs, state: state}, nil
+}
+
+// call calls the hook with the arguments, returning its result or nil if the
+// script doesn't define it.
+func (s *LuaScript) call(hook string, args ...lua.LValue) (lua.LValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.state.GetGlobal(hook)
+	if fn.Type() != lua.LTFunction {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), luaTimeout)
+	defer cancel()
+	s.state.SetContext(ctx)
+	defer s.state.RemoveContext()
+	err := s.state.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error in %s of %s: %w", hook, s.path, err)
+	}
+	result := s.state.Get(-1)
+	s.state.Pop(1)
+	return result, nil
+}
+
+// OnItem runs the on_item hook, returning the changed item and whether it
+// should be shown. Items are left as they are if the hook fails, and the
+// failure recorded.
+func (s *LuaScript) OnItem(item FeedItem) (FeedItem, bool) {
+	table := s.itemTable(item)
+	result, err := s.call("on_item", table)
+	if err != nil {
+		s.errs.Add(err)
+		return item, true
+	}
+	if title, ok := table.RawGetString("title").(lua.LString); ok {
+		item.Title = string(title)
+	}
+	if score, ok := table.RawGetString("score").(lua.LNumber); ok {
+		item.Score = int(score)
+	}
+	if tags, ok := table.RawGetString("tags").(*lua.LTable); ok {
+		item.Tags = luaStrings(tags)
+	}
+	return item, result != lua.LFalse
+}
+
+// OnFetchError runs the on_fetch_error hook.
+func (s *LuaScript) OnFetchError(url string, fetchErr error) {
+	_, err := s.call("on_fetch_error", lua.LString(url), lua.LString(fetchErr.Error()))
+	if err != nil {
+		s.errs.Add(err)
+	}
+}
+
+// FormatTitle returns the title given by the format_title hook, or the item's
+// own title if there is no hook or it fails.
+func (s *LuaScript) FormatTitle(item FeedItem) string {
+	result, err := s.call("format_title", lua.LString(item.Title), s.itemTable(item))
+	if err != nil {
+		s.errs.Add(err)
+		return item.Title
+	}
+	if title, ok := result.(lua.LString); ok {
+		return string(title)
+	}
+	return item.Title
+}
+
+// Close frees the script's state.
+func (s *LuaScript) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Close()
+}
+
+func (s *LuaScript) itemTable(item FeedItem) *lua.LTable {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	table := s.state.NewTable()
+	table.RawSetString("id", lua.LString(item.ID))
+	table.RawSetString("title", lua.LString(item.Title))
+	table.RawSetString("feed", lua.LString(item.Feed))
+	table.RawSetString("channel", lua.LString(item.Channel))
+	table.RawSetString("links", s.stringsTable(item.Links))
+	table.RawSetString("published", lua.LNumber(item.PublishTime.Unix()))
+	table.RawSetString("language", lua.LString(item.Language))
+	table.RawSetString("tags", s.stringsTable(item.Tags))
+	table.RawSetString("score", lua.LNumber(item.Score))
+	table.RawSetString("points", lua.LNumber(item.Points))
+	table.RawSetString("comments", lua.LNumber(item.Comments))
+	table.RawSetString("authors", s.stringsTable(item.Authors))
+	return table
+}
+
+func (s *LuaScript) stringsTable(values []string) *lua.LTable {
+	table := s.state.NewTable()
+	for _, v := range values {
+		table.Append(lua.LString(v))
+	}
+	return table
+}
+
+// luaStrings returns the strings in the array part of the table.
+func luaStrings(table *lua.LTable) []string {
+	var values []string
+	for i := 1; i <= table.Len(); i++ {
+		if v, ok := table.RawGetInt(i).(lua.LString); ok {
+			values = append(values, string(v))
+		}
+	}
+	return values
+}
+
+// Scripted returns a display mode which runs the on_item hook of each item's
+// script before the items are passed to mode, leaving out those it hides.
+// Scripts are keyed by the feed's name or else its URL, as they are displayed.
+func Scripted(mode DisplayMode, scripts map[string]*LuaScript) DisplayMode {
+	return func(feedItems []FeedItem) []FeedItem {
+		kept := feedItems[:0]
+		for _, item := range feedItems {
+			script, found := scripts[item.Feed]
+			if !found {
+				kept = append(kept, item)
+				continue
+			}
+			if item, show := script.OnItem(item); show {
+				kept = append(kept, item)
+			}
+		}
+		return mode(kept)
+	}
+}
+
+// ScriptedTitles formats the titles of items with the format_title hooks of
+// their scripts, keyed as for Scripted.
+func ScriptedTitles(scripts map[string]*LuaScript) DisplayOption {
+	return func(item FeedItem) FeedItem {
+		if script, found := scripts[item.Feed]; found {
+			item.Title = script.FormatTitle(item)
+		}
+		return item
+	}
+}
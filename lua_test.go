@@ -0,0 +1,150 @@
+package rss
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLuaScript(t *testing.T, errs *ErrorLog, script string) *LuaScript {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "hooks.lua")
+	err := os.WriteFile(p, []byte(script), 0o600)
+	assertEqual(t, nil, err)
+	s, err := LoadLuaScript(p, errs)
+	assertEqual(t, nil, err)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestLuaScriptOnItem(t *testing.T) {
+	t.Parallel()
+	script := writeLuaScript(t, nil, `
+function on_item(item)
+  if string.find(item.title, "Sponsored") then
+    return false
+  end
+  if item.points > 100 then
+    item.score = item.score + 2
+    table.insert(item.tags, "popular")
+  end
+  item.title = string.gsub(item.title, "^Show: ", "")
+end
+`)
+	tests := []struct {
+		name     string
+		item     FeedItem
+		expected FeedItem
+		show     bool
+	}{
+		{
+			name:     "Changed",
+			item:     FeedItem{Title: "Show: A reader", Points: 150, Score: 1, Tags: []string{"go"}},
+			expected: FeedItem{Title: "A reader", Points: 150, Score: 3, Tags: []string{"go", "popular"}},
+			show:     true,
+		},
+		{
+			name:     "Hidden",
+			item:     FeedItem{Title: "Sponsored: buy this"},
+			expected: FeedItem{Title: "Sponsored: buy this"},
+			show:     false,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			item, show := script.OnItem(tc.item)
+			assertEqual(t, tc.show, show)
+			assertEqual(t, tc.expected, item)
+		})
+	}
+}
+
+func TestLuaScriptHooks(t *testing.T) {
+	t.Parallel()
+	script := writeLuaScript(t, nil, `
+failures = {}
+
+function on_fetch_error(url, message)
+  table.insert(failures, url .. ": " .. message)
+end
+
+function format_title(title, item)
+  return "[" .. item.feed .. "] " .. title .. " (" .. #item.links .. ")"
+end
+
+function failures_count()
+  return #failures
+end
+`)
+	item := FeedItem{Title: "Title", Feed: "Example", Links: []string{"https://example.com/1"}, PublishTime: time.Unix(0, 0)}
+	assertEqual(t, "[Example] Title (1)", script.FormatTitle(item))
+
+	script.OnFetchError("https://example.com/rss", errors.New("404 Not Found"))
+	count, err := script.call("failures_count")
+	assertEqual(t, nil, err)
+	assertEqual(t, "1", count.String())
+
+	// Scripts without a hook leave items as they are
+	item, show := script.OnItem(item)
+	assertEqual(t, true, show)
+	assertEqual(t, "Title", item.Title)
+}
+
+func TestLuaScriptErrors(t *testing.T) {
+	t.Parallel()
+	var errs ErrorLog
+	script := writeLuaScript(t, &errs, `
+function format_title(title, item)
+  error("broken")
+end
+
+function on_item(item)
+  while true do end
+end
+`)
+	item := FeedItem{Title: "Title"}
+	assertEqual(t, "Title", script.FormatTitle(item))
+	// Hooks which run for too long are stopped
+	item, show := script.OnItem(item)
+	assertEqual(t, true, show)
+	assertEqual(t, "Title", item.Title)
+	// The failures are recorded rather than printed
+	assertEqual(t, 2, len(errs.Errors()))
+	assertEqual(t, true, strings.Contains(errs.Errors()[0].Error(), "format_title"))
+
+	_, err := LoadLuaScript(filepath.Join(t.TempDir(), "missing.lua"), nil)
+	assertEqual(t, true, err != nil)
+	p := filepath.Join(t.TempDir(), "invalid.lua")
+	os.WriteFile(p, []byte("function ("), 0o600)
+	_, err = LoadLuaScript(p, nil)
+	assertEqual(t, true, strings.Contains(err.Error(), "invalid.lua"))
+}
+
+func TestScripted(t *testing.T) {
+	t.Parallel()
+	script := writeLuaScript(t, nil, `
+function on_item(item)
+  return item.title ~= "Hidden"
+end
+
+function format_title(title, item)
+  return string.upper(title)
+end
+`)
+	scripts := map[string]*LuaScript{"Scripted": script}
+	feedItems := []FeedItem{
+		{Title: "Shown", Feed: "Scripted"},
+		{Title: "Hidden", Feed: "Scripted"},
+		{Title: "Hidden", Feed: "Other"},
+	}
+	var titles []string
+	for _, item := range Scripted(ReverseChronological, scripts)(feedItems) {
+		titles = append(titles, ScriptedTitles(scripts)(item).Title)
+	}
+	assertEqual(t, []string{"SHOWN", "Hidden"}, titles)
+}
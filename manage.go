@@ -0,0 +1,249 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	managerHelp  = "[yellow]a[white] add  [yellow]d[white] delete  [yellow]t[white] tags  [yellow]J/K[white] move  [yellow]x[white] enable/disable  [yellow]r[white] test  [yellow]q[white] quit"
+	previewItems = 10
+)
+
+// RunManager runs an app for adding, removing, tagging, reordering and testing
+// subscriptions, with a preview of the latest items from the highlighted feed.
+// save is called with the subscriptions whenever they are changed.
+func RunManager(subs []Subscription, save func([]Subscription) error) error {
+	app := tview.NewApplication()
+	list := tview.NewList()
+	list.ShowSecondaryText(false)
+	list.SetHighlightFullLine(true)
+
+	preview := tview.NewTextView().SetDynamicColors(true)
+	status := tview.NewTextView().SetDynamicColors(true)
+	status.SetText(managerHelp)
+	input := tview.NewInputField()
+
+	listFlex := tview.NewFlex()
+	listFlex.AddItem(list, 0, 1, true)
+	listFlex.SetBorder(true)
+	listFlex.SetBorderColor(tcell.ColorGreen)
+
+	previewFlex := tview.NewFlex()
+	previewFlex.AddItem(preview, 0, 1, false)
+	previewFlex.SetBorder(true)
+	previewFlex.SetBorderColor(tcell.ColorGray)
+
+	panes := tview.NewFlex()
+	panes.AddItem(listFlex, 0, 1, true)
+	panes.AddItem(previewFlex, 0, 1, false)
+
+	bottom := tview.NewFlex()
+	bottom.AddItem(status, 0, 1, false)
+
+	root := tview.NewFlex().SetDirection(tview.FlexRow)
+	root.AddItem(panes, 0, 1, true)
+	root.AddItem(bottom, 1, 0, false)
+
+	setStatus := func(format string, a ...interface{}) {
+		status.SetText(fmt.Sprintf(format, a...))
+	}
+
+	refresh := func(current int) {
+		list.Clear()
+		for _, sub := range subs {
+			list.AddItem(formatSubscription(sub), sub.URL, 0, nil)
+		}
+		if current >= len(subs) {
+			current = len(subs) - 1
+		}
+		if current >= 0 {
+			list.SetCurrentItem(current)
+		}
+	}
+
+	changed := func(current int) {
+		refresh(current)
+		err := save(subs)
+		if err != nil {
+			setStatus("[red]%s[white]", tview.Escape(err.Error()))
+			return
+		}
+		setStatus(managerHelp)
+	}
+
+	// Previews are cached so that moving up and down the list is quick
+	previews := make(map[string]string)
+	var previewsMu sync.Mutex
+	showPreview := func(url string) {
+		previewsMu.Lock()
+		text, ok := previews[url]
+		previewsMu.Unlock()
+		if ok {
+			preview.SetText(text)
+			return
+		}
+		preview.SetText("Loading...")
+		go func() {
+			text := previewFeed(url)
+			previewsMu.Lock()
+			previews[url] = text
+			previewsMu.Unlock()
+			app.QueueUpdateDraw(func() {
+				_, current := list.GetItemText(list.GetCurrentItem())
+				if current == url {
+					preview.SetText(text)
+				}
+			})
+		}()
+	}
+	list.SetChangedFunc(func(i int, main, secondary string, r rune) {
+		showPreview(secondary)
+	})
+
+	// prompt asks for a line of input at the bottom of the screen
+	prompt := func(label, text string, done func(string)) {
+		input.SetLabel(label)
+		input.SetText(text)
+		input.SetDoneFunc(func(key tcell.Key) {
+			bottom.RemoveItem(input)
+			bottom.AddItem(status, 0, 1, false)
+			app.SetFocus(list)
+			if key == tcell.KeyEnter {
+				done(input.GetText())
+			}
+		})
+		bottom.RemoveItem(status)
+		bottom.AddItem(input, 0, 1, true)
+		app.SetFocus(input)
+	}
+
+	testFeed := func(i int) {
+		url := subs[i].URL
+		setStatus("Testing %s...", tview.Escape(url))
+		go func() {
+			start := time.Now()
+			feeds, err := FetchFeeds(url)
+			latency := time.Since(start).Round(time.Millisecond)
+			app.QueueUpdateDraw(func() {
+				if err != nil {
+					setStatus("[red]%s[white]", tview.Escape(err.Error()))
+					return
+				}
+				var titles []string
+				var items int
+				for _, feed := range feeds {
+					titles = append(titles, feed.Title())
+					items += len(feed.Channel.Items)
+				}
+				setStatus("[green]OK[white] %s: %d items in %s", tview.Escape(strings.Join(titles, ", ")), items, latency)
+			})
+		}()
+	}
+
+	list.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() != tcell.KeyRune {
+			return event
+		}
+		i := list.GetCurrentItem()
+		if event.Rune() == 'a' {
+			prompt("Add URL: ", "", func(text string) {
+				added := ReadSubscriptions(strings.NewReader(text))
+				if len(added) == 0 {
+					return
+				}
+				if existing, found := FindSubscription(subs, added[0].URL); found {
+					setStatus("[yellow]Already subscribed as %s[white]", tview.Escape(existing.URL))
+					return
+				}
+				subs = append(subs, added...)
+				changed(len(subs) - 1)
+			})
+			return nil
+		}
+		if len(subs) == 0 {
+			return event
+		}
+		switch event.Rune() {
+		case 'd':
+			subs = append(subs[:i], subs[i+1:]...)
+			changed(i)
+		case 't':
+			prompt("Tags: ", strings.Join(subs[i].Tags, " "), func(text string) {
+				subs[i].Tags = strings.Fields(text)
+				changed(i)
+			})
+		case 'J':
+			if i < len(subs)-1 {
+				subs[i], subs[i+1] = subs[i+1], subs[i]
+				changed(i + 1)
+			}
+		case 'K':
+			if i > 0 {
+				subs[i], subs[i-1] = subs[i-1], subs[i]
+				changed(i - 1)
+			}
+		case 'x':
+			subs[i].Disabled = !subs[i].Disabled
+			changed(i)
+		case 'r':
+			testFeed(i)
+		case 'q':
+			app.Stop()
+		default:
+			return event
+		}
+		return nil
+	})
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlQ, tcell.KeyCtrlC:
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	refresh(0)
+	if len(subs) > 0 {
+		showPreview(subs[0].URL)
+	}
+	app.SetRoot(root, true)
+	return app.Run()
+}
+
+func formatSubscription(sub Subscription) string {
+	builder := &strings.Builder{}
+	if sub.Disabled {
+		builder.WriteString(colourizeInteractive("# "+tview.Escape(sub.URL), gray))
+	} else {
+		builder.WriteString(tview.Escape(sub.URL))
+	}
+	for _, tag := range sub.Tags {
+		builder.WriteString(" " + colourizeInteractive("#"+tview.Escape(tag), purple))
+	}
+	return builder.String()
+}
+
+// previewFeed fetches the feed and formats the latest items of each of its
+// channels for display.
+func previewFeed(url string) string {
+	feeds, err := FetchFeeds(url, StopAfter(previewItems))
+	if err != nil {
+		return colourizeInteractive(tview.Escape(err.Error()), red)
+	}
+	builder := &strings.Builder{}
+	for _, feed := range feeds {
+		builder.WriteString(colourizeInteractive(tview.Escape(feed.Title()), green) + "\n\n")
+		for _, item := range ReverseChronological(UnpackFeed(feed)) {
+			builder.WriteString(formatFeedInteractive(item))
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,250 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxMatrixSyncEvents is the number of reactions read from a room at a time.
+const maxMatrixSyncEvents = 100
+
+// Follower is a Poster to a room, which can remember what it posted there to
+// thread the items of each feed and find those reacted to.
+type Follower interface {
+	Poster
+	// Follow returns the poster remembering what it posts in the room,
+	// posting the items of each feed in a thread if threads is set.
+	Follow(room *MatrixRoom, threads bool) Follower
+	// Reacted returns the items which have been reacted to since it was last
+	// called, as read when they were reacted to.
+	Reacted() ([]HistoryEntry, error)
+}
+
+// MatrixRoom is what has been posted to a Matrix room.
+type MatrixRoom struct {
+	// Threads holds the ID of the event starting the thread of each feed.
+	Threads map[string]string `json:"threads,omitempty"`
+	// Messages are the items posted which haven't been reacted to yet.
+	Messages []MatrixMessage `json:"messages,omitempty"`
+	// Since is the point in the room's timeline up to which reactions have
+	// been read.
+	Since string `json:"since,omitempty"`
+}
+
+// MatrixMessage is an item posted to a Matrix room as the event with the ID.
+type MatrixMessage struct {
+	Event string       `json:"event"`
+	Item  HistoryEntry `json:"item"`
+}
+
+// MatrixRooms holds the rooms posted to, keyed by a hash of their target.
+type MatrixRooms map[string]*MatrixRoom
+
+// Room returns the room of the target, adding it if it is new.
+func (r MatrixRooms) Room(target string) *MatrixRoom {
+	key := postedKey(target)
+	room, found := r[key]
+	if !found {
+		room = &MatrixRoom{}
+		r[key] = room
+	}
+	return room
+}
+
+// WriteMatrixRooms writes the rooms to w.
+func WriteMatrixRooms(w io.Writer, rooms MatrixRooms) error {
+	return json.NewEncoder(w).Encode(rooms)
+}
+
+// ReadMatrixRooms reads rooms written by WriteMatrixRooms.
+func ReadMatrixRooms(r io.Reader) (MatrixRooms, error) {
+	rooms := make(MatrixRooms)
+	err := json.NewDecoder(r).Decode(&rooms)
+	return rooms, err
+}
+
+type matrixPoster struct {
+	homeserver string
+	token      string
+	room       string
+	// state is nil unless the poster follows the room.
+	state   *MatrixRoom
+	threads bool
+}
+
+// matrixMessage is the content of an m.room.message event.
+type matrixMessage struct {
+	MsgType       string          `json:"msgtype"`
+	Body          string          `json:"body"`
+	Format        string          `json:"format"`
+	FormattedBody string          `json:"formatted_body"`
+	RelatesTo     *matrixRelation `json:"m.relates_to,omitempty"`
+}
+
+type matrixRelation struct {
+	RelType string `json:"rel_type"`
+	EventID string `json:"event_id"`
+	// Clients without threads show messages in them as replies to the
+	// event starting the thread
+	IsFallingBack bool            `json:"is_falling_back,omitempty"`
+	InReplyTo     *matrixEventRef `json:"m.in_reply_to,omitempty"`
+}
+
+type matrixEventRef struct {
+	EventID string `json:"event_id"`
+}
+
+func (m matrixPoster) Follow(room *MatrixRoom, threads bool) Follower {
+	m.state = room
+	m.threads = threads
+	return m
+}
+
+func (m matrixPoster) Post(item FeedItem) error {
+	message := matrixMessage{
+		MsgType:       "m.text",
+		Body:          item.Title + " · " + item.Source(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: html.EscapeString(item.Title),
+	}
+	link := postLink(item)
+	if link != "" {
+		message.Body += "\n" + link
+		message.FormattedBody = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), message.FormattedBody)
+	}
+	message.FormattedBody += " · " + html.EscapeString(item.Source())
+	if m.threads && m.state != nil {
+		thread, err := m.thread(item)
+		if err != nil {
+			return err
+		}
+		message.RelatesTo = &matrixRelation{
+			RelType:       "m.thread",
+			EventID:       thread,
+			IsFallingBack: true,
+			InReplyTo:     &matrixEventRef{EventID: thread},
+		}
+	}
+	// The transaction ID is that of the item, so that the homeserver ignores
+	// it if it is sent twice
+	event, err := m.send(postedKey(checkpointKey(item)), message)
+	if err != nil || m.state == nil {
+		return err
+	}
+	m.state.Messages = append(m.state.Messages, MatrixMessage{
+		Event: event,
+		Item: HistoryEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      link,
+			Feed:      item.Source(),
+			Published: item.PublishTime,
+		},
+	})
+	if len(m.state.Messages) > maxPostedIDs {
+		m.state.Messages = m.state.Messages[len(m.state.Messages)-maxPostedIDs:]
+	}
+	return nil
+}
+
+// thread returns the ID of the event starting the thread of the item's feed,
+// starting it with the name of the feed if there isn't one yet.
+func (m matrixPoster) thread(item FeedItem) (string, error) {
+	if event, found := m.state.Threads[item.Feed]; found {
+		return event, nil
+	}
+	event, err := m.send(postedKey("thread\n"+item.Feed), matrixMessage{
+		MsgType:       "m.text",
+		Body:          item.Source(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: "<b>" + html.EscapeString(item.Source()) + "</b>",
+	})
+	if err != nil {
+		return "", err
+	}
+	if m.state.Threads == nil {
+		m.state.Threads = make(map[string]string)
+	}
+	m.state.Threads[item.Feed] = event
+	return event, nil
+}
+
+// send sends the message to the room, returning the ID of its event.
+func (m matrixPoster) send(txn string, message matrixMessage) (string, error) {
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", m.homeserver, url.PathEscape(m.room), txn)
+	var response struct {
+		EventID string `json:"event_id"`
+	}
+	err := postJSON(http.MethodPut, endpoint, m.token, message, &response)
+	return response.EventID, err
+}
+
+func (m matrixPoster) Reacted() ([]HistoryEntry, error) {
+	if m.state == nil {
+		return nil, nil
+	}
+	// Only the reactions in the room are synced
+	filter, err := json.Marshal(map[string]interface{}{
+		"room": map[string]interface{}{
+			"rooms":        []string{m.room},
+			"timeline":     map[string]interface{}{"types": []string{"m.reaction"}, "limit": maxMatrixSyncEvents},
+			"state":        map[string]interface{}{"types": []string{}},
+			"ephemeral":    map[string]interface{}{"types": []string{}},
+			"account_data": map[string]interface{}{"types": []string{}},
+		},
+		"presence":     map[string]interface{}{"types": []string{}},
+		"account_data": map[string]interface{}{"types": []string{}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"filter": {string(filter)}, "timeout": {"0"}}
+	if m.state.Since != "" {
+		query.Set("since", m.state.Since)
+	}
+	var response struct {
+		NextBatch string `json:"next_batch"`
+		Rooms     struct {
+			Join map[string]struct {
+				Timeline struct {
+					Events []struct {
+						Type    string `json:"type"`
+						Time    int64  `json:"origin_server_ts"`
+						Content struct {
+							RelatesTo matrixRelation `json:"m.relates_to"`
+						} `json:"content"`
+					} `json:"events"`
+				} `json:"timeline"`
+			} `json:"join"`
+		} `json:"rooms"`
+	}
+	err = postJSON(http.MethodGet, m.homeserver+"/_matrix/client/v3/sync?"+query.Encode(), m.token, nil, &response)
+	if err != nil {
+		return nil, err
+	}
+	var read []HistoryEntry
+	for _, event := range response.Rooms.Join[m.room].Timeline.Events {
+		relation := event.Content.RelatesTo
+		if event.Type != "m.reaction" || relation.RelType != "m.annotation" {
+			continue
+		}
+		for i, message := range m.state.Messages {
+			if message.Event != relation.EventID {
+				continue
+			}
+			entry := message.Item
+			entry.Time = time.UnixMilli(event.Time)
+			read = append(read, entry)
+			// Each item is only read once, however many react to it
+			m.state.Messages = append(m.state.Messages[:i], m.state.Messages[i+1:]...)
+			break
+		}
+	}
+	m.state.Since = response.NextBatch
+	return read, nil
+}
@@ -0,0 +1,113 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMatrixThreads(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var sent []matrixMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var message matrixMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		sent = append(sent, message)
+		fmt.Fprintf(w, `{"event_id": "$%d"}`, len(sent))
+	}))
+	defer server.Close()
+
+	room := &MatrixRoom{}
+	poster := matrixPoster{homeserver: server.URL, token: "secret", room: "!room:example.org"}.Follow(room, true)
+	feedItems := []FeedItem{
+		{ID: "1", Title: "One", Feed: "Go Blog", Links: []string{"https://go.dev/1"}},
+		{ID: "2", Title: "Two", Feed: "Go Blog", Links: []string{"https://go.dev/2"}},
+		{ID: "3", Title: "Three", Feed: "Rust Blog", Links: []string{"https://rust-lang.org/3"}},
+	}
+	for _, item := range feedItems {
+		err := poster.Post(item)
+		assertEqual(t, nil, err)
+	}
+
+	// Each feed's thread is started once, by a message naming it
+	var bodies []string
+	var threads []string
+	for _, message := range sent {
+		bodies = append(bodies, strings.SplitN(message.Body, "\n", 2)[0])
+		if message.RelatesTo == nil {
+			threads = append(threads, "")
+			continue
+		}
+		assertEqual(t, "m.thread", message.RelatesTo.RelType)
+		assertEqual(t, message.RelatesTo.EventID, message.RelatesTo.InReplyTo.EventID)
+		threads = append(threads, message.RelatesTo.EventID)
+	}
+	assertEqual(t, []string{"Go Blog", "One · Go Blog", "Two · Go Blog", "Rust Blog", "Three · Rust Blog"}, bodies)
+	assertEqual(t, []string{"", "$1", "$1", "", "$4"}, threads)
+	assertEqual(t, map[string]string{"Go Blog": "$1", "Rust Blog": "$4"}, room.Threads)
+	assertEqual(t, 3, len(room.Messages))
+	assertEqual(t, MatrixMessage{Event: "$2", Item: HistoryEntry{ID: "1", Title: "One", Link: "https://go.dev/1", Feed: "Go Blog"}}, room.Messages[0])
+}
+
+func TestMatrixReacted(t *testing.T) {
+	t.Parallel()
+	reactedAt := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	var since []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertEqual(t, "/_matrix/client/v3/sync", r.URL.Path)
+		assertEqual(t, "Bearer secret", r.Header.Get("Authorization"))
+		since = append(since, r.URL.Query().Get("since"))
+		fmt.Fprintf(w, `{
+			"next_batch": "s%d",
+			"rooms": {"join": {"!room:example.org": {"timeline": {"events": [
+				{"type": "m.reaction", "origin_server_ts": %d, "content": {"m.relates_to": {"rel_type": "m.annotation", "event_id": "$2", "key": "👍"}}},
+				{"type": "m.reaction", "origin_server_ts": %d, "content": {"m.relates_to": {"rel_type": "m.annotation", "event_id": "$2", "key": "✅"}}},
+				{"type": "m.reaction", "origin_server_ts": %d, "content": {"m.relates_to": {"rel_type": "m.annotation", "event_id": "$9", "key": "👍"}}}
+			]}}}}
+		}`, len(since), reactedAt.UnixMilli(), reactedAt.UnixMilli(), reactedAt.UnixMilli())
+	}))
+	defer server.Close()
+
+	room := &MatrixRoom{Messages: []MatrixMessage{
+		{Event: "$1", Item: HistoryEntry{Title: "One", Link: "https://go.dev/1"}},
+		{Event: "$2", Item: HistoryEntry{Title: "Two", Link: "https://go.dev/2"}},
+	}}
+	poster := matrixPoster{homeserver: server.URL, token: "secret", room: "!room:example.org"}.Follow(room, false)
+	read, err := poster.Reacted()
+	assertEqual(t, nil, err)
+	// Items are read once, however many reactions they have
+	assertEqual(t, []HistoryEntry{{Time: reactedAt.Local(), Title: "Two", Link: "https://go.dev/2"}}, read)
+	assertEqual(t, []MatrixMessage{{Event: "$1", Item: HistoryEntry{Title: "One", Link: "https://go.dev/1"}}}, room.Messages)
+
+	read, err = poster.Reacted()
+	assertEqual(t, nil, err)
+	assertEqual(t, 0, len(read))
+	assertEqual(t, []string{"", "s1"}, since)
+	assertEqual(t, "s2", room.Since)
+}
+
+func TestMatrixRooms(t *testing.T) {
+	t.Parallel()
+	target := "matrix://secret@matrix.example.org/!room:example.org"
+	rooms := make(MatrixRooms)
+	rooms.Room(target).Threads = map[string]string{"Go Blog": "$1"}
+
+	var buf bytes.Buffer
+	err := WriteMatrixRooms(&buf, rooms)
+	assertEqual(t, nil, err)
+	// Targets aren't written, since they hold access tokens
+	assertEqual(t, false, bytes.Contains(buf.Bytes(), []byte("secret")))
+	rooms, err = ReadMatrixRooms(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, map[string]string{"Go Blog": "$1"}, rooms.Room(target).Threads)
+	assertEqual(t, 0, len(rooms.Room("matrix://other@matrix.example.org/!room:example.org").Threads))
+}
@@ -0,0 +1,73 @@
+package rss
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Opener opens links outside of the app, with commands chosen by the tags of
+// their items or feeds, e.g. a video player for the feeds tagged "video".
+// Commands are split into arguments at spaces, and %s in them is replaced by
+// the link, which is otherwise added as the last argument e.g.
+//
+//	firefox --private-window %s
+type Opener struct {
+	// Command opens the links without a command of their own, and defaults
+	// to the desktop's handler for links.
+	Command string
+	// Tags maps tags to the commands opening the links of items, or feeds,
+	// with them.
+	Tags map[string]string
+	// FeedTags holds the tags of each feed, keyed by the feed's name or else
+	// its URL as they are displayed. See Config.LinkOpener.
+	FeedTags map[string][]string
+}
+
+// Open opens the link of the item, without waiting for it to be closed.
+func (o Opener) Open(item FeedItem, link string) error {
+	name, args := o.command(item, link)
+	cmd := exec.Command(name, args...)
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+	// The command is waited for in the background so that it is cleaned up
+	// once it exits
+	go cmd.Wait()
+	return nil
+}
+
+// command returns the command opening the link of the item and its arguments.
+func (o Opener) command(item FeedItem, link string) (string, []string) {
+	command := o.Command
+	tags := append(append([]string(nil), item.Tags...), o.FeedTags[item.Feed]...)
+find:
+	for _, tag := range tags {
+		for t, c := range o.Tags {
+			if strings.EqualFold(t, tag) {
+				command = c
+				break find
+			}
+		}
+	}
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		if runtime.GOOS == "darwin" {
+			return "open", []string{link}
+		}
+		return "xdg-open", []string{link}
+	}
+	var replaced bool
+	args := fields[1:]
+	for i, arg := range args {
+		if strings.Contains(arg, "%s") {
+			args[i] = strings.ReplaceAll(arg, "%s", link)
+			replaced = true
+		}
+	}
+	if !replaced {
+		args = append(args, link)
+	}
+	return fields[0], args
+}
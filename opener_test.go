@@ -0,0 +1,65 @@
+package rss
+
+import "testing"
+
+func TestOpenerCommand(t *testing.T) {
+	opener := Opener{
+		Command:  "firefox --private-window %s",
+		Tags:     map[string]string{"video": "mpv --ytdl-format=best", "podcast": "vlc"},
+		FeedTags: map[string][]string{"https://example.com/videos.xml": {"video"}},
+	}
+	link := "https://example.com/1"
+	tests := []struct {
+		name         string
+		opener       Opener
+		item         FeedItem
+		expectedName string
+		expectedArgs []string
+	}{
+		{
+			name:         "Default command",
+			opener:       opener,
+			item:         FeedItem{Feed: "https://example.com/feed.xml"},
+			expectedName: "firefox",
+			expectedArgs: []string{"--private-window", link},
+		},
+		{
+			name:         "Feed tag",
+			opener:       opener,
+			item:         FeedItem{Feed: "https://example.com/videos.xml"},
+			expectedName: "mpv",
+			expectedArgs: []string{"--ytdl-format=best", link},
+		},
+		{
+			name:         "Item tag before feed tag",
+			opener:       opener,
+			item:         FeedItem{Feed: "https://example.com/videos.xml", Tags: []string{"Podcast"}},
+			expectedName: "vlc",
+			expectedArgs: []string{link},
+		},
+		{
+			name:         "Link within argument",
+			opener:       Opener{Command: "lynx -cfg=%s.cfg %s"},
+			item:         FeedItem{},
+			expectedName: "lynx",
+			expectedArgs: []string{"-cfg=" + link + ".cfg", link},
+		},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			name, args := tc.opener.command(tc.item, link)
+			assertEqual(t, tc.expectedName, name)
+			assertEqual(t, tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestOpenerSystemDefault(t *testing.T) {
+	t.Parallel()
+	name, args := Opener{Command: "  "}.command(FeedItem{}, "https://example.com/1")
+	assertEqual(t, true, name == "xdg-open" || name == "open")
+	assertEqual(t, []string{"https://example.com/1"}, args)
+}
@@ -0,0 +1,113 @@
+package rss
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Outline is an entry in an OPML file which describes a feed.
+type Outline struct {
+	Title    string    `xml:"title,attr"`
+	Text     string    `xml:"text,attr"`
+	XMLURL   string    `xml:"xmlUrl,attr"`
+	HTMLURL  string    `xml:"htmlUrl,attr"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// ParseOPML reads an OPML file and returns the outlines which describe feeds,
+// flattening any nested outlines.
+func ParseOPML(r io.Reader) ([]Outline, error) {
+	var opml struct {
+		Outlines []Outline `xml:"body>outline"`
+	}
+	err := xml.NewDecoder(r).Decode(&opml)
+	if err != nil {
+		return nil, err
+	}
+	var feeds []Outline
+	var flatten func([]Outline)
+	flatten = func(outlines []Outline) {
+		for _, outline := range outlines {
+			if outline.XMLURL != "" {
+				feeds = append(feeds, outline)
+			}
+			flatten(outline.Outlines)
+		}
+	}
+	flatten(opml.Outlines)
+	return feeds, nil
+}
+
+// Suggestion is a feed found in the blogroll of a subscribed feed.
+type Suggestion struct {
+	Title string
+	URL   string
+	// Via is the title of the subscribed feed whose blogroll listed it.
+	Via string
+}
+
+// DiscoverRelated fetches the blogrolls advertised by the feeds and returns
+// the feeds they list which aren't among those subscribed to. Blogrolls which
+// cannot be fetched are reported to stderr and skipped.
+func DiscoverRelated(feeds []*Feed, subscribed []string) []Suggestion {
+	seen := make(map[string]struct{})
+	for _, u := range subscribed {
+		seen[normalizeLink(u)] = struct{}{}
+	}
+	var suggestions []Suggestion
+	for _, feed := range feeds {
+		if feed == nil {
+			continue
+		}
+		for _, blogroll := range feed.Channel.Blogrolls {
+			outlines, err := getOPML(resolveURL(feed.URL, blogroll))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error getting blogroll %s: %s\n", blogroll, err.Error())
+				continue
+			}
+			for _, outline := range outlines {
+				key := normalizeLink(outline.XMLURL)
+				if _, found := seen[key]; found {
+					continue
+				}
+				seen[key] = struct{}{}
+				title := outline.Title
+				if title == "" {
+					title = outline.Text
+				}
+				suggestions = append(suggestions, Suggestion{
+					Title: title,
+					URL:   outline.XMLURL,
+					Via:   feed.Title(),
+				})
+			}
+		}
+	}
+	return suggestions
+}
+
+func getOPML(u string) ([]Outline, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ParseOPML(resp.Body)
+}
+
+// resolveURL resolves a possibly relative reference against the base URL.
+func resolveURL(base, ref string) string {
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
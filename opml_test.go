@@ -0,0 +1,42 @@
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testOPML = `<?xml version="1.0"?>
+<opml version="2.0">
+<head><title>Blogroll</title></head>
+<body>
+  <outline text="Friends">
+    <outline text="Alice" title="Alice's blog" xmlUrl="https://alice.example.com/rss" htmlUrl="https://alice.example.com"/>
+    <outline text="Bob" xmlUrl="https://bob.example.com/rss"/>
+  </outline>
+  <outline text="Me" xmlUrl="https://me.example.com/rss/"/>
+</body>
+</opml>`
+
+func TestDiscoverRelated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testOPML)
+	}))
+	defer server.Close()
+
+	doc := fmt.Sprintf(`<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Me</title>
+<atom:link rel="blogroll" href="%s/blogroll.opml"/>
+</channel></rss>`, server.URL)
+	channels, err := decodeRSS(strings.NewReader(doc), fetchOptions{})
+	assertEqual(t, nil, err)
+	assertEqual(t, []string{server.URL + "/blogroll.opml"}, channels[0].Channel.Blogrolls)
+
+	feeds := []*Feed{{URL: "https://me.example.com/rss", RSS: channels[0]}, nil}
+	suggestions := DiscoverRelated(feeds, []string{"https://me.example.com/rss"})
+	assertEqual(t, []Suggestion{
+		{Title: "Alice's blog", URL: "https://alice.example.com/rss", Via: "Me"},
+		{Title: "Bob", URL: "https://bob.example.com/rss", Via: "Me"},
+	}, suggestions)
+}
@@ -0,0 +1,91 @@
+package rss
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set overrides the option at the dotted path e.g. "junk.empty_title" with
+// the value, which is parsed as YAML so that lists can be given too e.g.
+// "[en, de]". Keys containing dots, such as the URLs of feeds, can't be set.
+func (c *Config) Set(path, value string) error {
+	var doc yaml.Node
+	err := yaml.Unmarshal([]byte(value), &doc)
+	if err != nil {
+		return fmt.Errorf("can't set %s: %w", path, err)
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if len(doc.Content) > 0 {
+		node = doc.Content[0]
+	}
+	keys := strings.Split(path, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: keys[i]}
+		node = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, node}}
+	}
+	b, err := yaml.Marshal(node)
+	if err != nil {
+		return fmt.Errorf("can't set %s: %w", path, err)
+	}
+	// Decoding over the config only changes the fields which are given
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	err = d.Decode(c)
+	if err != nil {
+		return fmt.Errorf("can't set %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overrides options with environment variables named after their
+// paths e.g. $RSS_DATE_FORMAT for date_format or $RSS_JUNK_EMPTY_TITLE for
+// junk.empty_title. Only options holding a single value or a list of values
+// can be set from the environment. Lookup is usually os.LookupEnv.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, path := range optionPaths(reflect.TypeOf(*c), "") {
+		value, found := lookup(EnvName(path))
+		if !found {
+			continue
+		}
+		err := c.Set(path, value)
+		if err != nil {
+			return fmt.Errorf("$%s: %w", EnvName(path), err)
+		}
+	}
+	return nil
+}
+
+// EnvName returns the name of the environment variable which sets the option
+// at the path.
+func EnvName(path string) string {
+	return "RSS_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// optionPaths lists the dotted paths of the options in the struct type which
+// hold single values or lists of them.
+func optionPaths(t reflect.Type, prefix string) []string {
+	var paths []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		path := prefix + name
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			paths = append(paths, optionPaths(field.Type, path+".")...)
+		case reflect.String, reflect.Bool, reflect.Int:
+			paths = append(paths, path)
+		case reflect.Slice:
+			if field.Type.Elem().Kind() == reflect.String {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,46 @@
+package rss
+
+import "testing"
+
+func TestConfigSet(t *testing.T) {
+	config := Config{DateFormat: "iso", Junk: JunkOptions{FeedTitle: true}}
+	err := config.Set("junk.empty_title", "true")
+	assertEqual(t, nil, err)
+	err = config.Set("languages", "[en, de]")
+	assertEqual(t, nil, err)
+	err = config.Set("time_zone", "")
+	assertEqual(t, nil, err)
+	assertEqual(t, Config{
+		DateFormat: "iso",
+		Junk:       JunkOptions{EmptyTitle: true, FeedTitle: true},
+		Languages:  []string{"en", "de"},
+	}, config)
+
+	err = config.Set("junk.typo", "true")
+	assertEqual(t, true, err != nil)
+	err = config.Set("max_age", "soon")
+	assertEqual(t, true, err != nil)
+}
+
+func TestConfigApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"RSS_MAX_AGE":           "48",
+		"RSS_SERVE_ADDR":        ":9000",
+		"RSS_REDIRECTS_RESOLVE": "yes",
+		"RSS_PASSPHRASE":        "not an option",
+	}
+	lookup := func(name string) (string, bool) {
+		value, found := env[name]
+		return value, found
+	}
+	var config Config
+	err := config.ApplyEnv(lookup)
+	assertEqual(t, nil, err)
+	assertEqual(t, 48, config.MaxAge)
+	assertEqual(t, ":9000", config.Serve.Addr)
+	assertEqual(t, true, config.Redirects.Resolve)
+
+	env["RSS_LIMIT"] = "lots"
+	err = config.ApplyEnv(lookup)
+	assertEqual(t, true, err != nil)
+}
@@ -0,0 +1,61 @@
+package rss
+
+// FetchPages fetches the feed at the url along with up to maxPages older
+// pages of it, following the links to the next page of a paged feed or the
+// previous archive of an archived feed (RFC 5005). Items from older pages are
+// added to the channel of the first page with the same title, skipping those
+// already found. Failing to fetch an older page stops the paging, keeping the
+// items found so far.
+func FetchPages(url string, maxPages int, opts ...FetchOption) ([]*Feed, error) {
+	feeds, err := FetchFeeds(url, opts...)
+	if err != nil || len(feeds) == 0 {
+		return feeds, err
+	}
+	seen := map[string]bool{url: true}
+	page, pageURL := feeds, url
+	for i := 0; i < maxPages; i++ {
+		older := page[0].Channel.PrevArchive
+		if older == "" {
+			older = page[0].Channel.Next
+		}
+		if older == "" {
+			break
+		}
+		older = resolveURL(pageURL, older)
+		if seen[older] {
+			break
+		}
+		seen[older] = true
+		page, err = FetchFeeds(older, opts...)
+		if err != nil || len(page) == 0 {
+			break
+		}
+		for _, p := range page {
+			addPage(feeds, p.Channel)
+		}
+		pageURL = older
+	}
+	return feeds, nil
+}
+
+// addPage adds the items of a channel from an older page to the feed with the
+// same channel title, or else the first.
+func addPage(feeds []*Feed, channel Channel) {
+	feed := feeds[0]
+	for _, f := range feeds {
+		if f.Channel.Title == channel.Title {
+			feed = f
+			break
+		}
+	}
+	found := make(map[string]bool)
+	for _, item := range feed.Channel.Items {
+		found[storeKey(item)] = true
+	}
+	for _, item := range channel.Items {
+		if key := storeKey(item); !found[key] {
+			found[key] = true
+			feed.Channel.Items = append(feed.Channel.Items, item)
+		}
+	}
+}
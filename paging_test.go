@@ -0,0 +1,64 @@
+package rss
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchPages(t *testing.T) {
+	t.Parallel()
+	pages := map[string]string{
+		// Paged feeds link to the next page
+		"/feed":  `<link rel="next" href="/page2"/><item><guid>3</guid><title>Three</title></item>`,
+		"/page2": `<atom:link rel="next" href="page3"/><item><guid>3</guid><title>Three</title></item><item><guid>2</guid><title>Two</title></item>`,
+		"/page3": `<atom:link rel="next" href="/feed"/><item><guid>1</guid><title>One</title></item>`,
+		// Archived feeds link to the previous archive
+		"/archived":  `<atom:link rel="prev-archive" href="/archive/1"/><item><guid>b</guid><title>B</title></item>`,
+		"/archive/1": `<atom:link rel="prev-archive" href="/missing"/><item><guid>a</guid><title>A</title></item>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, found := pages[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Paged</title>%s</channel></rss>`, page)
+	}))
+	t.Cleanup(server.Close)
+
+	titles := func(feeds []*Feed) []string {
+		var titles []string
+		for _, item := range feeds[0].Channel.Items {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+	testCases := []struct {
+		name     string
+		path     string
+		maxPages int
+		expected []string
+	}{
+		{name: "latest only", path: "/feed", maxPages: 0, expected: []string{"Three"}},
+		{name: "limited", path: "/feed", maxPages: 1, expected: []string{"Three", "Two"}},
+		{name: "loop", path: "/feed", maxPages: 10, expected: []string{"Three", "Two", "One"}},
+		{name: "archived", path: "/archived", maxPages: 10, expected: []string{"B", "A"}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			feeds, err := FetchPages(server.URL+tc.path, tc.maxPages)
+			assertEqual(t, nil, err)
+			assertEqual(t, 1, len(feeds))
+			assertEqual(t, server.URL+tc.path, feeds[0].URL)
+			assertEqual(t, tc.expected, titles(feeds))
+		})
+	}
+
+	feeds, err := FetchPages(server.URL+"/missing", 10)
+	assertEqual(t, nil, err)
+	assertEqual(t, 0, len(feeds))
+}
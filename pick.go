@@ -0,0 +1,42 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatPick formats the item on a single line for choosing from with tools
+// such as fzf, dmenu or rofi: its date, feed and title, then its link after a
+// tab so that the chosen line can be given back to ParsePick.
+func FormatPick(item FeedItem) string {
+	var link string
+	if len(item.Links) > 0 {
+		link = item.Links[0]
+	}
+	oneLine := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	return fmt.Sprintf("%s %s: %s\t%s",
+		item.PublishTime.Format("2006-01-02"),
+		oneLine.Replace(item.Source()),
+		oneLine.Replace(item.Title),
+		link,
+	)
+}
+
+// ParsePick returns the title and link of an item from a line written by
+// FormatPick.
+func ParsePick(line string) (title, link string, err error) {
+	line = strings.TrimRight(line, "\r\n")
+	i := strings.LastIndex(line, "\t")
+	if i < 0 {
+		return "", "", fmt.Errorf("no link in %q", line)
+	}
+	link = line[i+1:]
+	if err := validateURL(link); err != nil {
+		return "", "", err
+	}
+	title = line[:i]
+	if _, rest, found := strings.Cut(title, ": "); found {
+		title = rest
+	}
+	return title, link, nil
+}
@@ -0,0 +1,27 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPick(t *testing.T) {
+	item := FeedItem{
+		Title:       "Tabs\tand\nnewlines: in titles",
+		Links:       []string{"https://example.com/1", "https://example.com/1/comments"},
+		PublishTime: time.Date(2022, 11, 5, 12, 0, 0, 0, time.UTC),
+		Feed:        "Example",
+	}
+	line := FormatPick(item)
+	assertEqual(t, "2022-11-05 Example: Tabs and newlines: in titles\thttps://example.com/1", line)
+
+	title, link, err := ParsePick(line + "\n")
+	assertEqual(t, nil, err)
+	assertEqual(t, "Tabs and newlines: in titles", title)
+	assertEqual(t, "https://example.com/1", link)
+
+	_, _, err = ParsePick("2022-11-05 Example: No link")
+	assertEqual(t, true, err != nil)
+	_, _, err = ParsePick("2022-11-05 Example: Bad link\tnot a link")
+	assertEqual(t, true, err != nil)
+}
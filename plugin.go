@@ -0,0 +1,164 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pluginTimeout is how long a plugin may take to enrich the items of a feed.
+const pluginTimeout = 30 * time.Second
+
+// Plugin enriches items after they are fetched, e.g. scoring, summarising or
+// translating them, so that custom pipelines can be built without changing
+// the reader itself.
+type Plugin interface {
+	Enrich(feedItems []FeedItem) ([]Enrichment, error)
+}
+
+// Enrichment is what a plugin changes about an item. Titles and summaries
+// replace the item's, scores are added to its score and tags are added to its
+// tags.
+type Enrichment struct {
+	// ID is the ID of the item to change.
+	ID      string   `json:"id"`
+	Title   string   `json:"title,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Score   int      `json:"score,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func (e Enrichment) apply(item FeedItem) FeedItem {
+	if e.Title != "" {
+		item.Title = e.Title
+	}
+	if e.Summary != "" {
+		item.Summary = e.Summary
+	}
+	item.Score += e.Score
+	for _, tag := range e.Tags {
+		if !hasTag(item.Tags, tag) {
+			item.Tags = append(item.Tags, tag)
+		}
+	}
+	return item
+}
+
+// CommandPlugin is a plugin run as a command. The items are written to its
+// stdin as a JSON array, as served by 'rss serve', and it writes a JSON array
+// of enrichments to its stdout, e.g.
+//
+//	[{"id": "e7e5a800f5dc9d05", "summary": "A reader's question", "score": 2}]
+//
+// Items it doesn't give enrichments for are left as they are.
+type CommandPlugin struct {
+	Command []string
+}
+
+// NewCommandPlugin returns the plugin running the command, given with its
+// arguments separated by spaces.
+func NewCommandPlugin(command string) (*CommandPlugin, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, errors.New("empty plugin command")
+	}
+	return &CommandPlugin{Command: fields}, nil
+}
+
+func (p *CommandPlugin) Enrich(feedItems []FeedItem) ([]Enrichment, error) {
+	input, err := json.Marshal(feedItems)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("plugin %s: %s: %s", p.Command[0], err.Error(), message)
+		}
+		return nil, fmt.Errorf("plugin %s: %s", p.Command[0], err.Error())
+	}
+	var enrichments []Enrichment
+	err = json.Unmarshal(output, &enrichments)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: error decoding its output: %s", p.Command[0], err.Error())
+	}
+	return enrichments, nil
+}
+
+// Enriched returns a display mode which passes the items of each feed through
+// its plugins in turn, before they are passed to mode. Plugins are keyed by the
+// feed's name or else its URL, as they are displayed. A plugin which fails is
+// recorded in errs, and the items left as they were.
+func Enriched(mode DisplayMode, plugins map[string][]Plugin, errs *ErrorLog) DisplayMode {
+	return func(feedItems []FeedItem) []FeedItem {
+		byFeed := make(map[string][]int)
+		for i, item := range feedItems {
+			if len(plugins[item.Feed]) > 0 {
+				byFeed[item.Feed] = append(byFeed[item.Feed], i)
+			}
+		}
+		// Feeds are enriched in parallel since plugins can be slow, but
+		// each item is only changed by its own feed's plugins
+		var wg sync.WaitGroup
+		limit := make(chan struct{}, runtime.NumCPU())
+		for feed, indices := range byFeed {
+			wg.Add(1)
+			go func(feed string, indices []int) {
+				defer wg.Done()
+				limit <- struct{}{}
+				defer func() { <-limit }()
+				enrich(feedItems, indices, plugins[feed], errs)
+			}(feed, indices)
+		}
+		wg.Wait()
+		return mode(feedItems)
+	}
+}
+
+// enrich applies the enrichments of each plugin to the items at the indices,
+// so that later plugins see what earlier ones did.
+func enrich(feedItems []FeedItem, indices []int, plugins []Plugin, errs *ErrorLog) {
+	for _, plugin := range plugins {
+		batch := make([]FeedItem, len(indices))
+		byID := make(map[string]int)
+		for i, index := range indices {
+			batch[i] = feedItems[index]
+			byID[feedItems[index].ID] = index
+		}
+		enrichments, err := plugin.Enrich(batch)
+		if err != nil {
+			errs.Add(err)
+			continue
+		}
+		for _, e := range enrichments {
+			if index, found := byID[e.ID]; found {
+				feedItems[index] = e.apply(feedItems[index])
+			}
+		}
+	}
+}
+
+// ShowSummaries appends the summaries given to items by plugins to their
+// titles e.g. "Title — A summary".
+func ShowSummaries() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		if item.Summary == "" {
+			return item
+		}
+		item.Title = item.Title + " — " + item.Summary
+		return item
+	}
+}
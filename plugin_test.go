@@ -0,0 +1,97 @@
+package rss
+
+import (
+	"errors"
+	"testing"
+)
+
+type pluginFunc func([]FeedItem) ([]Enrichment, error)
+
+func (f pluginFunc) Enrich(feedItems []FeedItem) ([]Enrichment, error) {
+	return f(feedItems)
+}
+
+func TestEnriched(t *testing.T) {
+	t.Parallel()
+	translate := pluginFunc(func(feedItems []FeedItem) ([]Enrichment, error) {
+		var enrichments []Enrichment
+		for _, item := range feedItems {
+			if item.Title == "Bonjour" {
+				enrichments = append(enrichments, Enrichment{ID: item.ID, Title: "Hello", Tags: []string{"translated"}})
+			}
+		}
+		return enrichments, nil
+	})
+	// Later plugins see what earlier ones did
+	score := pluginFunc(func(feedItems []FeedItem) ([]Enrichment, error) {
+		var enrichments []Enrichment
+		for _, item := range feedItems {
+			if item.Title == "Hello" {
+				enrichments = append(enrichments, Enrichment{ID: item.ID, Score: 2, Summary: "A greeting"})
+			}
+		}
+		return enrichments, nil
+	})
+	failing := pluginFunc(func([]FeedItem) ([]Enrichment, error) {
+		return nil, errors.New("plugin failed")
+	})
+
+	tests := []struct {
+		name     string
+		plugins  map[string][]Plugin
+		expected []FeedItem
+		failures int
+	}{
+		{
+			name:    "In turn",
+			plugins: map[string][]Plugin{"French": {translate, score}},
+			expected: []FeedItem{
+				{ID: "1", Title: "Hello", Feed: "French", Tags: []string{"translated"}, Score: 2, Summary: "A greeting"},
+				{ID: "2", Title: "Bonjour", Feed: "Other"},
+			},
+		},
+		{
+			name:    "Failing",
+			plugins: map[string][]Plugin{"French": {failing, translate}},
+			expected: []FeedItem{
+				{ID: "1", Title: "Hello", Feed: "French", Tags: []string{"translated"}},
+				{ID: "2", Title: "Bonjour", Feed: "Other"},
+			},
+			failures: 1,
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			feedItems := []FeedItem{
+				{ID: "1", Title: "Bonjour", Feed: "French"},
+				{ID: "2", Title: "Bonjour", Feed: "Other"},
+			}
+			var errs ErrorLog
+			assertEqual(t, tc.expected, Enriched(ReverseChronological, tc.plugins, &errs)(feedItems))
+			assertEqual(t, tc.failures, len(errs.Errors()))
+		})
+	}
+}
+
+func TestCommandPlugin(t *testing.T) {
+	t.Parallel()
+	plugin := &CommandPlugin{Command: []string{"sh", "-c", `cat >/dev/null; echo '[{"id": "1", "summary": "Summed up"}]'`}}
+	enrichments, err := plugin.Enrich([]FeedItem{{ID: "1", Title: "Title"}})
+	assertEqual(t, nil, err)
+	assertEqual(t, []Enrichment{{ID: "1", Summary: "Summed up"}}, enrichments)
+
+	plugin = &CommandPlugin{Command: []string{"sh", "-c", "echo broken >&2; exit 1"}}
+	_, err = plugin.Enrich(nil)
+	assertEqual(t, "plugin sh: exit status 1: broken", err.Error())
+
+	_, err = NewCommandPlugin(" ")
+	assertEqual(t, true, err != nil)
+}
+
+func TestShowSummaries(t *testing.T) {
+	t.Parallel()
+	assertEqual(t, "Title — Summed up", ShowSummaries()(FeedItem{Title: "Title", Summary: "Summed up"}).Title)
+	assertEqual(t, "Title", ShowSummaries()(FeedItem{Title: "Title"}).Title)
+}
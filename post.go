@@ -0,0 +1,189 @@
+package rss
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// maxPostedIDs is the number of items remembered as posted to each target.
+const maxPostedIDs = 1000
+
+// Poster posts items to a chat service as messages.
+type Poster interface {
+	Post(item FeedItem) error
+}
+
+// ParseTarget returns the poster for a chat target, given as one of
+//
+//	slack://hooks.slack.com/services/T000/B000/XXXX     a Slack incoming webhook
+//	discord://discord.com/api/webhooks/ID/TOKEN         a Discord webhook
+//	matrix://TOKEN@matrix.example.org/!room:example.org a Matrix room, by ID
+//
+// where the rest of the target is the URL of the webhook or homeserver
+// without https://.
+func ParseTarget(target string) (Poster, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("target %q has no host", target)
+	}
+	endpoint := "https://" + u.Host + u.EscapedPath()
+	switch u.Scheme {
+	case "slack":
+		return slackPoster{webhook: endpoint}, nil
+	case "discord":
+		return discordPoster{webhook: endpoint}, nil
+	case "matrix":
+		room := strings.TrimPrefix(u.Path, "/")
+		if u.User == nil || room == "" {
+			return nil, fmt.Errorf("matrix target needs an access token and room e.g. matrix://TOKEN@matrix.example.org/!room:example.org")
+		}
+		return matrixPoster{homeserver: "https://" + u.Host, token: u.User.Username(), room: room}, nil
+	}
+	return nil, fmt.Errorf("unknown target %q, expected slack://, discord:// or matrix://", u.Scheme)
+}
+
+// postJSON sends the body as JSON, authorised with the token if there is one,
+// decoding the response into result unless it is nil.
+func postJSON(method, endpoint, token string, body, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, endpoint, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error would include the endpoint, which holds secrets
+		if urlErr, ok := err.(*url.Error); ok {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s responded %s", req.URL.Host, resp.Status)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// postLink returns the link of the item, if it has one.
+func postLink(item FeedItem) string {
+	if len(item.Links) == 0 {
+		return ""
+	}
+	return item.Links[0]
+}
+
+type slackPoster struct {
+	webhook string
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func (s slackPoster) Post(item FeedItem) error {
+	text := slackEscaper.Replace(item.Title)
+	if link := postLink(item); link != "" {
+		text = fmt.Sprintf("<%s|%s>", link, text)
+	}
+	text += " · " + slackEscaper.Replace(item.Source())
+	return postJSON(http.MethodPost, s.webhook, "", struct {
+		Text string `json:"text"`
+	}{Text: text}, nil)
+}
+
+type discordPoster struct {
+	webhook string
+}
+
+var discordEscaper = strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "[", "\\[", "]", "\\]", "|", "\\|")
+
+func (d discordPoster) Post(item FeedItem) error {
+	content := discordEscaper.Replace(item.Title)
+	if link := postLink(item); link != "" {
+		content = fmt.Sprintf("[%s](%s)", content, link)
+	}
+	content += " · " + discordEscaper.Replace(item.Source())
+	return postJSON(http.MethodPost, d.webhook, "", struct {
+		Content string `json:"content"`
+	}{Content: content}, nil)
+}
+
+// postedKey hashes the text, so that targets aren't recorded with their
+// secrets.
+func postedKey(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Posted records the items posted to each target, so that each is only posted
+// once.
+type Posted map[string][]string
+
+// Has returns whether anything has been posted to the target.
+func (p Posted) Has(target string) bool {
+	_, found := p[postedKey(target)]
+	return found
+}
+
+// Filter passes the items which haven't been posted to the target.
+func (p Posted) Filter(target string) Filter {
+	ids := make(map[string]struct{})
+	for _, id := range p[postedKey(target)] {
+		ids[id] = struct{}{}
+	}
+	return func(item FeedItem) bool {
+		_, found := ids[checkpointKey(item)]
+		return !found
+	}
+}
+
+// Add records the items as posted to the target.
+func (p Posted) Add(target string, feedItems ...FeedItem) {
+	key := postedKey(target)
+	ids := p[key]
+	if ids == nil {
+		ids = []string{}
+	}
+	for _, item := range feedItems {
+		ids = append(ids, checkpointKey(item))
+	}
+	if len(ids) > maxPostedIDs {
+		ids = ids[len(ids)-maxPostedIDs:]
+	}
+	p[key] = ids
+}
+
+// WritePosted writes what has been posted to w.
+func WritePosted(w io.Writer, posted Posted) error {
+	return json.NewEncoder(w).Encode(posted)
+}
+
+// ReadPosted reads what has been posted, as written by WritePosted.
+func ReadPosted(r io.Reader) (Posted, error) {
+	posted := make(Posted)
+	err := json.NewDecoder(r).Decode(&posted)
+	return posted, err
+}
@@ -0,0 +1,145 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		expected Poster
+		err      bool
+	}{
+		{
+			target:   "slack://hooks.slack.com/services/T000/B000/XXXX",
+			expected: slackPoster{webhook: "https://hooks.slack.com/services/T000/B000/XXXX"},
+		},
+		{
+			target:   "discord://discord.com/api/webhooks/123/token",
+			expected: discordPoster{webhook: "https://discord.com/api/webhooks/123/token"},
+		},
+		{
+			target:   "matrix://secret@matrix.example.org/!room:example.org",
+			expected: matrixPoster{homeserver: "https://matrix.example.org", token: "secret", room: "!room:example.org"},
+		},
+		{target: "matrix://matrix.example.org/!room:example.org", err: true},
+		{target: "irc://irc.example.org/#go", err: true},
+		{target: "slack:", err: true},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.target, func(t *testing.T) {
+			t.Parallel()
+			poster, err := ParseTarget(tc.target)
+			assertEqual(t, tc.err, err != nil)
+			assertEqual(t, tc.expected, poster)
+		})
+	}
+}
+
+func TestPosters(t *testing.T) {
+	item := FeedItem{ID: "1", Title: "Go <1.22> *released*", Feed: "Go Blog", Links: []string{"https://go.dev/blog/go1.22"}}
+	tests := []struct {
+		name           string
+		poster         func(url string) Poster
+		expectedMethod string
+		expectedPath   string
+		expectedAuth   string
+		expected       map[string]string
+	}{
+		{
+			name:           "Slack",
+			poster:         func(url string) Poster { return slackPoster{webhook: url + "/services/T/B/X"} },
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/services/T/B/X",
+			expected:       map[string]string{"text": "<https://go.dev/blog/go1.22|Go &lt;1.22&gt; *released*> · Go Blog"},
+		},
+		{
+			name:           "Discord",
+			poster:         func(url string) Poster { return discordPoster{webhook: url + "/api/webhooks/1/token"} },
+			expectedMethod: http.MethodPost,
+			expectedPath:   "/api/webhooks/1/token",
+			expected:       map[string]string{"content": `[Go <1.22> \*released\*](https://go.dev/blog/go1.22) · Go Blog`},
+		},
+		{
+			name: "Matrix",
+			poster: func(url string) Poster {
+				return matrixPoster{homeserver: url, token: "secret", room: "!room:example.org"}
+			},
+			expectedMethod: http.MethodPut,
+			expectedPath:   "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/" + postedKey("1"),
+			expectedAuth:   "Bearer secret",
+			expected: map[string]string{
+				"msgtype":        "m.text",
+				"body":           "Go <1.22> *released* · Go Blog\nhttps://go.dev/blog/go1.22",
+				"format":         "org.matrix.custom.html",
+				"formatted_body": `<a href="https://go.dev/blog/go1.22">Go &lt;1.22&gt; *released*</a> · Go Blog`,
+			},
+		},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			var body map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assertEqual(t, tc.expectedMethod, r.Method)
+				assertEqual(t, tc.expectedPath, r.URL.Path)
+				assertEqual(t, tc.expectedAuth, r.Header.Get("Authorization"))
+				b, _ := io.ReadAll(r.Body)
+				json.Unmarshal(b, &body)
+				w.Write([]byte(`{"event_id": "$1"}`))
+			}))
+			defer server.Close()
+			err := tc.poster(server.URL).Post(item)
+			assertEqual(t, nil, err)
+			assertEqual(t, tc.expected, body)
+		})
+	}
+}
+
+func TestPosterError(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	err := slackPoster{webhook: server.URL + "/secret"}.Post(FeedItem{Title: "Title"})
+	assertEqual(t, true, err != nil)
+	assertEqual(t, false, bytes.Contains([]byte(err.Error()), []byte("secret")))
+
+	err = slackPoster{webhook: "http://127.0.0.1:0/secret"}.Post(FeedItem{Title: "Title"})
+	assertEqual(t, true, err != nil)
+	assertEqual(t, false, bytes.Contains([]byte(err.Error()), []byte("secret")))
+}
+
+func TestPosted(t *testing.T) {
+	t.Parallel()
+	target := "slack://hooks.slack.com/services/T000/B000/XXXX"
+	posted := make(Posted)
+	assertEqual(t, false, posted.Has(target))
+	posted.Add(target, FeedItem{ID: "1"}, FeedItem{Links: []string{"https://example.com/2"}})
+	assertEqual(t, true, posted.Has(target))
+
+	var buf bytes.Buffer
+	err := WritePosted(&buf, posted)
+	assertEqual(t, nil, err)
+	// Targets aren't written, since they hold secrets
+	assertEqual(t, false, bytes.Contains(buf.Bytes(), []byte("XXXX")))
+	posted, err = ReadPosted(&buf)
+	assertEqual(t, nil, err)
+
+	filter := posted.Filter(target)
+	assertEqual(t, false, filter(FeedItem{ID: "1"}))
+	assertEqual(t, false, filter(FeedItem{Links: []string{"https://example.com/2"}}))
+	assertEqual(t, true, filter(FeedItem{ID: "3"}))
+	// Items are posted to each target once
+	assertEqual(t, true, posted.Filter("discord://discord.com/api/webhooks/123/token")(FeedItem{ID: "1"}))
+}
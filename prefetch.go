@@ -0,0 +1,114 @@
+package rss
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// prefetchConcurrency is the number of pages fetched at once when
+// prefetching.
+const prefetchConcurrency = 3
+
+// Articles keeps the text of pages fetched by the browser on disk, so that
+// they open instantly and can be read offline.
+type Articles struct {
+	dir string
+}
+
+type article struct {
+	Link      string     `json:"link"`
+	Canonical string     `json:"canonical,omitempty"`
+	Text      string     `json:"text"`
+	Links     []PageLink `json:"links,omitempty"`
+}
+
+// NewArticles returns the articles kept in the directory, which is created if
+// need be. Articles older than maxAge are removed.
+func NewArticles(dir string, maxAge time.Duration) (*Articles, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && time.Since(info.ModTime()) > maxAge {
+			os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return &Articles{dir: dir}, nil
+}
+
+func (a *Articles) path(link string) string {
+	sum := sha256.Sum256([]byte(link))
+	return filepath.Join(a.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// Get returns the page kept for the link.
+func (a *Articles) Get(link string) (*Page, bool) {
+	b, err := os.ReadFile(a.path(link))
+	if err != nil {
+		return nil, false
+	}
+	var art article
+	if json.Unmarshal(b, &art) != nil || art.Link != link {
+		return nil, false
+	}
+	return &Page{Buffer: bytes.NewBufferString(art.Text), Canonical: art.Canonical, Links: art.Links}, true
+}
+
+// Has returns true if a page is kept for the link.
+func (a *Articles) Has(link string) bool {
+	_, err := os.Stat(a.path(link))
+	return err == nil
+}
+
+// Put keeps the page fetched from the link, without reading it.
+func (a *Articles) Put(link string, page *Page) error {
+	b, err := json.Marshal(article{Link: link, Canonical: page.Canonical, Text: page.String(), Links: page.Links})
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(a.path(link), b, 0644)
+}
+
+// Prefetch fetches the pages of the links which aren't already kept in the
+// articles, a few at a time, and keeps them. Links which fail to fetch are
+// skipped. Returns the number of pages fetched.
+func Prefetch(links []string, articles *Articles, fetch func(string) (*Page, error)) int {
+	sem := make(chan struct{}, prefetchConcurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var fetched int
+	for _, link := range links {
+		if articles.Has(link) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(link string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			page, err := fetch(link)
+			if err != nil || page == nil {
+				return
+			}
+			if articles.Put(link, page) == nil {
+				mu.Lock()
+				fetched++
+				mu.Unlock()
+			}
+		}(link)
+	}
+	wg.Wait()
+	return fetched
+}
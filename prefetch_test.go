@@ -0,0 +1,86 @@
+package rss
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestArticles(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	articles, err := NewArticles(dir, time.Hour)
+	assertEqual(t, nil, err)
+
+	_, found := articles.Get("https://example.com/a")
+	assertEqual(t, false, found)
+	links := []PageLink{{Text: "Go", URL: "https://go.dev"}}
+	page := &Page{Buffer: bytes.NewBufferString("\tHello\n"), Canonical: "https://example.com/canonical", Links: links}
+	err = articles.Put("https://example.com/a", page)
+	assertEqual(t, nil, err)
+	// Keeping a page doesn't read it
+	assertEqual(t, "\tHello\n", page.String())
+
+	kept, found := articles.Get("https://example.com/a")
+	assertEqual(t, true, found)
+	assertEqual(t, "\tHello\n", kept.String())
+	assertEqual(t, "https://example.com/canonical", kept.Canonical)
+	assertEqual(t, links, kept.Links)
+	assertEqual(t, true, articles.Has("https://example.com/a"))
+
+	// Old articles are removed when the articles are opened
+	old := time.Now().Add(-2 * time.Hour)
+	err = os.Chtimes(articles.path("https://example.com/a"), old, old)
+	assertEqual(t, nil, err)
+	articles, err = NewArticles(dir, time.Hour)
+	assertEqual(t, nil, err)
+	assertEqual(t, false, articles.Has("https://example.com/a"))
+	entries, err := os.ReadDir(dir)
+	assertEqual(t, nil, err)
+	assertEqual(t, 0, len(entries))
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+	articles, err := NewArticles(filepath.Join(t.TempDir(), "articles"), time.Hour)
+	assertEqual(t, nil, err)
+	err = articles.Put("https://example.com/kept", &Page{Buffer: bytes.NewBufferString("kept")})
+	assertEqual(t, nil, err)
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	var fetched []string
+	fetch := func(link string) (*Page, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		fetched = append(fetched, link)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if link == "https://example.com/broken" {
+			return nil, errors.New("broken")
+		}
+		return &Page{Buffer: bytes.NewBufferString("text of " + link)}, nil
+	}
+	links := []string{"https://example.com/kept", "https://example.com/broken"}
+	for _, c := range "abcdefgh" {
+		links = append(links, "https://example.com/"+string(c))
+	}
+	n := Prefetch(links, articles, fetch)
+	assertEqual(t, 8, n)
+	assertEqual(t, 9, len(fetched))
+	assertEqual(t, true, maxRunning <= prefetchConcurrency)
+	page, found := articles.Get("https://example.com/h")
+	assertEqual(t, true, found)
+	assertEqual(t, "text of https://example.com/h", page.String())
+	assertEqual(t, false, articles.Has("https://example.com/broken"))
+}
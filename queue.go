@@ -0,0 +1,79 @@
+package rss
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// QueueEntry records an item being added to the reading queue, or popped from
+// it once it has been read.
+type QueueEntry struct {
+	HistoryEntry
+	Popped bool `json:"popped,omitempty"`
+}
+
+// WriteQueueEntry appends the entry to w as a single line.
+func WriteQueueEntry(w io.Writer, entry QueueEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadQueue reads the entries from r and returns the items still in the queue,
+// in the order they were added. Items are identified by their link, and adding
+// an item already in the queue leaves it where it is.
+func ReadQueue(r io.Reader) ([]HistoryEntry, error) {
+	var queue []HistoryEntry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry QueueEntry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, err
+		}
+		i := queueIndex(queue, entry.Link)
+		switch {
+		case entry.Popped && i >= 0:
+			queue = append(queue[:i], queue[i+1:]...)
+		case !entry.Popped && i < 0:
+			queue = append(queue, entry.HistoryEntry)
+		}
+	}
+	return queue, scanner.Err()
+}
+
+func queueIndex(queue []HistoryEntry, link string) int {
+	for i, entry := range queue {
+		if entry.Link == link {
+			return i
+		}
+	}
+	return -1
+}
+
+// QueueFeed returns a feed of the queued items, in order, for reading them in
+// the app.
+func QueueFeed(queue []HistoryEntry) *Feed {
+	feed := &Feed{URL: "queue", Name: "Queue"}
+	for _, entry := range queue {
+		published := entry.Published
+		if published.IsZero() {
+			published = entry.Time
+		}
+		feed.Channel.Items = append(feed.Channel.Items, Item{
+			Title:   entry.Title,
+			Link:    entry.Link,
+			PubDate: published.Format(time.RFC1123Z),
+		})
+	}
+	return feed
+}
@@ -0,0 +1,48 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestReadQueue(t *testing.T) {
+	entry := func(link string, popped bool) QueueEntry {
+		return QueueEntry{HistoryEntry: HistoryEntry{Title: link, Link: "https://example.com/" + link}, Popped: popped}
+	}
+	var buf bytes.Buffer
+	for _, e := range []QueueEntry{
+		entry("1", false),
+		entry("2", false),
+		entry("3", false),
+		entry("1", false),
+		entry("2", true),
+		entry("4", false),
+		entry("2", false),
+	} {
+		err := WriteQueueEntry(&buf, e)
+		assertEqual(t, nil, err)
+	}
+
+	queue, err := ReadQueue(&buf)
+	assertEqual(t, nil, err)
+	var titles []string
+	for _, e := range queue {
+		titles = append(titles, e.Title)
+	}
+	assertEqual(t, []string{"1", "3", "4", "2"}, titles)
+}
+
+func TestQueueFeed(t *testing.T) {
+	published := time.Date(2022, 11, 1, 9, 0, 0, 0, time.UTC)
+	queue := []HistoryEntry{
+		{Title: "Old", Link: "https://example.com/old", Published: published},
+		{Title: "New", Link: "https://example.com/new", Published: published.Add(time.Hour)},
+	}
+	feedItems := UnpackFeed(QueueFeed(queue))
+	assertEqual(t, 2, len(feedItems))
+	// The queue keeps its order rather than being sorted by date
+	assertEqual(t, "Old", feedItems[0].Title)
+	assertEqual(t, true, published.Equal(feedItems[0].PublishTime))
+	assertEqual(t, []string{"https://example.com/new"}, feedItems[1].Links)
+}
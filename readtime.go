@@ -0,0 +1,49 @@
+package rss
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const wordsPerMinute = 200
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// estimateReadTime estimates how long it takes to read the item from the
+// number of words in its content, falling back to its description.
+func estimateReadTime(item Item) time.Duration {
+	body := item.Content
+	if len(body) == 0 {
+		body = item.Description
+	}
+	text := html.UnescapeString(htmlTag.ReplaceAllString(string(body), " "))
+	words := len(strings.Fields(text))
+	if words == 0 {
+		return 0
+	}
+	minutes := (words + wordsPerMinute - 1) / wordsPerMinute
+	return time.Duration(minutes) * time.Minute
+}
+
+// ShowReadTime appends the estimated read time to the titles of items which
+// have one e.g. "Title (~7 min)".
+func ShowReadTime() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		if item.ReadTime == 0 {
+			return item
+		}
+		item.Title = fmt.Sprintf("%s (~%d min)", item.Title, int(item.ReadTime.Minutes()))
+		return item
+	}
+}
+
+// MaxReadTime filters out items which are estimated to take longer than d to
+// read. Items without a read time estimate are kept.
+func MaxReadTime(d time.Duration) Filter {
+	return func(item FeedItem) bool {
+		return item.ReadTime <= d
+	}
+}
@@ -0,0 +1,43 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateReadTime(t *testing.T) {
+	testcases := []struct {
+		name     string
+		item     Item
+		expected time.Duration
+	}{
+		{
+			name:     "No content",
+			item:     Item{},
+			expected: 0,
+		},
+		{
+			name:     "Short description rounds up to a minute",
+			item:     Item{Description: []byte("<p>A <b>short</b> description</p>")},
+			expected: time.Minute,
+		},
+		{
+			name: "Content preferred over description",
+			item: Item{
+				Description: []byte("short"),
+				Content:     []byte(strings.Repeat("word ", 401)),
+			},
+			expected: 3 * time.Minute,
+		},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			assertEqual(t, tc.expected, estimateReadTime(tc.item))
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package rss
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// minKeywordLength excludes short, common words such as "the" from keywords.
+const minKeywordLength = 4
+
+// Recommend scores items by how likely they are to be read, based on the
+// history of opened items. Feeds are treated as the arms of a bandit: feeds
+// which are read often score highly, but feeds which have rarely been read are
+// given a bonus so that they are still explored. Items sharing keywords with
+// titles which have been read score more highly. The score is added to the
+// item's Score, so the items can be ordered with ByScore.
+func Recommend(history []HistoryEntry) DisplayOption {
+	feedReads := make(map[string]int)
+	keywordReads := make(map[string]int)
+	for _, entry := range history {
+		feedReads[entry.Feed]++
+		for _, keyword := range keywords(entry.Title) {
+			keywordReads[keyword]++
+		}
+	}
+	total := float64(len(history))
+
+	return func(item FeedItem) FeedItem {
+		reads := float64(feedReads[item.Source()])
+		var score float64
+		if total > 0 {
+			// Upper confidence bound of the feed's share of reads
+			score = reads/total + math.Sqrt(2*math.Log(total+1)/(reads+1))
+		}
+		words := keywords(item.Title)
+		if len(words) > 0 && total > 0 {
+			var affinity float64
+			for _, word := range words {
+				affinity += float64(keywordReads[word]) / total
+			}
+			score += affinity / float64(len(words))
+		}
+		item.Score += int(math.Round(score * 100))
+		return item
+	}
+}
+
+// Unread filters out items which appear in the history.
+func Unread(history []HistoryEntry) Filter {
+	read := make(map[string]struct{})
+	for _, entry := range history {
+		if entry.ID != "" {
+			read[entry.ID] = struct{}{}
+		}
+		read[entry.Link] = struct{}{}
+		if entry.Canonical != "" {
+			read[entry.Canonical] = struct{}{}
+		}
+	}
+	return func(item FeedItem) bool {
+		if _, found := read[item.ID]; found && item.ID != "" {
+			return false
+		}
+		for _, link := range item.Links {
+			if _, found := read[link]; found {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// keywords returns the distinct lowercase words in the title which are long
+// enough to be meaningful.
+func keywords(title string) []string {
+	seen := make(map[string]struct{})
+	var result []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(word)) < minKeywordLength {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		result = append(result, word)
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package rss
+
+import "testing"
+
+func TestRecommend(t *testing.T) {
+	history := []HistoryEntry{
+		{Feed: "Go", Title: "Generics in practice", Link: "https://go.dev/1"},
+		{Feed: "Go", Title: "Faster generics", Link: "https://go.dev/2"},
+		{Feed: "Go", Title: "Fuzzing", Link: "https://go.dev/3"},
+		{Feed: "News", Title: "Elections", Link: "https://news.com/1"},
+	}
+	recommend := Recommend(history)
+
+	favourite := recommend(FeedItem{Feed: "Go", Title: "Generics and fuzzing"})
+	other := recommend(FeedItem{Feed: "News", Title: "Weather"})
+	assertEqual(t, true, favourite.Score > other.Score)
+
+	// Feeds which have never been read get an exploration bonus
+	unexplored := recommend(FeedItem{Feed: "New", Title: "Weather"})
+	assertEqual(t, true, unexplored.Score > other.Score)
+}
+
+func TestUnread(t *testing.T) {
+	unread := Unread([]HistoryEntry{
+		{ID: "1", Link: "https://example.com/1"},
+		{Link: "https://example.com/2"},
+		{Link: "https://example.com/amp/4", Canonical: "https://example.com/4"},
+	})
+	assertEqual(t, false, unread(FeedItem{ID: "1", Links: []string{"https://example.com/other"}}))
+	assertEqual(t, false, unread(FeedItem{Links: []string{"https://example.com/2"}}))
+	assertEqual(t, true, unread(FeedItem{ID: "3", Links: []string{"https://example.com/3"}}))
+	assertEqual(t, false, unread(FeedItem{ID: "4", Links: []string{"https://example.com/4"}}))
+}
@@ -0,0 +1,93 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var redditAPI = "https://www.reddit.com/"
+
+type redditListing struct {
+	Data struct {
+		Children []struct {
+			Data redditPost `json:"data"`
+		} `json:"children"`
+	} `json:"data"`
+}
+
+type redditPost struct {
+	Title        string  `json:"title"`
+	URL          string  `json:"url"`
+	Permalink    string  `json:"permalink"`
+	Score        int     `json:"score"`
+	NumComments  int     `json:"num_comments"`
+	CreatedUTC   float64 `json:"created_utc"`
+	SelftextHTML string  `json:"selftext_html"`
+	Stickied     bool    `json:"stickied"`
+}
+
+// fetchReddit fetches posts from a subreddit using Reddit's JSON API, which
+// gives their points and number of comments unlike its RSS feed. URLs name
+// the subreddit e.g. "reddit://golang?points=50", with "sort" one of hot,
+// new, top or rising and "t" the period of top posts e.g. "week". Stickied
+// posts are left out.
+func fetchReddit(ctx context.Context, u *url.URL, options fetchOptions) ([]RSS, error) {
+	subreddit := u.Host
+	if subreddit == "" {
+		return nil, fmt.Errorf("no subreddit in %s", u)
+	}
+	query := u.Query()
+	minPoints, err := queryInt(query, "points", 0)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := queryInt(query, "limit", 25)
+	if err != nil {
+		return nil, err
+	}
+	sort := query.Get("sort")
+	if sort == "" {
+		sort = "hot"
+	}
+	params := url.Values{"limit": {fmt.Sprint(limit)}}
+	if t := query.Get("t"); t != "" {
+		params.Set("t", t)
+	}
+	var listing redditListing
+	err = getJSON(ctx, fmt.Sprintf("%sr/%s/%s.json?%s", redditAPI, subreddit, sort, params.Encode()), &listing)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := Channel{Title: "r/" + subreddit, Link: "https://www.reddit.com/r/" + subreddit}
+	for _, child := range listing.Data.Children {
+		post := child.Data
+		if post.Stickied || post.Score < minPoints {
+			continue
+		}
+		channel.Items = append(channel.Items, post.item())
+	}
+	return []RSS{{Channel: limitItems(channel, options)}}, nil
+}
+
+func (post redditPost) item() Item {
+	discussion := "https://www.reddit.com" + post.Permalink
+	link := post.URL
+	if link == "" || strings.HasPrefix(link, "/r/") {
+		link = discussion
+	}
+	return Item{
+		Title:        post.Title,
+		Link:         link,
+		PubDate:      time.Unix(int64(post.CreatedUTC), 0).UTC().Format(time.RFC1123Z),
+		GUID:         discussion,
+		Comments:     discussion,
+		Description:  []byte(html.UnescapeString(post.SelftextHTML)),
+		Points:       post.Score,
+		CommentCount: post.NumComments,
+	}
+}
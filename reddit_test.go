@@ -0,0 +1,69 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchReddit(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/r/golang/top.json" || r.Header.Get("User-Agent") != userAgent {
+			http.NotFound(w, r)
+			return
+		}
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"data": {"children": [
+			{"data": {"title": "Rules", "url": "https://www.reddit.com/r/golang/comments/1/rules/", "permalink": "/r/golang/comments/1/rules/", "score": 500, "created_utc": 1667293200.0, "stickied": true}},
+			{"data": {"title": "Popular", "url": "https://example.com/popular", "permalink": "/r/golang/comments/2/popular/", "score": 120, "num_comments": 30, "created_utc": 1667293200.0}},
+			{"data": {"title": "Unpopular", "url": "https://example.com/unpopular", "permalink": "/r/golang/comments/3/unpopular/", "score": 3, "created_utc": 1667293200.0}},
+			{"data": {"title": "Question", "url": "/r/golang/comments/4/question/", "permalink": "/r/golang/comments/4/question/", "score": 60, "num_comments": 8, "created_utc": 1667293200.0, "selftext_html": "&lt;p&gt;Why?&lt;/p&gt;"}}
+		]}}`)
+	}))
+	defer server.Close()
+	defer func(api string) { redditAPI = api }(redditAPI)
+	redditAPI = server.URL + "/"
+
+	feeds, err := FetchFeeds("reddit://golang?points=50&sort=top&t=week&limit=10")
+	assertEqual(t, nil, err)
+	assertEqual(t, "limit=10&t=week", query)
+	assertEqual(t, 1, len(feeds))
+	assertEqual(t, "r/golang", feeds[0].Title())
+	feedItems := UnpackFeed(feeds[0])
+	assertEqual(t, 2, len(feedItems))
+	assertEqual(t, "Popular", feedItems[0].Title)
+	assertEqual(t, []string{"https://example.com/popular", "https://www.reddit.com/r/golang/comments/2/popular/"}, feedItems[0].Links)
+	assertEqual(t, 120, feedItems[0].Points)
+	assertEqual(t, 30, feedItems[0].Comments)
+	assertEqual(t, "https://www.reddit.com/r/golang/comments/4/question/", feedItems[1].Links[0])
+	assertEqual(t, "<p>Why?</p>", string(feeds[0].Channel.Items[1].Description))
+
+	_, err = FetchFeeds("reddit://golang")
+	assertEqual(t, true, err != nil)
+	_, err = FetchFeeds("reddit://")
+	assertEqual(t, true, err != nil)
+}
+
+// FuzzRedditListing checks that no response from Reddit crashes turning its
+// posts into items.
+func FuzzRedditListing(f *testing.F) {
+	f.Add([]byte(`{"data": {"children": [{"data": {"title": "Popular", "url": "https://example.com/popular", "permalink": "/r/golang/comments/2/popular/", "score": 120, "num_comments": 30, "created_utc": 1667293200.0}}]}}`))
+	f.Add([]byte(`{"data": {"children": [{"data": {"url": "/r/golang/comments/3/", "selftext_html": "&lt;p&gt;Text&lt;/p&gt;", "created_utc": -1e300}}]}}`))
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var listing redditListing
+		if json.Unmarshal(b, &listing) != nil {
+			return
+		}
+		feed := &Feed{URL: "reddit://r/golang", RSS: RSS{Channel: Channel{Title: "r/golang"}}}
+		for _, child := range listing.Data.Children {
+			feed.Channel.Items = append(feed.Channel.Items, child.Data.item())
+		}
+		newFeedItem := newFeedItemCreator(feed)
+		for _, item := range feed.Channel.Items {
+			newFeedItem(item)
+		}
+	})
+}
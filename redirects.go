@@ -0,0 +1,133 @@
+package rss
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultRedirectors are the hosts of link shorteners and trackers whose links
+// are resolved by a LinkResolver.
+var DefaultRedirectors = []string{
+	"feedproxy.google.com",
+	"feeds.feedburner.com",
+	"ad.doubleclick.net",
+	"t.co",
+	"bit.ly",
+	"buff.ly",
+	"ow.ly",
+	"lnkd.in",
+	"trib.al",
+}
+
+// RedirectOptions configures the resolution of links through redirects.
+type RedirectOptions struct {
+	// Resolve replaces links which redirect with where they end up.
+	Resolve bool `yaml:"resolve"`
+	// Hosts are resolved in addition to DefaultRedirectors.
+	Hosts []string `yaml:"hosts"`
+}
+
+// LinkResolver replaces links through redirectors with the links they end
+// up at, caching the results.
+type LinkResolver struct {
+	hosts   []string
+	mu      sync.Mutex
+	cache   map[string]string
+	changed bool
+}
+
+// NewLinkResolver returns a resolver for links on the given hosts, including
+// their subdomains.
+func NewLinkResolver(hosts []string) *LinkResolver {
+	return &LinkResolver{hosts: hosts, cache: make(map[string]string)}
+}
+
+// ResolveLinks resolves the links of items with the resolver as they are
+// unpacked.
+func ResolveLinks(r *LinkResolver) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.resolver = r
+	}
+}
+
+// Resolve returns where the link ends up if it is on one of the resolver's
+// hosts, otherwise the link itself. Links which can't be resolved are kept.
+func (r *LinkResolver) Resolve(link string) string {
+	if r == nil || !r.redirects(link) {
+		return link
+	}
+	r.mu.Lock()
+	resolved, found := r.cache[link]
+	r.mu.Unlock()
+	if found {
+		return resolved
+	}
+	resolved, err := ResolveURL(link)
+	if err != nil {
+		return link
+	}
+	r.mu.Lock()
+	r.cache[link] = resolved
+	r.changed = true
+	r.mu.Unlock()
+	return resolved
+}
+
+func (r *LinkResolver) redirects(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range r.hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// Load adds the resolved links in r, as written by Save, to the cache.
+func (r *LinkResolver) Load(rd io.Reader) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		from, to, found := strings.Cut(scanner.Text(), "\t")
+		if found {
+			r.cache[from] = to
+		}
+	}
+	return scanner.Err()
+}
+
+// Changed returns true if links have been resolved since the cache was
+// loaded.
+func (r *LinkResolver) Changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changed
+}
+
+// Save writes the cache to w, a link and where it ends up on each line.
+func (r *LinkResolver) Save(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	links := make([]string, 0, len(r.cache))
+	for link := range r.cache {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	bw := bufio.NewWriter(w)
+	for _, link := range links {
+		_, err := bw.WriteString(link + "\t" + r.cache[link] + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,45 @@
+package rss
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestLinkResolver(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/proxy" {
+			http.Redirect(w, r, "/post", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := NewLinkResolver([]string{u.Hostname()})
+	assertEqual(t, server.URL+"/post", resolver.Resolve(server.URL+"/proxy"))
+	// The second resolution is cached
+	assertEqual(t, server.URL+"/post", resolver.Resolve(server.URL+"/proxy"))
+	assertEqual(t, 2, requests)
+	assertEqual(t, true, resolver.Changed())
+	// Links on other hosts are left alone
+	assertEqual(t, "https://example.com/proxy", resolver.Resolve("https://example.com/proxy"))
+
+	var buf bytes.Buffer
+	err = resolver.Save(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, server.URL+"/proxy\t"+server.URL+"/post\n", buf.String())
+
+	loaded := NewLinkResolver([]string{u.Hostname()})
+	err = loaded.Load(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, server.URL+"/post", loaded.Resolve(server.URL+"/proxy"))
+	assertEqual(t, 2, requests)
+	assertEqual(t, false, loaded.Changed())
+}
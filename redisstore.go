@@ -0,0 +1,291 @@
+package rss
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+)
+
+// redisTimeout is how long connecting to Redis, and each command, may take.
+const redisTimeout = 10 * time.Second
+
+// RedisStore is the Store which keeps the history, stars and other state in
+// Redis, so that several machines reading the same feeds see each other's
+// state as soon as it is recorded. Each record is pushed onto a list of its
+// own, so records are appended atomically. Feeds are saved in another store.
+type RedisStore struct {
+	Store
+	client   *redisClient
+	prefix   string
+	identity *age.X25519Identity
+}
+
+type RedisOption func(*RedisStore)
+
+// WithRedisPrefix puts the prefix before the name of each key, instead of
+// "rss:", so that the database can be shared.
+func WithRedisPrefix(prefix string) RedisOption {
+	return func(rs *RedisStore) {
+		rs.prefix = prefix
+	}
+}
+
+// WithRedisIdentity encrypts each record of the state to the identity, as
+// WithIdentity does for files.
+func WithRedisIdentity(identity *age.X25519Identity) RedisOption {
+	return func(rs *RedisStore) {
+		rs.identity = identity
+	}
+}
+
+// NewRedisStore returns the store keeping state in the Redis at the URL e.g.
+// "redis://:password@localhost:6379/0", or "rediss://" for TLS. Feeds are
+// saved in feeds.
+func NewRedisStore(rawURL string, feeds Store, opts ...RedisOption) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" && u.Scheme != "rediss" {
+		return nil, fmt.Errorf("%s isn't a redis or rediss URL", rawURL)
+	}
+	client := &redisClient{addr: u.Host, tls: u.Scheme == "rediss"}
+	if u.Port() == "" {
+		client.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		client.username = u.User.Username()
+		client.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		client.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("database %q in %s isn't a number", db, rawURL)
+		}
+	}
+	rs := &RedisStore{Store: feeds, client: client, prefix: "rss:"}
+	for _, o := range opts {
+		o(rs)
+	}
+	return rs, nil
+}
+
+// String returns where the feeds are saved, since they aren't kept in Redis.
+func (rs *RedisStore) String() string {
+	return fmt.Sprint(rs.Store)
+}
+
+func (rs *RedisStore) ReadState(name string) (io.Reader, error) {
+	reply, err := rs.client.do("LRANGE", rs.prefix+name, "0", "-1")
+	if err != nil {
+		return nil, err
+	}
+	records, _ := reply.([]interface{})
+	var b bytes.Buffer
+	for _, record := range records {
+		s, _ := record.(string)
+		b.WriteString(s)
+	}
+	if rs.identity == nil {
+		return &b, nil
+	}
+	return DecryptLines(&b, rs.identity)
+}
+
+func (rs *RedisStore) AppendState(name string) (io.WriteCloser, error) {
+	w := redisAppender{client: rs.client, key: rs.prefix + name}
+	if rs.identity == nil {
+		return w, nil
+	}
+	return struct {
+		io.Writer
+		io.Closer
+	}{EncryptLines(w, rs.identity.Recipient()), w}, nil
+}
+
+func (rs *RedisStore) Stars() ([]Star, error) {
+	r, err := rs.ReadState(StarsState)
+	if err != nil {
+		return nil, err
+	}
+	return ReadStars(r)
+}
+
+// Close closes the connection to Redis.
+func (rs *RedisStore) Close() error {
+	return rs.client.close()
+}
+
+// redisAppender pushes each write onto a list, so each write should be a
+// whole record.
+type redisAppender struct {
+	client *redisClient
+	key    string
+}
+
+func (a redisAppender) Write(p []byte) (int, error) {
+	_, err := a.client.do("RPUSH", a.key, string(p))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (a redisAppender) Close() error {
+	return nil
+}
+
+// RedisError is an error answered by Redis.
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// redisClient sends commands to Redis one at a time over a single connection,
+// which is made when it is first needed and again after it fails.
+type redisClient struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      bool
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// do sends the command and returns its reply, which is a string, an int64, a
+// slice of replies or nil.
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		err := c.connect()
+		if err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.command(args...)
+	var redisErr RedisError
+	if err != nil && !errors.As(err, &redisErr) {
+		// The connection can't be trusted after a network error
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+func (c *redisClient) connect() error {
+	dialer := &net.Dialer{Timeout: redisTimeout}
+	var conn net.Conn
+	var err error
+	if c.tls {
+		host, _, _ := net.SplitHostPort(c.addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", c.addr)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.username != "" {
+			args = []string{"AUTH", c.username, c.password}
+		}
+		_, err = c.command(args...)
+	}
+	if err == nil && c.db != 0 {
+		_, err = c.command("SELECT", strconv.Itoa(c.db))
+	}
+	if err != nil {
+		conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+func (c *redisClient) command(args ...string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(redisTimeout))
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := c.conn.Write(b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return readRedisReply(c.r)
+}
+
+// readRedisReply reads a reply in the Redis serialization protocol.
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		b := make([]byte, n+2)
+		_, err = io.ReadFull(r, b)
+		if err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		replies := make([]interface{}, n)
+		for i := range replies {
+			replies[i], err = readRedisReply(r)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
+
+func (c *redisClient) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,162 @@
+package rss
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+)
+
+// fakeRedis answers the commands used by RedisStore, keeping lists in memory.
+// Connections must authenticate with the password and select database 2.
+type fakeRedis struct {
+	mu    sync.Mutex
+	lists map[string][]string
+}
+
+func (f *fakeRedis) serve(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var authed bool
+	for {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		f.mu.Lock()
+		switch {
+		case args[0] == "AUTH" && args[len(args)-1] == "secret":
+			authed = true
+			fmt.Fprint(conn, "+OK\r\n")
+		case !authed:
+			fmt.Fprint(conn, "-NOAUTH Authentication required.\r\n")
+		case args[0] == "SELECT" && args[1] == "2":
+			fmt.Fprint(conn, "+OK\r\n")
+		case args[0] == "RPUSH":
+			f.lists[args[1]] = append(f.lists[args[1]], args[2:]...)
+			fmt.Fprintf(conn, ":%d\r\n", len(f.lists[args[1]]))
+		case args[0] == "LRANGE":
+			list := f.lists[args[1]]
+			fmt.Fprintf(conn, "*%d\r\n", len(list))
+			for _, item := range list {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(item), item)
+			}
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+func TestRedisStoreState(t *testing.T) {
+	t.Parallel()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, identity := range map[string]*age.X25519Identity{"plain": nil, "encrypted": identity} {
+		identity := identity
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			fake := &fakeRedis{lists: make(map[string][]string)}
+			addr := fake.serve(t)
+			feeds := NewFileStore(t.TempDir())
+			store, err := NewRedisStore("redis://:secret@"+addr+"/2", feeds, WithRedisIdentity(identity))
+			assertEqual(t, nil, err)
+			defer store.Close()
+
+			stars, err := store.Stars()
+			assertEqual(t, nil, err)
+			assertEqual(t, 0, len(stars))
+
+			w, err := store.AppendState(StarsState)
+			assertEqual(t, nil, err)
+			star := Star{HistoryEntry: HistoryEntry{ID: "1", Time: time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), Title: "One", Link: "https://example.com/1"}}
+			assertEqual(t, nil, WriteStar(w, star))
+			assertEqual(t, nil, w.Close())
+
+			// Another machine sees the star straight away
+			other, err := NewRedisStore("redis://:secret@"+addr+"/2", feeds, WithRedisIdentity(identity))
+			assertEqual(t, nil, err)
+			defer other.Close()
+			stars, err = other.Stars()
+			assertEqual(t, nil, err)
+			assertEqual(t, []Star{star}, stars)
+
+			fake.mu.Lock()
+			record := fake.lists["rss:stars"][0]
+			fake.mu.Unlock()
+			assertEqual(t, identity == nil, strings.Contains(record, "https://example.com/1"))
+
+			// Feeds are saved in the other store
+			assertEqual(t, nil, store.SaveFeed(&Feed{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Blog"}}}))
+			loaded, err := feeds.LoadFeeds()
+			assertEqual(t, nil, err)
+			assertEqual(t, 1, len(loaded))
+		})
+	}
+}
+
+func TestRedisStoreErrors(t *testing.T) {
+	t.Parallel()
+	fake := &fakeRedis{lists: make(map[string][]string)}
+	addr := fake.serve(t)
+	store, err := NewRedisStore("redis://:wrong@"+addr, NewFileStore(t.TempDir()))
+	assertEqual(t, nil, err)
+	_, err = store.ReadState(HistoryState)
+	assertEqual(t, RedisError("NOAUTH Authentication required."), err)
+
+	_, err = NewRedisStore("http://"+addr, nil)
+	assertEqual(t, true, err != nil)
+}
+
+func TestReadRedisReply(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		reply    string
+		expected interface{}
+	}{
+		{"+OK\r\n", "OK"},
+		{":42\r\n", int64(42)},
+		{"$5\r\nhello\r\n", "hello"},
+		{"$-1\r\n", nil},
+		{"*2\r\n$1\r\na\r\n:1\r\n", []interface{}{"a", int64(1)}},
+		{"*0\r\n", []interface{}{}},
+	}
+	for _, test := range tests {
+		reply, err := readRedisReply(bufio.NewReader(strings.NewReader(test.reply)))
+		assertEqual(t, nil, err)
+		assertEqual(t, test.expected, reply)
+	}
+	_, err := readRedisReply(bufio.NewReader(strings.NewReader("$5\r\nhel")))
+	assertEqual(t, io.ErrUnexpectedEOF, err)
+}
@@ -0,0 +1,252 @@
+package rss
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version found in the title of a release.
+type Version struct {
+	Major, Minor, Patch int
+	// Prerelease is the label of a prerelease e.g. "rc.1", empty for
+	// releases.
+	Prerelease string
+}
+
+// versionPattern matches versions e.g. "v1.2.3", "1.2", "2.0.0-rc.1" or
+// "1.4beta2".
+var versionPattern = regexp.MustCompile(`(?i)(?:^|[^\d.])(v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9a-z][0-9a-z.-]*)|((?:alpha|beta|rc|pre|dev)[0-9a-z.]*))?)\b`)
+
+// FindVersion returns the first version in the text e.g. the title of a
+// release, and the text with the version removed.
+func FindVersion(text string) (Version, string, bool) {
+	m := versionPattern.FindStringSubmatchIndex(text)
+	if m == nil {
+		return Version{}, text, false
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return text[m[2*i]:m[2*i+1]]
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(group(2))
+	v.Minor, _ = strconv.Atoi(group(3))
+	v.Patch, _ = strconv.Atoi(group(4))
+	v.Prerelease = group(5) + group(6)
+	return v, text[:m[2]] + text[m[3]:], true
+}
+
+// ParseVersion parses a version e.g. "v1.2.3". Missing minor and patch
+// versions are zero.
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(s), "v")); err == nil && n >= 0 {
+		return Version{Major: n}, nil
+	}
+	v, rest, found := FindVersion(s)
+	if !found || strings.TrimSpace(rest) != "" {
+		return Version{}, fmt.Errorf("invalid version %q", s)
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer than
+// other. Prereleases are older than the release they lead up to.
+func (v Version) Compare(other Version) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	case v.Prerelease < other.Prerelease:
+		return -1
+	}
+	return 1
+}
+
+// VersionConstraint reports whether a version is wanted.
+type VersionConstraint func(Version) bool
+
+// ParseVersionConstraint parses a constraint on versions made of comparisons
+// which must all hold, e.g. ">=1.2, <2", alternatives separated by "||", and
+// "^1.2" and "~1.2" for versions compatible with 1.2 and patches of 1.2. An
+// empty constraint allows any version.
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	if strings.TrimSpace(s) == "" {
+		return func(Version) bool { return true }, nil
+	}
+	var alternatives [][]VersionConstraint
+	for _, alternative := range strings.Split(s, "||") {
+		var all []VersionConstraint
+		for _, field := range strings.Fields(strings.ReplaceAll(alternative, ",", " ")) {
+			c, err := parseComparison(field)
+			if err != nil {
+				return nil, err
+			}
+			all = append(all, c)
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("empty alternative in version constraint %q", s)
+		}
+		alternatives = append(alternatives, all)
+	}
+	return func(v Version) bool {
+		for _, all := range alternatives {
+			matches := true
+			for _, c := range all {
+				matches = matches && c(v)
+			}
+			if matches {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func parseComparison(s string) (VersionConstraint, error) {
+	op := s
+	if i := strings.IndexAny(s, "v0123456789"); i >= 0 {
+		op = s[:i]
+	}
+	target, err := ParseVersion(s[len(op):])
+	if err != nil {
+		return nil, err
+	}
+	compare := func(v Version) int { return v.Compare(target) }
+	switch op {
+	case "", "=", "==":
+		return func(v Version) bool { return compare(v) == 0 }, nil
+	case "!=":
+		return func(v Version) bool { return compare(v) != 0 }, nil
+	case ">":
+		return func(v Version) bool { return compare(v) > 0 }, nil
+	case ">=":
+		return func(v Version) bool { return compare(v) >= 0 }, nil
+	case "<":
+		return func(v Version) bool { return compare(v) < 0 }, nil
+	case "<=":
+		return func(v Version) bool { return compare(v) <= 0 }, nil
+	case "^":
+		return func(v Version) bool {
+			if compare(v) < 0 || v.Major != target.Major {
+				return false
+			}
+			// Below 1.0 minor versions can break compatibility
+			return target.Major > 0 || v.Minor == target.Minor
+		}, nil
+	case "~":
+		return func(v Version) bool {
+			return compare(v) >= 0 && v.Major == target.Major && v.Minor == target.Minor
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q in version constraint", op)
+}
+
+// Project returns the name of the project the item is a release of: the
+// repository of releases from GitHub e.g. "AzinKhan/rss", otherwise where the
+// item came from.
+func (fi FeedItem) Project() string {
+	u, err := url.Parse(fi.Feed)
+	if err == nil && strings.TrimPrefix(u.Hostname(), "www.") == "github.com" {
+		parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if len(parts) >= 2 {
+			return parts[0] + "/" + parts[1]
+		}
+	}
+	return fi.Source()
+}
+
+// Releases groups the items by project, newest release first, with a title
+// card for each and the projects in alphabetical order. Each title shows the
+// version found in it followed by the rest of the title, and prereleases are
+// greyed out.
+func Releases(feedItems []FeedItem) []FeedItem {
+	byProject := make(map[string][]FeedItem)
+	for _, item := range feedItems {
+		project := item.Project()
+		byProject[project] = append(byProject[project], releaseTitle(item, project))
+	}
+	projects := make([]string, 0, len(byProject))
+	for project := range byProject {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+
+	result := make([]FeedItem, 0, len(feedItems)+2*len(projects))
+	for _, project := range projects {
+		result = append(result, FeedItem{}, FeedItem{Title: project})
+		result = append(result, ReverseChronological(byProject[project])...)
+	}
+	return result
+}
+
+// releaseTitle moves the version to the front of the item's title, leaving out
+// words which only name the release or project e.g. "rss v1.2.0".
+func releaseTitle(item FeedItem, project string) FeedItem {
+	v, rest, found := FindVersion(item.Title)
+	if !found {
+		return item
+	}
+	const separators = " \t-–—:|()[]"
+	rest = strings.Trim(rest, separators)
+	_, name, _ := strings.Cut(project, "/")
+	for _, word := range []string{"release", "version", project, name} {
+		if word == "" || len(rest) < len(word) || !strings.EqualFold(rest[:len(word)], word) {
+			continue
+		}
+		// Only whole words are left out
+		if after := rest[len(word):]; after == "" || strings.ContainsAny(after[:1], separators) {
+			rest = strings.Trim(after, separators)
+		}
+	}
+	item.Title = v.String()
+	if rest != "" {
+		item.Title += " — " + rest
+	}
+	if v.Prerelease != "" {
+		item.colour = gray
+	}
+	return item
+}
+
+// ReleaseNotify passes releases which aren't prereleases and whose version
+// meets the constraint for their feed, keyed by the feed's name or URL, or
+// else the fallback. Items without a version are not passed.
+func ReleaseNotify(fallback VersionConstraint, feeds map[string]VersionConstraint) Filter {
+	return func(item FeedItem) bool {
+		v, _, found := FindVersion(item.Title)
+		if !found || v.Prerelease != "" {
+			return false
+		}
+		constraint, found := feeds[item.Feed]
+		if !found {
+			constraint = fallback
+		}
+		return constraint(v)
+	}
+}
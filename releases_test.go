@@ -0,0 +1,102 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFindVersion(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		title   string
+		version Version
+		rest    string
+		found   bool
+	}{
+		{title: "v1.2.3", version: Version{1, 2, 3, ""}, rest: ""},
+		{title: "Release 2.0", version: Version{2, 0, 0, ""}, rest: "Release "},
+		{title: "rss v2.0.0-rc.1: faster fetching", version: Version{2, 0, 0, "rc.1"}, rest: "rss : faster fetching"},
+		{title: "go1.21.0", version: Version{1, 21, 0, ""}, rest: "go"},
+		{title: "1.4beta2", version: Version{1, 4, 0, "beta2"}, rest: ""},
+		{title: "Nothing to see here", rest: "Nothing to see here"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			version, rest, found := FindVersion(tc.title)
+			assertEqual(t, tc.version, version)
+			assertEqual(t, tc.rest, rest)
+			assertEqual(t, tc.version != Version{}, found)
+		})
+	}
+}
+
+func TestParseVersionConstraint(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		constraint string
+		allowed    []string
+		denied     []string
+	}{
+		{constraint: "", allowed: []string{"0.1.0", "3.0.0"}},
+		{constraint: ">=1.2, <2", allowed: []string{"1.2.0", "1.9.9"}, denied: []string{"1.1.9", "2.0.0", "1.2.0-rc.1"}},
+		{constraint: "^1.2", allowed: []string{"1.2.0", "1.8.0"}, denied: []string{"2.0.0", "1.1.0"}},
+		{constraint: "^0.3", allowed: []string{"0.3.5"}, denied: []string{"0.4.0"}},
+		{constraint: "~1.2.3", allowed: []string{"1.2.3", "1.2.9"}, denied: []string{"1.3.0"}},
+		{constraint: "1.0 || >=3", allowed: []string{"1.0.0", "3.1.0"}, denied: []string{"2.0.0"}},
+		{constraint: "!=v2.1.0", allowed: []string{"2.1.1"}, denied: []string{"2.1.0"}},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.constraint, func(t *testing.T) {
+			t.Parallel()
+			constraint, err := ParseVersionConstraint(tc.constraint)
+			assertEqual(t, nil, err)
+			for _, v := range tc.allowed {
+				version, err := ParseVersion(v)
+				assertEqual(t, nil, err)
+				assertEqual(t, true, constraint(version))
+			}
+			for _, v := range tc.denied {
+				version, err := ParseVersion(v)
+				assertEqual(t, nil, err)
+				assertEqual(t, false, constraint(version))
+			}
+		})
+	}
+	for _, invalid := range []string{">=one", "=>1.0", "1.0 ||", "1.0 extra"} {
+		_, err := ParseVersionConstraint(invalid)
+		assertEqual(t, true, err != nil)
+	}
+}
+
+func TestReleases(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	feed := "https://github.com/AzinKhan/rss/releases.atom"
+	feedItems := []FeedItem{
+		{Title: "v1.0.0", Feed: feed, Channel: "Release notes from rss", Links: []string{"a"}, PublishTime: now.Add(-time.Hour)},
+		{Title: "Changelog 3.1", Feed: "Tool", Links: []string{"b"}, PublishTime: now},
+		{Title: "rss v1.1.0-rc.1", Feed: feed, Channel: "Release notes from rss", Links: []string{"c"}, PublishTime: now},
+	}
+	releases := Releases(feedItems)
+	titles := make([]string, len(releases))
+	for i, item := range releases {
+		titles[i] = item.Title
+	}
+	assertEqual(t, []string{"", "AzinKhan/rss", "1.1.0-rc.1", "1.0.0", "", "Tool", "3.1.0 — Changelog"}, titles)
+	assertEqual(t, gray, releases[2].colour)
+	assertEqual(t, "1.0.0 — Released today", releaseTitle(FeedItem{Title: "1.0.0 Released today"}, "x").Title)
+	assertEqual(t, "2.0.0 — Faster fetching", releaseTitle(FeedItem{Title: "Release rss v2.0.0: Faster fetching"}, "AzinKhan/rss").Title)
+
+	atLeast1, err := ParseVersionConstraint(">=1")
+	assertEqual(t, nil, err)
+	below3, err := ParseVersionConstraint("<3")
+	assertEqual(t, nil, err)
+	notify := ReleaseNotify(atLeast1, map[string]VersionConstraint{"Tool": below3})
+	assertEqual(t, true, notify(feedItems[0]))
+	assertEqual(t, false, notify(feedItems[1]))
+	assertEqual(t, false, notify(feedItems[2]))
+	assertEqual(t, false, notify(FeedItem{Title: "No version", Feed: feed}))
+}
@@ -0,0 +1,123 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FetchRemote gets the items held by the daemon at the URL, run by 'rss serve'
+// on e.g. a home server, rather than fetching the feeds themselves. The items
+// are returned in a feed for each of their channels, so that they can be shown
+// as if they had been fetched. Items older than SkipOlderThan aren't asked
+// for, and names given with Rename apply to the URLs of the feeds.
+//
+// Descriptions aren't served by the daemon, so the read times of the items
+// aren't known.
+func FetchRemote(remote, token string, opts ...FetchOption) ([]*Feed, error) {
+	options := newFetchOptions(opts...)
+	parent := options.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	if !options.deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(parent, options.deadline)
+	}
+	defer cancel()
+	feedItems, err := fetchRemoteItems(ctx, remote, token, options.maxAge)
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("error getting %s: %w", remote, ErrTimedOut)
+	case errors.Is(ctx.Err(), context.Canceled):
+		err = fmt.Errorf("error getting %s: %w", remote, ErrCancelled)
+	}
+	options.report.record(err, false)
+	if err != nil {
+		return nil, err
+	}
+	return remoteFeeds(feedItems, options), nil
+}
+
+func fetchRemoteItems(ctx context.Context, remote, token string, maxAge time.Duration) ([]FeedItem, error) {
+	u, err := url.Parse(strings.TrimSuffix(remote, "/") + "/items")
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s: %s", remote, err.Error())
+	}
+	if maxAge > 0 {
+		u.RawQuery = url.Values{"since": {time.Now().Add(-maxAge).UTC().Format(time.RFC3339)}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s: %s", remote, err.Error())
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// The URL is already given, without the query
+		err = urlErr.Err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s: %s", remote, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("error getting %s: %s: %s", remote, resp.Status, strings.TrimSpace(string(message)))
+	}
+	var feedItems []FeedItem
+	err = json.NewDecoder(resp.Body).Decode(&feedItems)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding items from %s: %s", remote, err.Error())
+	}
+	return feedItems, nil
+}
+
+// remoteFeeds packs the items back into feeds, one for each channel, keeping
+// the order in which the channels were first found.
+func remoteFeeds(feedItems []FeedItem, options fetchOptions) []*Feed {
+	type key struct{ feed, channel string }
+	byChannel := make(map[key]*Feed)
+	var feeds []*Feed
+	for _, feedItem := range feedItems {
+		k := key{feedItem.Feed, feedItem.Channel}
+		feed, found := byChannel[k]
+		if !found {
+			feed = &Feed{
+				URL:            feedItem.Feed,
+				Name:           options.names[feedItem.Feed],
+				RSS:            RSS{Channel: Channel{Title: feedItem.Channel, Language: feedItem.Language}},
+				transformTitle: options.titles[feedItem.Feed],
+				future:         options.future,
+			}
+			byChannel[k] = feed
+			feeds = append(feeds, feed)
+		}
+		item := Item{
+			Title:        feedItem.Title,
+			PubDate:      feedItem.PublishTime.Format(time.RFC3339Nano),
+			CommentCount: feedItem.Comments,
+			Points:       feedItem.Points,
+			Creators:     feedItem.Authors,
+			id:           feedItem.ID,
+		}
+		if len(feedItem.Links) > 0 {
+			item.Link = feedItem.Links[0]
+		}
+		if len(feedItem.Links) > 1 {
+			item.Comments = feedItem.Links[1]
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	return feeds
+}
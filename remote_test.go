@@ -0,0 +1,64 @@
+package rss
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFetchRemote(t *testing.T) {
+	server, err := NewServer(nil, WithToken("secret"))
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	served := &Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Served", Items: []Item{
+		{Title: "New", Link: "https://example.com/1", Comments: "https://example.com/1#comments", GUID: "1", PubDate: now.Format(time.RFC1123Z), CommentCount: 3},
+		{Title: "Old", Link: "https://example.com/2", GUID: "2", PubDate: now.Add(-48 * time.Hour).Format(time.RFC1123Z)},
+	}}}}
+	server.ingest(served)
+	server.ingest(&Feed{URL: "https://example.org/rss", RSS: RSS{Channel: Channel{Title: "Other", Items: []Item{
+		{Title: "Elsewhere", Link: "https://example.org/1", PubDate: now.Add(-time.Minute).Format(time.RFC1123Z)},
+	}}}})
+
+	feeds, err := FetchRemote(api.URL, "secret", Rename(map[string]string{"https://example.com/rss": "Example"}))
+	assertEqual(t, nil, err)
+	assertEqual(t, 2, len(feeds))
+	assertEqual(t, "Example", feeds[0].Title())
+
+	fetched := UnpackFeed(feeds[0])
+	expected := UnpackFeed(served)
+	assertEqual(t, len(expected), len(fetched))
+	for i := range expected {
+		// Items keep their IDs so that the history still applies
+		assertEqual(t, expected[i].ID, fetched[i].ID)
+		assertEqual(t, expected[i].Title, fetched[i].Title)
+		assertEqual(t, expected[i].Links, fetched[i].Links)
+		assertEqual(t, expected[i].Comments, fetched[i].Comments)
+		assertEqual(t, true, expected[i].PublishTime.Equal(fetched[i].PublishTime))
+	}
+	assertEqual(t, "Example", fetched[0].Feed)
+
+	recent, err := FetchRemote(api.URL, "secret", SkipOlderThan(time.Hour))
+	assertEqual(t, nil, err)
+	var titles []string
+	for _, item := range GetFeedItems(recent) {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, []string{"New", "Elsewhere"}, titles)
+}
+
+func TestFetchRemoteErrors(t *testing.T) {
+	server, err := NewServer(nil, WithToken("secret"))
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+
+	var report FetchReport
+	_, err = FetchRemote(api.URL, "wrong", ReportTo(&report))
+	assertEqual(t, true, err != nil)
+	assertEqual(t, true, strings.Contains(err.Error(), "401"))
+	assertEqual(t, 1, report.Failed)
+}
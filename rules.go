@@ -0,0 +1,174 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Condition matches items. All of the fields which are set must match.
+type Condition struct {
+	// Title, Link and Feed are regular expressions matched against the
+	// item's title, links and feed. Feed matches either the feed's name or
+	// URL, or its channel's title.
+	Title string `yaml:"title"`
+	Link  string `yaml:"link"`
+	Feed  string `yaml:"feed"`
+	// OlderThan and NewerThan are durations e.g. "48h" compared against the
+	// item's age.
+	OlderThan string `yaml:"older_than"`
+	NewerThan string `yaml:"newer_than"`
+	// All, Any and Not combine nested conditions.
+	All []Condition `yaml:"all"`
+	Any []Condition `yaml:"any"`
+	Not *Condition  `yaml:"not"`
+}
+
+// Rule applies actions to the items matching its condition.
+type Rule struct {
+	When Condition `yaml:"when"`
+	// Hide removes matching items from the output.
+	Hide bool `yaml:"hide"`
+	// Highlight is the name of the colour to give matching items.
+	Highlight string `yaml:"highlight"`
+	// Tag is added to the tags of matching items.
+	Tag string `yaml:"tag"`
+	// Notify sends a notification for matching items.
+	Notify bool `yaml:"notify"`
+	// Score is added to the score of matching items.
+	Score int `yaml:"score"`
+}
+
+// CompiledRules holds the filters and display options built from rules.
+type CompiledRules struct {
+	// Filters hide items.
+	Filters []Filter
+	// Display highlights, tags and scores items.
+	Display []DisplayOption
+	// Notify passes the items which should be notified about.
+	Notify Filter
+}
+
+// CompileRules builds the filters and display options which implement the
+// rules. Returns an error if any rule is invalid.
+func CompileRules(rules []Rule) (CompiledRules, error) {
+	compiled := CompiledRules{}
+	var notify []Filter
+	for i, rule := range rules {
+		matches, err := rule.When.compile()
+		if err != nil {
+			return CompiledRules{}, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if rule.Hide {
+			compiled.Filters = append(compiled.Filters, Not(matches))
+		}
+		if rule.Notify {
+			notify = append(notify, matches)
+		}
+		var colour Colour
+		if rule.Highlight != "" {
+			colour, err = ParseColour(rule.Highlight)
+			if err != nil {
+				return CompiledRules{}, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+		}
+		if colour == "" && rule.Tag == "" && rule.Score == 0 {
+			continue
+		}
+		rule := rule
+		compiled.Display = append(compiled.Display, func(item FeedItem) FeedItem {
+			if !matches(item) {
+				return item
+			}
+			if colour != "" {
+				item.colour = colour
+			}
+			if rule.Tag != "" {
+				item.Tags = append(item.Tags, rule.Tag)
+			}
+			item.Score += rule.Score
+			return item
+		})
+	}
+	compiled.Notify = Or(notify...)
+	return compiled, nil
+}
+
+func (c Condition) compile() (Filter, error) {
+	var filters []Filter
+	for _, field := range []struct {
+		pattern string
+		values  func(FeedItem) []string
+	}{
+		{c.Title, func(item FeedItem) []string { return []string{item.Title} }},
+		{c.Link, func(item FeedItem) []string { return item.Links }},
+		{c.Feed, func(item FeedItem) []string { return []string{item.Feed, item.Channel} }},
+	} {
+		if field.pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(field.pattern)
+		if err != nil {
+			return nil, err
+		}
+		values := field.values
+		filters = append(filters, func(item FeedItem) bool {
+			for _, value := range values(item) {
+				if re.MatchString(value) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+
+	if c.OlderThan != "" {
+		age, err := time.ParseDuration(c.OlderThan)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, Not(OldestItem(age)))
+	}
+	if c.NewerThan != "" {
+		age, err := time.ParseDuration(c.NewerThan)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, OldestItem(age))
+	}
+
+	if len(c.All) > 0 {
+		allOf, err := compileConditions(c.All)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, And(allOf...))
+	}
+	if len(c.Any) > 0 {
+		anyOf, err := compileConditions(c.Any)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, Or(anyOf...))
+	}
+	if c.Not != nil {
+		not, err := c.Not.compile()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, Not(not))
+	}
+	return And(filters...), nil
+}
+
+func compileConditions(conditions []Condition) ([]Filter, error) {
+	filters := make([]Filter, 0, len(conditions))
+	for _, c := range conditions {
+		f, err := c.compile()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
@@ -0,0 +1,82 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompileRules(t *testing.T) {
+	raw := `
+rules:
+  - when:
+      link: /sponsored/
+    hide: true
+  - when:
+      any:
+        - title: (?i)\bgo\b
+        - feed: ^Go Blog$
+      not:
+        older_than: 48h
+    highlight: red
+    tag: golang
+    score: 5
+  - when:
+      title: (?i)security
+    notify: true
+    score: 1
+`
+	config, err := LoadConfig(strings.NewReader(raw))
+	assertEqual(t, nil, err)
+	compiled, err := CompileRules(config.Rules)
+	assertEqual(t, nil, err)
+
+	now := time.Now()
+	sponsored := FeedItem{Title: "Buy this", Links: []string{"https://example.com/sponsored/1"}, PublishTime: now}
+	golang := FeedItem{Title: "Go security release", Links: []string{"https://go.dev"}, PublishTime: now}
+	oldGolang := FeedItem{Title: "Go 1.0", Links: []string{"https://go.dev"}, PublishTime: now.Add(-72 * time.Hour)}
+
+	fs := Filters(compiled.Filters)
+	assertEqual(t, false, fs.Apply(sponsored))
+	assertEqual(t, true, fs.Apply(golang))
+
+	apply := func(item FeedItem) FeedItem {
+		for _, o := range compiled.Display {
+			item = o(item)
+		}
+		return item
+	}
+	result := apply(golang)
+	assertEqual(t, Red, result.colour)
+	assertEqual(t, []string{"golang"}, result.Tags)
+	assertEqual(t, 6, result.Score)
+
+	result = apply(oldGolang)
+	assertEqual(t, Colour(""), result.colour)
+	assertEqual(t, 0, result.Score)
+
+	assertEqual(t, true, compiled.Notify(golang))
+	assertEqual(t, false, compiled.Notify(sponsored))
+}
+
+func TestCompileRulesInvalid(t *testing.T) {
+	testcases := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "Bad regex", rule: Rule{When: Condition{Title: "("}}},
+		{name: "Bad duration", rule: Rule{When: Condition{OlderThan: "2 days"}}},
+		{name: "Bad nested", rule: Rule{When: Condition{Not: &Condition{Link: "["}}}},
+		{name: "Bad colour", rule: Rule{Highlight: "mauve"}},
+	}
+
+	t.Parallel()
+	for _, tc := range testcases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := CompileRules([]Rule{tc.rule})
+			assertEqual(t, true, err != nil)
+		})
+	}
+}
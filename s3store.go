@@ -0,0 +1,445 @@
+package rss
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+)
+
+// s3Retries is the number of times an object is read and written again when
+// something else changes it in between.
+const s3Retries = 5
+
+// errS3Conflict is the failure to replace an object because it has changed
+// since it was read.
+var errS3Conflict = errors.New("object changed while it was written")
+
+// S3Store is the Store which keeps feeds and state as objects in a bucket on
+// S3, or a service compatible with it such as MinIO or R2, so that a daemon
+// can run without a disk of its own. Objects are only replaced if they haven't
+// changed since they were read, so that the bucket can be shared.
+type S3Store struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	token     string
+	identity  *age.X25519Identity
+	client    *http.Client
+	now       func() time.Time
+}
+
+type S3Option func(*S3Store)
+
+// WithS3Prefix keeps the objects under the prefix e.g. "rss/", so that the
+// bucket can hold other things.
+func WithS3Prefix(prefix string) S3Option {
+	return func(s *S3Store) {
+		s.prefix = prefix
+	}
+}
+
+// WithS3Credentials signs requests with the access key. The session token is
+// only needed for temporary credentials, e.g. those of a role. Requests are
+// anonymous without credentials.
+func WithS3Credentials(accessKey, secretKey, token string) S3Option {
+	return func(s *S3Store) {
+		s.accessKey = accessKey
+		s.secretKey = secretKey
+		s.token = token
+	}
+}
+
+// WithS3Identity encrypts each record of the state to the identity, as
+// WithIdentity does for files.
+func WithS3Identity(identity *age.X25519Identity) S3Option {
+	return func(s *S3Store) {
+		s.identity = identity
+	}
+}
+
+// WithS3Client makes requests with the client instead of the default one.
+func WithS3Client(client *http.Client) S3Option {
+	return func(s *S3Store) {
+		s.client = client
+	}
+}
+
+// NewS3Store returns the store keeping objects in the bucket at the endpoint
+// e.g. "https://s3.eu-west-2.amazonaws.com". The endpoint of the region on S3
+// is used if none is given, and the region defaults to us-east-1.
+func NewS3Store(endpoint, region, bucket string, opts ...S3Option) (*S3Store, error) {
+	if bucket == "" {
+		return nil, errors.New("no bucket given for S3")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("S3 endpoint %s isn't a http or https URL", endpoint)
+	}
+	s := &S3Store{endpoint: u, region: region, bucket: bucket, client: http.DefaultClient, now: time.Now}
+	for _, o := range opts {
+		o(s)
+	}
+	return s, nil
+}
+
+// String returns the location of the objects e.g. "s3://bucket/rss/".
+func (s *S3Store) String() string {
+	return "s3://" + s.bucket + "/" + s.prefix
+}
+
+func (s *S3Store) SaveFeed(feed *Feed) error {
+	return s.update(s.prefix+"feeds/"+storeName(feed.URL)+".xml", func(b []byte) ([]byte, error) {
+		b, err := DecryptDocument(b, s.identity)
+		if err != nil {
+			return nil, err
+		}
+		b, err = mergeStored(b, []*Feed{feed})
+		if err != nil {
+			return nil, err
+		}
+		return EncryptDocument(b, s.identity)
+	})
+}
+
+func (s *S3Store) LoadFeeds() ([]*Feed, error) {
+	keys, err := s.list(s.prefix + "feeds/")
+	if err != nil {
+		return nil, err
+	}
+	var feeds []*Feed
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".xml") {
+			continue
+		}
+		b, _, err := s.get(key)
+		if err != nil {
+			return nil, err
+		}
+		b, err = DecryptDocument(b, s.identity)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", key, err)
+		}
+		stored, err := decodeStored(b)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", key, err)
+		}
+		feeds = append(feeds, stored...)
+	}
+	sort.SliceStable(feeds, func(i, j int) bool { return feeds[i].URL < feeds[j].URL })
+	return feeds, nil
+}
+
+func (s *S3Store) ReadState(name string) (io.Reader, error) {
+	b, _, err := s.get(s.prefix + "state/" + name)
+	if errors.Is(err, os.ErrNotExist) {
+		return strings.NewReader(""), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.identity == nil {
+		return bytes.NewReader(b), nil
+	}
+	return DecryptLines(bytes.NewReader(b), s.identity)
+}
+
+func (s *S3Store) AppendState(name string) (io.WriteCloser, error) {
+	w := &s3Appender{store: s, key: s.prefix + "state/" + name}
+	if s.identity == nil {
+		return w, nil
+	}
+	return struct {
+		io.Writer
+		io.Closer
+	}{EncryptLines(w, s.identity.Recipient()), w}, nil
+}
+
+func (s *S3Store) Stars() ([]Star, error) {
+	r, err := s.ReadState(StarsState)
+	if err != nil {
+		return nil, err
+	}
+	return ReadStars(r)
+}
+
+// s3Appender appends records to an object. Objects can't be appended to, so
+// the records are buffered and the whole object is replaced once, when the
+// appender is closed.
+type s3Appender struct {
+	store *S3Store
+	key   string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (a *s3Appender) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.buf.Write(p)
+}
+
+func (a *s3Appender) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.buf.Len() == 0 {
+		return nil
+	}
+	err := a.store.update(a.key, func(b []byte) ([]byte, error) {
+		return append(b, a.buf.Bytes()...), nil
+	})
+	if err != nil {
+		return err
+	}
+	a.buf.Reset()
+	return nil
+}
+
+// update replaces the object with what change makes of it, given nothing if it
+// doesn't exist yet. Change is called again if something else replaces the
+// object in between.
+func (s *S3Store) update(key string, change func([]byte) ([]byte, error)) error {
+	for i := 0; i < s3Retries; i++ {
+		b, etag, err := s.get(key)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		b, err = change(b)
+		if err != nil {
+			return err
+		}
+		err = s.put(key, b, etag)
+		if !errors.Is(err, errS3Conflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("error writing %s: %w", key, errS3Conflict)
+}
+
+// get returns the object and its ETag, or an error wrapping os.ErrNotExist if
+// there is no such object.
+func (s *S3Store) get(key string) ([]byte, string, error) {
+	resp, err := s.do(http.MethodGet, key, nil, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", fmt.Errorf("%s: %w", key, os.ErrNotExist)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", s3Error(resp)
+	}
+	b, err := io.ReadAll(resp.Body)
+	return b, resp.Header.Get("ETag"), err
+}
+
+// put replaces the object if it still has the ETag, or creates it if the ETag
+// is empty and it doesn't exist.
+func (s *S3Store) put(key string, b []byte, etag string) error {
+	header := make(http.Header)
+	if etag != "" {
+		header.Set("If-Match", etag)
+	} else {
+		header.Set("If-None-Match", "*")
+	}
+	resp, err := s.do(http.MethodPut, key, nil, b, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return errS3Conflict
+	}
+	return s3Error(resp)
+}
+
+// list returns the keys of the objects with the prefix.
+func (s *S3Store) list(prefix string) ([]string, error) {
+	var keys []string
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		resp, err := s.do(http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = s3Error(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+		var listing struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(resp.Body).Decode(&listing)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range listing.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !listing.IsTruncated || listing.NextContinuationToken == "" {
+			return keys, nil
+		}
+		query.Set("continuation-token", listing.NextContinuationToken)
+	}
+}
+
+// do makes a signed request for the object with the key, or the bucket if the
+// key is empty.
+func (s *S3Store) do(method, key string, query url.Values, body []byte, header http.Header) (*http.Response, error) {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = awsEscape(u.Path, true)
+	u.RawQuery = s3Query(query)
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if s.accessKey != "" {
+		signV4(req, body, s.region, s.accessKey, s.secretKey, s.token, s.now())
+	}
+	return s.client.Do(req)
+}
+
+// s3Error returns the error answered by S3.
+func s3Error(resp *http.Response) error {
+	var answer struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(b, &answer) != nil || answer.Code == "" {
+		return fmt.Errorf("error from %s: %s", resp.Request.URL.Host, resp.Status)
+	}
+	return fmt.Errorf("error from %s: %s: %s", resp.Request.URL.Host, answer.Code, answer.Message)
+}
+
+// s3Query encodes the query as it is signed, sorted by key with spaces as %20.
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, awsEscape(key, false)+"="+awsEscape(value, false))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// awsEscape percent encodes everything but the characters which are never
+// encoded in a URI, and slashes if it is a path.
+func awsEscape(s string, path bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (path && c == '/') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// signV4 signs the request for S3 with version 4 of AWS's signatures, which
+// every header set on the request is included in.
+func signV4(req *http.Request, payload []byte, region, accessKey, secretKey, token string, now time.Time) {
+	now = now.UTC()
+	stamp := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", stamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	key := []byte("AWS4" + secretKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,92 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SanitizeMode is how the titles of items are cleaned up for display.
+type SanitizeMode string
+
+const (
+	// SanitizeNone leaves titles as they are.
+	SanitizeNone SanitizeMode = "none"
+	// SanitizeNormalize removes control and zero-width characters and
+	// replaces unusual spaces with plain ones.
+	SanitizeNormalize SanitizeMode = "normalize"
+	// SanitizeStrip also removes emoji.
+	SanitizeStrip SanitizeMode = "strip"
+)
+
+// ParseSanitizeMode returns the mode with the given name. An empty name is
+// SanitizeNone.
+func ParseSanitizeMode(name string) (SanitizeMode, error) {
+	switch mode := SanitizeMode(strings.ToLower(name)); mode {
+	case "":
+		return SanitizeNone, nil
+	case SanitizeNone, SanitizeNormalize, SanitizeStrip:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown sanitize mode %q, expected none, normalize or strip", name)
+}
+
+// SanitizeTitles cleans up the titles of items, which can contain characters
+// that break the alignment of columns. Items are sanitized with the mode given
+// for their feed, keyed by the feed's name or URL, or else the fallback.
+func SanitizeTitles(fallback SanitizeMode, feeds map[string]SanitizeMode) DisplayOption {
+	return func(item FeedItem) FeedItem {
+		mode, found := feeds[item.Feed]
+		if !found {
+			mode = fallback
+		}
+		item.Title = sanitize(item.Title, mode)
+		return item
+	}
+}
+
+func sanitize(s string, mode SanitizeMode) string {
+	if mode != SanitizeNormalize && mode != SanitizeStrip {
+		return s
+	}
+	var builder strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			// Runs of spaces are collapsed into one
+			space = builder.Len() > 0
+			continue
+		case unicode.IsControl(r), unicode.Is(unicode.Cf, r):
+			continue
+		case mode == SanitizeStrip && isEmoji(r):
+			continue
+		}
+		if space {
+			builder.WriteByte(' ')
+			space = false
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// isEmoji reports whether r is part of an emoji: pictographs, dingbats,
+// flags, skin tones, keycaps and variation selectors.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return true
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r >= 0xFE00 && r <= 0xFE0F:
+		return true
+	case r == 0x20E3:
+		return true
+	case r >= 0xE0020 && r <= 0xE007F:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package rss
+
+import "testing"
+
+func TestSanitizeTitles(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     SanitizeMode
+		feed     string
+		title    string
+		expected string
+	}{
+		{
+			name:     "None",
+			mode:     SanitizeNone,
+			title:    "🔥 Hot​ take",
+			expected: "🔥 Hot​ take",
+		},
+		{
+			name:     "Normalize",
+			mode:     SanitizeNormalize,
+			title:    " Hot​  take\n\t🔥",
+			expected: "Hot take 🔥",
+		},
+		{
+			name:     "Strip",
+			mode:     SanitizeStrip,
+			title:    "🔥 Hot take ❤️ 🇬🇧",
+			expected: "Hot take",
+		},
+		{
+			name:     "Per feed",
+			mode:     SanitizeNone,
+			feed:     "Noisy",
+			title:    "🔥 Hot take",
+			expected: "Hot take",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			sanitize := SanitizeTitles(tc.mode, map[string]SanitizeMode{"Noisy": SanitizeStrip})
+			result := sanitize(FeedItem{Title: tc.title, Feed: tc.feed})
+			assertEqual(t, tc.expected, result.Title)
+		})
+	}
+}
+
+func TestParseSanitizeMode(t *testing.T) {
+	mode, err := ParseSanitizeMode("")
+	assertEqual(t, nil, err)
+	assertEqual(t, SanitizeNone, mode)
+	mode, err = ParseSanitizeMode("Strip")
+	assertEqual(t, nil, err)
+	assertEqual(t, SanitizeStrip, mode)
+	_, err = ParseSanitizeMode("emoji")
+	assertEqual(t, true, err != nil)
+}
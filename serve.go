@@ -0,0 +1,725 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxServedItems is the number of items the server holds, the oldest
+	// being dropped first.
+	maxServedItems = 1000
+	// seenRetention is how long the IDs of items no longer held are
+	// remembered, so that they aren't sent again while they are still in
+	// their feeds.
+	seenRetention = 30 * 24 * time.Hour
+	// streamBuffer is the number of new items held for each client of the
+	// event stream before they are dropped.
+	streamBuffer = 100
+	websubPath   = "/websub/"
+	websubLease  = 24 * time.Hour
+	rssCloudPath = "/rsscloud"
+	// rssCloudLease is how long rssCloud registrations last before they must
+	// be renewed.
+	rssCloudLease = 25 * time.Hour
+)
+
+// Server polls feeds and serves their items over HTTP. Feeds which advertise a
+// WebSub hub or an rssCloud service are subscribed to when the server has a
+// public callback URL, so that their updates are pushed rather than polled.
+type Server struct {
+	interval  time.Duration
+	callback  string
+	secret    []byte
+	stateFile string
+	// reloaded is sent on when the feeds are reloaded, so that they are
+	// polled straight away.
+	reloaded chan struct{}
+
+	mu sync.Mutex
+	// urls, fetchOpts, filters and token can be changed by Reload.
+	urls      []string
+	fetchOpts []FetchOption
+	filters   []Filter
+	token     string
+	lastPoll  time.Time
+	items     []FeedItem
+	// seen holds when each item was first seen.
+	seen map[string]time.Time
+	// subscriptions are the WebSub subscriptions, keyed by the id in their
+	// callback URL.
+	subscriptions map[string]*websubSubscription
+	// clouds are when the rssCloud registrations of feeds expire.
+	clouds map[string]time.Time
+	// streams receive new items as they are found.
+	streams map[chan FeedItem]bool
+}
+
+type websubSubscription struct {
+	feed  string
+	topic string
+	hub   string
+	// expires is when the lease on the subscription ends, zero until the hub
+	// has verified it.
+	expires time.Time
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// PollEvery sets how often feeds are fetched. Defaults to 15 minutes.
+func PollEvery(interval time.Duration) ServerOption {
+	return func(s *Server) {
+		s.interval = interval
+	}
+}
+
+// WithCallbackURL enables WebSub subscriptions, with hubs pushing updates to
+// the server at the given public URL.
+func WithCallbackURL(callback string) ServerOption {
+	return func(s *Server) {
+		s.callback = strings.TrimSuffix(callback, "/")
+	}
+}
+
+// WithFetchOptions sets the options used to fetch and decode feeds, including
+// those pushed by hubs.
+func WithFetchOptions(opts ...FetchOption) ServerOption {
+	return func(s *Server) {
+		s.fetchOpts = opts
+	}
+}
+
+// WithServerFilters sets the filters which items must pass to be served.
+func WithServerFilters(filters ...Filter) ServerOption {
+	return func(s *Server) {
+		s.filters = filters
+	}
+}
+
+// WithToken requires the token to be given to access the API, either in an
+// "Authorization: Bearer" header or the access_token parameter for clients
+// which can't set headers e.g. EventSource. Push callbacks are verified by
+// their own means so don't need it.
+func WithToken(token string) ServerOption {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithStateFile keeps the server's items, subscriptions and when it last
+// polled in the file, so that a restarted server carries on where it left off.
+// The state is saved after each poll and by Save.
+func WithStateFile(path string) ServerOption {
+	return func(s *Server) {
+		s.stateFile = path
+	}
+}
+
+// serverState is the state of a server kept in its state file.
+type serverState struct {
+	LastPoll time.Time  `json:"last_poll"`
+	Secret   string     `json:"secret"`
+	Items    []FeedItem `json:"items"`
+	// Seen is the IDs of items seen by older versions, which didn't
+	// record when.
+	Seen          []string             `json:"seen,omitempty"`
+	SeenAt        map[string]time.Time `json:"seen_at"`
+	Subscriptions []savedSubscription  `json:"subscriptions,omitempty"`
+	Clouds        map[string]time.Time `json:"clouds,omitempty"`
+}
+
+type savedSubscription struct {
+	ID      string    `json:"id"`
+	Feed    string    `json:"feed"`
+	Topic   string    `json:"topic"`
+	Hub     string    `json:"hub"`
+	Expires time.Time `json:"expires"`
+}
+
+// NewServer returns a server for the feeds at the given urls.
+func NewServer(urls []string, opts ...ServerOption) (*Server, error) {
+	s := &Server{
+		urls:          urls,
+		interval:      15 * time.Minute,
+		reloaded:      make(chan struct{}, 1),
+		seen:          make(map[string]time.Time),
+		subscriptions: make(map[string]*websubSubscription),
+		clouds:        make(map[string]time.Time),
+		streams:       make(map[chan FeedItem]bool),
+		secret:        make([]byte, 32),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	// The secret lets hubs sign the content they push so that it can't be
+	// forged by anyone who learns the callback URL.
+	_, err := rand.Read(s.secret)
+	if err != nil {
+		return nil, err
+	}
+	err = s.load()
+	return s, err
+}
+
+// load restores the state saved in the state file, if there is one.
+func (s *Server) load() error {
+	if s.stateFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state serverState
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", s.stateFile, err)
+	}
+	// Subscriptions were made with the old secret, so hubs sign with it
+	if secret, err := hex.DecodeString(state.Secret); err == nil && len(secret) > 0 {
+		s.secret = secret
+	}
+	s.lastPoll = state.LastPoll
+	s.items = state.Items
+	for _, id := range state.Seen {
+		s.seen[id] = state.LastPoll
+	}
+	for id, seen := range state.SeenAt {
+		s.seen[id] = seen
+	}
+	for _, sub := range state.Subscriptions {
+		s.subscriptions[sub.ID] = &websubSubscription{feed: sub.Feed, topic: sub.Topic, hub: sub.Hub, expires: sub.Expires}
+	}
+	for feed, expires := range state.Clouds {
+		s.clouds[feed] = expires
+	}
+	return nil
+}
+
+// Save writes the server's state to its state file, if it has one.
+func (s *Server) Save() error {
+	if s.stateFile == "" {
+		return nil
+	}
+	s.mu.Lock()
+	state := serverState{
+		LastPoll: s.lastPoll,
+		Secret:   hex.EncodeToString(s.secret),
+		Items:    s.items,
+		SeenAt:   s.seen,
+		Clouds:   s.clouds,
+	}
+	for id, sub := range s.subscriptions {
+		state.Subscriptions = append(state.Subscriptions, savedSubscription{ID: id, Feed: sub.feed, Topic: sub.topic, Hub: sub.hub, Expires: sub.expires})
+	}
+	b, err := json.Marshal(state)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	// The secret is kept in the state, so only the owner can read it
+	return WriteFileAtomic(s.stateFile, b, 0600)
+}
+
+// Reload replaces the feeds, and applies the options e.g. to change the
+// filters or token, without interrupting the requests being served. The feeds
+// are polled straight away. The interval and callback URL can't be changed.
+func (s *Server) Reload(urls []string, opts ...ServerOption) {
+	s.mu.Lock()
+	interval, callback := s.interval, s.callback
+	s.urls = urls
+	for _, o := range opts {
+		o(s)
+	}
+	s.interval, s.callback = interval, callback
+	s.mu.Unlock()
+	select {
+	case s.reloaded <- struct{}{}:
+	default:
+	}
+}
+
+// Run polls the feeds until the context is cancelled. A poll in progress when
+// it is cancelled is finished, and the state is saved after each poll. A
+// server restored from its state file waits for the rest of the interval
+// since it last polled.
+func (s *Server) Run(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		wait := s.interval - time.Since(s.lastPoll)
+		s.mu.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.reloaded:
+			timer.Stop()
+		case <-timer.C:
+		}
+		s.poll()
+		err := s.Save()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// poll fetches the feeds whose updates are not pushed, subscribing to any which
+// support it. WebSub is preferred to rssCloud. Feeds carry on being polled if
+// subscribing fails.
+func (s *Server) poll() {
+	s.mu.Lock()
+	s.lastPoll = time.Now()
+	all, fetchOpts := s.urls, s.fetchOpts
+	s.mu.Unlock()
+	var urls []string
+	for _, u := range all {
+		if !s.pushed(u) {
+			urls = append(urls, u)
+		}
+	}
+	for _, feed := range GetFeeds(urls, fetchOpts...) {
+		s.ingest(feed)
+		if s.callback == "" {
+			continue
+		}
+		var err error
+		switch {
+		case feed.Channel.Hub != "":
+			err = s.subscribe(feed)
+		case feed.Channel.Cloud.Domain != "":
+			err = s.register(feed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+// pushed returns true if the feed has a verified subscription which will not
+// expire before the next poll.
+func (s *Server) pushed(feedURL string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, found := s.subscriptions[websubID(feedURL)]
+	if found && time.Until(sub.expires) > s.interval {
+		return true
+	}
+	return time.Until(s.clouds[feedURL]) > s.interval
+}
+
+// ingest adds the new items from the feed.
+func (s *Server) ingest(feed *Feed) {
+	s.mu.Lock()
+	filters := s.filters
+	s.mu.Unlock()
+	feedItems := UnpackFeed(feed, filters...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, item := range feedItems {
+		if _, found := s.seen[item.ID]; found {
+			continue
+		}
+		s.seen[item.ID] = now
+		s.items = append(s.items, item)
+		for stream := range s.streams {
+			select {
+			case stream <- item:
+			default:
+				// Drop the item rather than hold up ingesting for a
+				// slow client
+			}
+		}
+	}
+	if len(s.items) > maxServedItems {
+		s.items = ReverseChronological(s.items)[:maxServedItems]
+	}
+	s.forget(now)
+}
+
+// forget forgets the items seen longer than seenRetention ago which are no
+// longer held, so that the state doesn't grow forever.
+func (s *Server) forget(now time.Time) {
+	held := make(map[string]bool, len(s.items))
+	for _, item := range s.items {
+		held[item.ID] = true
+	}
+	for id, seen := range s.seen {
+		if !held[id] && now.Sub(seen) > seenRetention {
+			delete(s.seen, id)
+		}
+	}
+}
+
+// Items returns the items which have been found, newest first.
+func (s *Server) Items() []FeedItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ReverseChronological(append([]FeedItem(nil), s.items...))
+}
+
+// ServeHTTP serves the items as JSON at /items, optionally only those
+// published after the time given in the since parameter, streams new items as
+// server-sent events at /events and receives push callbacks. The same items
+// are served to gRPC calls, as described by proto/rss.proto.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case isGRPC(r):
+		s.serveGRPC(w, r)
+	case (r.URL.Path == "/items" || r.URL.Path == "/events") && !s.authorized(r):
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	case r.URL.Path == "/items" && r.Method == http.MethodGet:
+		s.serveItems(w, r)
+	case r.URL.Path == "/events" && r.Method == http.MethodGet:
+		s.serveEvents(w, r)
+	case strings.HasPrefix(r.URL.Path, websubPath):
+		s.serveWebSub(w, r, strings.TrimPrefix(r.URL.Path, websubPath))
+	case r.URL.Path == rssCloudPath:
+		s.serveRSSCloud(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authorized returns true if the request has the server's token, or the server
+// has none.
+func (s *Server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	expected := s.token
+	s.mu.Unlock()
+	if expected == "" {
+		return true
+	}
+	token := r.URL.Query().Get("access_token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+func (s *Server) serveItems(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if param := r.URL.Query().Get("since"); param != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339, param)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	feedItems := []FeedItem{}
+	for _, item := range s.Items() {
+		if item.PublishTime.After(since) {
+			feedItems = append(feedItems, item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedItems)
+}
+
+// serveEvents sends each new item as an "item" event with the item as JSON
+// until the client disconnects.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	stream, stop := s.watch()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case item := <-stream:
+			data, err := json.Marshal(item)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "id: %s\nevent: item\ndata: %s\n\n", item.ID, data)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// watch returns a channel receiving each new item, until stop is called.
+func (s *Server) watch() (stream <-chan FeedItem, stop func()) {
+	ch := make(chan FeedItem, streamBuffer)
+	s.mu.Lock()
+	s.streams[ch] = true
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.streams, ch)
+		s.mu.Unlock()
+	}
+}
+
+// subscribe asks the feed's hub to push its updates to the server. The
+// subscription is only used once the hub has verified it.
+func (s *Server) subscribe(feed *Feed) error {
+	id := websubID(feed.URL)
+	topic := feed.Channel.Self
+	if topic == "" {
+		topic = feed.URL
+	}
+	s.mu.Lock()
+	sub, found := s.subscriptions[id]
+	if found && sub.hub == feed.Channel.Hub && time.Until(sub.expires) > s.interval {
+		s.mu.Unlock()
+		return nil
+	}
+	s.subscriptions[id] = &websubSubscription{feed: feed.URL, topic: topic, hub: feed.Channel.Hub}
+	s.mu.Unlock()
+
+	form := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {topic},
+		"hub.callback":      {s.callback + websubPath + id},
+		"hub.lease_seconds": {strconv.Itoa(int(websubLease.Seconds()))},
+		"hub.secret":        {hex.EncodeToString(s.secret)},
+	}
+	resp, err := client.PostForm(feed.Channel.Hub, form)
+	if err != nil {
+		return fmt.Errorf("subscribing to %s: %s", feed.URL, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("subscribing to %s: hub responded %s", feed.URL, resp.Status)
+	}
+	return nil
+}
+
+// serveWebSub handles the hub's verification of subscriptions and the content
+// it pushes.
+func (s *Server) serveWebSub(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	sub, found := s.subscriptions[id]
+	s.mu.Unlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		if query.Get("hub.topic") != sub.topic {
+			http.NotFound(w, r)
+			return
+		}
+		switch query.Get("hub.mode") {
+		case "subscribe":
+			lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+			if err != nil {
+				lease = int(websubLease.Seconds())
+			}
+			s.mu.Lock()
+			sub.expires = time.Now().Add(time.Duration(lease) * time.Second)
+			s.mu.Unlock()
+		case "denied":
+			// Carry on polling
+			s.mu.Lock()
+			delete(s.subscriptions, id)
+			s.mu.Unlock()
+			return
+		}
+		io.WriteString(w, query.Get("hub.challenge"))
+	case http.MethodPost:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Content which isn't signed correctly is acknowledged but ignored,
+		// as the spec requires
+		if !s.validSignature(r.Header.Get("X-Hub-Signature"), body) {
+			return
+		}
+		options := newFetchOptions(s.fetchOptions()...)
+		channels, err := decodeRSS(bytes.NewReader(body), options)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error unmarshaling content pushed for %s: %s\n", sub.feed, err.Error())
+			return
+		}
+		// Pushed feeds are unpacked as those which are polled are
+		for _, feed := range newFeeds(sub.feed, channels, options) {
+			s.ingest(feed)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// validSignature checks the signature of content pushed by a hub e.g.
+// "sha256=<hex encoded HMAC>".
+func (s *Server) validSignature(signature string, body []byte) bool {
+	method, sum, found := strings.Cut(signature, "=")
+	if !found {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	expected, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(hex.EncodeToString(s.secret)))
+	mac.Write(body)
+	return hmac.Equal(expected, mac.Sum(nil))
+}
+
+// register asks the feed's rssCloud service to notify the server of updates.
+// Only the http-post protocol is supported.
+func (s *Server) register(feed *Feed) error {
+	cloud := feed.Channel.Cloud
+	if cloud.Protocol != "http-post" {
+		return fmt.Errorf("registering %s: unsupported rssCloud protocol %q", feed.URL, cloud.Protocol)
+	}
+	callback, err := url.Parse(s.callback)
+	if err != nil {
+		return err
+	}
+	port := callback.Port()
+	if port == "" {
+		port = "80"
+		if callback.Scheme == "https" {
+			port = "443"
+		}
+	}
+	form := url.Values{
+		"notifyProcedure": {""},
+		"domain":          {callback.Hostname()},
+		"port":            {port},
+		"path":            {callback.Path + rssCloudPath},
+		"protocol":        {"http-post"},
+		"url1":            {feed.URL},
+	}
+	if cloud.Port == "" {
+		cloud.Port = "80"
+	}
+	resp, err := client.PostForm(fmt.Sprintf("http://%s:%s%s", cloud.Domain, cloud.Port, cloud.Path), form)
+	if err != nil {
+		return fmt.Errorf("registering %s: %s", feed.URL, err.Error())
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Success bool   `xml:"success,attr"`
+		Message string `xml:"msg,attr"`
+	}
+	err = xml.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return fmt.Errorf("registering %s: %s", feed.URL, err.Error())
+	}
+	if !result.Success {
+		return fmt.Errorf("registering %s: %s", feed.URL, result.Message)
+	}
+	s.mu.Lock()
+	s.clouds[feed.URL] = time.Now().Add(rssCloudLease)
+	s.mu.Unlock()
+	return nil
+}
+
+// serveRSSCloud handles the verification of rssCloud registrations and the
+// notifications of updates, fetching the feeds which have been updated.
+func (s *Server) serveRSSCloud(w http.ResponseWriter, r *http.Request) {
+	var feedURL string
+	switch r.Method {
+	case http.MethodGet:
+		feedURL = r.URL.Query().Get("url")
+	case http.MethodPost:
+		feedURL = r.PostFormValue("url")
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.hasFeed(feedURL) {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		io.WriteString(w, r.URL.Query().Get("challenge"))
+		return
+	}
+	feeds, err := FetchFeeds(feedURL, s.fetchOptions()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	for _, feed := range feeds {
+		s.ingest(feed)
+	}
+}
+
+// hasFeed returns true if the feed is one of the server's. Any of them may be
+// verified or notified by an rssCloud service, since registrations are
+// verified before they have been recorded.
+func (s *Server) hasFeed(feedURL string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.urls {
+		if u == feedURL {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) fetchOptions() []FetchOption {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetchOpts
+}
+
+// websubID identifies the feed in its callback URL.
+func websubID(feedURL string) string {
+	sum := sha256.Sum256([]byte(feedURL))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,293 @@
+package rss
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServerWebSub(t *testing.T) {
+	var subscription url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		subscription = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	pubDate := time.Now().Add(-time.Hour).Format(time.RFC1123Z)
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><title>Pushy</title>
+<atom:link rel="hub" href="%s"/><atom:link rel="self" href="https://example.com/topic"/>
+<item><title>Polled</title><link>https://example.com/1</link><pubDate>%s</pubDate></item>
+</channel></rss>`, hub.URL, pubDate)
+	}))
+	defer feeds.Close()
+
+	server, err := NewServer([]string{feeds.URL})
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+	WithCallbackURL(api.URL + "/")(server)
+
+	server.poll()
+	assertEqual(t, "https://example.com/topic", subscription.Get("hub.topic"))
+	assertEqual(t, false, server.pushed(feeds.URL))
+
+	// The hub verifies the subscription
+	callback := subscription.Get("hub.callback")
+	resp, err := http.Get(callback + "?" + url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {"https://example.com/topic"},
+		"hub.challenge":     {"abc"},
+		"hub.lease_seconds": {"86400"},
+	}.Encode())
+	assertEqual(t, nil, err)
+	challenge, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assertEqual(t, "abc", string(challenge))
+	assertEqual(t, true, server.pushed(feeds.URL))
+
+	// Then pushes new content, which is only accepted when signed
+	push := func(title, signature string) {
+		body := fmt.Sprintf(`<rss><channel><title>Pushy</title><item><title>%s</title><link>https://example.com/%s</link><pubDate>%s</pubDate></item></channel></rss>`, title, title, time.Now().Format(time.RFC1123Z))
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte(subscription.Get("hub.secret")))
+			mac.Write([]byte(body))
+			signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		}
+		req, _ := http.NewRequest(http.MethodPost, callback, strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature", signature)
+		resp, err := http.DefaultClient.Do(req)
+		assertEqual(t, nil, err)
+		resp.Body.Close()
+	}
+	push("Pushed", "")
+	push("Forged", "sha256=00")
+
+	resp, err = http.Get(api.URL + "/items")
+	assertEqual(t, nil, err)
+	defer resp.Body.Close()
+	var feedItems []FeedItem
+	err = json.NewDecoder(resp.Body).Decode(&feedItems)
+	assertEqual(t, nil, err)
+	var titles []string
+	for _, item := range feedItems {
+		titles = append(titles, item.Title)
+		assertEqual(t, feeds.URL, item.Feed)
+	}
+	assertEqual(t, []string{"Pushed", "Polled"}, titles)
+}
+
+func TestServerRSSCloud(t *testing.T) {
+	var registration url.Values
+	var success bool
+	cloud := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		registration = r.PostForm
+		fmt.Fprintf(w, `<?xml version="1.0"?><notifyResult success="%t" msg="Registered"/>`, success)
+	}))
+	defer cloud.Close()
+	cloudURL, _ := url.Parse(cloud.URL)
+
+	title := "Polled"
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss><channel><title>Cloudy</title>
+<cloud domain="%s" port="%s" path="/RPC2" registerProcedure="" protocol="http-post"/>
+<item><title>%s</title><link>https://example.com/%s</link></item>
+</channel></rss>`, cloudURL.Hostname(), cloudURL.Port(), title, title)
+	}))
+	defer feeds.Close()
+
+	server, err := NewServer([]string{feeds.URL})
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+	WithCallbackURL(api.URL)(server)
+
+	// Failed registrations leave the feed being polled
+	server.poll()
+	assertEqual(t, feeds.URL, registration.Get("url1"))
+	assertEqual(t, "/rsscloud", registration.Get("path"))
+	assertEqual(t, false, server.pushed(feeds.URL))
+
+	success = true
+	server.poll()
+	assertEqual(t, true, server.pushed(feeds.URL))
+
+	resp, err := http.Get(api.URL + "/rsscloud?" + url.Values{"url": {feeds.URL}, "challenge": {"abc"}}.Encode())
+	assertEqual(t, nil, err)
+	challenge, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assertEqual(t, "abc", string(challenge))
+
+	title = "Notified"
+	resp, err = http.PostForm(api.URL+"/rsscloud", url.Values{"url": {feeds.URL}})
+	assertEqual(t, nil, err)
+	resp.Body.Close()
+	var titles []string
+	for _, item := range server.Items() {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, 2, len(titles))
+	assertEqual(t, true, strings.Contains(strings.Join(titles, ","), "Notified"))
+
+	resp, err = http.PostForm(api.URL+"/rsscloud", url.Values{"url": {"https://example.com/other"}})
+	assertEqual(t, nil, err)
+	resp.Body.Close()
+	assertEqual(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServerEvents(t *testing.T) {
+	server, err := NewServer(nil)
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+
+	resp, err := http.Get(api.URL + "/events")
+	assertEqual(t, nil, err)
+	defer resp.Body.Close()
+	assertEqual(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	feed := &Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Live", Items: []Item{{Title: "New", Link: "https://example.com/1"}}}}}
+	server.ingest(feed)
+	// Items which have already been seen aren't sent again
+	server.ingest(feed)
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		assertEqual(t, nil, err)
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	assertEqual(t, "event: item", lines[1])
+	var item FeedItem
+	err = json.Unmarshal([]byte(strings.TrimPrefix(lines[2], "data: ")), &item)
+	assertEqual(t, nil, err)
+	assertEqual(t, "New", item.Title)
+	assertEqual(t, "id: "+item.ID, lines[0])
+}
+
+func TestServerToken(t *testing.T) {
+	server, err := NewServer(nil, WithToken("secret"))
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		expected int
+	}{
+		{name: "No token", path: "/items", expected: http.StatusUnauthorized},
+		{name: "Wrong token", path: "/items", header: "Bearer wrong", expected: http.StatusUnauthorized},
+		{name: "Header", path: "/items", header: "Bearer secret", expected: http.StatusOK},
+		{name: "Parameter", path: "/items?access_token=secret", expected: http.StatusOK},
+		{name: "Callbacks need no token", path: "/websub/unknown", expected: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, api.URL+tc.path, nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			assertEqual(t, nil, err)
+			resp.Body.Close()
+			assertEqual(t, tc.expected, resp.StatusCode)
+		})
+	}
+}
+
+func TestServerState(t *testing.T) {
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss><channel><title>Kept</title><item><title>One</title><link>https://example.com/1</link></item></channel></rss>`)
+	}))
+	defer feeds.Close()
+	stateFile := filepath.Join(t.TempDir(), "serve.json")
+
+	server, err := NewServer([]string{feeds.URL}, WithStateFile(stateFile))
+	assertEqual(t, nil, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(done)
+	}()
+	for len(server.Items()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	assertEqual(t, nil, server.Save())
+
+	restored, err := NewServer(nil, WithStateFile(stateFile))
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(restored.Items()))
+	assertEqual(t, "One", restored.Items()[0].Title)
+	assertEqual(t, server.secret, restored.secret)
+	_, seen := restored.seen[restored.Items()[0].ID]
+	assertEqual(t, true, seen)
+	// The restored server waits for the rest of the interval before polling
+	assertEqual(t, false, restored.lastPoll.IsZero())
+}
+
+func TestServerForget(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	s := &Server{
+		items: []FeedItem{{ID: "held"}},
+		seen: map[string]time.Time{
+			"held":   now.Add(-2 * seenRetention),
+			"old":    now.Add(-2 * seenRetention),
+			"recent": now.Add(-time.Hour),
+		},
+	}
+	s.forget(now)
+	assertEqual(t, map[string]time.Time{"held": now.Add(-2 * seenRetention), "recent": now.Add(-time.Hour)}, s.seen)
+}
+
+func TestServerReload(t *testing.T) {
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<rss><channel><title>%s</title><item><title>%s</title><link>https://example.com%s</link></item></channel></rss>`, r.URL.Path, r.URL.Path, r.URL.Path)
+	}))
+	defer feeds.Close()
+
+	server, err := NewServer([]string{feeds.URL + "/old"}, PollEvery(time.Hour))
+	assertEqual(t, nil, err)
+	api := httptest.NewServer(server)
+	defer api.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Run(ctx)
+	for len(server.Items()) == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The new feeds are polled straight away, rather than after the
+	// interval, and the new token is required
+	server.Reload([]string{feeds.URL + "/new"}, WithToken("secret"))
+	for len(server.Items()) < 2 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assertEqual(t, true, server.hasFeed(feeds.URL+"/new"))
+	assertEqual(t, false, server.hasFeed(feeds.URL+"/old"))
+	resp, err := http.Get(api.URL + "/items")
+	assertEqual(t, nil, err)
+	resp.Body.Close()
+	assertEqual(t, http.StatusUnauthorized, resp.StatusCode)
+}
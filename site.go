@@ -0,0 +1,148 @@
+package rss
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+var siteTemplate = template.Must(template.New("site").Parse(`{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 50em; margin: 2em auto; padding: 0 1em; line-height: 1.5; }
+h2 { font-size: 1em; margin-top: 2em; border-bottom: 1px solid #ccc; }
+ul { list-style: none; padding: 0; }
+.meta { color: #666; font-size: 0.9em; }
+.note { margin: 0 0 0.5em 1em; font-style: italic; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Feeds</a> · <a href="{{.Root}}dates.html">By date</a></nav>
+<h1>{{.Title}}</h1>
+{{end}}
+{{define "items"}}<ul>
+{{range .}}<li><a href="{{.Link}}">{{.Title}}</a> <span class="meta">{{.Meta}}</span>{{if .Note}}<p class="note">{{.Note}}</p>{{end}}</li>
+{{end}}</ul>
+{{end}}
+{{define "index"}}{{template "header" .}}<ul>
+{{range .Feeds}}<li><a href="{{.Page}}">{{.Title}}</a> <span class="meta">{{.Count}}</span></li>
+{{end}}</ul>
+</body>
+</html>
+{{end}}
+{{define "feed"}}{{template "header" .}}{{template "items" .Items}}</body>
+</html>
+{{end}}
+{{define "dates"}}{{template "header" .}}{{range .Days}}<h2>{{.Date}}</h2>
+{{template "items" .Items}}{{end}}</body>
+</html>
+{{end}}`))
+
+type sitePage struct {
+	Title string
+	// Root is the path from the page to the root of the site.
+	Root  string
+	Feeds []siteFeed
+	Items []siteItem
+	Days  []siteDay
+}
+
+type siteFeed struct {
+	Title string
+	Page  string
+	// Count is the number of items e.g. "3 items".
+	Count string
+}
+
+type siteItem struct {
+	Title string
+	Link  string
+	Meta  string
+	Note  string
+}
+
+type siteDay struct {
+	Date  string
+	Items []siteItem
+}
+
+// ExportSite writes the feeds to the directory as a static HTML site, with an
+// index of the feeds linking to a page for each, and a page of every item by
+// the date it was published. Only items passing the filters are included, and
+// the options are applied to them e.g. to add notes.
+func ExportSite(dir string, feeds []*Feed, filters []Filter, opts ...DisplayOption) error {
+	err := os.MkdirAll(filepath.Join(dir, "feeds"), 0755)
+	if err != nil {
+		return err
+	}
+	index := sitePage{Title: "Feeds"}
+	var all []FeedItem
+	pages := make(map[string]int)
+	for _, feed := range feeds {
+		feedItems := Annotated(ReverseChronological, opts...)(UnpackFeed(feed, filters...))
+		if len(feedItems) == 0 {
+			continue
+		}
+		all = append(all, feedItems...)
+		// Channels fetched from the same URL are numbered after the first
+		name := storeName(feed.URL)
+		pages[name]++
+		if pages[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, pages[name])
+		}
+		page := "feeds/" + name + ".html"
+		index.Feeds = append(index.Feeds, siteFeed{Title: feed.Title(), Page: page, Count: plural(len(feedItems), "item")})
+
+		items := make([]siteItem, len(feedItems))
+		for i, item := range feedItems {
+			items[i] = newSiteItem(item, item.PublishTime.Format(outputTimeLayout))
+		}
+		err = writeSitePage(filepath.Join(dir, page), "feed", sitePage{Title: feed.Title(), Root: "../", Items: items})
+		if err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(index.Feeds, func(i, j int) bool { return index.Feeds[i].Title < index.Feeds[j].Title })
+	err = writeSitePage(filepath.Join(dir, "index.html"), "index", index)
+	if err != nil {
+		return err
+	}
+
+	dates := sitePage{Title: "By date"}
+	for _, item := range ReverseChronological(all) {
+		date := item.PublishTime.Format("Monday 2 January 2006")
+		if len(dates.Days) == 0 || dates.Days[len(dates.Days)-1].Date != date {
+			dates.Days = append(dates.Days, siteDay{Date: date})
+		}
+		day := &dates.Days[len(dates.Days)-1]
+		day.Items = append(day.Items, newSiteItem(item, item.Source()))
+	}
+	return writeSitePage(filepath.Join(dir, "dates.html"), "dates", dates)
+}
+
+func newSiteItem(item FeedItem, meta string) siteItem {
+	var link string
+	if len(item.Links) > 0 {
+		link = item.Links[0]
+	}
+	return siteItem{Title: item.Title, Link: link, Meta: meta, Note: item.Note}
+}
+
+func writeSitePage(path, name string, page sitePage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = siteTemplate.ExecuteTemplate(f, name, page)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
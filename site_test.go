@@ -0,0 +1,43 @@
+package rss
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportSite(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	feeds := []*Feed{
+		{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Blog", Items: []Item{
+			{Title: "Fish & chips", Link: "https://example.com/1", PubDate: "Mon, 02 Jan 2006 15:04:05 GMT"},
+			{Title: "Hidden", Link: "https://example.com/2", PubDate: "Mon, 02 Jan 2006 15:04:05 GMT"},
+		}}}},
+		{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Links", Items: []Item{
+			{Title: "Elsewhere", Link: "https://example.org/", PubDate: "Tue, 03 Jan 2006 15:04:05 GMT"},
+		}}}},
+	}
+	notes := WithNotes([]Star{{HistoryEntry: HistoryEntry{Link: "https://example.org/"}, Note: "Worth a look"}})
+	err := ExportSite(dir, feeds, []Filter{func(item FeedItem) bool { return item.Title != "Hidden" }}, notes)
+	assertEqual(t, nil, err)
+
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		assertEqual(t, nil, err)
+		return string(b)
+	}
+	index := read("index.html")
+	name := storeName("https://example.com/feed")
+	assertEqual(t, true, strings.Contains(index, `<a href="feeds/`+name+`.html">Blog</a> <span class="meta">1 item</span>`))
+	assertEqual(t, true, strings.Contains(index, `<a href="feeds/`+name+`-2.html">Links</a>`))
+
+	blog := read("feeds/" + name + ".html")
+	assertEqual(t, true, strings.Contains(blog, `<a href="https://example.com/1">Fish &amp; chips</a> <span class="meta">2006/01/02</span>`))
+	assertEqual(t, false, strings.Contains(blog, "Hidden"))
+
+	dates := read("dates.html")
+	assertEqual(t, true, strings.Contains(dates, `<a href="https://example.org/">Elsewhere</a> <span class="meta">Links</span><p class="note">Worth a look</p>`))
+	assertEqual(t, true, strings.Index(dates, "Tuesday 3 January 2006") < strings.Index(dates, "Monday 2 January 2006"))
+}
@@ -0,0 +1,101 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// source fetches the channels of a feed from somewhere other than an RSS or
+// Atom document, e.g. a site's API.
+type source func(ctx context.Context, u *url.URL, options fetchOptions) ([]RSS, error)
+
+// sources are subscribed to with URLs using their scheme e.g. "hn://front".
+var sources = map[string]source{
+	"hn":       fetchHackerNews,
+	"lobsters": fetchLobsters,
+	"reddit":   fetchReddit,
+}
+
+// userAgent identifies requests to APIs, some of which refuse requests
+// without one of their own.
+const userAgent = "rss (+https://github.com/AzinKhan/rss)"
+
+// sourceOf returns the source of the feed at the URL, if it isn't a document.
+func sourceOf(rawURL string) (source, *url.URL, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, false
+	}
+	s, found := sources[u.Scheme]
+	return s, u, found
+}
+
+func getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error getting %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// limitItems applies the options which limit the items decoded from documents
+// to a channel from a source.
+func limitItems(channel Channel, options fetchOptions) Channel {
+	parseDate := newDateParser(time.Now())
+	items := channel.Items[:0]
+	for _, item := range channel.Items {
+		if options.maxItems > 0 && len(items) >= options.maxItems {
+			break
+		}
+		if options.maxAge > 0 {
+			pubTime, err := parseDate(item.PubDate)
+			if err == nil && time.Since(pubTime) > options.maxAge {
+				continue
+			}
+		}
+		if options.dropDescriptions {
+			item.Description = nil
+			item.Content = nil
+		}
+		items = append(items, item)
+	}
+	channel.Items = items
+	return channel
+}
+
+// MinPoints only lets through items with at least n points, from sources which
+// give items points. Items from other feeds are kept.
+func MinPoints(n int) Filter {
+	return func(item FeedItem) bool {
+		return item.Points == 0 || item.Points >= n
+	}
+}
+
+// ShowPoints appends the points and number of comments to the titles of items
+// which have them e.g. "Title (150 points, 42 comments)".
+func ShowPoints() DisplayOption {
+	return func(item FeedItem) FeedItem {
+		if item.Points == 0 && item.Comments == 0 {
+			return item
+		}
+		if item.Points == 0 {
+			item.Title = fmt.Sprintf("%s (%s)", item.Title, plural(item.Comments, "comment"))
+			return item
+		}
+		item.Title = fmt.Sprintf("%s (%s, %s)", item.Title, plural(item.Points, "point"), plural(item.Comments, "comment"))
+		return item
+	}
+}
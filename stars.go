@@ -0,0 +1,167 @@
+package rss
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// waybackSaveTimeout bounds saving a page to the Wayback Machine, which the app
+// waits on before it returns.
+const waybackSaveTimeout = time.Minute
+
+var waybackSaveAPI = "https://web.archive.org/save/"
+
+// Star records an item being starred to keep it.
+type Star struct {
+	HistoryEntry
+	// Snapshot is the URL of the copy of the item saved on the Wayback
+	// Machine, if it has been saved.
+	Snapshot string `json:"snapshot,omitempty"`
+	// SaveError records why saving the item to the Wayback Machine failed.
+	SaveError string `json:"save_error,omitempty"`
+	// Note is the reader's note on why the item matters.
+	Note string `json:"note,omitempty"`
+}
+
+// FeedItem converts the star into a FeedItem, using the time it was starred
+// as the publish time, so that it can be displayed.
+func (s Star) FeedItem() FeedItem {
+	item := s.HistoryEntry.FeedItem()
+	item.Note = s.Note
+	return item
+}
+
+// WriteStar appends the star to w as a single line. Writing a star for an item
+// which is already starred updates it.
+func WriteStar(w io.Writer, star Star) error {
+	b, err := json.Marshal(star)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadStars reads the stars from r, in the order they were first starred. Where
+// an item has been written more than once, the last version is kept, along
+// with the last note written for it.
+func ReadStars(r io.Reader) ([]Star, error) {
+	var stars []Star
+	indices := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var star Star
+		err := json.Unmarshal(line, &star)
+		if err != nil {
+			return nil, err
+		}
+		key := star.key()
+		if i, ok := indices[key]; ok {
+			if star.Note == "" {
+				// e.g. the item was saved to the wayback machine after it
+				// was noted
+				star.Note = stars[i].Note
+			}
+			stars[i] = star
+			continue
+		}
+		indices[key] = len(stars)
+		stars = append(stars, star)
+	}
+	return stars, scanner.Err()
+}
+
+// key identifies the item: by its canonical URL if it's known, so that an item
+// starred from a mirror is the same as one starred from the original,
+// otherwise by its ID or link.
+func (he HistoryEntry) key() string {
+	if he.Canonical != "" {
+		return he.Canonical
+	}
+	if he.ID != "" {
+		return he.ID
+	}
+	return he.Link
+}
+
+// FindStar returns the star of the item with the given link, or whose ID
+// starts with ref, so that IDs can be abbreviated.
+func FindStar(stars []Star, ref string) (Star, error) {
+	var found []Star
+	for _, star := range stars {
+		if ref == star.Link || ref == star.Canonical {
+			return star, nil
+		}
+		if star.ID != "" && strings.HasPrefix(star.ID, ref) {
+			found = append(found, star)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Star{}, fmt.Errorf("no starred item matches %s", ref)
+	case 1:
+		return found[0], nil
+	}
+	return Star{}, fmt.Errorf("%d starred items match %s, give more of the ID", len(found), ref)
+}
+
+// WithNotes adds the notes on starred items to the items, matching them by ID
+// or link.
+func WithNotes(stars []Star) DisplayOption {
+	notes := make(map[string]string)
+	for _, star := range stars {
+		if star.Note == "" {
+			continue
+		}
+		for _, key := range []string{star.ID, star.Link, star.Canonical} {
+			if key != "" {
+				notes[key] = star.Note
+			}
+		}
+	}
+	return func(item FeedItem) FeedItem {
+		if note, found := notes[item.ID]; found && item.ID != "" {
+			item.Note = note
+			return item
+		}
+		for _, link := range item.Links {
+			if note, found := notes[link]; found {
+				item.Note = note
+				break
+			}
+		}
+		return item
+	}
+}
+
+// SaveToWayback asks the Wayback Machine to save a copy of the page at the
+// link using its Save Page Now API. Returns the URL of the saved copy.
+func SaveToWayback(link string) (string, error) {
+	saveClient := &http.Client{Transport: client.Transport, Timeout: waybackSaveTimeout}
+	resp, err := saveClient.Post(waybackSaveAPI+link, "", nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("saving %s to the wayback machine: %s", link, resp.Status)
+	}
+	if location := resp.Header.Get("Content-Location"); location != "" {
+		return "https://web.archive.org" + location, nil
+	}
+	// Otherwise we were redirected to the snapshot
+	snapshot := resp.Request.URL.String()
+	if !strings.Contains(snapshot, "/web/") {
+		return "", fmt.Errorf("saving %s to the wayback machine: no snapshot returned", link)
+	}
+	return snapshot, nil
+}
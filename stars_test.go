@@ -0,0 +1,116 @@
+package rss
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadStarsKeepsLatest(t *testing.T) {
+	first := Star{HistoryEntry: HistoryEntry{ID: "1", Title: "First", Link: "https://example.com/1"}}
+	second := Star{HistoryEntry: HistoryEntry{ID: "2", Title: "Second", Link: "https://example.com/2"}}
+	saved := first
+	saved.Snapshot = "https://web.archive.org/web/2022/https://example.com/1"
+
+	var buf bytes.Buffer
+	for _, star := range []Star{first, second, saved} {
+		err := WriteStar(&buf, star)
+		assertEqual(t, nil, err)
+	}
+
+	stars, err := ReadStars(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, []Star{saved, second}, stars)
+}
+
+func TestReadStarsByCanonicalURL(t *testing.T) {
+	amp := Star{HistoryEntry: HistoryEntry{ID: "amp", Link: "https://example.com/amp/1", Canonical: "https://example.com/1"}}
+	original := Star{HistoryEntry: HistoryEntry{ID: "1", Link: "https://example.com/1", Canonical: "https://example.com/1"}}
+
+	var buf bytes.Buffer
+	for _, star := range []Star{amp, original} {
+		err := WriteStar(&buf, star)
+		assertEqual(t, nil, err)
+	}
+
+	stars, err := ReadStars(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, []Star{original}, stars)
+}
+
+func TestReadStarsKeepsNote(t *testing.T) {
+	star := Star{HistoryEntry: HistoryEntry{ID: "1", Link: "https://example.com/1"}}
+	noted := star
+	noted.Note = "Explains the outage"
+	saved := star
+	saved.Snapshot = "https://web.archive.org/web/2022/https://example.com/1"
+
+	var buf bytes.Buffer
+	for _, star := range []Star{star, noted, saved} {
+		err := WriteStar(&buf, star)
+		assertEqual(t, nil, err)
+	}
+
+	stars, err := ReadStars(&buf)
+	assertEqual(t, nil, err)
+	saved.Note = noted.Note
+	assertEqual(t, []Star{saved}, stars)
+}
+
+func TestFindStar(t *testing.T) {
+	stars := []Star{
+		{HistoryEntry: HistoryEntry{ID: "abc123", Link: "https://example.com/1"}},
+		{HistoryEntry: HistoryEntry{ID: "abd456", Link: "https://example.com/2"}},
+	}
+	testCases := []struct {
+		name string
+		ref  string
+		link string
+	}{
+		{name: "ID", ref: "abd456", link: "https://example.com/2"},
+		{name: "Abbreviated ID", ref: "abc", link: "https://example.com/1"},
+		{name: "Link", ref: "https://example.com/2", link: "https://example.com/2"},
+		{name: "Ambiguous", ref: "ab"},
+		{name: "Missing", ref: "xyz"},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			star, err := FindStar(stars, tc.ref)
+			assertEqual(t, tc.link == "", err != nil)
+			assertEqual(t, tc.link, star.Link)
+		})
+	}
+}
+
+func TestWithNotes(t *testing.T) {
+	notes := WithNotes([]Star{
+		{HistoryEntry: HistoryEntry{ID: "1", Link: "https://example.com/1"}, Note: "By ID"},
+		{HistoryEntry: HistoryEntry{Link: "https://example.com/2"}, Note: "By link"},
+	})
+	assertEqual(t, "By ID", notes(FeedItem{ID: "1"}).Note)
+	assertEqual(t, "By link", notes(FeedItem{ID: "2", Links: []string{"https://example.com/2"}}).Note)
+	assertEqual(t, "", notes(FeedItem{ID: "3", Links: []string{"https://example.com/3"}}).Note)
+}
+
+func TestSaveToWayback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/save/https://example.com/fail" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Location", "/web/20221101000000/https://example.com/post")
+	}))
+	defer server.Close()
+	defer func(api string) { waybackSaveAPI = api }(waybackSaveAPI)
+	waybackSaveAPI = server.URL + "/save/"
+
+	snapshot, err := SaveToWayback("https://example.com/post")
+	assertEqual(t, nil, err)
+	assertEqual(t, "https://web.archive.org/web/20221101000000/https://example.com/post", snapshot)
+
+	_, err = SaveToWayback("https://example.com/fail")
+	assertEqual(t, true, err != nil)
+}
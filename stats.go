@@ -0,0 +1,112 @@
+package rss
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Stats summarises reading habits from the history of opened items.
+type Stats struct {
+	// Reads is the number of items read from each feed.
+	Reads map[string]int
+	// WeeklyReads is the number of items read from each feed, keyed by ISO
+	// week e.g. "2022-W44".
+	WeeklyReads map[string]map[string]int
+	// NeverRead lists the subscribed feeds which have no reads.
+	NeverRead []string
+	// AverageAge is the mean age of items at the time they were read.
+	AverageAge time.Duration
+}
+
+// NewStats computes the reading statistics of the history entries. Feeds are
+// the titles of the subscribed feeds, used to find the ones never read.
+func NewStats(entries []HistoryEntry, feeds []string) Stats {
+	stats := Stats{
+		Reads:       make(map[string]int),
+		WeeklyReads: make(map[string]map[string]int),
+	}
+	var totalAge time.Duration
+	var aged int
+	for _, entry := range entries {
+		stats.Reads[entry.Feed]++
+
+		year, week := entry.Time.ISOWeek()
+		key := fmt.Sprintf("%d-W%02d", year, week)
+		weekly, ok := stats.WeeklyReads[key]
+		if !ok {
+			weekly = make(map[string]int)
+			stats.WeeklyReads[key] = weekly
+		}
+		weekly[entry.Feed]++
+
+		if !entry.Published.IsZero() {
+			totalAge += entry.Time.Sub(entry.Published)
+			aged++
+		}
+	}
+	if aged > 0 {
+		stats.AverageAge = totalAge / time.Duration(aged)
+	}
+	for _, feed := range feeds {
+		if stats.Reads[feed] == 0 {
+			stats.NeverRead = append(stats.NeverRead, feed)
+		}
+	}
+	sort.Strings(stats.NeverRead)
+	return stats
+}
+
+// DisplayStats writes the stats to the given writer in tab-separated columns.
+func DisplayStats(w io.Writer, stats Stats, opts ...FormatOption) error {
+	c := colourizerOf(opts)
+	var err error
+	write := func(format string, a ...interface{}) {
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, format, a...)
+	}
+
+	write("%s\n", c.colourize("Most read feeds", green))
+	for _, feed := range sortedByCount(stats.Reads) {
+		write("\t%s\t%d\n", feed, stats.Reads[feed])
+	}
+
+	write("%s\n", c.colourize("Reads per week", green))
+	weeks := make([]string, 0, len(stats.WeeklyReads))
+	for week := range stats.WeeklyReads {
+		weeks = append(weeks, week)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(weeks)))
+	for _, week := range weeks {
+		counts := stats.WeeklyReads[week]
+		for _, feed := range sortedByCount(counts) {
+			write("\t%s\t%s\t%d\n", c.colourize(week, yellow), feed, counts[feed])
+		}
+	}
+
+	write("%s\n", c.colourize("Never read", green))
+	for _, feed := range stats.NeverRead {
+		write("\t%s\n", feed)
+	}
+
+	write("%s\t%s\n", c.colourize("Average age when read", green), stats.AverageAge.Round(time.Minute))
+	return err
+}
+
+// sortedByCount returns the keys of counts with the highest counts first.
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] == counts[keys[j]] {
+			return keys[i] < keys[j]
+		}
+		return counts[keys[i]] > counts[keys[j]]
+	})
+	return keys
+}
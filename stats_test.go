@@ -0,0 +1,25 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewStats(t *testing.T) {
+	read := time.Date(2022, 11, 1, 12, 0, 0, 0, time.UTC)
+	entries := []HistoryEntry{
+		{Time: read, Feed: "A", Published: read.Add(-2 * time.Hour)},
+		{Time: read.Add(7 * 24 * time.Hour), Feed: "A", Published: read.Add(7*24*time.Hour - 4*time.Hour)},
+		{Time: read, Feed: "B"},
+	}
+
+	stats := NewStats(entries, []string{"A", "B", "C"})
+
+	assertEqual(t, map[string]int{"A": 2, "B": 1}, stats.Reads)
+	assertEqual(t, map[string]map[string]int{
+		"2022-W44": {"A": 1, "B": 1},
+		"2022-W45": {"A": 1},
+	}, stats.WeeklyReads)
+	assertEqual(t, []string{"C"}, stats.NeverRead)
+	assertEqual(t, 3*time.Hour, stats.AverageAge)
+}
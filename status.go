@@ -0,0 +1,108 @@
+package rss
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Snapshot records the items shown by the last run, so that they can be
+// summarised quickly without fetching the feeds again.
+type Snapshot struct {
+	Time  time.Time      `json:"time"`
+	Items []SnapshotItem `json:"items"`
+}
+
+// SnapshotItem is an item recorded in a snapshot.
+type SnapshotItem struct {
+	ID        string    `json:"id,omitempty"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Feed      string    `json:"feed"`
+	Published time.Time `json:"published"`
+	Note      string    `json:"note,omitempty"`
+}
+
+// NewSnapshot records the items as of the given time.
+func NewSnapshot(feedItems []FeedItem, t time.Time) Snapshot {
+	snapshot := Snapshot{Time: t, Items: make([]SnapshotItem, 0, len(feedItems))}
+	for _, item := range feedItems {
+		// Skip the headers added by display modes
+		if len(item.Links) == 0 {
+			continue
+		}
+		var link string
+		if len(item.Links) > 0 {
+			link = item.Links[0]
+		}
+		snapshot.Items = append(snapshot.Items, SnapshotItem{
+			ID:        item.ID,
+			Title:     item.Title,
+			Link:      link,
+			Feed:      item.Source(),
+			Published: item.PublishTime,
+			Note:      item.Note,
+		})
+	}
+	return snapshot
+}
+
+// WriteSnapshot writes the snapshot to w.
+func WriteSnapshot(w io.Writer, snapshot Snapshot) error {
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// ReadSnapshot reads a snapshot written by WriteSnapshot.
+func ReadSnapshot(r io.Reader) (Snapshot, error) {
+	var snapshot Snapshot
+	err := json.NewDecoder(r).Decode(&snapshot)
+	return snapshot, err
+}
+
+// FormatStatus summarises the snapshot on a single line for status bars. The
+// format can contain the placeholders {unread}, the number of items not in
+// the history, {total}, the number of items, {newest} and {feed}, the title
+// and feed of the newest unread item, and {age}, how long ago the snapshot
+// was taken. Placeholders are empty if there isn't anything to fill them.
+func FormatStatus(format string, snapshot Snapshot, history []HistoryEntry, now time.Time) string {
+	unread := Unread(history)
+	var unreadItems []SnapshotItem
+	for _, item := range snapshot.Items {
+		feedItem := FeedItem{ID: item.ID, Links: []string{item.Link}}
+		if unread(feedItem) {
+			unreadItems = append(unreadItems, item)
+		}
+	}
+	sort.SliceStable(unreadItems, func(i, j int) bool {
+		return unreadItems[i].Published.After(unreadItems[j].Published)
+	})
+	var newest SnapshotItem
+	if len(unreadItems) > 0 {
+		newest = unreadItems[0]
+	}
+	var age string
+	if !snapshot.Time.IsZero() {
+		age = shortDuration(now.Sub(snapshot.Time))
+	}
+	return strings.NewReplacer(
+		"{unread}", strconv.Itoa(len(unreadItems)),
+		"{total}", strconv.Itoa(len(snapshot.Items)),
+		"{newest}", newest.Title,
+		"{feed}", newest.Feed,
+		"{age}", age,
+	).Replace(format)
+}
+
+// shortDuration formats d in its largest whole unit e.g. "5m", "3h" or "2d".
+func shortDuration(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return strconv.Itoa(int(d.Minutes())) + "m"
+	case d < 24*time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h"
+	}
+	return strconv.Itoa(int(d.Hours()/24)) + "d"
+}
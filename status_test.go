@@ -0,0 +1,27 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFormatStatus(t *testing.T) {
+	now := time.Date(2022, 11, 5, 12, 0, 0, 0, time.UTC)
+	feedItems := []FeedItem{
+		{ID: "1", Title: "Old", Links: []string{"https://example.com/1"}, Feed: "Example", PublishTime: now.Add(-3 * time.Hour)},
+		{ID: "2", Title: "Read", Links: []string{"https://example.com/2"}, Feed: "Example", PublishTime: now.Add(-time.Hour)},
+		{ID: "3", Title: "New", Links: []string{"https://example.com/3"}, Feed: "Other", PublishTime: now.Add(-2 * time.Hour)},
+	}
+	var buf bytes.Buffer
+	err := WriteSnapshot(&buf, NewSnapshot(feedItems, now.Add(-90*time.Second)))
+	assertEqual(t, nil, err)
+	snapshot, err := ReadSnapshot(&buf)
+	assertEqual(t, nil, err)
+
+	history := []HistoryEntry{{ID: "2", Link: "https://example.com/2"}}
+	status := FormatStatus("{unread}/{total} unread, {newest} ({feed}) {age} ago", snapshot, history, now)
+	assertEqual(t, "2/3 unread, New (Other) 1m ago", status)
+
+	assertEqual(t, "0 unread", FormatStatus("{unread} unread", Snapshot{}, nil, now))
+}
@@ -0,0 +1,338 @@
+package rss
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"filippo.io/age"
+)
+
+// storedFeed is the file a feed is stored in. It is an RSS document with the
+// URL and name of the feed added, holding every channel fetched from the URL.
+type storedFeed struct {
+	XMLName  xml.Name  `xml:"rss"`
+	Version  string    `xml:"version,attr"`
+	URL      string    `xml:"url,attr"`
+	Name     string    `xml:"name,attr,omitempty"`
+	Channels []Channel `xml:"channel"`
+}
+
+// StoreError is the failure to store a feed.
+type StoreError struct {
+	URL string
+	Err error
+}
+
+func (e StoreError) Error() string {
+	return fmt.Sprintf("error storing %s: %s", e.URL, e.Err.Error())
+}
+
+func (e StoreError) Unwrap() error {
+	return e.Err
+}
+
+// StoreErrors are the failures to store feeds, one for each URL.
+type StoreErrors []StoreError
+
+func (e StoreErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Store keeps feeds and the state recorded while reading them, such as the
+// history and stars, so that other backends can be used instead of files.
+type Store interface {
+	// SaveFeed adds the feed's items to those stored from previous fetches,
+	// so that they are kept after they drop out of the feed.
+	SaveFeed(feed *Feed) error
+	// LoadFeeds returns the feeds which have been saved, ordered by URL.
+	LoadFeeds() ([]*Feed, error)
+	// ReadState returns the records of the named state e.g. HistoryState,
+	// which are empty if none have been appended yet.
+	ReadState(name string) (io.Reader, error)
+	// AppendState opens the named state for records to be appended to it.
+	AppendState(name string) (io.WriteCloser, error)
+	// Stars returns the starred items.
+	Stars() ([]Star, error)
+}
+
+// The names of the state recorded while reading feeds.
+const (
+	HistoryState = "history"
+	StarsState   = "stars"
+	TagsState    = "tags"
+	QueueState   = "queue"
+)
+
+// SaveAll saves each of the feeds in the store, saving feeds from different
+// URLs in parallel. Every feed is attempted, and failures are returned
+// together as StoreErrors.
+func SaveAll(store Store, feeds []*Feed) error {
+	// Feeds from the same URL are saved one after another so that they are
+	// merged
+	byURL := make(map[string][]*Feed)
+	for _, feed := range feeds {
+		byURL[feed.URL] = append(byURL[feed.URL], feed)
+	}
+	var mu sync.Mutex
+	var errs StoreErrors
+	var wg sync.WaitGroup
+	for url, feeds := range byURL {
+		wg.Add(1)
+		go func(url string, feeds []*Feed) {
+			defer wg.Done()
+			for _, feed := range feeds {
+				err := store.SaveFeed(feed)
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, StoreError{URL: url, Err: err})
+					mu.Unlock()
+					return
+				}
+			}
+		}(url, feeds)
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].URL < errs[j].URL })
+	return errs
+}
+
+// StoreFeed adds the feed's items to those stored in the folder from previous
+// fetches, so that they are kept after they drop out of the feed. Each URL is
+// stored in a file of its own, along with any other channels fetched from it.
+func StoreFeed(feed *Feed, folder string) error {
+	return storeFeed(feed, folder, nil)
+}
+
+// storeFeed stores the feed in the folder as StoreFeed does, encrypted to the
+// identity unless it is nil.
+func storeFeed(feed *Feed, folder string, identity *age.X25519Identity) error {
+	err := os.MkdirAll(folder, 0755)
+	if err != nil {
+		return err
+	}
+	return storeFeeds(folder, []*Feed{feed}, identity)
+}
+
+// StoreAll stores each of the feeds in the folder, writing feeds from
+// different URLs in parallel. Every feed is attempted, and failures are
+// returned together as StoreErrors.
+func StoreAll(feeds []*Feed, folder string) error {
+	return SaveAll(NewFileStore(folder, WithFeedsFolder(folder)), feeds)
+}
+
+// IsStored returns true if the feed with the given URL has been stored in the
+// folder.
+func IsStored(folder, url string) bool {
+	_, err := os.Stat(storePath(folder, url))
+	return err == nil
+}
+
+// LoadStored returns the feeds stored in the folder, ordered by URL. A folder
+// which doesn't exist has no feeds.
+func LoadStored(folder string) ([]*Feed, error) {
+	return loadStored(folder, nil)
+}
+
+// loadStored returns the feeds stored in the folder as LoadStored does,
+// decrypting those encrypted to the identity.
+func loadStored(folder string, identity *age.X25519Identity) ([]*Feed, error) {
+	paths, err := filepath.Glob(filepath.Join(folder, "*.xml"))
+	if err != nil {
+		return nil, err
+	}
+	var feeds []*Feed
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		b, err = DecryptDocument(b, identity)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		stored, err := decodeStored(b)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		feeds = append(feeds, stored...)
+	}
+	sort.SliceStable(feeds, func(i, j int) bool { return feeds[i].URL < feeds[j].URL })
+	return feeds, nil
+}
+
+// ErrNotStored is returned when an item can't be found among the stored feeds.
+var ErrNotStored = errors.New("item not stored")
+
+// StoredDescription returns the description of the item, or its content if it
+// has any, from the feeds saved in the store. It lets descriptions be dropped
+// while fetching and read when the item is opened.
+func StoredDescription(store Store, item FeedItem) ([]byte, error) {
+	feeds, err := store.LoadFeeds()
+	if err != nil {
+		return nil, err
+	}
+	for _, feed := range feeds {
+		if feed.source() != item.Feed {
+			continue
+		}
+		newFeedItem := newFeedItemCreator(feed)
+		for _, stored := range feed.Channel.Items {
+			feedItem, err := newFeedItem(stored)
+			if err != nil || feedItem.ID != item.ID {
+				continue
+			}
+			if len(stored.Content) > 0 {
+				return stored.Content, nil
+			}
+			return stored.Description, nil
+		}
+	}
+	return nil, ErrNotStored
+}
+
+// storeFeeds merges feeds, all from the same URL, into the file they are
+// stored in, encrypted to the identity unless it is nil.
+func storeFeeds(folder string, feeds []*Feed, identity *age.X25519Identity) error {
+	path := storePath(folder, feeds[0].URL)
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	b, err = DecryptDocument(b, identity)
+	if err != nil {
+		return err
+	}
+	b, err = mergeStored(b, feeds)
+	if err != nil {
+		return err
+	}
+	b, err = EncryptDocument(b, identity)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, b, 0644)
+}
+
+// mergeStored merges feeds, all from the same URL, into the stored document,
+// which is empty if none has been stored yet.
+func mergeStored(b []byte, feeds []*Feed) ([]byte, error) {
+	stored := storedFeed{URL: feeds[0].URL}
+	if len(b) > 0 {
+		err := xml.Unmarshal(b, &stored)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, feed := range feeds {
+		stored.Name = feed.Name
+		stored.Channels = mergeChannel(stored.Channels, feed.Channel)
+	}
+	stored.Version = "2.0"
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	err := encoder.Encode(stored)
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// decodeStored returns the feeds in a stored document, one for each channel.
+func decodeStored(b []byte) ([]*Feed, error) {
+	var stored storedFeed
+	err := xml.Unmarshal(b, &stored)
+	if err != nil {
+		return nil, err
+	}
+	var feeds []*Feed
+	for _, channel := range stored.Channels {
+		feeds = append(feeds, &Feed{URL: stored.URL, Name: stored.Name, RSS: RSS{Channel: channel}})
+	}
+	return feeds, nil
+}
+
+// mergeChannel replaces the channel with the same title, keeping the items
+// it had which are no longer in the new channel after the new items. Items
+// are the same if they have the same fingerprint, since some feeds give them
+// new GUIDs each time, or failing that the same GUID, e.g. if their title has
+// been edited.
+func mergeChannel(channels []Channel, channel Channel) []Channel {
+	for i, old := range channels {
+		if old.Title != channel.Title {
+			continue
+		}
+		seen := make(map[string]struct{})
+		seenGUIDs := make(map[string]struct{})
+		for _, item := range channel.Items {
+			seen[storeKey(item)] = struct{}{}
+			if guid := strings.TrimSpace(item.GUID); guid != "" {
+				seenGUIDs[guid] = struct{}{}
+			}
+		}
+		for _, item := range old.Items {
+			if _, found := seen[storeKey(item)]; found {
+				continue
+			}
+			if _, found := seenGUIDs[strings.TrimSpace(item.GUID)]; found {
+				continue
+			}
+			channel.Items = append(channel.Items, item)
+		}
+		channels[i] = channel
+		return channels
+	}
+	return append(channels, channel)
+}
+
+// storeKey identifies an item among the items stored for a channel by its
+// fingerprint, using its GUID as its link if it has none as feeds do.
+func storeKey(item Item) string {
+	link := strings.TrimSpace(item.Link)
+	if link == "" {
+		link = strings.TrimSpace(item.GUID)
+	}
+	return FeedItem{Title: item.Title, Links: []string{link}}.Fingerprint()
+}
+
+// storePath returns the file the feed with the given URL is stored in.
+func storePath(folder, url string) string {
+	return filepath.Join(folder, storeName(url)+".xml")
+}
+
+// storeName names the feed with the given URL after its host, with a hash of
+// the URL so that feeds never collide.
+func storeName(url string) string {
+	name := url
+	if _, rest, found := strings.Cut(url, "://"); found {
+		name = rest
+	}
+	name, _, _ = strings.Cut(name, "/")
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(name))
+	sum := sha256.Sum256([]byte(url))
+	return name + "-" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,107 @@
+package rss
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	folder := t.TempDir()
+	feed := func(title string, items ...Item) *Feed {
+		return &Feed{URL: "https://example.com/feed", Name: "Example", RSS: RSS{Channel: Channel{Title: title, Items: items}}}
+	}
+	one := Item{Title: "One", Link: "https://example.com/1", GUID: "1", Description: []byte("<p>One</p>")}
+	two := Item{Title: "Two", Link: "https://example.com/2", GUID: "2"}
+	three := Item{Title: "Three", Link: "https://example.com/3"}
+
+	assertEqual(t, false, IsStored(folder, "https://example.com/feed"))
+	err := StoreFeed(feed("Blog", one, two), folder)
+	assertEqual(t, nil, err)
+	assertEqual(t, true, IsStored(folder, "https://example.com/feed"))
+	// One has dropped out of the feed, and the second channel from the same
+	// URL is stored alongside the first
+	err = StoreAll([]*Feed{feed("Blog", three, two), feed("Links", one)}, folder)
+	assertEqual(t, nil, err)
+
+	feeds, err := LoadStored(folder)
+	assertEqual(t, nil, err)
+	assertEqual(t, 2, len(feeds))
+	assertEqual(t, "Example", feeds[0].Title())
+	assertEqual(t, "https://example.com/feed", feeds[0].URL)
+	assertEqual(t, "Blog", feeds[0].Channel.Title)
+	var titles []string
+	for _, item := range feeds[0].Channel.Items {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, []string{"Three", "Two", "One"}, titles)
+	assertEqual(t, "<p>One</p>", string(feeds[0].Channel.Items[2].Description))
+	assertEqual(t, "Links", feeds[1].Channel.Title)
+}
+
+func TestStoreRegeneratedGUIDs(t *testing.T) {
+	t.Parallel()
+	folder := t.TempDir()
+	feed := func(items ...Item) *Feed {
+		return &Feed{URL: "https://example.com/feed", RSS: RSS{Channel: Channel{Title: "Blog", Items: items}}}
+	}
+	// The feed gives its items new GUIDs each time it is built
+	err := StoreFeed(feed(Item{Title: "One", Link: "https://example.com/1", GUID: "a1"}), folder)
+	assertEqual(t, nil, err)
+	err = StoreFeed(feed(Item{Title: "One", Link: "https://example.com/1?utm_source=rss", GUID: "b2"}), folder)
+	assertEqual(t, nil, err)
+	// An item keeping its GUID is the same item even if its title is edited
+	err = StoreFeed(feed(Item{Title: "One, edited", Link: "https://example.com/1", GUID: "b2"}), folder)
+	assertEqual(t, nil, err)
+
+	feeds, err := LoadStored(folder)
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(feeds))
+	assertEqual(t, 1, len(feeds[0].Channel.Items))
+	assertEqual(t, "One, edited", feeds[0].Channel.Items[0].Title)
+}
+
+func TestStoreAllErrors(t *testing.T) {
+	t.Parallel()
+	folder := t.TempDir()
+	broken := &Feed{URL: "https://broken.example.com/feed"}
+	err := os.WriteFile(storePath(folder, broken.URL), []byte("<rss>"), 0644)
+	assertEqual(t, nil, err)
+
+	err = StoreAll([]*Feed{broken, {URL: "https://example.com/feed"}}, folder)
+	var errs StoreErrors
+	assertEqual(t, true, errors.As(err, &errs))
+	assertEqual(t, 1, len(errs))
+	assertEqual(t, broken.URL, errs[0].URL)
+	// The other feed is still stored
+	_, err = os.Stat(storePath(folder, "https://example.com/feed"))
+	assertEqual(t, nil, err)
+}
+
+func TestStoredDescription(t *testing.T) {
+	t.Parallel()
+	folder := t.TempDir()
+	store := NewFileStore(folder, WithFeedsFolder(folder))
+	feed := &Feed{URL: "https://example.com/feed", Name: "Example", RSS: RSS{Channel: Channel{Title: "Blog", Items: []Item{
+		{Title: "One", Link: "https://example.com/1", GUID: "1", Description: []byte("<p>One</p>")},
+		{Title: "Two", Link: "https://example.com/2", GUID: "2", Description: []byte("<p>Two</p>"), Content: []byte("<p>All of two</p>")},
+	}}}}
+	err := store.SaveFeed(feed)
+	assertEqual(t, nil, err)
+
+	feedItems := UnpackFeed(feed)
+	assertEqual(t, 2, len(feedItems))
+	for _, item := range feedItems {
+		description, err := StoredDescription(store, item)
+		assertEqual(t, nil, err)
+		if item.Title == "One" {
+			assertEqual(t, "<p>One</p>", string(description))
+		} else {
+			assertEqual(t, "<p>All of two</p>", string(description))
+		}
+	}
+
+	_, err = StoredDescription(store, FeedItem{ID: "missing", Feed: "Example"})
+	assertEqual(t, ErrNotStored, err)
+}
@@ -0,0 +1,142 @@
+package rss
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+)
+
+const pinnedTag = "pinned"
+
+// Subscription is a line of the feeds file: the URL of a feed followed by any
+// tags, separated by whitespace e.g. "https://example.com/rss tech news".
+// Lines starting with "#" are disabled.
+type Subscription struct {
+	URL      string
+	Tags     []string
+	Disabled bool
+}
+
+// HasTag returns true if the subscription has the given tag.
+func (s Subscription) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Pinned returns true if the subscription has the "pinned" tag, so that it
+// always comes first.
+func (s Subscription) Pinned() bool {
+	return s.HasTag(pinnedTag)
+}
+
+func (s Subscription) String() string {
+	line := strings.Join(append([]string{s.URL}, s.Tags...), " ")
+	if s.Disabled {
+		return "#" + line
+	}
+	return line
+}
+
+// ReadSubscriptions reads all of the subscriptions in the feeds file,
+// including disabled ones, skipping blank lines.
+func ReadSubscriptions(r io.Reader) []Subscription {
+	scanner := bufio.NewScanner(r)
+	var subs []Subscription
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		disabled := strings.HasPrefix(line, "#")
+		fields := strings.Fields(strings.TrimPrefix(line, "#"))
+		if len(fields) == 0 {
+			continue
+		}
+		subs = append(subs, Subscription{
+			URL:      fields[0],
+			Tags:     fields[1:],
+			Disabled: disabled,
+		})
+	}
+	return subs
+}
+
+// Ordered returns the subscriptions with the pinned ones first, otherwise
+// keeping the order of the feeds file.
+func Ordered(subs []Subscription) []Subscription {
+	ordered := make([]Subscription, len(subs))
+	copy(ordered, subs)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Pinned() && !ordered[j].Pinned()
+	})
+	return ordered
+}
+
+// WriteSubscriptions writes the subscriptions to w in the format of the feeds
+// file.
+func WriteSubscriptions(w io.Writer, subs []Subscription) error {
+	bw := bufio.NewWriter(w)
+	for _, sub := range subs {
+		_, err := bw.WriteString(sub.String() + "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// pinnedHeader is the title of the section of pinned feeds.
+const pinnedHeader = "Pinned"
+
+// PinnedFirst shows the newest item of each of the pinned feeds, keyed by
+// their name or URL, in a section at the top in the order given. The rest of
+// the items are passed to mode as usual.
+func PinnedFirst(mode DisplayMode, feeds ...string) DisplayMode {
+	rank := make(map[string]int)
+	for i, feed := range feeds {
+		rank[feed] = i
+	}
+	return func(feedItems []FeedItem) []FeedItem {
+		newest := make([]int, len(feeds))
+		for i := range newest {
+			newest[i] = -1
+		}
+		for i, item := range feedItems {
+			r, found := rank[item.Feed]
+			if !found {
+				continue
+			}
+			if newest[r] < 0 || item.PublishTime.After(feedItems[newest[r]].PublishTime) {
+				newest[r] = i
+			}
+		}
+		var pinned []FeedItem
+		header := make(map[int]bool)
+		for _, i := range newest {
+			if i >= 0 {
+				pinned = append(pinned, feedItems[i])
+				header[i] = true
+			}
+		}
+		if len(pinned) == 0 {
+			return mode(feedItems)
+		}
+		rest := make([]FeedItem, 0, len(feedItems)-len(pinned))
+		for i, item := range feedItems {
+			if !header[i] {
+				rest = append(rest, item)
+			}
+		}
+		rest = mode(rest)
+		result := make([]FeedItem, 0, len(rest)+len(pinned)+2)
+		result = append(result, FeedItem{Title: pinnedHeader})
+		result = append(result, pinned...)
+		// Grouped modes start with a gap of their own
+		if len(rest) > 0 && (rest[0].Title != "" || len(rest[0].Links) > 0) {
+			result = append(result, FeedItem{})
+		}
+		return append(result, rest...)
+	}
+}
@@ -0,0 +1,71 @@
+package rss
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionsRoundTrip(t *testing.T) {
+	raw := `https://example.com/rss
+https://news.example.com/rss news daily
+
+#https://old.example.com/rss tech
+`
+	subs := ReadSubscriptions(strings.NewReader(raw))
+	assertEqual(t, []Subscription{
+		{URL: "https://example.com/rss", Tags: []string{}},
+		{URL: "https://news.example.com/rss", Tags: []string{"news", "daily"}},
+		{URL: "https://old.example.com/rss", Tags: []string{"tech"}, Disabled: true},
+	}, subs)
+	assertEqual(t, true, subs[1].HasTag("News"))
+
+	var buf bytes.Buffer
+	err := WriteSubscriptions(&buf, subs)
+	assertEqual(t, nil, err)
+	assertEqual(t, strings.Replace(raw, "\n\n", "\n", 1), buf.String())
+
+	assertEqual(t, []string{"https://example.com/rss", "https://news.example.com/rss"}, GetURLs(strings.NewReader(raw)))
+}
+
+func TestOrdered(t *testing.T) {
+	subs := []Subscription{
+		{URL: "1"},
+		{URL: "2", Tags: []string{"pinned"}},
+		{URL: "3"},
+		{URL: "4", Tags: []string{"news", "pinned"}},
+	}
+	var urls []string
+	for _, sub := range Ordered(subs) {
+		urls = append(urls, sub.URL)
+	}
+	assertEqual(t, []string{"2", "4", "1", "3"}, urls)
+}
+
+func TestPinnedFirst(t *testing.T) {
+	now := time.Now()
+	feedItems := []FeedItem{
+		{Title: "Old news", Feed: "news", Links: []string{"a"}, PublishTime: now.Add(-3 * time.Hour)},
+		{Title: "Sunny", Feed: "weather", Links: []string{"b"}, PublishTime: now.Add(-2 * time.Hour)},
+		{Title: "All systems operational", Feed: "status", Links: []string{"c"}, PublishTime: now.Add(-5 * time.Hour)},
+		{Title: "Rainy", Feed: "weather", Links: []string{"d"}, PublishTime: now.Add(-time.Hour)},
+		{Title: "News", Feed: "news", Links: []string{"e"}, PublishTime: now},
+	}
+	titles := func(feedItems []FeedItem) []string {
+		var titles []string
+		for _, item := range feedItems {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+	mode := PinnedFirst(ReverseChronological, "weather", "status")
+	assertEqual(t, []string{"Pinned", "Rainy", "All systems operational", "", "News", "Sunny", "Old news"}, titles(mode(append([]FeedItem{}, feedItems...))))
+
+	// Grouped modes aren't given a second gap
+	mode = PinnedFirst(Grouped, "weather")
+	assertEqual(t, []string{"Pinned", "Rainy", "", "news", "News", "Old news", "", "weather", "Sunny", "", "status", "All systems operational"}, titles(mode(append([]FeedItem{}, feedItems...))))
+
+	mode = PinnedFirst(ReverseChronological, "missing")
+	assertEqual(t, []string{"News", "Rainy", "Sunny", "Old news", "All systems operational"}, titles(mode(append([]FeedItem{}, feedItems...))))
+}
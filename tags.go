@@ -0,0 +1,155 @@
+package rss
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// TagEntry records tags being added to or removed from an item. Tags starting
+// with "-" are removed.
+type TagEntry struct {
+	HistoryEntry
+	Tags []string `json:"tags"`
+}
+
+// TaggedItem is an item with the tags given to it.
+type TaggedItem struct {
+	HistoryEntry
+	Tags []string
+}
+
+// WriteTagEntry appends the entry to w as a single line.
+func WriteTagEntry(w io.Writer, entry TagEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ReadTags reads the entries from r and returns the items with tags, in the
+// order they were first tagged. Items whose tags have all been removed are
+// left out.
+func ReadTags(r io.Reader) ([]TaggedItem, error) {
+	var tagged []TaggedItem
+	indices := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var entry TagEntry
+		err := json.Unmarshal(line, &entry)
+		if err != nil {
+			return nil, err
+		}
+		key := entry.key()
+		i, found := indices[key]
+		if !found {
+			i = len(tagged)
+			indices[key] = i
+			tagged = append(tagged, TaggedItem{HistoryEntry: entry.HistoryEntry})
+		} else if entry.Title != "" {
+			// Later entries may know more about the item
+			tags := tagged[i].Tags
+			tagged[i] = TaggedItem{HistoryEntry: entry.HistoryEntry, Tags: tags}
+		}
+		tagged[i].Tags = applyTags(tagged[i].Tags, entry.Tags)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	kept := tagged[:0]
+	for _, item := range tagged {
+		if len(item.Tags) > 0 {
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// applyTags adds the changes to the tags, removing those starting with "-".
+func applyTags(tags, changes []string) []string {
+	for _, change := range changes {
+		if removed := strings.TrimPrefix(change, "-"); removed != change {
+			kept := tags[:0]
+			for _, tag := range tags {
+				if tag != removed {
+					kept = append(kept, tag)
+				}
+			}
+			tags = kept
+			continue
+		}
+		if change != "" && !hasTag(tags, change) {
+			tags = append(tags, change)
+		}
+	}
+	return tags
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// WithItemTags adds the tags given to items to them, matching them by ID or
+// link.
+func WithItemTags(tagged []TaggedItem) DisplayOption {
+	tags := make(map[string][]string)
+	for _, item := range tagged {
+		for _, key := range []string{item.ID, item.Link, item.Canonical} {
+			if key != "" {
+				tags[key] = item.Tags
+			}
+		}
+	}
+	return func(item FeedItem) FeedItem {
+		added, found := tags[item.ID]
+		if !found || item.ID == "" {
+			for _, link := range item.Links {
+				if added, found = tags[link]; found {
+					break
+				}
+			}
+		}
+		for _, tag := range added {
+			if !hasTag(item.Tags, tag) {
+				item.Tags = append(item.Tags, tag)
+			}
+		}
+		return item
+	}
+}
+
+// HasTag lets through items with the given tag, whether it was given by hand
+// or by a rule. Since tags are added as items are displayed, it should be used
+// with Only rather than when fetching items.
+func HasTag(tag string) Filter {
+	return func(item FeedItem) bool {
+		return hasTag(item.Tags, tag)
+	}
+}
+
+// Only applies the mode to the items passing the filters, for filtering on
+// what display options add to items e.g. tags.
+func Only(mode DisplayMode, filters ...Filter) DisplayMode {
+	fs := Filters(filters)
+	return func(feedItems []FeedItem) []FeedItem {
+		var kept []FeedItem
+		for _, item := range feedItems {
+			if fs.Apply(item) {
+				kept = append(kept, item)
+			}
+		}
+		return mode(kept)
+	}
+}
@@ -0,0 +1,50 @@
+package rss
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadTags(t *testing.T) {
+	entries := []TagEntry{
+		{HistoryEntry: HistoryEntry{ID: "1", Title: "One", Link: "https://example.com/1"}, Tags: []string{"toread", "go"}},
+		{HistoryEntry: HistoryEntry{ID: "2", Title: "Two", Link: "https://example.com/2"}, Tags: []string{"toread"}},
+		{HistoryEntry: HistoryEntry{ID: "1", Title: "One", Link: "https://example.com/1"}, Tags: []string{"-toread", "done"}},
+		{HistoryEntry: HistoryEntry{ID: "2", Title: "Two", Link: "https://example.com/2"}, Tags: []string{"-toread"}},
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		err := WriteTagEntry(&buf, entry)
+		assertEqual(t, nil, err)
+	}
+
+	tagged, err := ReadTags(&buf)
+	assertEqual(t, nil, err)
+	assertEqual(t, 1, len(tagged))
+	assertEqual(t, "One", tagged[0].Title)
+	assertEqual(t, []string{"go", "done"}, tagged[0].Tags)
+}
+
+func TestItemTags(t *testing.T) {
+	tagged := []TaggedItem{
+		{HistoryEntry: HistoryEntry{ID: "1"}, Tags: []string{"toread"}},
+		{HistoryEntry: HistoryEntry{Link: "https://example.com/2"}, Tags: []string{"toread", "go"}},
+	}
+	feedItems := []FeedItem{
+		{ID: "1", Title: "One"},
+		{ID: "2", Title: "Two", Links: []string{"https://example.com/2"}, Tags: []string{"go"}},
+		{ID: "3", Title: "Three", Tags: []string{"toread"}},
+		{ID: "4", Title: "Four"},
+	}
+	mode := Annotated(Only(unchangedMode, HasTag("toread")), WithItemTags(tagged))
+	var titles []string
+	for _, item := range mode(feedItems) {
+		titles = append(titles, item.Title)
+	}
+	assertEqual(t, []string{"One", "Two", "Three"}, titles)
+	assertEqual(t, []string{"go", "toread"}, feedItems[1].Tags)
+}
+
+func unchangedMode(feedItems []FeedItem) []FeedItem {
+	return feedItems
+}
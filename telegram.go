@@ -0,0 +1,338 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// telegramPollTimeout is how long Telegram holds a request for updates
+	// open while there are none.
+	telegramPollTimeout = 30 * time.Second
+	// telegramRetry is how long to wait after failing to get updates.
+	telegramRetry = 5 * time.Second
+	// telegramMaxMessage is the longest message Telegram accepts.
+	telegramMaxMessage = 4096
+	// telegramReadItems is the number of items /read sends by default, and
+	// telegramMaxReadItems the most it sends.
+	telegramReadItems    = 5
+	telegramMaxReadItems = 20
+)
+
+const telegramHelp = `/list lists the feeds
+/add <url> [tags...] subscribes to a feed
+/read [n] sends the newest n items`
+
+// TelegramBot runs a Telegram bot for a server, pushing its new items to chats
+// and answering commands from them to manage the feeds.
+type TelegramBot struct {
+	// api is the URL of the Bot API for the bot's token.
+	api    string
+	server *Server
+	chats  map[int64]bool
+	add    func(url string, tags []string) error
+	list   func() ([]string, error)
+	stream <-chan FeedItem
+	stop   func()
+	// started is when the bot was created, and fresh whether the server
+	// had any items by then.
+	started time.Time
+	fresh   bool
+
+	mu sync.Mutex
+	// quietBefore holds when feeds were added, keyed by URL, so that the
+	// items they had already published aren't pushed.
+	quietBefore map[string]time.Time
+}
+
+// TelegramOption configures a TelegramBot.
+type TelegramOption func(*TelegramBot)
+
+// OnAddFeed subscribes to a feed with the given tags when asked to with /add.
+func OnAddFeed(fn func(url string, tags []string) error) TelegramOption {
+	return func(b *TelegramBot) {
+		b.add = fn
+	}
+}
+
+// ListFeeds returns the URLs of the feeds listed by /list.
+func ListFeeds(fn func() ([]string, error)) TelegramOption {
+	return func(b *TelegramBot) {
+		b.list = fn
+	}
+}
+
+// NewTelegramBot returns a bot with the token given by BotFather which talks to
+// the chats with the given IDs, ignoring everyone else. The items the server
+// finds from then on are pushed once the bot is run.
+func NewTelegramBot(token string, chats []int64, server *Server, opts ...TelegramOption) *TelegramBot {
+	b := &TelegramBot{
+		api:         "https://api.telegram.org/bot" + token,
+		server:      server,
+		chats:       make(map[int64]bool),
+		started:     time.Now(),
+		fresh:       len(server.Items()) == 0,
+		quietBefore: make(map[string]time.Time),
+	}
+	b.stream, b.stop = server.watch()
+	for _, chat := range chats {
+		b.chats[chat] = true
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+// Run pushes new items and answers commands until the context is cancelled.
+// A server without any items yet doesn't push those already in its feeds, so
+// that the chats aren't flooded when it is first started.
+func (b *TelegramBot) Run(ctx context.Context) {
+	defer b.stop()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.answer(ctx)
+	}()
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-b.stream:
+			if b.fresh && item.PublishTime.Before(b.started) {
+				continue
+			}
+			b.mu.Lock()
+			added, found := b.quietBefore[item.Feed]
+			b.mu.Unlock()
+			if found && item.PublishTime.Before(added) {
+				continue
+			}
+			for chat := range b.chats {
+				err := b.send(ctx, chat, formatTelegramItem(item))
+				if err != nil && ctx.Err() == nil {
+					fmt.Fprintln(os.Stderr, err)
+				}
+			}
+		}
+	}
+}
+
+// answer answers the commands sent to the bot until the context is cancelled.
+func (b *TelegramBot) answer(ctx context.Context) {
+	var offset int64
+	for {
+		updates, err := b.updates(ctx, offset)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(telegramRetry):
+			}
+			continue
+		}
+		for _, update := range updates {
+			offset = update.ID + 1
+			if update.Message == nil {
+				continue
+			}
+			reply := b.reply(update.Message.Chat.ID, update.Message.Text)
+			if reply == "" {
+				continue
+			}
+			err := b.send(ctx, update.Message.Chat.ID, reply)
+			if err != nil && ctx.Err() == nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
+
+type telegramUpdate struct {
+	ID      int64 `json:"update_id"`
+	Message *struct {
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// updates waits for the updates after the offset.
+func (b *TelegramBot) updates(ctx context.Context, offset int64) ([]telegramUpdate, error) {
+	query := url.Values{
+		"offset":          {strconv.FormatInt(offset, 10)},
+		"timeout":         {strconv.Itoa(int(telegramPollTimeout.Seconds()))},
+		"allowed_updates": {`["message"]`},
+	}
+	var updates []telegramUpdate
+	err := b.call(ctx, http.MethodGet, "getUpdates?"+query.Encode(), nil, &updates)
+	return updates, err
+}
+
+// send sends the message, formatted with Telegram's HTML, to the chat.
+func (b *TelegramBot) send(ctx context.Context, chat int64, text string) error {
+	body := struct {
+		ChatID    int64  `json:"chat_id"`
+		Text      string `json:"text"`
+		ParseMode string `json:"parse_mode"`
+	}{ChatID: chat, Text: text, ParseMode: "HTML"}
+	return b.call(ctx, http.MethodPost, "sendMessage", body, nil)
+}
+
+// call calls the method of the Bot API, decoding its result into result.
+func (b *TelegramBot) call(ctx context.Context, httpMethod, method string, body, result interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		if err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, httpMethod, b.api+"/"+method, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The error would include the URL, which holds the bot's token
+		if urlErr, ok := err.(*url.Error); ok {
+			return fmt.Errorf("telegram: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	var response struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return fmt.Errorf("telegram responded %s", resp.Status)
+	}
+	if !response.OK {
+		return fmt.Errorf("telegram: %s", response.Description)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+// reply returns the answer to a message from the chat, or nothing if the
+// message isn't a command.
+func (b *TelegramBot) reply(chat int64, text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	if !b.chats[chat] {
+		// The ID is given so that the chat can be allowed in the config
+		return fmt.Sprintf("This chat isn't allowed, add %d to serve.telegram.chats in the config", chat)
+	}
+	// Commands in groups may be addressed to the bot e.g. /list@rss_bot
+	command, args := strings.SplitN(fields[0], "@", 2)[0], fields[1:]
+	switch command {
+	case "/start", "/help":
+		return html.EscapeString(telegramHelp)
+	case "/list":
+		if b.list == nil {
+			return "Listing the feeds isn't supported"
+		}
+		urls, err := b.list()
+		if err != nil {
+			return html.EscapeString(err.Error())
+		}
+		lines := make([]string, len(urls))
+		for i, u := range urls {
+			lines[i] = html.EscapeString(u)
+		}
+		return joinTelegramLines(plural(len(urls), "feed"), lines)
+	case "/add":
+		if b.add == nil {
+			return "Adding feeds isn't supported"
+		}
+		if len(args) == 0 {
+			return "Usage: /add &lt;url&gt; [tags...]"
+		}
+		b.mu.Lock()
+		b.quietBefore[args[0]] = time.Now()
+		b.mu.Unlock()
+		err := b.add(args[0], args[1:])
+		if err != nil {
+			return html.EscapeString(err.Error())
+		}
+		return "Added " + html.EscapeString(args[0])
+	case "/read":
+		n := telegramReadItems
+		if len(args) > 0 {
+			var err error
+			n, err = strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return "Usage: /read [n]"
+			}
+		}
+		if n > telegramMaxReadItems {
+			n = telegramMaxReadItems
+		}
+		feedItems := b.server.Items()
+		if len(feedItems) > n {
+			feedItems = feedItems[:n]
+		}
+		if len(feedItems) == 0 {
+			return "No items yet"
+		}
+		lines := make([]string, len(feedItems))
+		for i, item := range feedItems {
+			lines[i] = formatTelegramItem(item)
+		}
+		return joinTelegramLines("", lines)
+	}
+	return "Unknown command, try /help"
+}
+
+// joinTelegramLines joins the lines below the heading, leaving out those which
+// would make the message too long for Telegram.
+func joinTelegramLines(heading string, lines []string) string {
+	var message strings.Builder
+	message.WriteString(heading)
+	for i, line := range lines {
+		more := fmt.Sprintf("\n… and %d more", len(lines)-i)
+		if message.Len()+len(line)+1+len(more) > telegramMaxMessage {
+			message.WriteString(more)
+			break
+		}
+		if message.Len() > 0 {
+			message.WriteString("\n")
+		}
+		message.WriteString(line)
+	}
+	return message.String()
+}
+
+// formatTelegramItem links the title of the item to it, followed by its feed.
+func formatTelegramItem(item FeedItem) string {
+	title := html.EscapeString(item.Title)
+	if len(item.Links) > 0 {
+		title = fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(item.Links[0]), title)
+	}
+	return title + " · " + html.EscapeString(item.Source())
+}
@@ -0,0 +1,143 @@
+package rss
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTelegramBotReply(t *testing.T) {
+	t.Parallel()
+	server, err := NewServer(nil)
+	assertEqual(t, nil, err)
+	now := time.Now()
+	server.ingest(&Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Example", Items: []Item{
+		{Title: "Older", Link: "https://example.com/1", PubDate: now.Add(-time.Hour).Format(time.RFC1123Z)},
+		{Title: "Newer <b>", Link: "https://example.com/2", PubDate: now.Format(time.RFC1123Z)},
+	}}}})
+	var added []string
+	bot := NewTelegramBot("token", []int64{1}, server,
+		OnAddFeed(func(url string, tags []string) error {
+			if url == "https://example.com/bad" {
+				return errors.New("already subscribed")
+			}
+			added = append(added, url+" "+strings.Join(tags, ","))
+			return nil
+		}),
+		ListFeeds(func() ([]string, error) {
+			return []string{"https://example.com/rss", "https://example.org/feed?a=1&b=2"}, nil
+		}),
+	)
+	defer bot.stop()
+
+	tests := []struct {
+		chat     int64
+		text     string
+		expected string
+	}{
+		{chat: 1, text: "hello", expected: ""},
+		{chat: 2, text: "/list", expected: "This chat isn't allowed, add 2 to serve.telegram.chats in the config"},
+		{chat: 1, text: "/list@rss_bot", expected: "2 feeds\nhttps://example.com/rss\nhttps://example.org/feed?a=1&amp;b=2"},
+		{chat: 1, text: "/read 1", expected: `<a href="https://example.com/2">Newer &lt;b&gt;</a> · Example`},
+		{chat: 1, text: "/read", expected: `<a href="https://example.com/2">Newer &lt;b&gt;</a> · Example` + "\n" + `<a href="https://example.com/1">Older</a> · Example`},
+		{chat: 1, text: "/read none", expected: "Usage: /read [n]"},
+		{chat: 1, text: "/add https://example.net/atom go news", expected: "Added https://example.net/atom"},
+		{chat: 1, text: "/add https://example.com/bad", expected: "already subscribed"},
+		{chat: 1, text: "/add", expected: "Usage: /add &lt;url&gt; [tags...]"},
+		{chat: 1, text: "/remove", expected: "Unknown command, try /help"},
+	}
+	for _, tc := range tests {
+		assertEqual(t, tc.expected, bot.reply(tc.chat, tc.text))
+	}
+	assertEqual(t, []string{"https://example.net/atom go,news"}, added)
+}
+
+func TestJoinTelegramLines(t *testing.T) {
+	t.Parallel()
+	line := strings.Repeat("a", 1500)
+	message := joinTelegramLines("5 feeds", []string{line, line, line, line, line})
+	assertEqual(t, true, len(message) <= telegramMaxMessage)
+	assertEqual(t, true, strings.HasSuffix(message, "\n… and 3 more"))
+}
+
+func TestTelegramBotRun(t *testing.T) {
+	t.Parallel()
+	sent := make(chan string, 10)
+	offsets := make(chan string, 100)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bottoken/getUpdates":
+			offset := r.URL.Query().Get("offset")
+			offsets <- offset
+			if offset == "0" {
+				fmt.Fprint(w, `{"ok":true,"result":[{"update_id":7,"message":{"chat":{"id":1},"text":"/read 1"}}]}`)
+				return
+			}
+			// Nothing more is sent while the request is held open
+			select {
+			case <-r.Context().Done():
+			case <-time.After(50 * time.Millisecond):
+			}
+			fmt.Fprint(w, `{"ok":true,"result":[]}`)
+		case "/bottoken/sendMessage":
+			var message struct {
+				ChatID int64  `json:"chat_id"`
+				Text   string `json:"text"`
+			}
+			json.NewDecoder(r.Body).Decode(&message)
+			sent <- fmt.Sprintf("%d: %s", message.ChatID, message.Text)
+			fmt.Fprint(w, `{"ok":true,"result":{}}`)
+		default:
+			fmt.Fprint(w, `{"ok":false,"description":"Not Found"}`)
+		}
+	}))
+	defer api.Close()
+
+	server, err := NewServer(nil)
+	assertEqual(t, nil, err)
+	now := time.Now()
+	server.ingest(&Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Example", Items: []Item{
+		{Title: "Known", Link: "https://example.com/1", PubDate: now.Add(-time.Hour).Format(time.RFC1123Z)},
+	}}}})
+	bot := NewTelegramBot("token", []int64{1}, server, OnAddFeed(func(string, []string) error { return nil }))
+	bot.api = api.URL + "/bottoken"
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		bot.Run(ctx)
+		close(done)
+	}()
+
+	receive := func() string {
+		select {
+		case message := <-sent:
+			return message
+		case <-time.After(5 * time.Second):
+			t.Fatal("nothing was sent")
+		}
+		return ""
+	}
+	assertEqual(t, `1: <a href="https://example.com/1">Known</a> · Example`, receive())
+
+	// New items are pushed, except those a feed added by /add had before
+	bot.reply(1, "/add https://example.org/rss")
+	server.ingest(&Feed{URL: "https://example.org/rss", RSS: RSS{Channel: Channel{Title: "Added", Items: []Item{
+		{Title: "Back catalogue", Link: "https://example.org/1", PubDate: now.Add(-time.Hour).Format(time.RFC1123Z)},
+	}}}})
+	server.ingest(&Feed{URL: "https://example.com/rss", RSS: RSS{Channel: Channel{Title: "Example", Items: []Item{
+		{Title: "Pushed", Link: "https://example.com/2", PubDate: time.Now().Format(time.RFC1123Z)},
+	}}}})
+	assertEqual(t, `1: <a href="https://example.com/2">Pushed</a> · Example`, receive())
+
+	// Updates are asked for after those answered
+	assertEqual(t, "0", <-offsets)
+	assertEqual(t, "8", <-offsets)
+	cancel()
+	<-done
+}
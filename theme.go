@@ -0,0 +1,64 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// Theme colours the panes of interactive mode.
+type Theme struct {
+	Background tcell.Color
+	Text       tcell.Color
+	// Border is the colour of the border of the pane without focus, and
+	// FocusedBorder that of the pane with it.
+	Border        tcell.Color
+	FocusedBorder tcell.Color
+	// SelectedText and SelectedBackground colour the selected item.
+	SelectedText       tcell.Color
+	SelectedBackground tcell.Color
+	// Bold shows the text of the list in bold.
+	Bold bool
+	// Monochrome leaves items uncoloured, so that their dates and titles
+	// stand out as much as the rest of the text, including when selected.
+	Monochrome bool
+}
+
+var (
+	// DefaultTheme is white text on black, with the focused pane bordered in
+	// green.
+	DefaultTheme = Theme{
+		Background:         tcell.ColorBlack,
+		Text:               tcell.ColorWhite,
+		Border:             tcell.ColorGray,
+		FocusedBorder:      tcell.ColorGreen,
+		SelectedText:       tcell.ColorBlack,
+		SelectedBackground: tcell.ColorWhite,
+	}
+	// HighContrastTheme is bold white text on black, uncoloured, with the
+	// focused pane and selected item in yellow, for readability with low
+	// vision.
+	HighContrastTheme = Theme{
+		Background:         tcell.ColorBlack,
+		Text:               tcell.ColorWhite,
+		Border:             tcell.ColorWhite,
+		FocusedBorder:      tcell.ColorYellow,
+		SelectedText:       tcell.ColorBlack,
+		SelectedBackground: tcell.ColorYellow,
+		Bold:               true,
+		Monochrome:         true,
+	}
+)
+
+// ParseTheme returns the theme with the given name, either "default" or
+// "high-contrast". An empty name is DefaultTheme.
+func ParseTheme(name string) (Theme, error) {
+	switch strings.ToLower(name) {
+	case "", "default":
+		return DefaultTheme, nil
+	case "high-contrast":
+		return HighContrastTheme, nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q, expected default or high-contrast", name)
+}
@@ -0,0 +1,26 @@
+package rss
+
+import "testing"
+
+func TestParseTheme(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Theme
+		err      bool
+	}{
+		{name: "", expected: DefaultTheme},
+		{name: "default", expected: DefaultTheme},
+		{name: "High-Contrast", expected: HighContrastTheme},
+		{name: "dark", err: true},
+	}
+	t.Parallel()
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			theme, err := ParseTheme(tc.name)
+			assertEqual(t, tc.err, err != nil)
+			assertEqual(t, tc.expected, theme)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package rss
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TitleOptions configures how the titles of a feed's items are rewritten.
+type TitleOptions struct {
+	// StripSiteName removes a trailing site name from titles, e.g.
+	// "Title | Site". Names after a dash are only removed if they match the
+	// title of the feed's channel, since dashes are common within titles.
+	StripSiteName bool `yaml:"strip_site_name"`
+	// Replace substitutes each match of a regular expression, in order.
+	Replace []TitleReplacement `yaml:"replace"`
+	// MaxLength truncates titles longer than this many characters.
+	MaxLength int `yaml:"max_length"`
+}
+
+// TitleReplacement replaces matches of Pattern with With, which can refer to
+// submatches e.g. "$1".
+type TitleReplacement struct {
+	Pattern string `yaml:"pattern"`
+	With    string `yaml:"with"`
+}
+
+// TitleTransform rewrites the title of an item from the channel with the
+// given title.
+type TitleTransform func(title, channel string) string
+
+// Compile builds the transform for the options. Returns an error if any of
+// the patterns are invalid.
+func (o TitleOptions) Compile() (TitleTransform, error) {
+	patterns := make([]*regexp.Regexp, len(o.Replace))
+	for i, r := range o.Replace {
+		var err error
+		patterns[i], err = regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("title replacement %d: %w", i+1, err)
+		}
+	}
+	return func(title, channel string) string {
+		if o.StripSiteName {
+			title = stripSiteName(title, channel)
+		}
+		for i, pattern := range patterns {
+			title = pattern.ReplaceAllString(title, o.Replace[i].With)
+		}
+		title = strings.TrimSpace(title)
+		if o.MaxLength > 0 {
+			title = truncate(title, o.MaxLength)
+		}
+		return title
+	}, nil
+}
+
+func stripSiteName(title, channel string) string {
+	if i := strings.LastIndex(title, " | "); i > 0 {
+		return title[:i]
+	}
+	for _, sep := range []string{" - ", " – ", " — "} {
+		i := strings.LastIndex(title, sep)
+		if i > 0 && strings.EqualFold(strings.TrimSpace(title[i+len(sep):]), strings.TrimSpace(channel)) {
+			return title[:i]
+		}
+	}
+	return title
+}
+
+// truncate shortens s to at most n characters, ending with an ellipsis if
+// anything was removed.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-1])) + "…"
+}
+
+// TransformTitles rewrites the titles of items from feeds as they are
+// unpacked, before they are filtered or displayed, keyed by the URL of the
+// feed.
+func TransformTitles(transforms map[string]TitleTransform) FetchOption {
+	return func(fo *fetchOptions) {
+		fo.titles = transforms
+	}
+}
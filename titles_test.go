@@ -0,0 +1,75 @@
+package rss
+
+import "testing"
+
+func TestTitleOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  TitleOptions
+		title    string
+		expected string
+	}{
+		{
+			name:     "Strip site name after pipe",
+			options:  TitleOptions{StripSiteName: true},
+			title:    "Go 1.18 released | Some Blog",
+			expected: "Go 1.18 released",
+		},
+		{
+			name:     "Strip channel title after dash",
+			options:  TitleOptions{StripSiteName: true},
+			title:    "Go 1.18 released - Example News",
+			expected: "Go 1.18 released",
+		},
+		{
+			name:     "Keep other dashes",
+			options:  TitleOptions{StripSiteName: true},
+			title:    "Generics - a first look",
+			expected: "Generics - a first look",
+		},
+		{
+			name: "Replace",
+			options: TitleOptions{Replace: []TitleReplacement{
+				{Pattern: `^\[Sponsored\] `},
+				{Pattern: `You won't believe (.+)`, With: "$1"},
+			}},
+			title:    "[Sponsored] You won't believe this trick",
+			expected: "this trick",
+		},
+		{
+			name:     "Truncate",
+			options:  TitleOptions{MaxLength: 10},
+			title:    "A very long title indeed",
+			expected: "A very lo…",
+		},
+		{
+			name:     "Short enough",
+			options:  TitleOptions{MaxLength: 10},
+			title:    "Short",
+			expected: "Short",
+		},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			transform, err := tc.options.Compile()
+			assertEqual(t, nil, err)
+			assertEqual(t, tc.expected, transform(tc.title, "Example News"))
+		})
+	}
+}
+
+func TestTitleTransformKeepsID(t *testing.T) {
+	feed := &Feed{URL: "https://example.com/rss"}
+	item := Item{Title: "Title | Site", Link: "https://example.com/1"}
+	original, err := NewFeedItem(feed, item)
+	assertEqual(t, nil, err)
+
+	feed.transformTitle, err = TitleOptions{StripSiteName: true}.Compile()
+	assertEqual(t, nil, err)
+	transformed, err := NewFeedItem(feed, item)
+	assertEqual(t, nil, err)
+	assertEqual(t, "Title", transformed.Title)
+	assertEqual(t, original.ID, transformed.ID)
+}
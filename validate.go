@@ -0,0 +1,237 @@
+package rss
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigError is a problem with the config, found on the given line.
+type ConfigError struct {
+	Line    int
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ValidateConfig checks the config in r more strictly than LoadConfig, and
+// returns every problem found in it: unknown keys, values of the wrong type,
+// and invalid durations, regular expressions, colours, time zones and URLs.
+// Returns an error if the config can't be parsed at all.
+func ValidateConfig(r io.Reader) ([]ConfigError, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	err = yaml.Unmarshal(b, &root)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []ConfigError
+	d := yaml.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	var config Config
+	err = d.Decode(&config)
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		for _, message := range typeErr.Errors {
+			problems = append(problems, parseYAMLError(message))
+		}
+	}
+
+	if len(root.Content) > 0 {
+		problems = append(problems, validateConfigNode(root.Content[0])...)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// parseYAMLError splits the line number from errors reported by yaml e.g.
+// "line 3: field foo not found in type rss.Config".
+func parseYAMLError(message string) ConfigError {
+	number, text, found := strings.Cut(strings.TrimPrefix(message, "line "), ": ")
+	line, err := strconv.Atoi(number)
+	if !strings.HasPrefix(message, "line ") || !found || err != nil {
+		return ConfigError{Message: message}
+	}
+	return ConfigError{Line: line, Message: text}
+}
+
+func validateConfigNode(node *yaml.Node) []ConfigError {
+	var problems []ConfigError
+	check := func(node *yaml.Node, err error) {
+		if err != nil {
+			problems = append(problems, ConfigError{Line: node.Line, Message: err.Error()})
+		}
+	}
+	checkColour := func(node *yaml.Node) {
+		_, err := ParseColour(node.Value)
+		check(node, err)
+	}
+
+	eachPair(node, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "block_links":
+			for _, pattern := range value.Content {
+				_, err := regexp.Compile(pattern.Value)
+				check(pattern, err)
+			}
+		case "sanitize":
+			_, err := ParseSanitizeMode(value.Value)
+			check(value, err)
+		case "cache_ttl":
+			_, err := time.ParseDuration(value.Value)
+			check(value, err)
+		case "releases":
+			_, err := ParseVersionConstraint(value.Value)
+			check(value, err)
+		case "future_items":
+			_, err := ParseFuturePolicy(value.Value)
+			check(value, err)
+		case "theme":
+			_, err := ParseTheme(value.Value)
+			check(value, err)
+		case "layout":
+			_, err := ParseLayout(value.Value, 0)
+			check(value, err)
+		case "list_share":
+			// Values which aren't numbers are reported by the decoder
+			if share, err := strconv.Atoi(value.Value); err == nil {
+				_, err = ParseLayout("", share)
+				check(value, err)
+			}
+		case "time_zone":
+			_, err := time.LoadLocation(value.Value)
+			check(value, err)
+		case "highlight":
+			eachPair(value, func(_, colour *yaml.Node) {
+				checkColour(colour)
+			})
+		case "age_colours":
+			eachPair(value, func(age, colour *yaml.Node) {
+				_, err := time.ParseDuration(age.Value)
+				check(age, err)
+				if colour.Value != "default" {
+					checkColour(colour)
+				}
+			})
+		case "rules":
+			for _, rule := range value.Content {
+				eachPair(rule, func(key, value *yaml.Node) {
+					switch key.Value {
+					case "when":
+						problems = append(problems, validateCondition(value)...)
+					case "highlight":
+						checkColour(value)
+					}
+				})
+			}
+		case "feeds":
+			eachPair(value, func(feed, settings *yaml.Node) {
+				check(feed, validateURL(feed.Value))
+				eachPair(settings, func(key, value *yaml.Node) {
+					switch key.Value {
+					case "sanitize":
+						_, err := ParseSanitizeMode(value.Value)
+						check(value, err)
+					case "titles":
+						problems = append(problems, validateTitles(value)...)
+					case "releases":
+						_, err := ParseVersionConstraint(value.Value)
+						check(value, err)
+					}
+				})
+			})
+		case "archive":
+			eachPair(value, func(key, value *yaml.Node) {
+				if key.Value == "service" {
+					check(value, validateURL(value.Value))
+				}
+			})
+		}
+	})
+	return problems
+}
+
+func validateCondition(node *yaml.Node) []ConfigError {
+	var problems []ConfigError
+	check := func(node *yaml.Node, err error) {
+		if err != nil {
+			problems = append(problems, ConfigError{Line: node.Line, Message: err.Error()})
+		}
+	}
+	eachPair(node, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "title", "link", "feed":
+			_, err := regexp.Compile(value.Value)
+			check(value, err)
+		case "older_than", "newer_than":
+			_, err := time.ParseDuration(value.Value)
+			check(value, err)
+		case "all", "any":
+			for _, condition := range value.Content {
+				problems = append(problems, validateCondition(condition)...)
+			}
+		case "not":
+			problems = append(problems, validateCondition(value)...)
+		}
+	})
+	return problems
+}
+
+func validateTitles(node *yaml.Node) []ConfigError {
+	var problems []ConfigError
+	eachPair(node, func(key, replace *yaml.Node) {
+		if key.Value != "replace" {
+			return
+		}
+		for _, r := range replace.Content {
+			eachPair(r, func(key, value *yaml.Node) {
+				if key.Value != "pattern" {
+					return
+				}
+				_, err := regexp.Compile(value.Value)
+				if err != nil {
+					problems = append(problems, ConfigError{Line: value.Line, Message: err.Error()})
+				}
+			})
+		}
+	})
+	return problems
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", raw)
+	}
+	return nil
+}
+
+// eachPair calls fn with each key and value if the node is a mapping.
+func eachPair(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		fn(node.Content[i], node.Content[i+1])
+	}
+}
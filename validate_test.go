@@ -0,0 +1,50 @@
+package rss
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	raw := `date_format: iso
+time_zone: Mars/Olympus
+colour: red
+highlight:
+  go: mauve
+age_colours:
+  6h: yellow
+  soon: default
+rules:
+  - when:
+      title: "(unclosed"
+      any:
+        - older_than: 2 days
+    highlight: red
+feeds:
+  https://example.com/rss:
+    name: Example
+    nickname: Ex
+    titles:
+      replace:
+        - pattern: "[a-"
+  example.com: {}
+theme: dark
+layout: grid
+list_share: 5
+`
+	problems, err := ValidateConfig(strings.NewReader(raw))
+	assertEqual(t, nil, err)
+	var lines []int
+	for _, problem := range problems {
+		lines = append(lines, problem.Line)
+	}
+	assertEqual(t, []int{2, 3, 5, 8, 11, 13, 18, 21, 22, 23, 24, 25}, lines)
+	assertEqual(t, "line 3: field colour not found in type rss.Config", problems[1].Error())
+
+	problems, err = ValidateConfig(strings.NewReader("rules:\n  - when:\n      title: go\n"))
+	assertEqual(t, nil, err)
+	assertEqual(t, 0, len(problems))
+
+	_, err = ValidateConfig(strings.NewReader("rules: [\n"))
+	assertEqual(t, true, err != nil)
+}
@@ -0,0 +1,117 @@
+package rss
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// wasmTimeout is how long a module may take to filter or score an item.
+	wasmTimeout = time.Second
+	// wasmMemoryPages limits the memory of a module to 16MiB.
+	wasmMemoryPages = 256
+)
+
+// WasmModule runs filter and score functions compiled to WebAssembly, so that
+// custom logic can be added without recompiling the reader. Modules are
+// sandboxed: they are given no imports, so can't reach files or the network,
+// and each call is limited in time and memory.
+//
+// A module exports its memory, alloc(size i32) i32 returning where size bytes
+// may be written, and one or both of
+//
+//	filter(ptr, len i32) i32 returning 0 to hide the item
+//	score(ptr, len i32) i32 returning a score to add to the item's
+//
+// with the item written at ptr as JSON, as served by 'rss serve'. alloc is
+// called before each call, so the module may reuse the same memory each time.
+//
+// The module is run by wazero, which is only built in with the wasmfilters
+// build tag so that the reader stays small without it.
+type WasmModule struct {
+	path string
+	errs *ErrorLog
+	// Calls share the module's memory, so are made one at a time
+	mu       sync.Mutex
+	instance wasmInstance
+}
+
+// wasmInstance is a module which has been instantiated by the runtime.
+type wasmInstance interface {
+	// exports returns true if the module exports the function.
+	exports(function string) bool
+	// call writes the input to the module's memory, calls the function with
+	// where it was written and returns the function's result.
+	call(function string, input []byte) (uint64, error)
+	close() error
+}
+
+// LoadWasm loads the module at the path.
+func LoadWasm(path string, errs *ErrorLog) (*WasmModule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := newWasmInstance(b)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %w", path, err)
+	}
+	return &WasmModule{path: path, errs: errs, instance: instance}, nil
+}
+
+func (m *WasmModule) call(function string, item FeedItem) (uint64, error) {
+	input, err := json.Marshal(item)
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	result, err := m.instance.call(function, input)
+	if err != nil {
+		return 0, fmt.Errorf("error calling %s in %s: %w", function, m.path, err)
+	}
+	return result, nil
+}
+
+// Filter returns the module's filter, which keeps every item if the module
+// has none. Items are kept if the filter fails, and the failure recorded.
+func (m *WasmModule) Filter() Filter {
+	if !m.instance.exports("filter") {
+		return func(FeedItem) bool { return true }
+	}
+	return func(item FeedItem) bool {
+		keep, err := m.call("filter", item)
+		if err != nil {
+			m.errs.Add(err)
+			return true
+		}
+		return uint32(keep) != 0
+	}
+}
+
+// Score returns a display option adding the module's score to each item's,
+// which leaves items as they are if the module has none.
+func (m *WasmModule) Score() DisplayOption {
+	if !m.instance.exports("score") {
+		return func(item FeedItem) FeedItem { return item }
+	}
+	return func(item FeedItem) FeedItem {
+		score, err := m.call("score", item)
+		if err != nil {
+			m.errs.Add(err)
+			return item
+		}
+		item.Score += int(int32(score))
+		return item
+	}
+}
+
+// Close frees the module.
+func (m *WasmModule) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.instance.close()
+}
@@ -0,0 +1,9 @@
+//go:build !wasmfilters
+
+package rss
+
+import "errors"
+
+func newWasmInstance([]byte) (wasmInstance, error) {
+	return nil, errors.New("WASM filters need rss to be built with -tags wasmfilters")
+}
@@ -0,0 +1,92 @@
+package rss
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeWasm runs Go functions in place of a module's, given the item as JSON.
+type fakeWasm map[string]func(item FeedItem) (uint64, error)
+
+func (f fakeWasm) exports(function string) bool {
+	_, found := f[function]
+	return found
+}
+
+func (f fakeWasm) call(function string, input []byte) (uint64, error) {
+	var item FeedItem
+	err := json.Unmarshal(input, &item)
+	if err != nil {
+		return 0, err
+	}
+	return f[function](item)
+}
+
+func (f fakeWasm) close() error {
+	return nil
+}
+
+func TestWasmModule(t *testing.T) {
+	t.Parallel()
+	module := &WasmModule{path: "filter.wasm", instance: fakeWasm{
+		"filter": func(item FeedItem) (uint64, error) {
+			if item.Title == "Broken" {
+				return 0, errors.New("unreachable")
+			}
+			if strings.Contains(item.Title, "Sponsored") {
+				return 0, nil
+			}
+			return 1, nil
+		},
+		"score": func(item FeedItem) (uint64, error) {
+			if strings.Contains(item.Title, "Go") {
+				return 3, nil
+			}
+			// Scores are signed 32 bit integers
+			return uint64(uint32(0xffffffff)), nil
+		},
+	}}
+	tests := []struct {
+		title string
+		keep  bool
+		score int
+	}{
+		{title: "Go 1.18 released", keep: true, score: 4},
+		{title: "Sponsored: buy this", keep: false, score: 0},
+		// Items are kept if the filter fails
+		{title: "Broken", keep: true, score: 0},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+			item := FeedItem{Title: tc.title, Score: 1}
+			assertEqual(t, tc.keep, module.Filter()(item))
+			assertEqual(t, tc.score, module.Score()(item).Score)
+		})
+	}
+}
+
+func TestWasmModuleWithoutFunctions(t *testing.T) {
+	t.Parallel()
+	module := &WasmModule{path: "empty.wasm", instance: fakeWasm{}}
+	item := FeedItem{Title: "Title", Score: 1}
+	assertEqual(t, true, module.Filter()(item))
+	assertEqual(t, item, module.Score()(item))
+}
+
+func TestWasmModuleErrors(t *testing.T) {
+	t.Parallel()
+	var errs ErrorLog
+	module := &WasmModule{path: "broken.wasm", errs: &errs, instance: fakeWasm{
+		"filter": func(FeedItem) (uint64, error) { return 0, errors.New("unreachable") },
+		"score":  func(FeedItem) (uint64, error) { return 0, errors.New("unreachable") },
+	}}
+	item := FeedItem{Title: "Title", Score: 1}
+	assertEqual(t, true, module.Filter()(item))
+	assertEqual(t, item, module.Score()(item))
+	assertEqual(t, 2, len(errs.Errors()))
+	assertEqual(t, true, strings.Contains(errs.Errors()[0].Error(), "broken.wasm"))
+}
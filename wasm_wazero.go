@@ -0,0 +1,68 @@
+//go:build wasmfilters
+
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+type wazeroInstance struct {
+	runtime wazero.Runtime
+	module  api.Module
+}
+
+func newWasmInstance(b []byte) (wasmInstance, error) {
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(wasmMemoryPages).
+		// Calls which run past their deadline are stopped
+		WithCloseOnContextDone(true))
+	// No host modules are instantiated, so the module can only compute
+	module, err := runtime.Instantiate(ctx, b)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, err
+	}
+	if module.Memory() == nil || module.ExportedFunction("alloc") == nil {
+		runtime.Close(ctx)
+		return nil, errors.New("the module must export its memory and alloc")
+	}
+	return &wazeroInstance{runtime: runtime, module: module}, nil
+}
+
+func (w *wazeroInstance) exports(function string) bool {
+	return w.module.ExportedFunction(function) != nil
+}
+
+func (w *wazeroInstance) call(function string, input []byte) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wasmTimeout)
+	defer cancel()
+	results, err := w.module.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return 0, err
+	}
+	if len(results) != 1 {
+		return 0, errors.New("alloc must return where to write")
+	}
+	ptr := uint32(results[0])
+	if !w.module.Memory().Write(ptr, input) {
+		return 0, fmt.Errorf("alloc returned %d, which is outside of memory", ptr)
+	}
+	results, err = w.module.ExportedFunction(function).Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return 0, err
+	}
+	if len(results) != 1 {
+		return 0, fmt.Errorf("%s must return one result", function)
+	}
+	return results[0], nil
+}
+
+func (w *wazeroInstance) close() error {
+	return w.runtime.Close(context.Background())
+}
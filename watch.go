@@ -0,0 +1,54 @@
+package rss
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch calls changed whenever one of the files is written, created, removed
+// or replaced, until stop is called. The folders holding the files are
+// watched rather than the files themselves, so that files which editors
+// replace by renaming, or which don't exist yet, are still noticed.
+func Watch(files []string, changed func(file string)) (stop func() error, err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	watched := make(map[string]string)
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		watched[abs] = file
+		err = watcher.Add(filepath.Dir(abs))
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// Changes to permissions alone don't change the contents
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				if file, found := watched[filepath.Clean(event.Name)]; found {
+					changed(file)
+				}
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return watcher.Close, nil
+}
@@ -0,0 +1,53 @@
+package rss
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	feedsFile := filepath.Join(dir, "urls.txt")
+	configFile := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(feedsFile, []byte("https://example.com/feed\n"), 0644)
+	assertEqual(t, nil, err)
+
+	changed := make(chan string, 10)
+	stop, err := Watch([]string{feedsFile, configFile}, func(file string) {
+		changed <- file
+	})
+	assertEqual(t, nil, err)
+	defer stop()
+
+	next := func() string {
+		select {
+		case file := <-changed:
+			return file
+		case <-time.After(2 * time.Second):
+			return ""
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case <-changed:
+			case <-time.After(100 * time.Millisecond):
+				return
+			}
+		}
+	}
+
+	// Other files in the folder are ignored
+	err = os.WriteFile(filepath.Join(dir, "history"), []byte("x"), 0644)
+	assertEqual(t, nil, err)
+	// Files replaced by renaming, and files which didn't exist, are noticed
+	err = WriteFileAtomic(feedsFile, []byte("https://example.com/other\n"), 0644)
+	assertEqual(t, nil, err)
+	assertEqual(t, feedsFile, next())
+	drain()
+	err = os.WriteFile(configFile, []byte("max_age: 48\n"), 0644)
+	assertEqual(t, nil, err)
+	assertEqual(t, configFile, next())
+}